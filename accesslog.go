@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AccessOp 访问日志中的操作类型
+type AccessOp uint8
+
+const (
+	AccessGet    AccessOp = iota + 1 // 读取
+	AccessSet                        // 写入
+	AccessLoad                       // loader加载
+	AccessDelete                     // 删除
+)
+
+// 访问日志格式: 文件头为4字节魔数"DCAL"和1字节版本，之后是定长记录
+// 记录: 8字节键哈希、8字节时间(Unix纳秒)、4字节耗时(微秒)、1字节操作、1字节级别、1字节是否命中、1字节保留，均为大端序
+const (
+	accessLogMagic      = "DCAL"
+	accessLogVersion    = 1
+	accessLogRecordSize = 24
+	accessLogBuffer     = 4096
+)
+
+// AccessRecord 一条访问记录
+type AccessRecord struct {
+	KeyHash uint64        // 键的FNV-64a哈希，不记录原始键
+	Time    time.Time     // 操作开始时间
+	Latency time.Duration // 耗时(精度为微秒)
+	Op      AccessOp      // 操作类型
+	Level   uint8         // 提供结果的级别: 0表示无(未命中或写入)，1表示L1，2表示L2
+	Hit     bool          // 是否命中
+}
+
+// accessLog 采样的访问日志，由后台协程批量写入，队列满时丢弃记录而不阻塞缓存操作
+type accessLog struct {
+	records chan AccessRecord
+	every   uint64
+	counter uint64
+	dropped int64
+	done    sync.WaitGroup
+}
+
+// newAccessLog 创建访问日志并启动写入协程
+func newAccessLog(w io.Writer, every int, logf func(format string, v ...interface{})) *accessLog {
+	if every <= 0 {
+		every = 1
+	}
+	l := &accessLog{
+		records: make(chan AccessRecord, accessLogBuffer),
+		every:   uint64(every),
+	}
+	l.done.Add(1)
+	go l.writeRoutine(w, logf)
+	return l
+}
+
+// writeRoutine 写入文件头和记录，队列暂时为空时刷新缓冲
+func (l *accessLog) writeRoutine(w io.Writer, logf func(format string, v ...interface{})) {
+	defer l.done.Done()
+
+	bw := bufio.NewWriter(w)
+	failed := false
+	write := func(b []byte) {
+		if failed {
+			return
+		}
+		if _, err := bw.Write(b); err != nil {
+			failed = true
+			logf("dancache: write access log: %v", err)
+		}
+	}
+	write(append([]byte(accessLogMagic), accessLogVersion))
+
+	var buf [accessLogRecordSize]byte
+	for {
+		select {
+		case r, ok := <-l.records:
+			if !ok {
+				if !failed {
+					bw.Flush()
+				}
+				return
+			}
+			encodeAccessRecord(buf[:], r)
+			write(buf[:])
+		default:
+			if !failed {
+				bw.Flush()
+			}
+			r, ok := <-l.records
+			if !ok {
+				return
+			}
+			encodeAccessRecord(buf[:], r)
+			write(buf[:])
+		}
+	}
+}
+
+// add 按采样率记录一次访问
+func (l *accessLog) add(r AccessRecord) {
+	if atomic.AddUint64(&l.counter, 1)%l.every != 0 {
+		return
+	}
+	select {
+	case l.records <- r:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+	}
+}
+
+// close 写入剩余的记录并停止写入协程
+func (l *accessLog) close() {
+	close(l.records)
+	l.done.Wait()
+}
+
+// encodeAccessRecord 编码一条记录
+func encodeAccessRecord(buf []byte, r AccessRecord) {
+	binary.BigEndian.PutUint64(buf[0:8], r.KeyHash)
+	binary.BigEndian.PutUint64(buf[8:16], uint64(r.Time.UnixNano()))
+	binary.BigEndian.PutUint32(buf[16:20], uint32(r.Latency/time.Microsecond))
+	buf[20] = byte(r.Op)
+	buf[21] = r.Level
+	buf[22] = 0
+	if r.Hit {
+		buf[22] = 1
+	}
+	buf[23] = 0
+}
+
+// ReadAccessLog 读取访问日志，对每条记录调用fn，fn返回错误时停止
+func ReadAccessLog(r io.Reader, fn func(AccessRecord) error) error {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(accessLogMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return err
+	}
+	if string(header[:len(accessLogMagic)]) != accessLogMagic {
+		return errors.New("不是缓存访问日志")
+	}
+	if header[len(accessLogMagic)] > accessLogVersion {
+		return errors.New("不支持的访问日志版本")
+	}
+
+	var buf [accessLogRecordSize]byte
+	for {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		record := AccessRecord{
+			KeyHash: binary.BigEndian.Uint64(buf[0:8]),
+			Time:    time.Unix(0, int64(binary.BigEndian.Uint64(buf[8:16]))),
+			Latency: time.Duration(binary.BigEndian.Uint32(buf[16:20])) * time.Microsecond,
+			Op:      AccessOp(buf[20]),
+			Level:   buf[21],
+			Hit:     buf[22] == 1,
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// recordAccess 记录一次访问，level为nil或未命中时不记录级别
+func (c *MultiLevelCache) recordAccess(op AccessOp, key string, start time.Time, level *CacheLevel, hit bool) {
+	if c.accessLog == nil {
+		return
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	r := AccessRecord{
+		KeyHash: h.Sum64(),
+		Time:    start,
+		Latency: time.Since(start),
+		Op:      op,
+		Hit:     hit,
+	}
+	if level != nil && hit {
+		r.Level = uint8(*level) + 1
+	}
+	c.accessLog.add(r)
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strings"
+	"testing"
+)
+
+// readAccessRecords 读取访问日志中的全部记录
+func readAccessRecords(t *testing.T, data []byte) []AccessRecord {
+	t.Helper()
+	var records []AccessRecord
+	err := ReadAccessLog(bytes.NewReader(data), func(r AccessRecord) error {
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadAccessLog: %v", err)
+	}
+	return records
+}
+
+func TestAccessLogRecordsOperations(t *testing.T) {
+	var buf bytes.Buffer
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.AccessLog = &buf
+	})
+	if err := c.Set("a", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("a")
+	c.Get("missing")
+	if err := c.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	// Close写出剩余的记录后才能读取
+	c.Close()
+
+	records := readAccessRecords(t, buf.Bytes())
+	type want struct {
+		key   string
+		op    AccessOp
+		level uint8
+		hit   bool
+	}
+	wants := []want{
+		{"a", AccessSet, 0, false},
+		{"a", AccessGet, 1, true},
+		{"missing", AccessGet, 0, false},
+		{"a", AccessDelete, 0, false},
+	}
+	if len(records) != len(wants) {
+		t.Fatalf("got %d records, want %d", len(records), len(wants))
+	}
+	for i, w := range wants {
+		h := fnv.New64a()
+		h.Write([]byte(w.key))
+		r := records[i]
+		if r.KeyHash != h.Sum64() || r.Op != w.op || r.Level != w.level || r.Hit != w.hit {
+			t.Errorf("record %d = %+v, want key %q op %d level %d hit %v", i, r, w.key, w.op, w.level, w.hit)
+		}
+		if r.Time.IsZero() {
+			t.Errorf("record %d has zero time", i)
+		}
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.AccessLog = &buf
+		config.AccessLogSampleEvery = 3
+	})
+	for i := 0; i < 9; i++ {
+		c.Get("k")
+	}
+	c.Close()
+
+	if records := readAccessRecords(t, buf.Bytes()); len(records) != 3 {
+		t.Errorf("got %d records with SampleEvery 3 over 9 accesses, want 3", len(records))
+	}
+}
+
+func TestReadAccessLogRejectsOtherFormats(t *testing.T) {
+	noop := func(AccessRecord) error { return nil }
+	if err := ReadAccessLog(strings.NewReader("XXXX\x01"), noop); err == nil {
+		t.Error("ReadAccessLog accepted a wrong magic")
+	}
+	if err := ReadAccessLog(strings.NewReader(accessLogMagic+"\x02"), noop); err == nil {
+		t.Error("ReadAccessLog accepted a newer version")
+	}
+	// 截断的记录报告错误而不是静默结束
+	if err := ReadAccessLog(strings.NewReader(accessLogMagic+"\x01short"), noop); err == nil {
+		t.Error("ReadAccessLog accepted a truncated record")
+	}
+}
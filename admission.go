@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// loadLimiter 限制同时进行的加载数量，在冷启动或大面积失效引发的未命中风暴中保护源数据库
+type loadLimiter struct {
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+// newLoadLimiter 创建加载限流器
+func newLoadLimiter(maxConcurrent int, timeout time.Duration) *loadLimiter {
+	return &loadLimiter{
+		slots:   make(chan struct{}, maxConcurrent),
+		timeout: timeout,
+	}
+}
+
+// acquire 获取加载名额，等待超过timeout时返回false
+func (l *loadLimiter) acquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.timeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// release 归还加载名额
+func (l *loadLimiter) release() {
+	<-l.slots
+}
+
+// admitLoad 执行加载，超过并发限制且排队超时时放弃加载并返回ErrOverloaded
+func (c *MultiLevelCache) admitLoad(load func() (interface{}, error)) (interface{}, error) {
+	if c.loadLimiter == nil {
+		return c.callLoader(load)
+	}
+	if !c.loadLimiter.acquire() {
+		atomic.AddInt64(&c.shedLoads, 1)
+		return nil, ErrOverloaded
+	}
+	defer c.loadLimiter.release()
+	return c.callLoader(load)
+}
+
+// callLoader 调用loader，发生panic时返回*PanicError
+func (c *MultiLevelCache) callLoader(load func() (interface{}, error)) (val interface{}, err error) {
+	if perr := c.protect("loader", func() { val, err = load() }); perr != nil {
+		return nil, perr
+	}
+	return val, err
+}
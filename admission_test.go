@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoadAdmissionShedsExcessLoads(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.MaxConcurrentLoads = 1
+		config.LoadQueueTimeout = 10 * time.Millisecond
+	})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad("slow", 60, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "v", nil
+		})
+		done <- err
+	}()
+	<-started
+
+	// 名额被占用时排队等待LoadQueueTimeout后放弃，不调用loader
+	called := false
+	_, err := c.GetOrLoad("other", 60, func() (interface{}, error) {
+		called = true
+		return "v", nil
+	})
+	if !errors.Is(err, ErrOverloaded) {
+		t.Errorf("GetOrLoad while saturated = %v, want ErrOverloaded", err)
+	}
+	if called {
+		t.Error("loader was called for a shed load")
+	}
+	if _, err := c.GetOrLoadMulti([]string{"m1", "m2"}, 60, func(keys []string) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("loader called for %v", keys)
+	}); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("GetOrLoadMulti while saturated = %v, want ErrOverloaded", err)
+	}
+	if shed := c.GetStats()["shed_loads"]; shed != int64(2) {
+		t.Errorf("shed_loads = %v, want 2", shed)
+	}
+
+	// 名额归还后加载恢复
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.GetOrLoad("other", 60, func() (interface{}, error) { return "v", nil }); err != nil || v != "v" {
+		t.Errorf("GetOrLoad after release = %v, %v; want v, nil", v, err)
+	}
+}
+
+func TestLoadLimiterQueuesUntilRelease(t *testing.T) {
+	l := newLoadLimiter(1, time.Second)
+	if !l.acquire() {
+		t.Fatal("first acquire failed")
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.release()
+	}()
+	if !l.acquire() {
+		t.Error("queued acquire failed although a slot was released in time")
+	}
+	l.release()
+
+	noQueue := newLoadLimiter(1, 0)
+	noQueue.acquire()
+	if noQueue.acquire() {
+		t.Error("acquire without LoadQueueTimeout waited for a slot")
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultRingReplicas 一致性哈希环上每个节点的默认虚拟节点数
+const defaultRingReplicas = 100
+
+// HashRing 一致性哈希环，用于判断键是否归本实例所有
+// 与集群的路由层使用相同的节点列表和虚拟节点数时，两者对键的归属判断一致
+type HashRing struct {
+	self     string
+	replicas int
+	mu       sync.RWMutex
+	hashes   []uint32
+	owners   map[uint32]string
+}
+
+// NewHashRing 创建新的一致性哈希环，self为本实例在nodes中的名称，replicas为0时默认100
+func NewHashRing(self string, nodes []string, replicas int) *HashRing {
+	if replicas <= 0 {
+		replicas = defaultRingReplicas
+	}
+	r := &HashRing{self: self, replicas: replicas}
+	r.SetNodes(nodes)
+	return r
+}
+
+// SetNodes 替换集群的节点列表，用于扩缩容后更新归属
+func (r *HashRing) SetNodes(nodes []string) {
+	hashes := make([]uint32, 0, len(nodes)*r.replicas)
+	owners := make(map[uint32]string, len(nodes)*r.replicas)
+	for _, node := range nodes {
+		for i := 0; i < r.replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + node))
+			hashes = append(hashes, h)
+			owners[h] = node
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	r.mu.Lock()
+	r.hashes = hashes
+	r.owners = owners
+	r.mu.Unlock()
+}
+
+// Owner 返回键所属的节点，环为空时返回空字符串
+func (r *HashRing) Owner(key string) string {
+	h := crc32.ChecksumIEEE([]byte(key))
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
+
+// Owns 判断键是否归本实例所有，可直接用作CacheConfig.KeyOwner
+// 环为空时视为所有键都归本实例所有
+func (r *HashRing) Owns(key string) bool {
+	owner := r.Owner(key)
+	return owner == "" || owner == r.self
+}
+
+// ownsKey 判断键是否可以升级到本实例的L1
+func (c *MultiLevelCache) ownsKey(key string) bool {
+	owner := c.config().KeyOwner
+	if owner == nil || c.keyOwned(owner, key) {
+		return true
+	}
+	atomic.AddInt64(&c.affinitySkips, 1)
+	return false
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestHashRingPartitionsKeys(t *testing.T) {
+	nodes := []string{"a", "b", "c"}
+	rings := make([]*HashRing, len(nodes))
+	for i, node := range nodes {
+		rings[i] = NewHashRing(node, nodes, 0)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		owners := 0
+		for _, r := range rings {
+			if r.Owns(key) {
+				owners++
+			}
+		}
+		if owners != 1 {
+			t.Fatalf("%s owned by %d nodes, want 1", key, owners)
+		}
+		counts[rings[0].Owner(key)]++
+	}
+	for _, node := range nodes {
+		if counts[node] < 500 {
+			t.Errorf("node %s owns %d of 3000 keys, distribution too skewed: %v", node, counts[node], counts)
+		}
+	}
+
+	// 环为空时所有键都归本实例所有
+	empty := NewHashRing("a", nil, 0)
+	if !empty.Owns("any") {
+		t.Error("empty ring does not own keys")
+	}
+	rings[0].SetNodes([]string{"a"})
+	if !rings[0].Owns("key-1") {
+		t.Error("single node ring does not own key-1 after SetNodes")
+	}
+}
+
+func TestKeyOwnerLimitsPromotion(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newRedisTestCache(t, mr, nil)
+	reader := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+		config.KeyOwner = func(key string) bool { return key == "mine" }
+	})
+	for _, key := range []string{"mine", "theirs"} {
+		if err := writer.Set(key, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := reader.Get(key); !ok || v != "v" {
+			t.Fatalf("Get(%s) = %v, %v; want v", key, v, ok)
+		}
+	}
+
+	if _, ok := reader.shardFor("mine").load("mine"); !ok {
+		t.Error("owned key was not promoted to L1")
+	}
+	if _, ok := reader.shardFor("theirs").load("theirs"); ok {
+		t.Error("key owned by another instance was promoted to L1")
+	}
+	if n := reader.GetStats()["affinity_skipped_promotions"]; n != int64(1) {
+		t.Errorf("affinity_skipped_promotions = %v, want 1", n)
+	}
+}
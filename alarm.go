@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// AlarmKind 告警类型
+type AlarmKind int
+
+const (
+	AlarmHitRatio        AlarmKind = iota // 窗口内命中率低于阈值
+	AlarmEvictionRate                     // 窗口内每秒淘汰数高于阈值
+	AlarmInvalidationLag                  // 窗口内失效传播的最大延迟高于阈值
+)
+
+// String 返回告警类型的名称
+func (k AlarmKind) String() string {
+	switch k {
+	case AlarmHitRatio:
+		return "hit_ratio"
+	case AlarmEvictionRate:
+		return "eviction_rate"
+	case AlarmInvalidationLag:
+		return "invalidation_lag"
+	default:
+		return "unknown"
+	}
+}
+
+// defaultAlarmWindow 告警统计窗口的默认长度
+const defaultAlarmWindow = time.Minute
+
+// defaultAlarmMinLookups 命中率告警要求窗口内的最少查找次数，避免低流量时误报
+const defaultAlarmMinLookups = 100
+
+// Alarm 一次告警状态变化
+type Alarm struct {
+	Kind      AlarmKind
+	Firing    bool          // true表示开始告警，false表示恢复
+	Value     float64       // 窗口内的命中率、每秒淘汰数或最大传播延迟(秒)
+	Threshold float64       // 配置的阈值
+	Window    time.Duration // 统计窗口
+}
+
+// alarmState 告警检查的上一个窗口快照和当前告警状态
+type alarmState struct {
+	hits, lookups, evictions int64
+	firing                   [3]int32 // 各类型是否正在告警
+	fired                    int64    // 开始告警的次数
+}
+
+// alarmsEnabled 判断是否配置了告警
+func (c *MultiLevelCache) alarmsEnabled() bool {
+	config := c.config()
+	return config.HitRatioAlarm > 0 || config.EvictionRateAlarm > 0 || config.InvalidationLagAlarm > 0
+}
+
+// alarmWindow 返回告警统计窗口
+func (c *MultiLevelCache) alarmWindow() time.Duration {
+	if window := c.config().AlarmWindow; window > 0 {
+		return window
+	}
+	return defaultAlarmWindow
+}
+
+// alarmRoutine 每个窗口检查一次命中率和淘汰速率
+func (c *MultiLevelCache) alarmRoutine(stop <-chan struct{}) {
+	window := c.alarmWindow()
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	c.snapshotAlarms()
+	for {
+		select {
+		case <-ticker.C:
+			c.checkAlarms(window)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// alarmCounters 返回累计的命中、查找和淘汰次数
+func (c *MultiLevelCache) alarmCounters() (hits, lookups, evictions int64) {
+	hits = atomic.LoadInt64(&c.hits.l1Hits) + atomic.LoadInt64(&c.hits.l2Hits)
+	lookups = hits + atomic.LoadInt64(&c.hits.misses)
+	evictions = atomic.LoadInt64(&c.hits.evictions)
+	return
+}
+
+// snapshotAlarms 记录窗口起点的计数，暂停后恢复时不把暂停期间的计数算入第一个窗口
+func (c *MultiLevelCache) snapshotAlarms() {
+	s := &c.alarms
+	s.hits, s.lookups, s.evictions = c.alarmCounters()
+	c.takeLagWindow()
+}
+
+// checkAlarms 根据窗口内的计数变化判断告警开始或恢复
+func (c *MultiLevelCache) checkAlarms(window time.Duration) {
+	config := c.config()
+	s := &c.alarms
+	hits, lookups, evictions := c.alarmCounters()
+	dHits, dLookups, dEvictions := hits-s.hits, lookups-s.lookups, evictions-s.evictions
+	s.hits, s.lookups, s.evictions = hits, lookups, evictions
+
+	if config.HitRatioAlarm > 0 {
+		minLookups := config.AlarmMinLookups
+		if minLookups <= 0 {
+			minLookups = defaultAlarmMinLookups
+		}
+		// 查找次数不足时保持当前状态
+		if dLookups >= minLookups {
+			ratio := float64(dHits) / float64(dLookups)
+			c.updateAlarm(AlarmHitRatio, ratio < config.HitRatioAlarm, ratio, config.HitRatioAlarm, window)
+		}
+	}
+	if config.EvictionRateAlarm > 0 {
+		rate := float64(dEvictions) / window.Seconds()
+		c.updateAlarm(AlarmEvictionRate, rate > config.EvictionRateAlarm, rate, config.EvictionRateAlarm, window)
+	}
+	if config.InvalidationLagAlarm > 0 {
+		// 窗口内没有收到带发布时间的消息时保持当前状态
+		if lag, ok := c.takeLagWindow(); ok {
+			threshold := config.InvalidationLagAlarm.Seconds()
+			c.updateAlarm(AlarmInvalidationLag, lag > config.InvalidationLagAlarm, lag.Seconds(), threshold, window)
+		}
+	}
+}
+
+// updateAlarm 告警状态变化时记录日志并调用OnAlarm
+func (c *MultiLevelCache) updateAlarm(kind AlarmKind, firing bool, value, threshold float64, window time.Duration) {
+	s := &c.alarms
+	state := int32(0)
+	if firing {
+		state = 1
+	}
+	if atomic.SwapInt32(&s.firing[kind], state) == state {
+		return
+	}
+	if firing {
+		atomic.AddInt64(&s.fired, 1)
+		c.logf("dancache: alarm %s firing: %.4f (threshold %.4f over %s)", kind, value, threshold, window)
+	} else {
+		c.logf("dancache: alarm %s resolved: %.4f (threshold %.4f over %s)", kind, value, threshold, window)
+	}
+
+	onAlarm := c.config().OnAlarm
+	if onAlarm == nil {
+		return
+	}
+	alarm := Alarm{Kind: kind, Firing: firing, Value: value, Threshold: threshold, Window: window}
+	c.protect("OnAlarm", func() { onAlarm(alarm) })
+}
+
+// alarmStatsMap 返回告警统计
+func (c *MultiLevelCache) alarmStatsMap() map[string]interface{} {
+	firing := make([]string, 0, len(c.alarms.firing))
+	for kind := range c.alarms.firing {
+		if atomic.LoadInt32(&c.alarms.firing[kind]) != 0 {
+			firing = append(firing, AlarmKind(kind).String())
+		}
+	}
+	return map[string]interface{}{
+		"alarms_fired":  atomic.LoadInt64(&c.alarms.fired),
+		"alarms_firing": firing,
+	}
+}
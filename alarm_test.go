@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAlarmsFireAndResolve(t *testing.T) {
+	var alarms []Alarm
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.MaxL1Size = 2
+		config.HitRatioAlarm = 0.5
+		config.AlarmMinLookups = 10
+		config.EvictionRateAlarm = 1
+		config.OnAlarm = func(a Alarm) { alarms = append(alarms, a) }
+	})
+	// 停止告警协程，由测试控制检查的时机
+	c.PauseMaintenance(L1Cache)
+	c.snapshotAlarms()
+
+	for i := 0; i < 10; i++ {
+		c.Get("missing")
+	}
+	for i := 0; i < 5; i++ {
+		if err := c.Set(fmt.Sprintf("k%d", i), i, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.checkAlarms(time.Second)
+	if len(alarms) != 2 || !alarms[0].Firing || alarms[0].Kind != AlarmHitRatio || !alarms[1].Firing || alarms[1].Kind != AlarmEvictionRate {
+		t.Fatalf("alarms = %+v, want hit ratio and eviction rate firing", alarms)
+	}
+	if alarms[1].Value != 3 {
+		t.Errorf("eviction rate = %v, want 3", alarms[1].Value)
+	}
+	if firing := c.GetStats()["alarms_firing"].([]string); len(firing) != 2 {
+		t.Errorf("alarms_firing = %v, want two", firing)
+	}
+
+	// 查找次数不足时命中率告警保持不变，淘汰停止后恢复
+	c.Get("k4")
+	c.checkAlarms(time.Second)
+	if len(alarms) != 3 || alarms[2].Kind != AlarmEvictionRate || alarms[2].Firing {
+		t.Fatalf("alarms = %+v, want only the eviction rate alarm resolved", alarms)
+	}
+	for i := 0; i < 10; i++ {
+		c.Get("k4")
+	}
+	c.checkAlarms(time.Second)
+	if len(alarms) != 4 || alarms[3].Kind != AlarmHitRatio || alarms[3].Firing {
+		t.Fatalf("alarms = %+v, want the hit ratio alarm resolved", alarms)
+	}
+	if n := c.GetStats()["alarms_fired"]; n != int64(2) {
+		t.Errorf("alarms_fired = %v, want 2", n)
+	}
+}
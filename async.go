@@ -0,0 +1,95 @@
+package cache
+
+import "context"
+
+// SetAsync 异步设置缓存
+// 本地缓存同步写入，Redis写入在后台完成，错误通过Future和Logger报告
+// 启用SequencedWrites时写入序号在返回前登记，之后的删除不会被后台写入覆盖
+func (c *MultiLevelCache) SetAsync(key string, value interface{}, ttl int64) *Future {
+	return c.setAsync(c.ctx, key, value, ttl)
+}
+
+// SetAsyncContext 与SetAsync相同，后台的Redis写入使用由ctx派生、脱离取消的context
+func (c *MultiLevelCache) SetAsyncContext(ctx context.Context, key string, value interface{}, ttl int64) *Future {
+	return c.setAsync(detach(ctx), key, value, ttl)
+}
+
+// setAsync SetAsync的实现，ctx用于后台的Redis写入
+// 与Set经过同一路径(墓碑、SerializeSets、写入序号、gutter和广播)，只有L2写入在后台完成
+func (c *MultiLevelCache) setAsync(ctx context.Context, key string, value interface{}, ttl int64) *Future {
+	f := newFuture()
+	_ = c.set(ctx, key, value, ttl, nil, false, callOptions{async: f})
+	return f
+}
+
+// GetAsync 异步获取缓存，通过Future.Result获取值和是否命中，缓存已关闭时返回ErrClosed
+func (c *MultiLevelCache) GetAsync(key string) *Future {
+	f := newFuture()
+	if !c.enter() {
+		f.complete(nil, false, ErrClosed)
+		return f
+	}
+	go func() {
+		defer c.exit()
+		value, found := c.Get(key)
+		f.complete(value, found, nil)
+	}()
+	return f
+}
+
+// DeleteAsync 异步删除缓存
+// 本地缓存同步删除，Redis删除在后台完成，错误通过Future和Logger报告
+// 启用SequencedWrites时后台删除同样递增写入序号，之前登记的写入不会覆盖删除
+func (c *MultiLevelCache) DeleteAsync(key string) *Future {
+	return c.deleteAsync(c.ctx, key)
+}
+
+// DeleteAsyncContext 与DeleteAsync相同，后台的Redis删除使用由ctx派生、脱离取消的context
+func (c *MultiLevelCache) DeleteAsyncContext(ctx context.Context, key string) *Future {
+	return c.deleteAsync(detach(ctx), key)
+}
+
+// deleteAsync DeleteAsync的实现，ctx用于后台的Redis删除
+func (c *MultiLevelCache) deleteAsync(ctx context.Context, key string) *Future {
+	if !c.enter() {
+		f := newFuture()
+		f.complete(nil, false, ErrClosed)
+		return f
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		f := newFuture()
+		f.complete(nil, false, err)
+		return f
+	}
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		f := newFuture()
+		f.complete(nil, false, err)
+		return f
+	}
+	c.addTombstone(key)
+	if c.config().EnableL1Cache {
+		c.deleteL1(key)
+	}
+
+	f := newFuture()
+	if !c.config().EnableL2Cache {
+		f.complete(nil, true, nil)
+		return f
+	}
+
+	c.cancelL2Write(key)
+	c.retain()
+	go func() {
+		defer c.exit()
+		_, err := c.deleteL2(ctx, key)
+		if err != nil {
+			c.logf("dancache: async delete %q failed: %v", key, err)
+		} else {
+			c.replicate(key)
+		}
+		f.complete(nil, err == nil, err)
+	}()
+	return f
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSetAsyncL1Only(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.SetAsync("k", "v", 60).Wait(); err != nil {
+		t.Fatalf("SetAsync: %v", err)
+	}
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Errorf("Get = %v, %v; want v, true", v, ok)
+	}
+
+	if err := c.DeleteAsync("k").Wait(); err != nil {
+		t.Fatalf("DeleteAsync: %v", err)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("key still present after DeleteAsync")
+	}
+}
+
+func TestSetAsyncReportsErrors(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10, KeyChars: KeyCharsReject})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetAsync("bad key", "v", 60).Wait(); err == nil {
+		t.Error("SetAsync accepted an invalid key")
+	}
+	c.Close()
+	if err := c.SetAsync("k", "v", 60).Wait(); err != ErrClosed {
+		t.Errorf("SetAsync after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestGetAsync(t *testing.T) {
+	c := newRedisTestCache(t, miniredis.RunT(t), nil)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	f := c.GetAsync("k")
+	<-f.Done()
+	if v, found, err := f.Result(); err != nil || !found || v != "v" {
+		t.Errorf("GetAsync(k) = %v, %v, %v; want v, true, nil", v, found, err)
+	}
+	if v, found, err := c.GetAsync("missing").Result(); err != nil || found || v != nil {
+		t.Errorf("GetAsync(missing) = %v, %v, %v; want nil, false, nil", v, found, err)
+	}
+
+	c.Close()
+	if _, _, err := c.GetAsync("k").Result(); err != ErrClosed {
+		t.Errorf("GetAsync after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestAsyncWritesReachL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+
+	if err := c.SetAsyncContext(context.Background(), "k", "v", 60).Wait(); err != nil {
+		t.Fatalf("SetAsyncContext: %v", err)
+	}
+	if !mr.Exists("k") {
+		t.Fatal("SetAsync did not write to L2")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f := c.DeleteAsyncContext(ctx, "k")
+	// 调用方的context取消后后台删除照常完成
+	cancel()
+	if err := f.Wait(); err != nil {
+		t.Fatalf("DeleteAsyncContext: %v", err)
+	}
+	if mr.Exists("k") {
+		t.Error("DeleteAsync did not delete from L2")
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// AdminOp 需要授权的危险操作
+type AdminOp string
+
+const (
+	OpClear                AdminOp = "clear"                 // 清空L1并FLUSHDB整个Redis数据库
+	OpPurge                AdminOp = "purge"                 // PurgeByPredicate按条件扫描删除
+	OpScheduleInvalidation AdminOp = "schedule_invalidation" // 按模式定时删除L2中的键
+	OpInvalidatePrefix     AdminOp = "invalidate_prefix"     // 删除某个前缀下的所有键
+)
+
+// AdminRequest 一次危险操作的授权请求
+type AdminRequest struct {
+	Op     AdminOp
+	Target string // 操作的范围，如定时失效的模式；Clear为空
+}
+
+// Authorizer 判断是否允许执行危险操作，返回非nil的错误时拒绝
+// ctx来自调用方，可从中取出调用者身份等信息
+type Authorizer interface {
+	Authorize(ctx context.Context, req AdminRequest) error
+}
+
+// AuthorizerFunc 将函数适配为Authorizer
+type AuthorizerFunc func(ctx context.Context, req AdminRequest) error
+
+// Authorize 实现Authorizer接口
+func (f AuthorizerFunc) Authorize(ctx context.Context, req AdminRequest) error {
+	return f(ctx, req)
+}
+
+// ErrDataLossNotAccepted 清空缓存时未设置ClearOptions.IAcceptDataLoss
+var ErrDataLossNotAccepted = errors.New("清空缓存会删除Redis数据库中的所有数据，需要设置IAcceptDataLoss")
+
+// UnauthorizedError Authorizer拒绝了危险操作
+type UnauthorizedError struct {
+	Op  AdminOp
+	Err error // Authorizer返回的错误
+}
+
+// Error 实现error接口
+func (e *UnauthorizedError) Error() string {
+	return fmt.Sprintf("未授权执行%s: %v", e.Op, e.Err)
+}
+
+// Unwrap 返回Authorizer返回的错误
+func (e *UnauthorizedError) Unwrap() error {
+	return e.Err
+}
+
+// ClearOptions Clear的选项
+type ClearOptions struct {
+	IAcceptDataLoss bool // 确认清空L1和整个Redis数据库(FLUSHDB)，包括其他应用写入同一数据库的数据
+}
+
+// authorize 配置了Authorizer时检查是否允许执行危险操作
+func (c *MultiLevelCache) authorize(ctx context.Context, op AdminOp, target string) error {
+	authorizer := c.config().Authorizer
+	if authorizer == nil {
+		return nil
+	}
+	// Authorizer发生panic时拒绝
+	var err error
+	if perr := c.protect("Authorizer", func() {
+		err = authorizer.Authorize(ctx, AdminRequest{Op: op, Target: target})
+	}); perr != nil {
+		err = perr
+	}
+	if err != nil {
+		c.logf("dancache: %s %q denied: %v", op, target, err)
+		return &UnauthorizedError{Op: op, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestClearRequiresDataLossAcceptance(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Clear(ClearOptions{}); !errors.Is(err, ErrDataLossNotAccepted) {
+		t.Fatalf("Clear without IAcceptDataLoss = %v, want ErrDataLossNotAccepted", err)
+	}
+	if _, ok := c.Get("k"); !ok {
+		t.Fatal("unconfirmed Clear removed k")
+	}
+	if err := c.Clear(ClearOptions{IAcceptDataLoss: true}); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("k still cached after Clear")
+	}
+}
+
+func TestAuthorizerGatesAdminOperations(t *testing.T) {
+	denied := errors.New("not an admin")
+	var requests []AdminRequest
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.Authorizer = AuthorizerFunc(func(ctx context.Context, req AdminRequest) error {
+			requests = append(requests, req)
+			if ctx.Value(ctxKey{}) == nil {
+				return denied
+			}
+			return nil
+		})
+	})
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	admin := context.WithValue(context.Background(), ctxKey{}, true)
+
+	// 拒绝时返回UnauthorizedError并保留Authorizer的错误
+	err := c.ClearContext(context.Background(), ClearOptions{IAcceptDataLoss: true})
+	var unauthorized *UnauthorizedError
+	if !errors.As(err, &unauthorized) || unauthorized.Op != OpClear || !errors.Is(err, denied) {
+		t.Fatalf("ClearContext = %v, want an UnauthorizedError for clear", err)
+	}
+	if _, ok := c.Get("k"); !ok {
+		t.Fatal("denied Clear removed k")
+	}
+	if _, err := c.PurgeByPredicate(context.Background(), func(string, ItemMeta) bool { return true }); !errors.Is(err, denied) {
+		t.Errorf("PurgeByPredicate = %v, want denied", err)
+	}
+	if _, err := c.ScheduleInvalidationContext(context.Background(), "user:*", "0 0 * * *"); !errors.Is(err, denied) {
+		t.Errorf("ScheduleInvalidationContext = %v, want denied", err)
+	}
+
+	cancel, err := c.ScheduleInvalidationContext(admin, "user:*", "0 0 * * *")
+	if err != nil {
+		t.Fatalf("ScheduleInvalidationContext as admin: %v", err)
+	}
+	cancel()
+	if err := c.ClearContext(admin, ClearOptions{IAcceptDataLoss: true}); err != nil {
+		t.Fatalf("ClearContext as admin: %v", err)
+	}
+
+	want := []AdminRequest{
+		{Op: OpClear},
+		{Op: OpPurge},
+		{Op: OpScheduleInvalidation, Target: "user:*"},
+		{Op: OpScheduleInvalidation, Target: "user:*"},
+		{Op: OpClear},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("Authorizer saw %v, want %v", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("request %d = %+v, want %+v", i, requests[i], want[i])
+		}
+	}
+}
+
+func TestAuthorizerPanicDenies(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.Authorizer = AuthorizerFunc(func(context.Context, AdminRequest) error { panic("boom") })
+	})
+	var perr *PanicError
+	if err := c.Clear(ClearOptions{IAcceptDataLoss: true}); !errors.As(err, &perr) {
+		t.Fatalf("Clear = %v, want the panic to deny the operation", err)
+	}
+}
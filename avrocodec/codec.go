@@ -0,0 +1,71 @@
+// Package avrocodec 以Confluent wire format在L2中存储Avro记录
+// Redis中的值与Kafka中的Avro消息格式相同，其他语言的读取方可以直接通过Schema Registry解码
+package avrocodec
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/linkedin/goavro/v2"
+	cache "github.com/losanming/DanCache"
+)
+
+// magicByte Confluent wire format的首字节
+const magicByte = 0
+
+// headerSize 首字节加4字节schema ID
+const headerSize = 5
+
+// Codec 基于Schema Registry的Avro编解码器
+// 只编码缓存项的值，过期时间由Redis的TTL恢复；值需为goavro的native形式(如map[string]interface{})
+type Codec struct {
+	registry *Registry
+	id       int
+	codec    *goavro.Codec
+}
+
+var _ cache.Codec = (*Codec)(nil)
+
+// New 在subject下注册写入用的schema并创建编解码器
+// 读取时按值中的schema ID解码，旧版本schema写入的值同样可以读取
+func New(registry *Registry, subject, schema string) (*Codec, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+	id, err := registry.Register(subject, codec.Schema())
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{registry: registry, id: id, codec: codec}, nil
+}
+
+// Marshal 将缓存项的值编码为Confluent wire format
+func (c *Codec) Marshal(item *cache.CacheItem) ([]byte, error) {
+	buf := make([]byte, headerSize, 256)
+	buf[0] = magicByte
+	binary.BigEndian.PutUint32(buf[1:headerSize], uint32(c.id))
+	return c.codec.BinaryFromNative(buf, item.Value)
+}
+
+// Unmarshal 按值中的schema ID解码
+func (c *Codec) Unmarshal(data []byte, item *cache.CacheItem) error {
+	if len(data) < headerSize || data[0] != magicByte {
+		return errors.New("不是Confluent wire format的Avro数据")
+	}
+	id := int(binary.BigEndian.Uint32(data[1:headerSize]))
+
+	codec := c.codec
+	if id != c.id {
+		var err error
+		if codec, err = c.registry.codec(id); err != nil {
+			return err
+		}
+	}
+	native, _, err := codec.NativeFromBinary(data[headerSize:])
+	if err != nil {
+		return err
+	}
+	item.Value = native
+	return nil
+}
@@ -0,0 +1,97 @@
+package avrocodec
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	cache "github.com/losanming/DanCache"
+)
+
+const (
+	userV1 = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`
+	userV2 = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int","default":0}]}`
+)
+
+// fakeRegistry 内存中的Schema Registry，记录按ID获取schema的次数
+type fakeRegistry struct {
+	mutex   sync.Mutex
+	schemas []string
+	fetches int
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/versions"):
+		var body struct{ Schema string }
+		json.NewDecoder(r.Body).Decode(&body)
+		for i, s := range f.schemas {
+			if s == body.Schema {
+				fmt.Fprintf(w, `{"id":%d}`, i+1)
+				return
+			}
+		}
+		f.schemas = append(f.schemas, body.Schema)
+		fmt.Fprintf(w, `{"id":%d}`, len(f.schemas))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/schemas/ids/"):
+		var id int
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/schemas/ids/"), "%d", &id)
+		if id < 1 || id > len(f.schemas) {
+			http.NotFound(w, r)
+			return
+		}
+		f.fetches++
+		json.NewEncoder(w).Encode(map[string]string{"schema": f.schemas[id-1]})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestCodecReadsOlderSchemas(t *testing.T) {
+	fake := &fakeRegistry{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	registry := NewRegistry(server.URL+"/", nil)
+
+	v1, err := New(registry, "user-value", userV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := New(registry, "user-value", userV2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := v1.Marshal(&cache.CacheItem{Value: map[string]interface{}{"name": "alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != magicByte || data[4] != 1 {
+		t.Fatalf("header = %v, want magic byte and schema ID 1", data[:headerSize])
+	}
+
+	// v2按值中的schema ID从Registry获取v1解码，之后使用缓存的schema
+	for i := 0; i < 2; i++ {
+		var item cache.CacheItem
+		if err := v2.Unmarshal(data, &item); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if record := item.Value.(map[string]interface{}); record["name"] != "alice" {
+			t.Errorf("decoded %v, want name alice", record)
+		}
+	}
+	if fake.fetches != 1 {
+		t.Errorf("schema fetched %d times, want 1", fake.fetches)
+	}
+
+	var item cache.CacheItem
+	if err := v2.Unmarshal([]byte("{}"), &item); err == nil {
+		t.Error("Unmarshal of non-Avro data returned nil error")
+	}
+}
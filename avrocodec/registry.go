@@ -0,0 +1,102 @@
+package avrocodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// schemaRegistryContentType Schema Registry REST API的请求类型
+const schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+
+// Registry Confluent Schema Registry客户端，按ID缓存已获取的schema
+type Registry struct {
+	baseURL string
+	client  *http.Client
+
+	mu     sync.Mutex
+	codecs map[int]*goavro.Codec // schema ID->编解码器
+}
+
+// NewRegistry 创建Schema Registry客户端，client为空时使用http.DefaultClient
+func NewRegistry(baseURL string, client *http.Client) *Registry {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Registry{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+		codecs:  make(map[int]*goavro.Codec),
+	}
+}
+
+// Register 在subject下注册schema并返回schema ID，schema已存在时返回已有的ID
+func (r *Registry) Register(subject, schema string) (int, error) {
+	body, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, r.baseURL+"/subjects/"+url.PathEscape(subject)+"/versions", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", schemaRegistryContentType)
+
+	var result struct {
+		ID int `json:"id"`
+	}
+	if err := r.do(req, &result); err != nil {
+		return 0, err
+	}
+	return result.ID, nil
+}
+
+// codec 返回schema ID对应的编解码器，未缓存时从Schema Registry获取
+func (r *Registry) codec(id int) (*goavro.Codec, error) {
+	r.mu.Lock()
+	codec, ok := r.codecs[id]
+	r.mu.Unlock()
+	if ok {
+		return codec, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/schemas/ids/%d", r.baseURL, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Schema string `json:"schema"`
+	}
+	if err := r.do(req, &result); err != nil {
+		return nil, err
+	}
+	codec, err = goavro.NewCodec(result.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.codecs[id] = codec
+	r.mu.Unlock()
+	return codec, nil
+}
+
+// do 发送请求并解析JSON响应
+func (r *Registry) do(req *http.Request, out interface{}) error {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("schema registry返回%s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
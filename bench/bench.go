@@ -0,0 +1,290 @@
+// Package bench 对运行中的缓存实例执行可配置的合成负载，输出各负载和配置的对比表
+// 用于在自己的硬件和Redis上验证配置变更的效果，例如L1容量、编码或熔断参数
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	cache "github.com/losanming/DanCache"
+)
+
+// Distribution 键的访问分布
+type Distribution int
+
+const (
+	Uniform Distribution = iota // 均匀访问所有键
+	Zipfian                     // 少数热点键占大部分访问
+)
+
+// String 返回分布的名称
+func (d Distribution) String() string {
+	switch d {
+	case Uniform:
+		return "uniform"
+	case Zipfian:
+		return "zipfian"
+	default:
+		return "unknown"
+	}
+}
+
+// latencySamples 每个worker保留的延迟样本数，超出后按蓄水池抽样替换
+const latencySamples = 10000
+
+// Profile 一种合成负载
+type Profile struct {
+	Name         string
+	Keys         int           // 键空间大小(默认10000)
+	Distribution Distribution  // 键的访问分布
+	ZipfS        float64       // Zipfian分布的偏斜参数，必须大于1(默认1.1)，越大热点越集中
+	ReadRatio    float64       // 读操作的比例，0到1之间，其余为写入
+	ValueSize    int           // 写入的值的字节数(默认128)
+	TTL          int64         // 写入的过期时间(秒，默认300)
+	Concurrency  int           // 并发的worker数(默认8)
+	Duration     time.Duration // 运行时间(默认10秒)
+	Preload      bool          // 开始计时前写入全部键，使读操作从一开始就能命中
+	KeyPrefix    string        // 键前缀(默认"bench:<Name>:")，避免与业务键冲突
+}
+
+// Result 一次运行的结果
+type Result struct {
+	Label     string // 配置名称，由RunConfigs设置
+	Profile   string
+	Ops       int64
+	Reads     int64
+	Writes    int64
+	Hits      int64
+	Errors    int64
+	Elapsed   time.Duration
+	OpsPerSec float64
+	HitRatio  float64 // 读操作的命中比例
+	P50       time.Duration
+	P99       time.Duration
+	Max       time.Duration
+}
+
+// DefaultProfiles 返回常用的负载：读多写少的热点访问、均匀访问、写密集和大值
+func DefaultProfiles() []Profile {
+	return []Profile{
+		{Name: "zipf-read-heavy", Distribution: Zipfian, ReadRatio: 0.95, Preload: true},
+		{Name: "uniform-read-heavy", Distribution: Uniform, ReadRatio: 0.95, Preload: true},
+		{Name: "zipf-write-heavy", Distribution: Zipfian, ReadRatio: 0.5},
+		{Name: "zipf-large-values", Distribution: Zipfian, ReadRatio: 0.9, ValueSize: 16 << 10, Keys: 2000, Preload: true},
+	}
+}
+
+// withDefaults 填充未设置的字段
+func (p Profile) withDefaults() Profile {
+	if p.Keys <= 0 {
+		p.Keys = 10000
+	}
+	if p.ZipfS <= 1 {
+		p.ZipfS = 1.1
+	}
+	if p.ValueSize <= 0 {
+		p.ValueSize = 128
+	}
+	if p.TTL <= 0 {
+		p.TTL = 300
+	}
+	if p.Concurrency <= 0 {
+		p.Concurrency = 8
+	}
+	if p.Duration <= 0 {
+		p.Duration = 10 * time.Second
+	}
+	if p.KeyPrefix == "" {
+		p.KeyPrefix = "bench:" + p.Name + ":"
+	}
+	return p
+}
+
+// validate 检查负载参数
+func (p Profile) validate() error {
+	if p.ReadRatio < 0 || p.ReadRatio > 1 {
+		return fmt.Errorf("负载%q的ReadRatio必须在0到1之间", p.Name)
+	}
+	return nil
+}
+
+// keyChooser 按分布选择键的下标
+type keyChooser func() int
+
+// newChooser 为一个worker创建键选择器，每个worker使用独立的随机源
+func (p Profile) newChooser(r *rand.Rand) keyChooser {
+	if p.Distribution == Zipfian {
+		z := rand.NewZipf(r, p.ZipfS, 1, uint64(p.Keys-1))
+		return func() int { return int(z.Uint64()) }
+	}
+	return func() int { return r.Intn(p.Keys) }
+}
+
+// workerStats 一个worker的计数和延迟样本
+type workerStats struct {
+	reads, writes, hits, errors int64
+	ops                         int64
+	samples                     []time.Duration
+}
+
+// record 记录一次操作的延迟，样本满后按蓄水池抽样替换
+func (w *workerStats) record(d time.Duration, r *rand.Rand) {
+	w.ops++
+	if len(w.samples) < latencySamples {
+		w.samples = append(w.samples, d)
+		return
+	}
+	if i := r.Int63n(w.ops); i < latencySamples {
+		w.samples[i] = d
+	}
+}
+
+// Run 在缓存实例上运行一种负载，ctx取消时提前结束并返回已完成部分的结果
+// 写入的值为固定大小的字符串；负载使用的键不会在结束后删除，依赖TTL过期
+func Run(ctx context.Context, c *cache.MultiLevelCache, p Profile) (Result, error) {
+	p = p.withDefaults()
+	if err := p.validate(); err != nil {
+		return Result{}, err
+	}
+	value := strings.Repeat("x", p.ValueSize)
+	key := func(i int) string { return fmt.Sprintf("%s%d", p.KeyPrefix, i) }
+
+	if p.Preload {
+		for i := 0; i < p.Keys; i++ {
+			if err := ctx.Err(); err != nil {
+				return Result{Profile: p.Name}, err
+			}
+			if err := c.Set(key(i), value, p.TTL); err != nil {
+				return Result{Profile: p.Name}, fmt.Errorf("预写入失败: %w", err)
+			}
+		}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, p.Duration)
+	defer cancel()
+	workers := make([]workerStats, p.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range workers {
+		wg.Add(1)
+		go func(w *workerStats, seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			choose := p.newChooser(r)
+			for runCtx.Err() == nil {
+				k := key(choose())
+				begin := time.Now()
+				if r.Float64() < p.ReadRatio {
+					w.reads++
+					if _, ok := c.Get(k); ok {
+						w.hits++
+					}
+				} else {
+					w.writes++
+					if err := c.Set(k, value, p.TTL); err != nil {
+						w.errors++
+					}
+				}
+				w.record(time.Since(begin), r)
+			}
+		}(&workers[i], start.UnixNano()+int64(i))
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result := Result{Profile: p.Name, Elapsed: elapsed}
+	var samples []time.Duration
+	for i := range workers {
+		w := &workers[i]
+		result.Ops += w.ops
+		result.Reads += w.reads
+		result.Writes += w.writes
+		result.Hits += w.hits
+		result.Errors += w.errors
+		samples = append(samples, w.samples...)
+	}
+	if elapsed > 0 {
+		result.OpsPerSec = float64(result.Ops) / elapsed.Seconds()
+	}
+	if result.Reads > 0 {
+		result.HitRatio = float64(result.Hits) / float64(result.Reads)
+	}
+	if len(samples) > 0 {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		result.P50 = samples[len(samples)/2]
+		result.P99 = samples[len(samples)*99/100]
+		result.Max = samples[len(samples)-1]
+	}
+	// 运行时间到期是正常结束，只返回调用方的取消
+	return result, ctx.Err()
+}
+
+// RunAll 依次运行多种负载
+func RunAll(ctx context.Context, c *cache.MultiLevelCache, profiles []Profile) ([]Result, error) {
+	results := make([]Result, 0, len(profiles))
+	for _, p := range profiles {
+		result, err := Run(ctx, c, p)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Config 参与对比的一个缓存配置
+type Config struct {
+	Label  string
+	Config cache.CacheConfig
+}
+
+// RunConfigs 为每个配置创建缓存实例，依次运行全部负载后关闭实例，结果按配置和负载的顺序排列
+// 不同配置使用同一个Redis时，负载的键相同，前一个配置写入的值可能被后一个配置读到；需要隔离时为每个配置设置不同的KeyPrefix或Redis DB
+func RunConfigs(ctx context.Context, configs []Config, profiles []Profile) ([]Result, error) {
+	var results []Result
+	for _, cfg := range configs {
+		c, err := cache.NewMultiLevelCache(cfg.Config)
+		if err != nil {
+			return results, fmt.Errorf("创建配置%q的缓存失败: %w", cfg.Label, err)
+		}
+		runs, err := RunAll(ctx, c, profiles)
+		for _, r := range runs {
+			r.Label = cfg.Label
+			results = append(results, r)
+		}
+		closeErr := c.Close()
+		if err != nil {
+			return results, err
+		}
+		if closeErr != nil && !errors.Is(closeErr, cache.ErrClosed) {
+			return results, closeErr
+		}
+	}
+	return results, nil
+}
+
+// PrintTable 输出结果的对比表；多个配置运行同一负载时，ops/s一列附带相对第一个配置的变化
+func PrintTable(w io.Writer, results []Result) error {
+	baseline := make(map[string]float64)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "config\tprofile\tops/s\tvs first\thit ratio\tp50\tp99\tmax\terrors\t")
+	for _, r := range results {
+		change := "-"
+		if base, ok := baseline[r.Profile]; ok && base > 0 {
+			change = fmt.Sprintf("%+.1f%%", (r.OpsPerSec/base-1)*100)
+		} else {
+			baseline[r.Profile] = r.OpsPerSec
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%s\t%.3f\t%s\t%s\t%s\t%d\t\n",
+			r.Label, r.Profile, r.OpsPerSec, change, r.HitRatio, r.P50, r.P99, r.Max, r.Errors)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	cache "github.com/losanming/DanCache"
+)
+
+// l1Config 只启用L1的配置，容量足够容纳测试负载的全部键
+// L1命中时直接修改项的访问时间和次数，测试负载只用一个worker且不按空闲时间降级，避免与后台清理并发读写
+func l1Config() cache.CacheConfig {
+	return cache.CacheConfig{EnableL1Cache: true, MaxL1Size: 1000, DemotionStrategy: cache.NewFrequencyBasedStrategy(0, 0, 0)}
+}
+
+func TestRunPreloadedReads(t *testing.T) {
+	c, err := cache.NewMultiLevelCache(l1Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	p := Profile{Name: "reads", Keys: 100, Distribution: Zipfian, ReadRatio: 1, Concurrency: 1, Duration: 50 * time.Millisecond, Preload: true}
+	result, err := Run(context.Background(), c, p)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// 预写入全部键后，只读负载全部命中
+	if result.Profile != "reads" || result.Ops == 0 || result.Reads != result.Ops || result.Writes != 0 || result.HitRatio != 1 {
+		t.Errorf("result = %+v, want only hits", result)
+	}
+	if result.Errors != 0 || result.P50 <= 0 || result.P50 > result.P99 || result.P99 > result.Max {
+		t.Errorf("latencies = %v, %v, %v with %d errors; want ordered percentiles", result.P50, result.P99, result.Max, result.Errors)
+	}
+	if _, ok := c.Get("bench:reads:0"); !ok {
+		t.Error("preloaded key missing, want the default key prefix")
+	}
+}
+
+func TestRunValidatesProfile(t *testing.T) {
+	c, err := cache.NewMultiLevelCache(l1Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := Run(context.Background(), c, Profile{Name: "bad", ReadRatio: 1.5}); err == nil {
+		t.Error("Run accepted ReadRatio 1.5")
+	}
+
+	// 调用方取消时返回取消错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, c, Profile{Name: "cancelled", Keys: 10, Preload: true}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunConfigsAndPrintTable(t *testing.T) {
+	configs := []Config{{Label: "small", Config: l1Config()}, {Label: "large", Config: l1Config()}}
+	profiles := []Profile{
+		{Name: "uniform", Keys: 50, ReadRatio: 0.5, Concurrency: 1, Duration: 20 * time.Millisecond},
+		{Name: "zipf", Keys: 50, Distribution: Zipfian, ReadRatio: 0.9, Concurrency: 1, Duration: 20 * time.Millisecond},
+	}
+	results, err := RunConfigs(context.Background(), configs, profiles)
+	if err != nil {
+		t.Fatalf("RunConfigs: %v", err)
+	}
+	if len(results) != 4 || results[0].Label != "small" || results[1].Profile != "zipf" || results[2].Label != "large" {
+		t.Fatalf("results = %+v, want configs then profiles in order", results)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintTable(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], "vs first") {
+		t.Fatalf("table = %q, want a header and 4 rows", buf.String())
+	}
+	// 第一个配置的行没有对比，之后的配置附带相对变化
+	if !strings.Contains(lines[1], " - ") || !strings.Contains(lines[3], "%") {
+		t.Errorf("table = %q, want changes only for the second config", buf.String())
+	}
+}
+
+func TestDistributionString(t *testing.T) {
+	if Uniform.String() != "uniform" || Zipfian.String() != "zipfian" || Distribution(9).String() != "unknown" {
+		t.Error("unexpected distribution names")
+	}
+}
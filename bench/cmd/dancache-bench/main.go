@@ -0,0 +1,80 @@
+// dancache-bench 对本地Redis运行bench包的默认负载，对比不同的缓存级别组合
+//
+//	go run ./bench/cmd/dancache-bench -redis localhost:6379 -levels l1,l2,l1+l2 -duration 10s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	cache "github.com/losanming/DanCache"
+	"github.com/losanming/DanCache/bench"
+)
+
+func main() {
+	addr := flag.String("redis", "localhost:6379", "Redis地址")
+	db := flag.Int("db", 15, "负载使用的Redis DB，避免与业务数据混在一起")
+	levels := flag.String("levels", "l1,l1+l2", "参与对比的级别组合，逗号分隔：l1、l2、l1+l2")
+	profiles := flag.String("profiles", "", "运行的负载名称，逗号分隔，默认运行全部默认负载")
+	duration := flag.Duration("duration", 10*time.Second, "每个负载的运行时间")
+	concurrency := flag.Int("concurrency", 8, "并发的worker数")
+	l1Size := flag.Int("l1-size", 10000, "L1最大条目数")
+	flag.Parse()
+
+	var selected []bench.Profile
+	for _, p := range bench.DefaultProfiles() {
+		if *profiles == "" || contains(*profiles, p.Name) {
+			p.Duration = *duration
+			p.Concurrency = *concurrency
+			selected = append(selected, p)
+		}
+	}
+	if len(selected) == 0 {
+		log.Fatalf("没有匹配%q的负载", *profiles)
+	}
+
+	var configs []bench.Config
+	for _, level := range strings.Split(*levels, ",") {
+		level = strings.TrimSpace(level)
+		config := cache.CacheConfig{
+			EnableL1Cache: strings.Contains(level, "l1"),
+			EnableL2Cache: strings.Contains(level, "l2"),
+			L1TTL:         300,
+			L2TTL:         300,
+			MaxL1Size:     *l1Size,
+			RedisOptions:  &redis.Options{Addr: *addr, DB: *db},
+		}
+		if !config.EnableL1Cache && !config.EnableL2Cache {
+			log.Fatalf("未知的级别组合%q", level)
+		}
+		configs = append(configs, bench.Config{Label: level, Config: config})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	results, err := bench.RunConfigs(ctx, configs, selected)
+	if perr := bench.PrintTable(os.Stdout, results); perr != nil {
+		log.Fatal(perr)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// contains 判断逗号分隔的列表中是否有name
+func contains(list, name string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+)
+
+// broadcastMessage 广播的新值，Data为与L2中相同编码的缓存项
+// Hint为true时是Publish的预热提示，不带值，接收方在Until之前从L2读取该键时直接升级
+type broadcastMessage struct {
+	Origin string `json:"o"`
+	Key    string `json:"k"`
+	Data   []byte `json:"d"`
+	At     int64  `json:"t,omitempty"` // 发布时间(Unix纳秒)，用于统计传播延迟
+	Hint   bool   `json:"h,omitempty"`
+	Until  int64  `json:"u,omitempty"` // 提示的到期时间(Unix秒，0表示随键过期)
+}
+
+// newInstanceID 生成本实例的随机标识
+func newInstanceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SetBroadcast 设置缓存并向其他实例广播完整的值，其他实例直接更新L1
+// 适合即将被大量读取的值，避免所有实例同时未命中L1并涌向Redis；未配置BroadcastChannel时等同于Set
+func (c *MultiLevelCache) SetBroadcast(key string, value interface{}, ttl int64) error {
+	return c.set(c.ctx, key, value, ttl, nil, true, callOptions{})
+}
+
+// hotForBroadcast 判断被覆盖的L1项是否足够热，需要自动广播新值
+func (c *MultiLevelCache) hotForBroadcast(key string) bool {
+	config := c.config()
+	if config.BroadcastHotAccesses <= 0 || !config.EnableL1Cache {
+		return false
+	}
+	old, ok := c.shardFor(key).load(key)
+	return ok && old.AccessCount >= config.BroadcastHotAccesses
+}
+
+// broadcast 向其他实例发布新值，失败时只记录日志，其他实例仍可从L2读取
+func (c *MultiLevelCache) broadcast(key string, item *CacheItem) {
+	if !c.config().EnableL2Cache || c.config().BroadcastChannel == "" || c.proxyMode() {
+		return
+	}
+	data, err := c.marshalItem(key, item)
+	if err == nil {
+		data, err = json.Marshal(broadcastMessage{Origin: c.instanceID, Key: key, Data: data, At: c.now().UnixNano()})
+	}
+	if err == nil {
+		err = c.redisClient.Publish(c.ctx, c.config().BroadcastChannel, data).Err()
+	}
+	if err != nil {
+		c.logf("dancache: broadcast %q failed: %v", key, err)
+		return
+	}
+	atomic.AddInt64(&c.broadcastsSent, 1)
+}
+
+// broadcastListenerRoutine 接收其他实例广播的新值
+func (c *MultiLevelCache) broadcastListenerRoutine(stop <-chan struct{}) {
+	c.subscribeRoutine(c.config().BroadcastChannel, c.handleBroadcast, stop)
+}
+
+// handleBroadcast 将其他实例广播的新值写入L1
+// 跳过自己发出的消息、墓碑窗口内的键和已过期的值；配置了KeyOwner时只写入归本实例所有或已在L1中的键
+func (c *MultiLevelCache) handleBroadcast(payload []byte) {
+	config := c.config()
+	if !config.EnableL1Cache {
+		return
+	}
+	var msg broadcastMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		c.logf("dancache: decode broadcast failed: %v", err)
+		return
+	}
+	if msg.Origin == c.instanceID {
+		return
+	}
+	c.observeLag(TransportBroadcast, msg.Origin, msg.At)
+	if c.hasTombstone(msg.Key) {
+		return
+	}
+	if msg.Hint {
+		c.handleWarmHint(msg.Key, msg.Until)
+		return
+	}
+	if config.KeyOwner != nil && !c.keyOwned(config.KeyOwner, msg.Key) {
+		if _, ok := c.shardFor(msg.Key).load(msg.Key); !ok {
+			return
+		}
+	}
+
+	var item CacheItem
+	if err := c.decodeItem(msg.Key, msg.Data, &item); err != nil {
+		c.logf("dancache: decode broadcast value %q failed: %v", msg.Key, err)
+		return
+	}
+	if item.ExpireTime <= c.nowUnix() {
+		return
+	}
+	item.markL2Synced()
+	// 广播的值没有经过Redis读取，客户端跟踪不覆盖，改为删除旧值
+	if c.trackingBypassesWrites(msg.Key) {
+		c.deleteL1(msg.Key)
+	} else {
+		c.storeL1(msg.Key, &item)
+	}
+	atomic.AddInt64(&c.broadcastsRecv, 1)
+}
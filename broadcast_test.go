@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// waitL1Value 等待键以指定的值出现在L1中
+func waitL1Value(t *testing.T, c *MultiLevelCache, key string, want interface{}) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if item, ok := c.shardFor(key).load(key); ok && item.Value == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("L1 value of %q did not become %v", key, want)
+}
+
+func TestBroadcastUpdatesPeerL1(t *testing.T) {
+	mr := miniredis.RunT(t)
+	const channel = "dancache:broadcast"
+	configure := func(config *CacheConfig) {
+		config.BroadcastChannel = channel
+		config.BroadcastHotAccesses = 2
+	}
+	writer := newRedisTestCache(t, mr, configure)
+	reader := newRedisTestCache(t, mr, configure)
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub(channel)[channel] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("broadcast listeners did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := writer.SetBroadcast("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	waitL1Value(t, reader, "k", "v")
+
+	// 普通写入不广播，覆盖访问次数达到阈值的L1项时自动广播
+	if err := writer.Set("h", "1", 60); err != nil {
+		t.Fatal(err)
+	}
+	if n := writer.GetStats()["broadcasts_sent"]; n != int64(1) {
+		t.Errorf("broadcasts_sent after plain Set = %v, want 1", n)
+	}
+	writer.Get("h")
+	writer.Get("h")
+	if err := writer.Set("h", "2", 60); err != nil {
+		t.Fatal(err)
+	}
+	waitL1Value(t, reader, "h", "2")
+
+	if n := writer.GetStats()["broadcasts_sent"]; n != int64(2) {
+		t.Errorf("broadcasts_sent = %v, want 2", n)
+	}
+	// 自己发出的广播不写入自己的L1统计
+	if n := writer.GetStats()["broadcasts_received"]; n != int64(0) {
+		t.Errorf("writer broadcasts_received = %v, want 0", n)
+	}
+	if n := reader.GetStats()["broadcasts_received"]; n != int64(2) {
+		t.Errorf("reader broadcasts_received = %v, want 2", n)
+	}
+}
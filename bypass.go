@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// BypassSample 一次绕过缓存的对比采样
+type BypassSample struct {
+	Key          string
+	CacheHit     bool          // 缓存中是否有值
+	Cached       interface{}   // 缓存本来会返回的值(未命中时为nil)
+	Loaded       interface{}   // loader返回的值
+	Stale        bool          // 缓存命中但与loader返回的值不同
+	CacheLatency time.Duration // 查询缓存的耗时
+	LoadLatency  time.Duration // 调用loader的耗时
+}
+
+// bypassStats 对比采样的统计
+type bypassStats struct {
+	samples      int64
+	cacheHits    int64
+	stale        int64
+	cacheLatency int64 // 查询缓存的总耗时(纳秒)
+	loadLatency  int64 // 调用loader的总耗时(纳秒)
+}
+
+// sampleBypass 判断本次GetOrLoad是否绕过缓存
+func (c *MultiLevelCache) sampleBypass() bool {
+	percent := c.config().BypassPercent
+	return percent > 0 && rand.Float64()*100 < percent
+}
+
+// bypassLoad 绕过缓存直接调用loader并返回其结果，同时记录缓存本来会返回的值
+// 不合并并发加载也不回填缓存，以免影响对比；loader失败时直接返回错误
+func (c *MultiLevelCache) bypassLoad(ctx context.Context, key string, loader LoaderFuncWithTTLContext) (interface{}, error) {
+	start := time.Now()
+	cached, hit := c.Get(key)
+	cacheLatency := time.Since(start)
+
+	start = time.Now()
+	loaded, err := c.admitLoad(func() (interface{}, error) {
+		val, _, err := loader(ctx)
+		return val, err
+	})
+	loadLatency := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := BypassSample{
+		Key:          key,
+		CacheHit:     hit,
+		Cached:       cached,
+		Loaded:       loaded,
+		Stale:        hit && !c.sameValue(cached, loaded),
+		CacheLatency: cacheLatency,
+		LoadLatency:  loadLatency,
+	}
+	c.recordBypass(sample)
+	return loaded, nil
+}
+
+// sameValue 判断缓存的值与loader返回的值是否相同
+// 默认比较两者的JSON形式，从L2解码出的map与loader返回的结构体内容相同时视为相同
+func (c *MultiLevelCache) sameValue(cached, loaded interface{}) bool {
+	if compare := c.config().BypassCompare; compare != nil {
+		same := true
+		c.protect("BypassCompare", func() { same = compare(cached, loaded) })
+		return same
+	}
+	if reflect.DeepEqual(cached, loaded) {
+		return true
+	}
+	a, errA := normalizeJSON(cached)
+	b, errB := normalizeJSON(loaded)
+	return errA == nil && errB == nil && reflect.DeepEqual(a, b)
+}
+
+// normalizeJSON 将值编码为JSON再解码为通用类型，便于比较不同类型但内容相同的值
+// 数字解码为json.Number，超过float64精度的整数不会被误判为相同
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out interface{}
+	err = dec.Decode(&out)
+	return out, err
+}
+
+// recordBypass 记录对比采样的统计并调用OnBypassSample
+func (c *MultiLevelCache) recordBypass(sample BypassSample) {
+	s := &c.bypass
+	atomic.AddInt64(&s.samples, 1)
+	if sample.CacheHit {
+		atomic.AddInt64(&s.cacheHits, 1)
+	}
+	if sample.Stale {
+		atomic.AddInt64(&s.stale, 1)
+	}
+	atomic.AddInt64(&s.cacheLatency, int64(sample.CacheLatency))
+	atomic.AddInt64(&s.loadLatency, int64(sample.LoadLatency))
+
+	if onSample := c.config().OnBypassSample; onSample != nil {
+		c.protect("OnBypassSample", func() { onSample(sample) })
+	}
+}
+
+// bypassStatsMap 返回对比采样的统计，延迟为平均值(微秒)
+func (c *MultiLevelCache) bypassStatsMap() map[string]interface{} {
+	s := &c.bypass
+	samples := atomic.LoadInt64(&s.samples)
+	stats := map[string]interface{}{
+		"bypass_samples":    samples,
+		"bypass_cache_hits": atomic.LoadInt64(&s.cacheHits),
+		"bypass_stale":      atomic.LoadInt64(&s.stale),
+	}
+	if samples > 0 {
+		stats["bypass_cache_latency_us"] = atomic.LoadInt64(&s.cacheLatency) / samples / int64(time.Microsecond)
+		stats["bypass_load_latency_us"] = atomic.LoadInt64(&s.loadLatency) / samples / int64(time.Microsecond)
+	}
+	return stats
+}
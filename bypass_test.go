@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"testing"
+)
+
+// bypassUser 对比采样测试用的值
+type bypassUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestBypassSamplesCompareWithCache(t *testing.T) {
+	var samples []BypassSample
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.BypassPercent = 100
+		config.OnBypassSample = func(s BypassSample) { samples = append(samples, s) }
+	})
+	if err := c.Set("fresh", map[string]interface{}{"id": 1, "name": "alice"}, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("stale", bypassUser{ID: 2, Name: "old"}, 60); err != nil {
+		t.Fatal(err)
+	}
+	load := func(u bypassUser) LoaderFunc {
+		return func() (interface{}, error) { return u, nil }
+	}
+
+	// 内容相同但类型不同的值按JSON形式比较视为相同
+	if v, err := c.GetOrLoad("fresh", 60, load(bypassUser{ID: 1, Name: "alice"})); err != nil || v != (bypassUser{ID: 1, Name: "alice"}) {
+		t.Fatalf("GetOrLoad = %v, %v; want the loaded value", v, err)
+	}
+	c.GetOrLoad("stale", 60, load(bypassUser{ID: 2, Name: "new"}))
+	c.GetOrLoad("missing", 60, load(bypassUser{ID: 3}))
+
+	if len(samples) != 3 {
+		t.Fatalf("got %d samples, want 3", len(samples))
+	}
+	if !samples[0].CacheHit || samples[0].Stale {
+		t.Errorf("fresh sample = %+v, want a hit that is not stale", samples[0])
+	}
+	if !samples[1].CacheHit || !samples[1].Stale {
+		t.Errorf("stale sample = %+v, want a stale hit", samples[1])
+	}
+	if samples[2].CacheHit || samples[2].Stale {
+		t.Errorf("missing sample = %+v, want a miss", samples[2])
+	}
+	// 绕过时不回填缓存
+	if _, ok := c.Get("missing"); ok {
+		t.Error("bypassed load was backfilled")
+	}
+	stats := c.GetStats()
+	if stats["bypass_samples"] != int64(3) || stats["bypass_cache_hits"] != int64(2) || stats["bypass_stale"] != int64(1) {
+		t.Errorf("bypass stats = %v, %v, %v", stats["bypass_samples"], stats["bypass_cache_hits"], stats["bypass_stale"])
+	}
+}
+
+func TestBypassPercentValidated(t *testing.T) {
+	if _, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, BypassPercent: 101}); err == nil {
+		t.Error("NewMultiLevelCache accepted BypassPercent 101")
+	}
+}
@@ -2,10 +2,9 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"sort"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -26,9 +25,187 @@ type CacheConfig struct {
 	L1TTL            int64          // 本地缓存默认过期时间(秒)
 	L2TTL            int64          // Redis缓存默认过期时间(秒)
 	MaxL1Size        int            // 本地缓存最大条目数
+	InternValues     bool           // L1中内容相同的值共享一个对象，读取时返回副本(按规范JSON编码判断内容是否相同)
+	InternMinSize    int            // 参与去重的值JSON编码后的最小字节数(默认64)
+	CopyPolicy       CopyPolicy     // 写入和读取时是否复制值，防止调用方修改L1中共享的对象(默认不复制)
+	CloneFunc        func(v interface{}) interface{} // 自定义的值复制函数，设置后代替CopyPolicy
+	VerifyImmutable  bool           // 调试模式：写入L1时记录值的内容哈希，读取时发现值被修改则记录日志并丢弃该项
 	RedisOptions     *redis.Options // Redis配置
+	RedisClient      RedisCommander // 自定义的Redis客户端，设置后忽略RedisOptions
+	RedisProxyMode   bool           // 通过Twemproxy/Envoy等代理访问Redis，不使用SCAN、pub/sub、MULTI/EXEC等代理不支持的命令
+	CompatibilityMode bool          // 创建时检测服务端(Redis/Valkey/KeyDB/Dragonfly)支持的功能，对不支持的功能降级
+	ClientTracking    bool          // 通过Redis 6 CLIENT TRACKING让Redis在L1中的键被修改时通知本实例删除，需要同时启用L1和L2
+	TrackingPrefixes  []string      // 非空时跟踪使用BCAST模式，Redis通知匹配这些前缀的所有键的修改，不匹配的键不进入L1
+	InternalKeyPrefix string        // 内部键(写入序号、墓碑、标签索引等)的命名空间前缀；为空时内部键使用seq:、tag:等前缀，以这些前缀开头的缓存键被拒绝
+
+	RedisUsername      string // Redis 6 ACL用户名，覆盖RedisOptions和GutterRedisOptions中的Username
+	RedisPassword      string // Redis密码，覆盖RedisOptions和GutterRedisOptions中的Password
+	RedisTLS           bool   // 通过TLS连接Redis
+	RedisTLSCAFile     string // 验证服务端证书的CA证书文件(PEM，为空时使用系统根证书)
+	RedisTLSCertFile   string // 双向TLS的客户端证书文件(PEM)
+	RedisTLSKeyFile    string // 双向TLS的客户端私钥文件(PEM)
+	RedisTLSServerName string // 验证服务端证书时使用的主机名(为空时使用连接地址)
+	RedisClientName    string // 连接建立后通过CLIENT SETNAME设置的连接名(默认"dancache-<实例标识>"，RedisProxyMode时默认不设置，"-"表示不设置)
+
+	Region             string                    // 本实例所在的区域，多区域双活部署时设置，本区域的写入和删除会异步失效其他区域的Redis和L1
+	RegionRedisOptions map[string]*redis.Options // 各区域的Redis配置，Region对应的配置代替RedisOptions作为本区域的L2
+	RegionChannel      string                    // 跨区域失效的Redis频道(默认"dancache:region")
+	RegionQueueSize    int                       // 每个远端区域等待发送的失效消息上限(默认1024)，超出时丢弃并计入统计
 	PromotionStrategy PromotionStrategy // 缓存升级策略
+	KeyOwner          func(key string) bool // 一致性哈希集群中判断键是否归本实例所有，只有返回true的键才会升级到L1(nil表示所有键都可升级)
+	PromotionTTL      int64                 // 从L2升级的项在L1中最多停留的时间(秒)，到期后即使未过期也要重新从L2读取，限制各实例L1之间的不一致(0表示不限制)
+
+	L1MinTTL int64 // 未启用L2时写入的最短过期时间(秒)，更短的ttl会被提高(0表示不限制)
+	L1MaxTTL int64 // 写入的最长过期时间(秒)；启用L2时只限制项在L1中停留的时间，到期后重新从L2读取(0表示不限制)
+	L2MinTTL int64 // 启用L2时写入的最短过期时间(秒)，防止过短的ttl造成频繁回源(0表示不限制)
+	L2MaxTTL int64 // 启用L2时写入的最长过期时间(秒)，防止过长的ttl留下永不过期的垃圾(0表示不限制)
+
+	TagIndexMaxSize int // 单个标签索引的最大键数，超出时随机移出多余的键并删除其缓存，保证按标签失效仍然完整(0表示不限制)
+	TagPruneSample  int // 写入标签时每轮抽查索引中已过期键的数量，索引大于该值时在后台清理(默认20，负数表示不清理)
+	TagMaxTTLs      map[string]int64 // 标签的最长过期时间(秒)，关联该标签的写入不会超过该时间，优先于MinTTL；运行时可用SetTagMaxTTL修改
+
+	PathSeparator      string        // 分层键的分隔符(默认"/")
+	GenerationCacheTTL time.Duration // 本地缓存分层键各级代数的时长(默认1秒，负数表示每次从L2读取)
+
+	PurgeRate int // PurgeByPredicate每秒最多检查的L2键数(0表示只受L2RateLimit限制)
+	Authorizer Authorizer // 授权Clear、PurgeByPredicate、ScheduleInvalidation等危险操作(nil表示不检查)
+
+	BroadcastChannel     string          // 向其他实例广播新值的Redis频道，收到的值直接写入L1(为空表示不广播)
+	BroadcastHotAccesses int64           // 被覆盖的L1项访问次数达到该值时自动广播新值(0表示只广播SetBroadcast写入的值)
+	ReconcilePolicy      ReconcilePolicy // 广播或跨区域失效的订阅连接重连后，如何处理可能错过消息的L1项(默认删除重连前写入的项)
 	DemotionStrategy  DemotionStrategy  // 缓存降级策略
+
+	MemoryLimitRatio    float64       // 堆内存达到GOMEMLIMIT的该比例时主动收缩L1(0表示不启用)
+	MemoryShrinkRatio   float64       // 每次收缩淘汰的L1项比例(默认0.25)
+	MemoryCheckInterval time.Duration // 内存检查间隔(默认5秒)
+
+	SweepInterval time.Duration // 后台清扫过期项的间隔(0表示不启用)
+	SweepBudget   time.Duration // 每轮清扫的最长耗时(默认1毫秒)
+
+	L1Partitions []L1Partition // 按键前缀划分L1容量份额，淘汰时优先从超出份额最多的分区中淘汰(为空表示全局LRU)
+
+	L1Shards           int // 本地缓存分片数(默认16)
+	CleanupParallelism int // 同时清理的分片数上限(默认GOMAXPROCS)
+	LazyMaintenance    bool // 为true时清理、监听等后台协程在首次写入L1或访问L2时才启动，适合不需要后台维护的短生命周期工具
+
+	SerializeWorkers   int // 大值序列化工作池的协程数(0表示不启用)
+	SerializeThreshold int // 交给工作池处理的最小值大小(字节，默认64KB)
+
+	MaxDemotionBatchBytes int // 批量降级时单个管道的最大字节数(默认1MB)
+	MaxBatchPromotions    int // 单次批量读取最多升级到L1的键数，超出时优先升级访问次数多的键(默认64)
+
+	AccessWriteBackEvery int64 // 从L2读取时平均每N次访问回写一次访问信息(按1/N抽样，0表示仅在升级时回写)
+
+	WriteCoalesceWindow time.Duration // 合并同一键L2写入的时间窗口，窗口内只写入最后的值(0表示不合并)
+	SerializeSets       bool          // 同一键的并发写入逐个执行，L1和L2写入同一个值(登记最晚的写入胜出)
+	SequencedWrites     bool          // 写入和删除在L2中维护每个键的写入序号，较早登记的写入不会覆盖之后的写入或删除
+	SequenceTTL         time.Duration // 写入序号的最短保留时间(默认1小时)，较慢的写入超过该时间后不再受保护
+
+	TombstoneTTL time.Duration // 删除后阻止该键被写入的墓碑窗口，防止进行中的加载回填旧数据(0表示不启用)
+
+	MaxValueSize   int            // 缓存项序列化后的最大字节数(0表示不限制)
+	OversizePolicy OversizePolicy // 超过MaxValueSize时的处理策略(默认拒绝)
+
+	L2ReadTimeout  time.Duration // 单个L2读操作的超时时间，与调用方context无关(0表示不限制)
+	L2WriteTimeout time.Duration // 单个L2写操作的超时时间(0表示不限制)
+
+	CircuitFailureThreshold int            // 连续失败多少次后打开L2熔断器(0表示不启用)
+	CircuitOpenDuration     time.Duration  // 熔断器打开后的冷却时间(默认5秒)
+	GutterRedisOptions      *redis.Options // 熔断期间改用的gutter Redis配置
+	GutterTTL               int64          // 熔断期间写入的缓存项最长保留时间(秒)；未配置gutter Redis时大于0表示用L1作为gutter
+
+	EvictionExporter EvictionExporter // 未启用L2时接收被永久淘汰的缓存项
+
+	MaxConcurrentLoads int           // 同时进行的加载数量上限，超出的加载排队或被拒绝(0表示不限制)
+	LoadQueueTimeout   time.Duration // 加载排队等待的最长时间，超时返回ErrOverloaded(0表示不排队)
+
+	OnExpire             func(key string) // L2中的键过期时调用(键仍在L1中时不调用)，需要Redis开启过期事件通知
+	ExpireKeyPrefix      string           // 只对该前缀的键调用OnExpire(为空表示所有缓存键)
+	EnableKeyspaceEvents bool             // 启动时执行CONFIG SET notify-keyspace-events Ex
+
+	HitRatioAlarm     float64       // 统计窗口内命中率低于该值时告警(0表示不检查)
+	EvictionRateAlarm float64       // 统计窗口内每秒L1淘汰数高于该值时告警(0表示不检查)
+	AlarmWindow       time.Duration // 告警统计窗口(默认1分钟)
+	AlarmMinLookups   int64         // 命中率告警要求窗口内的最少查找次数(默认100)
+	OnAlarm           func(Alarm)   // 告警开始和恢复时调用
+
+	InvalidationLagAlarm time.Duration    // 统计窗口内失效传播的最大延迟高于该值时告警(0表示不检查)
+	OnInvalidationLag    func(LagSample) // 每次收到带发布时间的广播、跨区域失效或失效事件后调用，用于接入监控系统
+
+	BypassPercent  float64                            // GetOrLoad绕过缓存直接调用loader的百分比(0-100)，用于测量真实的陈旧率和缓存带来的延迟收益
+	BypassCompare  func(cached, loaded interface{}) bool // 判断缓存的值与loader返回的值是否相同(默认比较规范JSON形式)
+	OnBypassSample func(BypassSample)                 // 每次对比采样后调用
+
+	ReadRepairPercent float64          // Get命中L1时同时读取L2核对的百分比(0-100)，发现两级的过期时间或版本不一致时按ReadRepairPolicy处理(0表示不核对)
+	ReadRepairPolicy  ReadRepairPolicy // 两级不一致时的处理方式(默认只记录)
+
+	L2Quotas []PrefixQuota   // 按键前缀限制本实例写入L2的字节数(估计值)，保护共享的Redis
+	OnQuota  func(QuotaEvent) // 前缀的用量超过或回落到软配额以下时调用
+
+	DegradedModes           map[DegradedState]DegradedAction // 各故障组合下GetOrLoad的行为(未列出的状态使用可用的级别)
+	DegradedStaleFor        int64                            // DegradeServeStale时返回过期多久以内的L1项(秒，默认60)
+	DegradedLoadConcurrency int                              // DegradeQueue时同时执行的loader数(默认4)
+	DegradedQueueTimeout    time.Duration                    // DegradeQueue时排队的最长时间，超时返回ErrOverloaded(默认5秒)
+	OnDegradedChange        func(DegradedStatus)             // 故障状态变化时调用
+
+	MeasureStaleness bool                // 写入替换L1中已有值时对比新旧值，统计数据变化的频率和旧值缓存的时长
+	OnRefresh        func(RefreshSample) // MeasureStaleness时每次替换后调用
+
+	CanaryInterval time.Duration // 定期探测L1、Redis、pipeline和pub/sub的间隔(0表示不定期探测，仍可调用RunCanary)
+	CanaryTimeout  time.Duration // 每个组件探测的超时(默认2秒)
+
+	Codec Codec // L2中缓存项的编解码器(默认JSONCodec)
+
+	Envelope          bool              // L2中的值带上版本化信封，记录编解码器和压缩方式(读取时总是兼容带信封和不带信封的值)
+	CodecID           CodecID           // Codec在信封中的编号(CodecIDJSON以外的值，由使用同一Redis的服务约定)
+	Codecs            map[CodecID]Codec // 额外用于解码的编解码器，读取其他服务写入的值
+	Compression       Compression       // 信封中载荷的压缩方式(默认不压缩)
+	CompressThreshold int               // 载荷超过该大小才压缩(字节，默认1KB)
+	Checksum          bool              // 信封中附带载荷的CRC32C校验和，读取时校验失败返回ErrCorrupted
+	MaxDecodedSize    int64             // 解压后载荷的最大字节数，超过时按解码失败处理(默认64MB)
+	MaxJSONDepth      int               // 解码JSON载荷前检查的最大嵌套深度(默认1000，负数表示不检查)
+	Encryption        KeyProvider       // 信封中的载荷用AES-GCM加密，按键选择密钥(需要启用Envelope)
+	DecodePolicy      DecodePolicy      // L2中的值无法解码时的处理策略(默认按未命中处理)
+	QuarantineAfter   int               // DecodeQuarantine策略下，窗口内解码失败多少次后隔离(默认3)
+	QuarantineWindow  time.Duration     // 统计解码失败次数的窗口(默认1分钟)
+	QuarantineTTL     time.Duration     // 隔离记录的保留时间(默认24小时)
+	QuarantineBlock   time.Duration     // 隔离后阻止该键被重新写入的时长(0表示不阻止)
+
+	ValueVersion int                   // 当前写入的值版本，读到旧版本的值时按Migrations逐级升级
+	Migrations   map[int]MigrationFunc // 版本v->v+1的迁移函数，缺少某一级时旧值作废
+
+	MaxKeyLength int             // 键的最大字节数(0表示最多64KB，超过时拒绝)
+	KeyLength    KeyLengthPolicy // 键超长时的处理策略(默认拒绝)
+	KeyChars     KeyCharsPolicy  // 键中包含空白或控制字符时的处理策略(默认不检查)
+
+	TopKeys int // 统计访问最多的键的数量，用于仪表盘(0表示不统计)
+
+	SlowGetThreshold  time.Duration // 读取耗时超过该值时记入慢操作日志(0表示不记录)
+	SlowSetThreshold  time.Duration // 写入耗时超过该值时记入慢操作日志(0表示不记录)
+	SlowLoadThreshold time.Duration // loader耗时超过该值时记入慢操作日志(0表示不记录)
+	SlowLogSize       int           // 慢操作日志保留的条数(默认128)
+
+	AccessLog            io.Writer // 采样的访问日志输出(二进制格式，可用ReadAccessLog读取；nil表示不记录)
+	AccessLogSampleEvery int       // 每多少次访问记录一次(默认1，即全部记录)
+
+	TestHooks *TestHooks // 测试同步点，仅用于集成测试
+
+	GhostListSize int           // 记录最近被淘汰键的影子列表容量，淘汰后很快被再次请求的键会直接升级(0表示不启用；动态调整容量时默认为上下限之差)
+	GhostWindow   time.Duration // 淘汰后多久内再次请求会直接升级(0表示只受影子列表容量限制)
+
+	L1SizeMin           int           // 动态调整本地缓存容量的下限(与L1SizeMax同时配置时启用)
+	L1SizeMax           int           // 动态调整本地缓存容量的上限
+	L1SizeStep          int           // 每次调整的条目数(默认上下限之差的1/10)
+	L1SizeInterval      time.Duration // 容量调整间隔(默认1分钟)
+	L1SizeGrowThreshold float64       // 影子列表命中占L1查找的比例达到该值时扩容(默认0.01)
+
+	TimeSource         TimeSource    // 过期时间计算使用的时钟(默认本机时钟；TimeRedis需要启用L2)
+	TimeSyncInterval   time.Duration // TimeRedis时与Redis TIME校准的间隔(默认1分钟)
+	ClockSkewTolerance time.Duration // 从L2读取的值按本机时间已过期、但仍在该范围内时按命中处理，避免写入方时钟偏快导致提前过期(0表示不容忍)
+
+	L2RateLimit int // 每秒L2操作数的共享上限，正常请求不受限制，预热只使用剩余的额度(0表示不限制)
+
+	Logger Logger // 日志输出，用于报告异步操作等无法返回给调用方的错误(为空时不输出)
 }
 
 // CacheItem 缓存项
@@ -38,320 +215,843 @@ type CacheItem struct {
 	CreateTime int64       `json:"create_time"` // 创建时间戳
 	AccessTime int64       `json:"access_time"` // 最后访问时间戳
 	AccessCount int64      `json:"access_count"` // 访问次数
+	FreshUntil  int64      `json:"fresh_until,omitempty"` // 新鲜截止时间戳(0表示不区分新鲜与陈旧)
+	Version     int        `json:"version,omitempty"`     // 值的版本，用于部署后迁移旧格式的值
+	Type        string     `json:"type,omitempty"`        // 值的注册类型名(RegisterType)，指针类型以*开头
+
+	l2Synced int32 // 值与L2中的副本一致(写入或读取自L2后未修改)
+	dirty    int64 // 尚未回写到L2的访问次数
+	promotion int32 // 升级状态，用于统计升级效果
+	seq      uint64 // 写入本地缓存的序号，用于淘汰顺序的平局裁决
+	internKey string // 值与其他键共享时为内容哈希，读取时需要返回副本
+	checksum  string // VerifyImmutable时写入L1的值的内容哈希，用于发现被调用方修改的值
+	revalidateAt int64 // 从L2升级的项在L1中的停留期限，到期后需重新从L2读取(0表示不限制)
+	l2Seq    uint64 // 启用SequencedWrites时写入该值登记的写入序号(0表示未知，如从L2读取的项)
 }
 
 // MultiLevelCache 多级缓存实现
 type MultiLevelCache struct {
-	config         CacheConfig
-	localCache     sync.Map      // 本地内存缓存
-	redisClient    *redis.Client // Redis客户端
+	cfg            atomic.Value  // 当前配置(*CacheConfig)，可通过UpdateConfig在运行时替换
+	shards         []*l1Shard    // 本地内存缓存分片
+	redisClient    RedisCommander // Redis客户端
+	server         *ServerInfo     // CompatibilityMode检测到的服务端信息(nil表示未检测)
+	tracking       *clientTracking // Redis客户端缓存跟踪(nil表示未启用)
+	regions        *regionState    // 多区域部署中的远端区域(nil表示未配置Region)
 	mutex          sync.RWMutex  // 读写锁
 	ctx            context.Context
-	itemCount      int           // 当前本地缓存项数量
-	cleanupTicker  *time.Ticker  // 清理过期项的定时器
 	stopCleanup    chan struct{} // 停止清理的信号
+	pressureShrinks int64        // 因内存压力收缩L1的次数
+	sweptItems     int64         // 后台清扫回收的过期项数量
+	serializer     *serializePool // 大值序列化工作池
+	loads          loadGroup      // 合并同一键的并发加载
+	tagIndex       map[string]*tagSet // 本地缓存的标签索引(标签->键)
+	tagPruning     sync.Map        // 正在后台清理Redis索引的标签
+	tagPruned      int64           // 从标签索引中清理的已过期键数
+	tagCapped      int64           // 因超过TagIndexMaxSize被移出标签索引的键数
+	tagTTLs        sync.Map        // 运行时设置的标签最长过期时间(标签->秒)，优先于TagMaxTTLs
+	coalescer      *writeCoalescer // L2写入合并器
+	tombstones     sync.Map        // 最近删除的键(键->墓碑到期时间)
+	circuit        *circuitBreaker // L2熔断器
+	gutterClient   *redis.Client   // 熔断期间使用的gutter Redis客户端
+	loadLimiter    *loadLimiter    // 加载并发限制
+	shedLoads      int64           // 因过载被拒绝的加载次数
+	promoStats     promotionStats  // 升级效果统计
+	insertSeq      uint64          // 本地缓存写入序号
+	sizer          *l1Sizer        // 本地缓存容量控制器
+	ghost          *ghostList      // 最近被淘汰键的影子列表
+	readmissions   int64           // 因影子列表命中直接升级的次数
+	affinitySkips  int64           // 因键不归本实例所有而跳过升级的次数
+	cappedPromotions int64         // 批量读取中因超过MaxBatchPromotions而未升级的次数
+	clampedTTLs    int64           // 因MinTTL/MaxTTL被调整过期时间的写入次数
+	instanceID     string          // 本实例的随机标识，用于忽略自己发出的广播和区分探测键
+	broadcastsSent int64           // 发出的广播数量
+	broadcastsRecv int64           // 收到并写入L1的广播数量
+	clockOffset    int64           // Redis时钟减去本机时钟的偏差(纳秒)，TimeRedis时定期校准
+	l2Limiter      *rateLimiter    // L2操作的共享速率限制
+	scheduledInvalidations int64   // 定时失效的触发次数
+	interned       *internTable    // L1中内容相同的值共享的对象
+	mutatedValues  int64           // VerifyImmutable发现的被修改的值数量
+	recoveredPanics int64          // 用户回调和loader中被恢复的panic数量
+	corruptedItems int64           // 校验失败的L2值数量
+	decodeFailures int64           // 解码失败的L2值数量(包括校验失败)
+	failingKeys    sync.Map        // 键->*decodeFailureCount，用于判断是否隔离
+	hits           hitStats        // 命中和淘汰统计
+	topKeys        *topKeys        // 访问最多的键
+	slowLog        *slowLog        // 慢操作日志
+	accessLog      *accessLog      // 采样的访问日志
+	epoch          sync.RWMutex    // SetMulti写入L1时持有写锁，读取L1时持有读锁，保证多个键同时可见
+	maint          [2]maintenance  // L1和L2的后台维护协程，按CacheLevel索引
+	alarms         alarmState      // 命中率和淘汰速率告警
+	rates          opRates         // 各类操作的滚动速率
+	bypass         bypassStats     // 绕过缓存的对比采样统计
+	staleness      stalenessStats  // 替换值的对比统计
+	canary         canaryState     // 最近一次探测的结果
+	lag            invalidationLag // 失效传播延迟统计
+	reconcile      reconcileState  // 订阅重连后的对账状态
+	hints          warmHints       // 其他实例Publish的预热提示
+	readRepairs    readRepairStats // 读修复统计
+	quotaLedgers   sync.Map        // 前缀->*quotaLedger，写入L2的字节数
+	quotaWarnings  int64           // 用量超过软配额的次数
+	degraded       degradedTracker // 故障状态
+	lastPressure   int64           // 最近一次因内存压力收缩L1的时间(Unix纳秒)
+	scripts        scriptCache     // 内部Lua脚本的加载状态和复用的pipeline
+	gutterProbing  int32           // 使用gutter期间是否有进行中的主Redis探测
+	ciphers        cipherCache     // 按密钥编号缓存的加密cipher
+	setLanes       setLanes        // SerializeSets按键串行化写入
+	supersededWrites int64         // SequencedWrites下被更晚的写入或删除取代的写入数
+	updateFailures   int64         // UpdateThrough中数据源写入失败后删除缓存的次数
+	generations      generations   // 分层键各级前缀的代数
+	closed         int32           // Close之后为1，新的操作返回ErrClosed
+	active         int64           // 进行中的操作数，Close等待其归零后再关闭Redis连接
+	bg             sync.WaitGroup  // 后台协程，Close等待其全部退出
 }
 
 // NewMultiLevelCache 创建新的多级缓存
 func NewMultiLevelCache(config CacheConfig) (*MultiLevelCache, error) {
 	cache := &MultiLevelCache{
-		config:      config,
+		shards:      newL1Shards(config.L1Shards),
 		ctx:         context.Background(),
 		stopCleanup: make(chan struct{}),
 	}
+	cache.lag.windowMax = -1
+	cache.loads.onPanic = cache.reportPanic
+	cache.loads.onStart = cache.retain
+	cache.loads.onDone = cache.exit
+
+	// 校验配置，错误导致创建失败，警告在配置生效后通过Logger输出
+	var errs, warnings []ConfigIssue
+	for _, issue := range config.issues() {
+		if issue.Warning {
+			warnings = append(warnings, issue)
+		} else {
+			errs = append(errs, issue)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ConfigError{Issues: errs}
+	}
+
+	// 实例标识，用于忽略自己发出的广播、区分各实例的探测键和Redis连接名
+	cache.instanceID = newInstanceID()
 
 	// 初始化Redis客户端(如果启用)
 	if config.EnableL2Cache {
-		if config.RedisOptions == nil {
-			return nil, errors.New("Redis配置不能为空")
+		cache.redisClient = config.RedisClient
+		if cache.redisClient == nil {
+			opts, err := cache.redisOptions(&config, regionRedisOptions(&config))
+			if err != nil {
+				return nil, err
+			}
+			if config.ClientTracking {
+				if opts, err = cache.setupTracking(opts, config.TrackingPrefixes); err != nil {
+					return nil, err
+				}
+			}
+			cache.redisClient = redis.NewClient(opts)
+		}
+		if config.L2ReadTimeout > 0 || config.L2WriteTimeout > 0 {
+			cache.redisClient.AddHook(&timeoutHook{read: config.L2ReadTimeout, write: config.L2WriteTimeout})
 		}
-		cache.redisClient = redis.NewClient(config.RedisOptions)
 		// 测试连接
 		_, err := cache.redisClient.Ping(cache.ctx).Result()
 		if err != nil {
 			return nil, err
 		}
+
+		// 启用熔断器和gutter(如果配置)
+		if config.CircuitFailureThreshold > 0 {
+			cache.circuit = newCircuitBreaker(config.CircuitFailureThreshold, config.CircuitOpenDuration)
+			cache.redisClient.AddHook(&circuitHook{cb: cache.circuit})
+			if config.GutterRedisOptions != nil {
+				opts, err := cache.redisOptions(&config, config.GutterRedisOptions)
+				if err != nil {
+					return nil, err
+				}
+				cache.gutterClient = redis.NewClient(opts)
+				if config.L2ReadTimeout > 0 || config.L2WriteTimeout > 0 {
+					cache.gutterClient.AddHook(&timeoutHook{read: config.L2ReadTimeout, write: config.L2WriteTimeout})
+				}
+			}
+		}
+
+		// 连接其他区域的Redis(如果配置)
+		if err := cache.setupRegions(&config); err != nil {
+			return nil, err
+		}
 	}
 
 	// 如果未设置策略，使用默认策略
 	if config.PromotionStrategy == nil {
-		cache.config.PromotionStrategy = NewFrequencyBasedStrategy(3, 60, 0)
+		config.PromotionStrategy = NewFrequencyBasedStrategy(3, 60, 0)
 	}
 	
 	if config.DemotionStrategy == nil {
-		cache.config.DemotionStrategy = NewFrequencyBasedStrategy(0, 0, 300) // 5分钟未访问降级
+		config.DemotionStrategy = NewFrequencyBasedStrategy(0, 0, 300) // 5分钟未访问降级
 	}
 
-	// 启动定期清理过期项的协程
-	if config.EnableL1Cache {
-		cache.cleanupTicker = time.NewTicker(time.Minute) // 每分钟清理一次
-		go cache.cleanupRoutine()
+	// 动态调整容量时初始容量限制在上下限之间
+	if config.L1SizeMin > 0 && config.L1SizeMax > config.L1SizeMin {
+		if config.MaxL1Size < config.L1SizeMin {
+			config.MaxL1Size = config.L1SizeMin
+		}
+		if config.MaxL1Size > config.L1SizeMax {
+			config.MaxL1Size = config.L1SizeMax
+		}
+	}
+	cache.cfg.Store(&config)
+	for _, issue := range warnings {
+		cache.logf("dancache: config %s: %s", issue.Field, issue.Message)
 	}
 
-	return cache, nil
-}
+	// 检测服务端支持的功能(如果配置)，需在启动维护协程之前完成
+	if config.EnableL2Cache && config.CompatibilityMode && !config.RedisProxyMode {
+		cache.detectServer()
+	}
+
+	// 预加载内部Lua脚本，之后使用EVALSHA
+	if config.EnableL2Cache {
+		cache.preloadScripts()
+	}
 
-// cleanupRoutine 定期清理过期和需要降级的缓存项
-func (c *MultiLevelCache) cleanupRoutine() {
-	for {
-		select {
-		case <-c.cleanupTicker.C:
-			c.cleanupExpiredItems()
-		case <-c.stopCleanup:
-			c.cleanupTicker.Stop()
-			return
+	// 统计访问最多的键(如果配置)
+	if config.TopKeys > 0 {
+		cache.topKeys = newTopKeys(config.TopKeys)
+	}
+
+	// 启用慢操作日志(如果配置)
+	if config.SlowGetThreshold > 0 || config.SlowSetThreshold > 0 || config.SlowLoadThreshold > 0 {
+		cache.slowLog = newSlowLog(config.SlowLogSize)
+	}
+
+	// 启用访问日志(如果配置)
+	if config.AccessLog != nil {
+		cache.accessLog = newAccessLog(config.AccessLog, config.AccessLogSampleEvery, cache.logf)
+	}
+
+	// 启用L2写入合并(如果配置)
+	if config.EnableL2Cache && config.WriteCoalesceWindow > 0 {
+		cache.coalescer = newWriteCoalescer(config.WriteCoalesceWindow)
+	}
+
+	// 启用L1值去重(如果配置)
+	if config.EnableL1Cache && config.InternValues {
+		cache.interned = newInternTable()
+	}
+
+	// 启用L2速率限制(如果配置)
+	if config.EnableL2Cache && config.L2RateLimit > 0 {
+		cache.l2Limiter = newRateLimiter(config.L2RateLimit)
+	}
+
+	// 启用加载准入控制(如果配置)
+	if config.MaxConcurrentLoads > 0 {
+		cache.loadLimiter = newLoadLimiter(config.MaxConcurrentLoads, config.LoadQueueTimeout)
+	}
+
+	// 启动序列化工作池(如果配置)
+	if config.SerializeWorkers > 0 {
+		cache.serializer = newSerializePool(config.SerializeWorkers, cache.stopCleanup)
+	}
+
+	// 与Redis时钟校准(如果配置)，先同步校准一次，保证之后写入的过期时间基于Redis时钟
+	if config.EnableL2Cache && config.TimeSource == TimeRedis && !config.RedisProxyMode {
+		if err := cache.syncClock(); err != nil {
+			cache.logf("dancache: sync clock with redis failed: %v", err)
 		}
 	}
+
+	// 启用影子列表和本地缓存容量动态调整(如果配置)
+	if config.EnableL1Cache {
+		ghostSize := config.GhostListSize
+		if config.L1SizeMin > 0 && config.L1SizeMax > config.L1SizeMin {
+			if ghostSize <= 0 {
+				ghostSize = config.L1SizeMax - config.L1SizeMin
+			}
+			cache.sizer = &l1Sizer{}
+		}
+		if ghostSize > 0 {
+			cache.ghost = newGhostList(ghostSize, config.GhostWindow)
+		}
+	}
+
+	// 启动清理、监听等后台维护协程，LazyMaintenance时推迟到首次使用对应级别
+	if !config.LazyMaintenance {
+		cache.ensureMaintenance(L1Cache)
+		cache.ensureMaintenance(L2Cache)
+	}
+
+	// 启动跨区域失效的发送协程(如果配置)
+	cache.startRegionSenders()
+
+	return cache, nil
 }
 
-// cleanupExpiredItems 清理过期和需要降级的缓存项
-func (c *MultiLevelCache) cleanupExpiredItems() {
-	now := time.Now().Unix()
-	keysToDelete := make([]string, 0)
-	keysToDemote := make([]string, 0)
+// cleanupShard 清理分片中过期和需要降级的缓存项
+func (c *MultiLevelCache) cleanupShard(shard *l1Shard) {
+	now := c.nowUnix()
+	expired := make([]keyedItem, 0)
+	toDemote := make([]keyedItem, 0)
 	
-	// 收集需要删除和降级的键
-	c.localCache.Range(func(key, value interface{}) bool {
-		k := key.(string)
-		item := value.(*CacheItem)
-		
-		// 检查是否过期
-		if item.ExpireTime <= now {
-			keysToDelete = append(keysToDelete, k)
+	// 收集需要删除和降级的项
+	shard.rangeItems(func(k string, item *CacheItem) bool {
+		// 检查是否过期(带新鲜度的项超过FreshUntil后不再留在L1)
+		if !item.validInL1(now) {
+			expired = append(expired, keyedItem{key: k, item: item})
 			return true
 		}
 		
 		// 检查是否需要降级
-		if c.config.DemotionStrategy.ShouldDemote(item) {
-			keysToDemote = append(keysToDemote, k)
+		if c.shouldDemote(item) {
+			toDemote = append(toDemote, keyedItem{key: k, item: item})
 		}
 		
 		return true
 	})
 	
-	// 删除过期项
-	for _, k := range keysToDelete {
-		c.localCache.Delete(k)
-		c.itemCount--
+	// 删除过期项，只删除仍是同一项的键，避免误删收集之后并发写入的新值
+	for _, ki := range expired {
+		if shard.removeIf(ki.key, ki.item) {
+			c.recordL1Removal(ki.item)
+		}
 	}
 	
 	// 处理需要降级的项
-	for _, k := range keysToDemote {
-		if v, ok := c.localCache.Load(k); ok {
-			item := v.(*CacheItem)
-			// 如果启用了L2缓存，将项降级到L2
-			if c.config.EnableL2Cache {
-				jsonData, err := json.Marshal(item)
-				if err == nil {
-					ttl := item.ExpireTime - now
-					if ttl > 0 {
-						c.redisClient.Set(c.ctx, k, jsonData, time.Duration(ttl)*time.Second)
-					}
-				}
-			}
-			// 从本地缓存中删除
-			c.localCache.Delete(k)
-			c.itemCount--
+	demoted := make([]keyedItem, 0, len(toDemote))
+	for _, ki := range toDemote {
+		if shard.removeIf(ki.key, ki.item) {
+			demoted = append(demoted, ki)
+			c.recordL1Removal(ki.item)
 		}
 	}
+	// 如果启用了L2缓存，将项批量降级到L2，否则交给淘汰导出器
+	if c.config().EnableL2Cache {
+		c.demoteBatch(demoted)
+	}
+	c.exportEvicted(demoted, EvictDemotion)
+	
+	// 清理过期的墓碑和解码失败计数(由第一个分片负责)
+	if shard == c.shards[0] {
+		c.cleanupTombstones()
+		c.cleanupDecodeFailures()
+		c.pruneInterned()
+	}
 	
 	// 如果超过最大大小限制，进行LRU淘汰
-	if c.config.MaxL1Size > 0 && c.itemCount > c.config.MaxL1Size {
-		c.evictLRU(c.itemCount - c.config.MaxL1Size)
+	if count := c.l1Count(); c.config().MaxL1Size > 0 && count > c.config().MaxL1Size {
+		c.evictLRU(count-c.config().MaxL1Size, EvictCapacity)
 	}
 }
 
 // evictLRU 淘汰最近最少使用的缓存项
-func (c *MultiLevelCache) evictLRU(count int) {
-	type itemWithKey struct {
-		key  string
-		item *CacheItem
-	}
-	
-	// 收集所有项并按访问时间排序
-	items := make([]itemWithKey, 0, c.itemCount)
-	c.localCache.Range(func(key, value interface{}) bool {
-		k := key.(string)
-		item := value.(*CacheItem)
-		items = append(items, itemWithKey{key: k, item: item})
+func (c *MultiLevelCache) evictLRU(count int, reason EvictionReason) {
+	// 收集所有项，按访问时间(配置了分区时先按分区份额)选出淘汰的项
+	items := make([]keyedItem, 0, c.l1Count())
+	c.rangeL1(func(k string, item *CacheItem) bool {
+		items = append(items, keyedItem{key: k, item: item})
 		return true
 	})
+	victims := c.evictionVictims(items, count)
 	
-	// 按访问时间排序（升序，最早访问的在前面）
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].item.AccessTime < items[j].item.AccessTime
-	})
-	
-	// 淘汰指定数量的项
-	evictCount := count
-	if evictCount > len(items) {
-		evictCount = len(items)
+	// 从本地缓存中删除
+	evicted := make([]keyedItem, 0, len(victims))
+	for _, ki := range victims {
+		if c.shardFor(ki.key).removeIf(ki.key, ki.item) {
+			evicted = append(evicted, ki)
+			c.recordL1Removal(ki.item)
+		}
 	}
 	
-	for i := 0; i < evictCount; i++ {
-		k := items[i].key
-		item := items[i].item
-		
-		// 如果启用了L2缓存，将项降级到L2
-		if c.config.EnableL2Cache {
-			jsonData, err := json.Marshal(item)
-			if err == nil {
-				ttl := item.ExpireTime - time.Now().Unix()
-				if ttl > 0 {
-					c.redisClient.Set(c.ctx, k, jsonData, time.Duration(ttl)*time.Second)
-				}
+	// 如果启用了L2缓存，将项批量降级到L2，否则交给淘汰导出器
+	if c.config().EnableL2Cache {
+		c.demoteBatch(evicted)
+	}
+	c.recordEvicted(evicted, reason)
+	atomic.AddInt64(&c.hits.evictions, int64(len(evicted)))
+	c.exportEvicted(evicted, reason)
+}
+
+// Set 设置缓存，opts可以跳过L1或L2(SkipL1、SkipL2)
+func (c *MultiLevelCache) Set(key string, value interface{}, ttl int64, opts ...CallOption) error {
+	return c.set(c.ctx, key, value, ttl, nil, false, applyCallOptions(opts))
+}
+
+// set Set的实现，broadcast为true或覆盖的是热点键时写入成功后向其他实例广播新值
+// ctx用于L2写入，调用方需传入已脱离取消的context；tags用于按标签的最长过期时间限制ttl
+// o.async非nil时L1同步写入，L2写入在后台完成后通过o.async报告结果，不在后台写入的情况在返回前完成o.async
+func (c *MultiLevelCache) set(ctx context.Context, key string, value interface{}, ttl int64, tags []string, broadcast bool, o callOptions) (err error) {
+	launched := false
+	if o.async != nil {
+		defer func() {
+			if !launched {
+				o.async.complete(nil, err == nil, err)
 			}
+		}()
+	}
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return err
+	}
+	if err := o.requireLevel(c.config()); err != nil {
+		return err
+	}
+	key, err = c.normalizeKey(key)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	defer c.recordSlow("set", key, start, "", value)
+	defer c.recordAccess(AccessSet, key, start, nil, false)
+	if c.hasTombstone(key) {
+		return ErrTombstoned
+	}
+	done, superseded := c.serializeSet(key)
+	defer func() {
+		// 后台写入L2时由后台协程在写入完成后释放
+		if !launched {
+			done()
 		}
-		
-		// 从本地缓存中删除
-		c.localCache.Delete(k)
-		c.itemCount--
+	}()
+	if superseded {
+		return nil
+	}
+	// 启用写入序号时在写入开始时登记，之后登记的写入或删除胜出
+	seq := o.seq
+	if seq == 0 && !o.skipL2 && c.sequenced() {
+		reserved, rerr := c.reserveSequence(ctx, key)
+		if rerr != nil {
+			c.logf("dancache: reserve write sequence for %q failed: %v", key, rerr)
+		}
+		seq = reserved
+	}
+	item := c.newCacheItem(value, ttl)
+	item.FreshUntil = o.freshUntil
+	if c.sequenced() {
+		item.l2Seq = seq
+	}
+	c.applyTagTTL(item, tags)
+	ttl = item.lifetime()
+	c.measureRefresh(key, item)
+	broadcast = !o.skipL2 && (broadcast || c.hotForBroadcast(key))
+	if broadcast {
+		defer func() {
+			if err == nil && !launched {
+				c.broadcast(key, item)
+			}
+		}()
 	}
-}
 
-// Set 设置缓存
-func (c *MultiLevelCache) Set(key string, value interface{}, ttl int64) error {
-	now := time.Now().Unix()
-	expireTime := now + ttl
-	
-	item := &CacheItem{
-		Value:      value,
-		ExpireTime: expireTime,
-		CreateTime: now,
-		AccessTime: now,
-		AccessCount: 0,
+	// 检查值大小，决定写入哪些级别
+	jsonData, toL1, toL2, err := c.admitSize(key, item)
+	if err != nil {
+		return err
 	}
+	toL2 = toL2 && !o.skipL2
 
-	// 设置本地缓存
-	if c.config.EnableL1Cache {
-		// 检查是否已存在该键
-		if _, exists := c.localCache.Load(key); !exists {
-			c.itemCount++
+	// 设置本地缓存；跳过L1时删除旧值，避免之后读到与L2不一致的值
+	if o.skipL1 {
+		if c.config().EnableL1Cache {
+			c.deleteL1(key)
 		}
-		c.localCache.Store(key, item)
-		
-		// 如果超过最大大小限制，进行LRU淘汰
-		if c.config.MaxL1Size > 0 && c.itemCount > c.config.MaxL1Size {
-			c.evictLRU(1) // 淘汰一项
+	} else if toL1 {
+		c.storeL1(key, item)
+	}
+
+	// 熔断期间用本地缓存代替Redis，只保留较短时间
+	if toL2 && !o.skipL1 && c.useL1Gutter() {
+		c.storeL1Gutter(key, item)
+		return nil
+	}
+
+	// 设置Redis缓存，启用写入合并时在窗口结束后写入最新值
+	if toL2 {
+		if c.coalescer != nil {
+			c.queueL2Write(ctx, key, item, ttl)
+			c.replicate(key)
+			return nil
+		}
+
+		if o.async != nil {
+			launched = true
+			c.retain()
+			go func() {
+				defer c.exit()
+				defer done()
+				err := c.writeSetL2(ctx, key, item, jsonData, ttl, seq, o)
+				if err != nil {
+					c.logf("dancache: async set %q failed: %v", key, err)
+				} else if broadcast {
+					c.broadcast(key, item)
+				}
+				o.async.complete(nil, err == nil, err)
+			}()
+			return nil
 		}
+		return c.writeSetL2(ctx, key, item, jsonData, ttl, seq, o)
 	}
 
-	// 设置Redis缓存
-	if c.config.EnableL2Cache {
-		jsonData, err := json.Marshal(item)
-		if err != nil {
+	return nil
+}
+
+// writeSetL2 将set创建的缓存项写入L2，data为nil时先序列化
+// 带写入序号的写入已被更晚的写入或删除取代时撤销本次写入L1的值，o.strict时返回ErrSuperseded
+func (c *MultiLevelCache) writeSetL2(ctx context.Context, key string, item *CacheItem, data []byte, ttl int64, seq uint64, o callOptions) error {
+	if data == nil {
+		var err error
+		if data, err = c.marshalItem(key, item); err != nil {
 			return err
 		}
-		
-		err = c.redisClient.Set(c.ctx, key, jsonData, time.Duration(ttl)*time.Second).Err()
+	}
+
+	if seq > 0 && c.sequenced() {
+		written, err := c.setL2Sequenced(ctx, key, data, time.Duration(ttl)*time.Second, seq)
 		if err != nil {
 			return err
 		}
+		if !written {
+			if c.shardFor(key).removeIf(key, item) {
+				c.recordL1Removal(item)
+			}
+			if o.strict {
+				return ErrSuperseded
+			}
+			return nil
+		}
+	} else if err := c.setL2(ctx, key, data, time.Duration(ttl)*time.Second).Err(); err != nil {
+		return err
 	}
-
+	item.markL2Synced()
+	c.replicate(key)
 	return nil
 }
 
-// Get 获取缓存
-func (c *MultiLevelCache) Get(key string) (interface{}, bool) {
-	now := time.Now().Unix()
+// promote 根据升级策略将从L2读取的项升级到L1，返回是否升级
+func (c *MultiLevelCache) promote(key string, item *CacheItem) bool {
+	if !c.promotable(key, item) {
+		return false
+	}
+	c.applyPromotion(key, item)
+	return true
+}
+
+// promotable 判断从L2读取的项是否应升级到L1
+func (c *MultiLevelCache) promotable(key string, item *CacheItem) bool {
+	if !c.config().EnableL1Cache || item.isStale(c.nowUnix()) || c.trackingBlocksPromotion(key) {
+		return false
+	}
+	// 一致性哈希集群中只升级归本实例所有的键，避免同一热点键占用每个实例的L1
+	if !c.ownsKey(key) {
+		return false
+	}
+	return c.readmitted(key) || c.takeWarmHint(key) || c.shouldPromote(item)
+}
+
+// applyPromotion 将项从L2升级到L1，配置了PromotionTTL时只在L1中停留有限的时间
+func (c *MultiLevelCache) applyPromotion(key string, item *CacheItem) {
+	if budget := c.config().PromotionTTL; budget > 0 {
+		item.revalidateAt = c.nowUnix() + budget
+	}
+	c.boundL1(item, c.nowUnix())
+	c.recordPromotion(item)
+	c.storeL1(key, item)
+	c.hookAfterPromotion(key)
+}
+
+// newCacheItem 创建新的缓存项，值带上当前的值版本，过期时间按MinTTL/MaxTTL限制
+func (c *MultiLevelCache) newCacheItem(value interface{}, ttl int64) *CacheItem {
+	recordRate(&c.rates.sets)
+	now := c.nowUnix()
+	item := &CacheItem{
+		Value:      c.cloneOnWrite(value),
+		ExpireTime: now + c.clampTTL(ttl),
+		CreateTime: now,
+		AccessTime: now,
+		AccessCount: 0,
+		Version:    c.config().ValueVersion,
+	}
+	c.boundL1(item, now)
+	return item
+}
+
+// evictsBefore 判断淘汰时是否应排在other之前
+// 先比较访问时间，相同时依次比较创建时间和写入本地缓存的序号，保证淘汰顺序可复现
+func (item *CacheItem) evictsBefore(other *CacheItem) bool {
+	if item.AccessTime != other.AccessTime {
+		return item.AccessTime < other.AccessTime
+	}
+	if item.CreateTime != other.CreateTime {
+		return item.CreateTime < other.CreateTime
+	}
+	return item.seq < other.seq
+}
+
+// storeL1 写入本地缓存，超过大小限制时进行LRU淘汰
+func (c *MultiLevelCache) storeL1(key string, item *CacheItem) {
+	// 新增键时检查大小
+	if c.insertL1(key, item) {
+		c.enforceL1Size()
+	}
+}
+
+// insertL1 写入所在分片，不进行淘汰，返回是否新增了键
+func (c *MultiLevelCache) insertL1(key string, item *CacheItem) bool {
+	c.ensureMaintenance(L1Cache)
+	item.seq = atomic.AddUint64(&c.insertSeq, 1)
+	c.internItem(item)
+	c.sealItem(item)
+	return c.shardFor(key).store(key, item)
+}
+
+// enforceL1Size 超过最大大小限制时淘汰多出的项
+func (c *MultiLevelCache) enforceL1Size() {
+	if max := c.config().MaxL1Size; max > 0 {
+		if count := c.l1Count(); count > max {
+			c.evictLRU(count-max, EvictCapacity)
+		}
+	}
+}
+
+// Get 获取缓存，opts可以跳过L1或L2(SkipL1、SkipL2)
+func (c *MultiLevelCache) Get(key string, opts ...CallOption) (interface{}, bool) {
+	item, found, _ := c.getItemChecked(key, applyCallOptions(opts))
+	if !found {
+		return nil, false
+	}
+	return c.readValue(item), true
+}
+
+// GetWithError 获取缓存，L2中的值校验失败时返回ErrCorrupted，DecodePolicy为DecodeReturnError时解码失败返回*DecodeError，便于区分数据损坏与正常未命中
+func (c *MultiLevelCache) GetWithError(key string, opts ...CallOption) (interface{}, bool, error) {
+	item, found, err := c.getItemChecked(key, applyCallOptions(opts))
+	if !found {
+		return nil, false, err
+	}
+	return c.readValue(item), true, nil
+}
+
+// getItem 依次从本地缓存和Redis获取缓存项，并更新访问信息
+func (c *MultiLevelCache) getItem(key string) (*CacheItem, bool) {
+	item, found, _ := c.getItemChecked(key, callOptions{})
+	return item, found
+}
+
+// getItemChecked 获取缓存项，L2中的值损坏时返回ErrCorrupted
+func (c *MultiLevelCache) getItemChecked(key string, o callOptions) (*CacheItem, bool, error) {
+	if !c.enter() {
+		return nil, false, ErrClosed
+	}
+	defer c.exit()
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	start := time.Now()
+	item, level, found, err := c.lookupItem(key, o)
+	c.recordLookup(key, level, found)
+	c.recordAccess(AccessGet, key, start, &level, found)
+	if found {
+		c.recordSlow("get", key, start, level.String(), item.Value)
+	} else {
+		c.recordSlow("get", key, start, "miss", nil)
+	}
+	return item, found, err
+}
+
+// lookupItem 依次从L1、L2获取缓存项，返回命中的级别；o指定跳过的级别
+func (c *MultiLevelCache) lookupItem(key string, o callOptions) (*CacheItem, CacheLevel, bool, error) {
+	now := c.nowUnix()
 	
 	// 优先从本地缓存获取
-	if c.config.EnableL1Cache {
-		if val, ok := c.localCache.Load(key); ok {
-			item := val.(*CacheItem)
-			
-			// 检查是否过期
-			if item.ExpireTime > now {
-				// 更新访问信息
-				item.AccessTime = now
-				item.AccessCount++
-				c.localCache.Store(key, item)
-				return item.Value, true
-			} else {
-				// 过期了，删除
-				c.localCache.Delete(key)
-				c.itemCount--
+	if !o.skipL1 {
+		if item, ok := c.getL1(key, now); ok {
+			if !o.skipL2 && c.sampleReadRepair() {
+				item, level, found := c.readRepair(key, item, now)
+				return item, level, found, nil
 			}
+			return item, L1Cache, true, nil
 		}
 	}
 
 	// 如果本地缓存未命中或已过期，尝试从Redis获取
-	if c.config.EnableL2Cache {
-		jsonData, err := c.redisClient.Get(c.ctx, key).Bytes()
+	if c.config().EnableL2Cache && !o.skipL2 {
+		c.hookBeforeL2Get(key)
+		jsonData, err := c.l2().Get(c.ctx, key).Bytes()
 		if err != nil {
 			if err == redis.Nil {
-				return nil, false
+				return nil, L2Cache, false, nil
 			}
 			// Redis错误，返回未命中
-			return nil, false
+			return nil, L2Cache, false, nil
 		}
+		item, found, err := c.acceptL2Item(key, jsonData, now, !o.skipL1)
+		return item, L2Cache, found, err
+	}
 
-		var item CacheItem
-		if err := json.Unmarshal(jsonData, &item); err != nil {
-			return nil, false
-		}
+	return nil, L1Cache, false, nil
+}
 
-		// 检查是否过期(理论上Redis会自动过期，这里是双重检查)
-		if item.ExpireTime > now {
+// getL1 从本地缓存获取缓存项并更新访问信息，过期项会被删除
+func (c *MultiLevelCache) getL1(key string, now int64) (*CacheItem, bool) {
+	if !c.config().EnableL1Cache {
+		return nil, false
+	}
+	
+	c.epoch.RLock()
+	defer c.epoch.RUnlock()
+	return c.lookupL1(key, now)
+}
+
+// lookupL1 getL1的实现，调用方需持有epoch读锁
+func (c *MultiLevelCache) lookupL1(key string, now int64) (*CacheItem, bool) {
+	shard := c.shardFor(key)
+	if item, ok := shard.load(key); ok {
+		// 检查是否过期
+		if (item.validInL1(now) || c.servesStale(item, now)) && c.unmutated(key, item) {
 			// 更新访问信息
 			item.AccessTime = now
 			item.AccessCount++
-			
-			// 考虑是否需要升级到本地缓存
-			if c.config.EnableL1Cache && c.config.PromotionStrategy.ShouldPromote(&item) {
-				// 将项从L2升级到L1
-				c.localCache.Store(key, &item)
-				c.itemCount++
-				
-				// 如果超过最大大小限制，进行LRU淘汰
-				if c.config.MaxL1Size > 0 && c.itemCount > c.config.MaxL1Size {
-					c.evictLRU(1) // 淘汰一项
-				}
-			}
-			
-			// 更新Redis中的访问信息
-			jsonData, _ := json.Marshal(item)
-			c.redisClient.Set(c.ctx, key, jsonData, time.Duration(item.ExpireTime-now)*time.Second)
-			
-			return item.Value, true
+			item.markDirty()
+			c.recordL1Hit(item)
+			c.observeL1(key, true)
+			return item, true
+		} else if shard.removeIf(key, item) {
+			// 过期了，删除
+			c.recordL1Removal(item)
 		}
 	}
-
+	c.observeL1(key, false)
 	return nil, false
 }
 
+// acceptL2Item 解析从Redis读取的缓存项，更新访问信息，promotable为true时按策略升级到L1
+// 值损坏时按未命中处理并返回ErrCorrupted，其他解码失败按DecodePolicy处理
+func (c *MultiLevelCache) acceptL2Item(key string, jsonData []byte, now int64, promotable bool) (*CacheItem, bool, error) {
+	item, found, settled, err := c.readL2Item(key, jsonData, now)
+	if !found || !settled {
+		return item, found, err
+	}
+	
+	// 考虑是否需要升级到本地缓存
+	promoted := promotable && c.promote(key, item)
+	
+	// 按需更新Redis中的访问信息
+	c.writeBackAccess(key, item, promoted, time.Duration(item.ExpireTime-now)*time.Second)
+	
+	return item, true, nil
+}
+
+// readL2Item 解析从Redis读取的缓存项并更新访问信息，不升级
+// settled为false表示值在时钟偏差容忍范围内，不应升级到L1也不回写访问信息
+func (c *MultiLevelCache) readL2Item(key string, jsonData []byte, now int64) (item *CacheItem, found, settled bool, err error) {
+	item, err = c.unmarshalItem(key, jsonData, now)
+	if err != nil || item == nil {
+		return nil, false, false, err
+	}
+
+	// 检查是否过期(理论上Redis会自动过期，这里是双重检查)
+	// 写入方时钟偏快时，Redis中仍然有效的值按本机时间可能已过期，容忍范围内按命中处理
+	expired := item.ExpireTime <= now
+	if expired && item.ExpireTime+c.skewTolerance() <= now {
+		return nil, false, false, nil
+	}
+	
+	// 旧版本的值升级到当前版本
+	if !c.migrateItem(key, item, now) {
+		return nil, false, false, nil
+	}
+	
+	// 更新访问信息
+	item.AccessTime = now
+	item.AccessCount++
+	
+	return item, true, !expired, nil
+}
+
 // Delete 删除缓存
 func (c *MultiLevelCache) Delete(key string) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return err
+	}
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return err
+	}
+	defer c.recordAccess(AccessDelete, key, time.Now(), nil, false)
+
+	// 先记录墓碑，阻止进行中的加载回填旧数据
+	c.addTombstone(key)
+
 	// 删除本地缓存
-	if c.config.EnableL1Cache {
-		if _, exists := c.localCache.Load(key); exists {
-			c.localCache.Delete(key)
-			c.itemCount--
-		}
+	if c.config().EnableL1Cache {
+		c.deleteL1(key)
 	}
 
 	// 删除Redis缓存
-	if c.config.EnableL2Cache {
-		err := c.redisClient.Del(c.ctx, key).Err()
-		if err != nil {
+	if c.config().EnableL2Cache {
+		c.cancelL2Write(key)
+		if _, err = c.deleteL2(c.ctx, key); err != nil {
 			return err
 		}
+		c.replicate(key)
 	}
 
 	return nil
 }
 
-// Clear 清空所有缓存
-func (c *MultiLevelCache) Clear() error {
+// deleteL1 删除本地缓存项
+func (c *MultiLevelCache) deleteL1(key string) {
+	c.shardFor(key).remove(key)
+}
+
+// clearL1 清空本地缓存
+func (c *MultiLevelCache) clearL1() {
+	for _, shard := range c.shards {
+		shard.rangeItems(func(k string, item *CacheItem) bool {
+			shard.removeIf(k, item)
+			return true
+		})
+	}
+}
+
+// Clear 清空所有缓存，L2通过FLUSHDB清空整个Redis数据库
+// 必须设置opts.IAcceptDataLoss；配置了Authorizer时需要其允许OpClear
+func (c *MultiLevelCache) Clear(opts ClearOptions) error {
+	return c.ClearContext(c.ctx, opts)
+}
+
+// ClearContext 与Clear相同，ctx传给Authorizer
+func (c *MultiLevelCache) ClearContext(ctx context.Context, opts ClearOptions) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return err
+	}
+	if !opts.IAcceptDataLoss {
+		return ErrDataLossNotAccepted
+	}
+	if c.config().EnableL2Cache && c.proxyMode() {
+		return ErrProxyUnsupported
+	}
+	if err := c.authorize(ctx, OpClear, ""); err != nil {
+		return err
+	}
+	c.logf("dancache: clearing all cache levels")
 	// 清空本地缓存
-	if c.config.EnableL1Cache {
-		c.localCache = sync.Map{}
-		c.itemCount = 0
+	if c.config().EnableL1Cache {
+		c.clearL1()
 	}
 
 	// 清空Redis缓存(谨慎使用，这会清空整个Redis)
-	if c.config.EnableL2Cache {
+	if c.config().EnableL2Cache {
+		c.cancelAllL2Writes()
 		err := c.redisClient.FlushDB(c.ctx).Err()
 		if err != nil {
 			return err
@@ -363,49 +1063,83 @@ func (c *MultiLevelCache) Clear() error {
 
 // GetWithTTL 获取缓存并返回剩余TTL
 func (c *MultiLevelCache) GetWithTTL(key string) (interface{}, int64, bool) {
-	now := time.Now().Unix()
+	if !c.enter() {
+		return nil, 0, false
+	}
+	defer c.exit()
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return nil, 0, false
+	}
+	start := time.Now()
+	value, ttl, level, found := c.getWithTTL(key)
+	c.recordLookup(key, level, found)
+	c.recordAccess(AccessGet, key, start, &level, found)
+	if found {
+		c.recordSlow("get", key, start, level.String(), value)
+	} else {
+		c.recordSlow("get", key, start, "miss", nil)
+	}
+	return value, ttl, found
+}
+
+// getWithTTL GetWithTTL的实现，返回命中的级别
+func (c *MultiLevelCache) getWithTTL(key string) (interface{}, int64, CacheLevel, bool) {
+	now := c.nowUnix()
 	
 	// 优先从本地缓存获取
-	if c.config.EnableL1Cache {
-		if val, ok := c.localCache.Load(key); ok {
-			item := val.(*CacheItem)
-			
+	if c.config().EnableL1Cache {
+		shard := c.shardFor(key)
+		c.epoch.RLock()
+		item, ok := shard.load(key)
+		c.epoch.RUnlock()
+		if ok {
 			// 检查是否过期
-			if item.ExpireTime > now {
+			if (item.validInL1(now) || c.servesStale(item, now)) && c.unmutated(key, item) {
 				// 计算剩余TTL
 				ttl := item.ExpireTime - now
 				
 				// 更新访问信息
 				item.AccessTime = now
 				item.AccessCount++
-				c.localCache.Store(key, item)
+				item.markDirty()
+				c.recordL1Hit(item)
+				c.observeL1(key, true)
 				
-				return item.Value, ttl, true
-			} else {
+				return c.readValue(item), ttl, L1Cache, true
+			} else if shard.removeIf(key, item) {
 				// 过期了，删除
-				c.localCache.Delete(key)
-				c.itemCount--
+				c.recordL1Removal(item)
 			}
 		}
+		c.observeL1(key, false)
 	}
 
 	// 如果本地缓存未命中或已过期，尝试从Redis获取
-	if c.config.EnableL2Cache {
+	if c.config().EnableL2Cache {
 		// 获取TTL
-		ttl, err := c.redisClient.TTL(c.ctx, key).Result()
+		c.hookBeforeL2Get(key)
+		ttl, err := c.l2().TTL(c.ctx, key).Result()
 		if err != nil || ttl <= 0 {
-			return nil, 0, false
+			return nil, 0, L2Cache, false
 		}
 		
 		// 获取值
-		jsonData, err := c.redisClient.Get(c.ctx, key).Bytes()
+		jsonData, err := c.l2().Get(c.ctx, key).Bytes()
 		if err != nil {
-			return nil, 0, false
+			return nil, 0, L2Cache, false
 		}
 
 		var item CacheItem
-		if err := json.Unmarshal(jsonData, &item); err != nil {
-			return nil, 0, false
+		if err := c.decodeItem(key, jsonData, &item); err != nil {
+			c.decodeFailed(key, jsonData, err)
+			return nil, 0, L2Cache, false
+		}
+		if item.ExpireTime == 0 {
+			item.ExpireTime = now + int64(ttl/time.Second)
+		}
+		if !c.migrateItem(key, &item, now) {
+			return nil, 0, L2Cache, false
 		}
 
 		// 更新访问信息
@@ -413,30 +1147,20 @@ func (c *MultiLevelCache) GetWithTTL(key string) (interface{}, int64, bool) {
 		item.AccessCount++
 		
 		// 考虑是否需要升级到本地缓存
-		if c.config.EnableL1Cache && c.config.PromotionStrategy.ShouldPromote(&item) {
-			// 将项从L2升级到L1
-			c.localCache.Store(key, &item)
-			c.itemCount++
-			
-			// 如果超过最大大小限制，进行LRU淘汰
-			if c.config.MaxL1Size > 0 && c.itemCount > c.config.MaxL1Size {
-				c.evictLRU(1) // 淘汰一项
-			}
-		}
+		promoted := c.promote(key, &item)
 		
-		// 更新Redis中的访问信息
-		jsonData, _ = json.Marshal(item)
-		c.redisClient.Set(c.ctx, key, jsonData, ttl)
+		// 按需更新Redis中的访问信息
+		c.writeBackAccess(key, &item, promoted, ttl)
 		
-		return item.Value, int64(ttl.Seconds()), true
+		return c.readValue(&item), int64(ttl.Seconds()), L2Cache, true
 	}
 
-	return nil, 0, false
+	return nil, 0, L1Cache, false
 }
 
 // SetWithExpiration 设置缓存并指定过期时间
 func (c *MultiLevelCache) SetWithExpiration(key string, value interface{}, expiration time.Time) error {
-	now := time.Now().Unix()
+	now := c.nowUnix()
 	expireTime := expiration.Unix()
 	
 	// 如果过期时间已过，不设置缓存
@@ -453,38 +1177,238 @@ func (c *MultiLevelCache) GetStats() map[string]interface{} {
 	stats := make(map[string]interface{})
 	
 	// 本地缓存统计
-	if c.config.EnableL1Cache {
-		stats["l1_item_count"] = c.itemCount
-		stats["l1_max_size"] = c.config.MaxL1Size
+	if c.config().EnableL1Cache {
+		stats["l1_item_count"] = c.l1Count()
+		stats["l1_shards"] = len(c.shards)
+		stats["l1_max_size"] = c.config().MaxL1Size
+		stats["l1_pressure_shrinks"] = atomic.LoadInt64(&c.pressureShrinks)
+		stats["l1_swept_items"] = atomic.LoadInt64(&c.sweptItems)
+	}
+	
+	// 升级效果统计
+	for k, v := range c.promotionStatsMap() {
+		stats[k] = v
+	}
+	
+	// 命中统计
+	for k, v := range c.hitStatsMap() {
+		stats[k] = v
+	}
+	
+	// 告警统计
+	for k, v := range c.alarmStatsMap() {
+		stats[k] = v
+	}
+	
+	// 滚动速率
+	for k, v := range c.Rates() {
+		stats[k] = v
+	}
+	
+	// 对比采样统计
+	for k, v := range c.bypassStatsMap() {
+		stats[k] = v
+	}
+	
+	// 探测结果
+	for k, v := range c.canaryStatsMap() {
+		stats[k] = v
 	}
 	
-	// Redis统计(如果启用)
-	if c.config.EnableL2Cache {
-		// 获取Redis信息
-		info, err := c.redisClient.Info(c.ctx).Result()
-		if err == nil {
-			stats["redis_info"] = info
+	// 标签索引统计
+	for k, v := range c.tagStatsMap() {
+		stats[k] = v
+	}
+	
+	// 客户端跟踪统计
+	for k, v := range c.trackingStatsMap() {
+		stats[k] = v
+	}
+	
+	// 跨区域失效统计
+	for k, v := range c.regionStatsMap() {
+		stats[k] = v
+	}
+	
+	// 失效传播延迟统计
+	for k, v := range c.lagStatsMap() {
+		stats[k] = v
+	}
+	
+	// 订阅重连对账统计
+	for k, v := range c.reconcileStatsMap() {
+		stats[k] = v
+	}
+	
+	// 内部脚本统计
+	if c.config().EnableL2Cache {
+		for k, v := range c.scriptStatsMap() {
+			stats[k] = v
 		}
+	}
+
+	// 串行化写入统计
+	if c.config().SerializeSets {
+		stats["sets_superseded"] = atomic.LoadInt64(&c.setLanes.superseded)
+	}
+	if c.sequenced() {
+		stats["writes_superseded"] = atomic.LoadInt64(&c.supersededWrites)
+	}
+	stats["update_failures"] = atomic.LoadInt64(&c.updateFailures)
+	stats["subtree_invalidations"] = atomic.LoadInt64(&c.generations.invalidated)
+
+	// 故障状态统计
+	if len(c.config().DegradedModes) > 0 {
+		for k, v := range c.degradedStatsMap() {
+			stats[k] = v
+		}
+	}
+	
+	// L2配额统计
+	if len(c.config().L2Quotas) > 0 {
+		for k, v := range c.quotaStatsMap() {
+			stats[k] = v
+		}
+	}
+	
+	// 读修复统计
+	if c.config().ReadRepairPercent > 0 {
+		for k, v := range c.readRepairStatsMap() {
+			stats[k] = v
+		}
+	}
+	
+	// 替换值的对比统计
+	if c.config().MeasureStaleness {
+		for k, v := range c.stalenessStatsMap() {
+			stats[k] = v
+		}
+	}
+	
+	// 恢复的panic统计
+	stats["recovered_panics"] = atomic.LoadInt64(&c.recoveredPanics)
+	
+	// 去重统计
+	if c.interned != nil {
+		for k, v := range c.internStatsMap() {
+			stats[k] = v
+		}
+	}
+	
+	// 值修改检查统计
+	if c.config().VerifyImmutable {
+		stats["l1_mutated_values"] = atomic.LoadInt64(&c.mutatedValues)
+	}
+	
+	// L2值校验统计
+	stats["l2_corrupted_items"] = atomic.LoadInt64(&c.corruptedItems)
+	stats["l2_decode_failures"] = atomic.LoadInt64(&c.decodeFailures)
+	
+	// 影子列表统计
+	if c.ghost != nil {
+		stats["ghost_readmissions"] = atomic.LoadInt64(&c.readmissions)
+	}
+	
+	// 广播统计
+	if c.config().EnableL2Cache && c.config().BroadcastChannel != "" {
+		stats["broadcasts_sent"] = atomic.LoadInt64(&c.broadcastsSent)
+		stats["broadcasts_received"] = atomic.LoadInt64(&c.broadcastsRecv)
+		stats["warm_hints_received"] = atomic.LoadInt64(&c.hints.received)
+		stats["warm_hints_used"] = atomic.LoadInt64(&c.hints.used)
+	}
+	
+	// 定时失效统计
+	stats["scheduled_invalidations"] = atomic.LoadInt64(&c.scheduledInvalidations)
+	
+	// 过期时间限制统计
+	stats["clamped_ttls"] = atomic.LoadInt64(&c.clampedTTLs)
+	
+	// 时钟统计
+	if c.config().TimeSource == TimeRedis {
+		stats["clock_offset_ms"] = atomic.LoadInt64(&c.clockOffset) / int64(time.Millisecond)
+	}
+	
+	// 键归属统计
+	if c.config().KeyOwner != nil {
+		stats["affinity_skipped_promotions"] = atomic.LoadInt64(&c.affinitySkips)
+	}
+	
+	// 加载准入统计
+	stats["shed_loads"] = atomic.LoadInt64(&c.shedLoads)
+	
+	// 访问日志统计
+	if c.accessLog != nil {
+		stats["access_log_dropped"] = atomic.LoadInt64(&c.accessLog.dropped)
+	}
+	
+	// 后台维护协程状态
+	stats["l1_maintenance_running"] = c.maintenanceRunning(L1Cache)
+	stats["l2_maintenance_running"] = c.maintenanceRunning(L2Cache)
+
+	// Redis统计(如果启用)，关闭后只返回本地统计
+	stats["closed"] = c.Closed()
+	if c.config().EnableL2Cache && c.enter() {
+		defer c.exit()
+		stats["l2_circuit_open"] = c.circuitOpen()
 		
-		// 获取Redis键数量
-		dbSize, err := c.redisClient.DBSize(c.ctx).Result()
-		if err == nil {
-			stats["redis_key_count"] = dbSize
+		// 代理不支持INFO和DBSIZE
+		if !c.proxyMode() {
+			// 获取Redis信息
+			info, err := c.redisClient.Info(c.ctx).Result()
+			if err == nil {
+				stats["redis_info"] = info
+			}
+			
+			// 获取Redis键数量
+			dbSize, err := c.redisClient.DBSize(c.ctx).Result()
+			if err == nil {
+				stats["redis_key_count"] = dbSize
+			}
 		}
 	}
 	
 	return stats
 }
 
-// Close 关闭缓存连接
+// Close 关闭缓存连接，可以重复调用
+// 先拒绝新的操作(返回ErrClosed或未命中)，等待进行中的操作和后台协程全部结束后再关闭Redis连接
 func (c *MultiLevelCache) Close() error {
-	// 停止清理协程
-	if c.cleanupTicker != nil {
-		close(c.stopCleanup)
+	// 重复调用Close直接返回
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+
+	// 等待进行中的操作(包括异步写入和后台加载)完成，之后不会再有操作访问Redis
+	c.drain()
+
+	// 在停止序列化工作池之前写入等待合并的值，大值的编码仍由工作池完成
+	if c.config().EnableL2Cache && c.redisClient != nil {
+		c.flushAllL2Writes()
+	}
+
+	// 停止后台维护协程、定时失效和序列化工作池，并等待它们退出
+	close(c.stopCleanup)
+	c.stopMaintenance(L1Cache)
+	c.stopMaintenance(L2Cache)
+	c.bg.Wait()
+	if c.serializer != nil {
+		c.serializer.wait()
+	}
+	
+	// 写出剩余的访问日志记录
+	if c.accessLog != nil {
+		c.accessLog.close()
 	}
 	
 	// 关闭Redis连接
-	if c.config.EnableL2Cache && c.redisClient != nil {
+	if c.config().EnableL2Cache && c.redisClient != nil {
+		c.closeTracking()
+		if c.regions != nil {
+			c.regions.close()
+		}
+		if c.gutterClient != nil {
+			c.gutterClient.Close()
+		}
 		return c.redisClient.Close()
 	}
 	
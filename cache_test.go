@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvictsBeforeBreaksTies(t *testing.T) {
+	cases := []struct {
+		name  string
+		a, b  CacheItem
+		first bool
+	}{
+		{"older access", CacheItem{AccessTime: 1, CreateTime: 9, seq: 9}, CacheItem{AccessTime: 2}, true},
+		{"same access, older create", CacheItem{AccessTime: 5, CreateTime: 1, seq: 9}, CacheItem{AccessTime: 5, CreateTime: 2, seq: 1}, true},
+		{"same times, earlier insert", CacheItem{AccessTime: 5, CreateTime: 1, seq: 1}, CacheItem{AccessTime: 5, CreateTime: 1, seq: 2}, true},
+		{"same times, later insert", CacheItem{AccessTime: 5, CreateTime: 1, seq: 2}, CacheItem{AccessTime: 5, CreateTime: 1, seq: 1}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.a.evictsBefore(&tc.b); got != tc.first {
+			t.Errorf("%s: evictsBefore = %v, want %v", tc.name, got, tc.first)
+		}
+	}
+}
+
+func TestEvictionOrderIsDeterministic(t *testing.T) {
+	// 同一秒内写入的项按写入顺序淘汰，与遍历顺序无关
+	for run := 0; run < 5; run++ {
+		c := newL1TestCache(t, func(config *CacheConfig) { config.MaxL1Size = 5 })
+		for i := 0; i < 8; i++ {
+			if err := c.Set(fmt.Sprint(i), i, 60); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < 8; i++ {
+			_, ok := c.shardFor(fmt.Sprint(i)).load(fmt.Sprint(i))
+			if want := i >= 3; ok != want {
+				t.Errorf("run %d: key %d in L1 = %v, want %v", run, i, ok, want)
+			}
+		}
+	}
+}
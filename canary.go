@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// canaryKeyPrefix 探测键的前缀，属于内部键
+const canaryKeyPrefix = "canary:"
+
+// defaultCanaryTimeout 每个组件探测的默认超时
+const defaultCanaryTimeout = 2 * time.Second
+
+// 探测的组件
+const (
+	CanaryL1       = "l1"       // 本地缓存写入、读取和删除
+	CanaryL2       = "l2"       // Redis SET/GET及编解码往返
+	CanaryPipeline = "pipeline" // Redis pipeline
+	CanaryPubSub   = "pubsub"   // Redis pub/sub订阅与投递
+)
+
+// CanaryResult 一个组件最近一次探测的结果
+type CanaryResult struct {
+	OK        bool
+	Err       string        // 失败原因
+	Latency   time.Duration // 探测耗时
+	CheckedAt time.Time
+}
+
+// canaryState 最近一次探测的结果
+type canaryState struct {
+	mu      sync.RWMutex
+	results map[string]CanaryResult
+}
+
+// canaryRoutine 定期探测各组件
+func (c *MultiLevelCache) canaryRoutine(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.config().CanaryInterval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	c.runCanary(ctx)
+	for {
+		select {
+		case <-ticker.C:
+			c.runCanary(ctx)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RunCanary 立即探测各组件并返回结果，同时更新CanaryStatus
+// 探测键和频道带有实例标识，多个实例同时探测不会互相干扰
+func (c *MultiLevelCache) RunCanary(ctx context.Context) (map[string]CanaryResult, error) {
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return nil, err
+	}
+	return c.runCanary(ctx), nil
+}
+
+// CanaryStatus 返回各组件最近一次探测的结果，尚未探测时为空
+func (c *MultiLevelCache) CanaryStatus() map[string]CanaryResult {
+	c.canary.mu.RLock()
+	defer c.canary.mu.RUnlock()
+	results := make(map[string]CanaryResult, len(c.canary.results))
+	for component, result := range c.canary.results {
+		results[component] = result
+	}
+	return results
+}
+
+// runCanary 探测已启用的组件并记录结果
+func (c *MultiLevelCache) runCanary(ctx context.Context) map[string]CanaryResult {
+	config := c.config()
+	probes := make(map[string]func(ctx context.Context, key, nonce string) error)
+	if config.EnableL1Cache {
+		probes[CanaryL1] = c.probeL1
+	}
+	if config.EnableL2Cache {
+		probes[CanaryL2] = c.probeL2
+		probes[CanaryPipeline] = c.probePipeline
+		if !config.RedisProxyMode {
+			probes[CanaryPubSub] = c.probePubSub
+		}
+	}
+
+	timeout := config.CanaryTimeout
+	if timeout <= 0 {
+		timeout = defaultCanaryTimeout
+	}
+	key := c.internalKey(canaryKeyPrefix, c.instanceID)
+	results := make(map[string]CanaryResult, len(probes))
+	for component, probe := range probes {
+		nonce := strconv.FormatInt(time.Now().UnixNano(), 36)
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := probe(probeCtx, key+":"+component, nonce)
+		cancel()
+
+		result := CanaryResult{OK: err == nil, Latency: time.Since(start), CheckedAt: time.Now()}
+		if err != nil {
+			result.Err = err.Error()
+			c.logf("dancache: canary %s failed: %v", component, err)
+		}
+		results[component] = result
+	}
+
+	c.canary.mu.Lock()
+	c.canary.results = results
+	c.canary.mu.Unlock()
+	return results
+}
+
+// probeL1 在本地缓存中写入、读取并删除探测键，不计入命中和淘汰统计
+func (c *MultiLevelCache) probeL1(_ context.Context, key, nonce string) error {
+	shard := c.shardFor(key)
+	now := c.nowUnix()
+	shard.store(key, &CacheItem{Value: nonce, ExpireTime: now + 60, CreateTime: now, AccessTime: now})
+	defer shard.remove(key)
+	item, ok := shard.load(key)
+	if !ok || item.Value != nonce {
+		return errors.New("本地缓存读取的探测值不一致")
+	}
+	return nil
+}
+
+// probeL2 经过编解码器写入并读回探测键，直接使用主Redis，不受熔断器和gutter影响
+func (c *MultiLevelCache) probeL2(ctx context.Context, key, nonce string) error {
+	now := c.nowUnix()
+	data, err := c.marshalItem(key, &CacheItem{Value: nonce, ExpireTime: now + 60, CreateTime: now, AccessTime: now})
+	if err != nil {
+		return err
+	}
+	if err := c.redisClient.Set(ctx, key, data, time.Minute).Err(); err != nil {
+		return err
+	}
+	raw, err := c.redisClient.Get(ctx, key).Bytes()
+	if err != nil {
+		return err
+	}
+	var item CacheItem
+	if err := c.decodeItem(key, raw, &item); err != nil {
+		return err
+	}
+	if fmt.Sprint(item.Value) != nonce {
+		return errors.New("Redis读取的探测值不一致")
+	}
+	return c.redisClient.Del(ctx, key).Err()
+}
+
+// probePipeline 在一个pipeline中写入、读取并删除探测键
+func (c *MultiLevelCache) probePipeline(ctx context.Context, key, nonce string) error {
+	pipe := c.redisClient.Pipeline()
+	pipe.Set(ctx, key, nonce, time.Minute)
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if get.Val() != nonce {
+		return errors.New("pipeline读取的探测值不一致")
+	}
+	return nil
+}
+
+// probePubSub 订阅探测频道，发布一条消息并等待收到，用于发现Redis可用但pub/sub不可用的情况
+func (c *MultiLevelCache) probePubSub(ctx context.Context, channel, nonce string) error {
+	pubsub := c.redisClient.Subscribe(ctx, channel)
+	defer pubsub.Close()
+	// 等待订阅确认，保证发布时已在订阅
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	if err := c.redisClient.Publish(ctx, channel, nonce).Err(); err != nil {
+		return err
+	}
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		if msg.Payload == nonce {
+			return nil
+		}
+	}
+}
+
+// canaryStatsMap 返回各组件最近一次探测是否通过
+func (c *MultiLevelCache) canaryStatsMap() map[string]interface{} {
+	stats := make(map[string]interface{})
+	for component, result := range c.CanaryStatus() {
+		stats["canary_"+component+"_ok"] = result.OK
+	}
+	return stats
+}
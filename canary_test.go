@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRunCanaryProbesComponents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.CanaryTimeout = 500 * time.Millisecond
+	})
+
+	results, err := c.RunCanary(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, component := range []string{CanaryL1, CanaryL2, CanaryPipeline, CanaryPubSub} {
+		if r, ok := results[component]; !ok || !r.OK {
+			t.Errorf("canary %s = %+v, want ok", component, r)
+		}
+	}
+	// 探测不留下键，也不计入L1统计
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Errorf("canary left keys in Redis: %v", keys)
+	}
+	if n := c.l1Count(); n != 0 {
+		t.Errorf("canary left %d items in L1", n)
+	}
+	if ok := c.GetStats()["canary_pubsub_ok"]; ok != true {
+		t.Errorf("canary_pubsub_ok = %v, want true", ok)
+	}
+
+	// Redis不可用时L2相关的探测失败，L1照常通过
+	mr.Close()
+	results, _ = c.RunCanary(context.Background())
+	if !results[CanaryL1].OK {
+		t.Error("L1 canary failed without Redis")
+	}
+	if r := results[CanaryL2]; r.OK || r.Err == "" {
+		t.Errorf("L2 canary = %+v, want a failure with a reason", r)
+	}
+	if status := c.CanaryStatus(); status[CanaryPipeline].OK {
+		t.Error("CanaryStatus reports the pipeline ok without Redis")
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// CanonicalJSON 返回值的规范JSON编码：所有对象(包括结构体和自定义MarshalJSON的输出)的键按字典序排列，
+// 数字保留原始写法，不转义HTML字符，没有多余空白。内容相同的值无论类型和Go版本都得到相同的字节
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	data, err := marshalNoEscape(v)
+	if err != nil {
+		return nil, err
+	}
+	// 解码为通用类型后重新编码，map按键排序；UseNumber避免大整数经过float64丢失精度
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return marshalNoEscape(generic)
+}
+
+// marshalNoEscape 编码为JSON，不转义<、>和&，去掉Encoder追加的换行
+func marshalNoEscape(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// CanonicalJSONCodec 以规范JSON编码缓存项的编解码器，同一内容写入L2的字节稳定，便于按内容比较和去重
+// 输出仍是普通JSON，与JSONCodec互相兼容，信封中使用CodecIDJSON
+type CanonicalJSONCodec struct{}
+
+// Marshal 编码缓存项
+func (CanonicalJSONCodec) Marshal(item *CacheItem) ([]byte, error) {
+	return CanonicalJSON(item)
+}
+
+// Unmarshal 解码缓存项
+func (CanonicalJSONCodec) Unmarshal(data []byte, item *CacheItem) error {
+	return json.Unmarshal(data, item)
+}
+
+// isJSONCodec 判断编解码器是否输出JSONCodec可以解码的内容
+func isJSONCodec(codec Codec) bool {
+	switch codec.(type) {
+	case nil, JSONCodec, CanonicalJSONCodec:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// unorderedJSON 自定义MarshalJSON输出未排序的键
+type unorderedJSON struct{}
+
+func (unorderedJSON) MarshalJSON() ([]byte, error) {
+	return []byte(`{"b": 2, "a": {"y": 1, "x": 0}}`), nil
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		ID   int64  `json:"id"`
+	}
+	for _, tc := range []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"struct", user{Name: "alice", ID: 1}, `{"id":1,"name":"alice"}`},
+		{"map", map[string]interface{}{"name": "alice", "id": 1}, `{"id":1,"name":"alice"}`},
+		{"custom marshaler", unorderedJSON{}, `{"a":{"x":0,"y":1},"b":2}`},
+		{"large integer", map[string]int64{"n": 1<<62 + 1}, `{"n":4611686018427387905}`},
+		{"html", "<a&b>", `"<a&b>"`},
+		{"nested", []interface{}{map[string]int{"z": 1, "a": 2}}, `[{"a":2,"z":1}]`},
+	} {
+		got, err := CanonicalJSON(tc.v)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: CanonicalJSON = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+	if _, err := CanonicalJSON(make(chan int)); err == nil {
+		t.Error("CanonicalJSON accepted a channel")
+	}
+}
+
+func TestCanonicalJSONCodecIsJSONCompatible(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newRedisTestCache(t, mr, func(config *CacheConfig) { config.Codec = CanonicalJSONCodec{} })
+	reader := newRedisTestCache(t, mr, nil)
+	if err := writer.Set("k", map[string]interface{}{"b": 1, "a": "x"}, 60); err != nil {
+		t.Fatal(err)
+	}
+	// 内容相同的值无论类型都编码为相同的字节
+	type pair struct {
+		B int    `json:"b"`
+		A string `json:"a"`
+	}
+	fromStruct, err := CanonicalJSONCodec{}.Marshal(&CacheItem{Value: pair{B: 1, A: "x"}, ExpireTime: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromMap, err := CanonicalJSONCodec{}.Marshal(&CacheItem{Value: map[string]interface{}{"a": "x", "b": 1}, ExpireTime: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fromStruct) != string(fromMap) {
+		t.Errorf("struct encoded as %s, map as %s", fromStruct, fromMap)
+	}
+
+	v, ok := reader.Get("k")
+	if m, isMap := v.(map[string]interface{}); !ok || !isMap || m["a"] != "x" {
+		t.Errorf("JSONCodec reader Get = %v, %v; want the map", v, ok)
+	}
+	for _, codec := range []Codec{nil, JSONCodec{}, CanonicalJSONCodec{}} {
+		if !isJSONCodec(codec) {
+			t.Errorf("isJSONCodec(%T) = false", codec)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cache
+
+// Capabilities 当前配置下启用的功能
+type Capabilities struct {
+	L1                bool // 本地内存缓存
+	L2                bool // Redis缓存
+	Tags              bool // 标签索引和InvalidateTags
+	ExpiryEvents      bool // L2键过期回调(OnExpire)
+	CircuitBreaker    bool // L2熔断器
+	Gutter            bool // 熔断期间的gutter缓存(Redis或L1)
+	Tombstones        bool // 删除后的墓碑窗口
+	WriteCoalescing   bool // L2写入合并
+	LoadLimiter       bool // 加载准入控制
+	Envelope          bool // L2值的版本化信封
+	Checksum          bool // L2值的校验和
+	Quarantine        bool // 解码失败的值隔离
+	Migrations        bool // 值版本迁移
+	DynamicL1Size     bool // 根据影子列表命中动态调整L1容量
+	GhostList         bool // 淘汰后再次请求直接升级
+	TopKeys           bool // 热点键统计
+	SlowLog           bool // 慢操作日志
+	AccessLog         bool // 采样的访问日志
+	EvictionExporter  bool // 未启用L2时导出被淘汰的缓存项
+	MemoryPressure    bool // 按堆内存收缩L1
+	BackgroundSweeper bool // 后台清扫过期项
+}
+
+// Capabilities 返回当前配置下启用的功能，调用方可据此选择API，而不是依赖未启用功能时的错误
+func (c *MultiLevelCache) Capabilities() Capabilities {
+	config := c.config()
+	l2 := config.EnableL2Cache
+	return Capabilities{
+		L1:                config.EnableL1Cache,
+		L2:                l2,
+		Tags:              config.EnableL1Cache || l2,
+		ExpiryEvents:      l2 && config.OnExpire != nil && !config.RedisProxyMode && c.serverKeyspaceEvents(),
+		CircuitBreaker:    l2 && config.CircuitFailureThreshold > 0,
+		Gutter:            l2 && config.CircuitFailureThreshold > 0 && (c.gutterClient != nil || config.GutterTTL > 0),
+		Tombstones:        config.TombstoneTTL > 0,
+		WriteCoalescing:   c.coalescer != nil,
+		LoadLimiter:       c.loadLimiter != nil,
+		Envelope:          l2 && config.Envelope,
+		Checksum:          l2 && config.Envelope && config.Checksum,
+		Quarantine:        l2 && config.DecodePolicy == DecodeQuarantine,
+		Migrations:        l2 && len(config.Migrations) > 0,
+		DynamicL1Size:     config.EnableL1Cache && config.L1SizeMin > 0 && config.L1SizeMax > config.L1SizeMin,
+		GhostList:         c.ghost != nil,
+		TopKeys:           c.topKeys != nil,
+		SlowLog:           c.slowLog != nil,
+		AccessLog:         c.accessLog != nil,
+		EvictionExporter:  !l2 && config.EvictionExporter != nil,
+		MemoryPressure:    config.EnableL1Cache && config.MemoryLimitRatio > 0,
+		BackgroundSweeper: config.EnableL1Cache && config.SweepInterval > 0,
+	}
+}
+
+// requireLevel 未启用任何缓存级别时返回ErrNoCacheLevel
+func (c *MultiLevelCache) requireLevel() error {
+	config := c.config()
+	if !config.EnableL1Cache && !config.EnableL2Cache {
+		return ErrNoCacheLevel
+	}
+	return nil
+}
+
+// requireL2 未启用L2时返回ErrL2Disabled
+func (c *MultiLevelCache) requireL2() error {
+	if !c.config().EnableL2Cache {
+		return ErrL2Disabled
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCapabilitiesFollowConfig(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.TombstoneTTL = 1
+		config.SweepInterval = 1
+	})
+	caps := c.Capabilities()
+	if !caps.L1 || caps.L2 {
+		t.Errorf("L1, L2 = %v, %v; want true, false", caps.L1, caps.L2)
+	}
+	if !caps.Tombstones || !caps.BackgroundSweeper || !caps.Tags {
+		t.Errorf("Tombstones, BackgroundSweeper, Tags = %v, %v, %v; want all true", caps.Tombstones, caps.BackgroundSweeper, caps.Tags)
+	}
+	// 依赖L2的功能在只有L1时不启用
+	if caps.Envelope || caps.CircuitBreaker || caps.Quarantine || caps.ExpiryEvents {
+		t.Errorf("L2 features reported without L2: %+v", caps)
+	}
+}
+
+func TestDisabledSubsystemsReturnTypedErrors(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{})
+	if err != nil {
+		t.Fatalf("NewMultiLevelCache: %v", err)
+	}
+	defer c.Close()
+
+	checks := map[string]error{
+		"Set":            c.Set("k", "v", 60),
+		"Delete":         c.Delete("k"),
+		"SetMulti":       c.SetMulti(map[string]ItemOptions{"k": {Value: "v", TTL: 60}}),
+		"InvalidateTags": c.InvalidateTags("t"),
+		"DeleteAsync":    c.DeleteAsync("k").Wait(),
+	}
+	for name, err := range checks {
+		if !errors.Is(err, ErrNoCacheLevel) || !errors.Is(err, ErrDisabled) {
+			t.Errorf("%s error = %v, want ErrNoCacheLevel", name, err)
+		}
+	}
+
+	// GetOrLoad照常返回加载结果，只是不回填
+	v, err := c.GetOrLoad("k", 60, func() (interface{}, error) { return "loaded", nil })
+	if err != nil || v != "loaded" {
+		t.Errorf("GetOrLoad = %v, %v; want loaded", v, err)
+	}
+
+	l1 := newL1TestCache(t, nil)
+	if _, err := l1.Quarantined("k"); !errors.Is(err, ErrL2Disabled) {
+		t.Errorf("Quarantined error = %v, want ErrL2Disabled", err)
+	}
+	var disabled *DisabledError
+	if !errors.As(ErrL2Disabled, &disabled) || disabled.Feature == "" {
+		t.Errorf("ErrL2Disabled is not a *DisabledError with a feature")
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// circuitBreaker L2熔断器
+// 连续失败达到阈值后打开，打开期间请求直接失败；冷却结束后放行一个探测请求，成功则关闭
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	openFor   time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// newCircuitBreaker 创建熔断器
+func newCircuitBreaker(threshold int, openFor time.Duration) *circuitBreaker {
+	if openFor <= 0 {
+		openFor = 5 * time.Second
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		openFor:   openFor,
+	}
+}
+
+// configure 修改失败阈值和冷却时间，已打开的熔断器按新阈值重新判断
+func (cb *circuitBreaker) configure(threshold int, openFor time.Duration) {
+	if openFor <= 0 {
+		openFor = 5 * time.Second
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.threshold = threshold
+	cb.openFor = openFor
+}
+
+// allow 判断是否放行请求
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.failures < cb.threshold {
+		return true
+	}
+	// 冷却结束后只放行一个探测请求
+	if time.Now().After(cb.openUntil) && !cb.probing {
+		cb.probing = true
+		return true
+	}
+	return false
+}
+
+// probeDue 判断熔断器是否已过冷却时间且没有进行中的探测请求
+func (cb *circuitBreaker) probeDue() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.failures >= cb.threshold && !cb.probing && time.Now().After(cb.openUntil)
+}
+
+// isOpen 判断熔断器是否处于打开状态
+func (cb *circuitBreaker) isOpen() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.failures >= cb.threshold
+}
+
+// record 记录请求结果，redis.Nil等正常返回视为成功
+func (cb *circuitBreaker) record(err error) {
+	if err == ErrCircuitOpen {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+	if err == nil || err == redis.Nil {
+		cb.failures = 0
+		return
+	}
+	if _, isRedisErr := err.(redis.Error); isRedisErr {
+		// 服务端返回的命令错误说明连接正常
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.failures >= cb.threshold {
+		cb.openUntil = time.Now().Add(cb.openFor)
+	}
+}
+
+// circuitHook 将熔断器接入Redis客户端
+type circuitHook struct {
+	cb *circuitBreaker
+}
+
+var _ redis.Hook = (*circuitHook)(nil)
+
+// BeforeProcess 熔断器打开时直接拒绝命令
+func (h *circuitHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if !h.cb.allow() {
+		return ctx, ErrCircuitOpen
+	}
+	return ctx, nil
+}
+
+// AfterProcess 记录命令结果
+func (h *circuitHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.cb.record(cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline 熔断器打开时直接拒绝管道
+func (h *circuitHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if !h.cb.allow() {
+		return ctx, ErrCircuitOpen
+	}
+	return ctx, nil
+}
+
+// AfterProcessPipeline 按管道中第一个错误记录结果
+func (h *circuitHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var firstErr error
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			firstErr = err
+			break
+		}
+	}
+	h.cb.record(firstErr)
+	return nil
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestCircuitBreakerOpensAndProbes(t *testing.T) {
+	cb := newCircuitBreaker(2, 20*time.Millisecond)
+	down := errors.New("connection refused")
+
+	cb.record(down)
+	if cb.isOpen() || !cb.allow() {
+		t.Fatal("breaker opened before reaching the threshold")
+	}
+	// redis.Nil说明连接正常，重置失败计数
+	cb.record(redis.Nil)
+	cb.record(down)
+	if cb.isOpen() {
+		t.Fatal("redis.Nil did not reset the failure count")
+	}
+	cb.record(down)
+	if !cb.isOpen() || cb.allow() {
+		t.Fatal("breaker did not open after consecutive failures")
+	}
+
+	// 冷却结束后只放行一个探测请求
+	time.Sleep(30 * time.Millisecond)
+	if !cb.probeDue() || !cb.allow() {
+		t.Fatal("no probe allowed after the cool-down")
+	}
+	if cb.allow() || cb.probeDue() {
+		t.Error("second request allowed while a probe is in flight")
+	}
+	cb.record(down)
+	if cb.allow() {
+		t.Error("failed probe did not restart the cool-down")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !cb.allow() {
+		t.Fatal("no probe allowed after the second cool-down")
+	}
+	cb.record(nil)
+	if cb.isOpen() || !cb.allow() {
+		t.Error("successful probe did not close the breaker")
+	}
+}
+
+func TestL1GutterWhileCircuitOpen(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.SequencedWrites = false
+		config.CircuitFailureThreshold = 1
+		config.CircuitOpenDuration = time.Minute
+		config.GutterTTL = 5
+	})
+
+	mr.Close()
+	_ = c.Set("trip", "v", 60)
+	if !c.circuitOpen() {
+		t.Fatal("circuit did not open after a failure")
+	}
+
+	// 熔断期间写入只保存在L1，并使用较短的过期时间
+	if err := c.Set("k", "v", 3600); err != nil {
+		t.Fatalf("Set while the circuit is open: %v", err)
+	}
+	item, ok := c.shardFor("k").load("k")
+	if !ok {
+		t.Fatal("write was not kept in L1 while the circuit was open")
+	}
+	if ttl := item.ExpireTime - c.nowUnix(); ttl > 5 {
+		t.Errorf("L1 gutter TTL = %d, want at most GutterTTL", ttl)
+	}
+	if v, found := c.Get("k"); !found || v != "v" {
+		t.Errorf("Get = %v, %v; want v, true", v, found)
+	}
+}
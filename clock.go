@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TimeSource 过期时间计算使用的时钟
+type TimeSource int
+
+const (
+	// TimeLocal 使用本机时钟(默认)
+	TimeLocal TimeSource = iota
+	// TimeRedis 使用Redis TIME校准的时钟，所有实例的过期时间基于同一时钟，不受本机时钟偏差影响
+	TimeRedis
+)
+
+// defaultTimeSyncInterval 与Redis TIME校准的默认间隔
+const defaultTimeSyncInterval = time.Minute
+
+// now 返回当前时间，TimeRedis时加上与Redis时钟的偏差
+func (c *MultiLevelCache) now() time.Time {
+	return time.Now().Add(time.Duration(atomic.LoadInt64(&c.clockOffset)))
+}
+
+// nowUnix 返回当前时间戳(秒)，用于所有过期时间的计算
+func (c *MultiLevelCache) nowUnix() int64 {
+	return c.now().Unix()
+}
+
+// skewTolerance 返回容忍的时钟偏差(秒)
+func (c *MultiLevelCache) skewTolerance() int64 {
+	return int64(c.config().ClockSkewTolerance / time.Second)
+}
+
+// syncClock 读取Redis TIME并更新本机与Redis的时钟偏差
+// 以请求往返的中点作为Redis返回时间对应的本机时间，抵消网络延迟
+func (c *MultiLevelCache) syncClock() error {
+	start := time.Now()
+	redisTime, err := c.redisClient.Time(c.ctx).Result()
+	if err != nil {
+		return err
+	}
+	rtt := time.Since(start)
+	offset := redisTime.Sub(start.Add(rtt / 2))
+	atomic.StoreInt64(&c.clockOffset, int64(offset))
+	return nil
+}
+
+// clockSyncRoutine 定期与Redis TIME校准，失败时沿用上一次的偏差
+func (c *MultiLevelCache) clockSyncRoutine(stop <-chan struct{}) {
+	interval := c.config().TimeSyncInterval
+	if interval <= 0 {
+		interval = defaultTimeSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.syncClock(); err != nil {
+				c.logf("dancache: sync clock with redis failed: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
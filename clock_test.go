@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestTimeRedisFollowsRedisClock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Now().Add(time.Hour))
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.TimeSource = TimeRedis
+	})
+
+	offset, _ := c.GetStats()["clock_offset_ms"].(int64)
+	if offset < int64(59*time.Minute/time.Millisecond) || offset > int64(61*time.Minute/time.Millisecond) {
+		t.Errorf("clock_offset_ms = %d, want about one hour", offset)
+	}
+	// 过期时间基于Redis的时钟计算
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	item, ok := c.shardFor("k").load("k")
+	if !ok {
+		t.Fatal("k missing from L1")
+	}
+	if want := time.Now().Add(time.Hour).Unix() + 60; item.ExpireTime < want-5 || item.ExpireTime > want+5 {
+		t.Errorf("ExpireTime = %d, want about %d", item.ExpireTime, want)
+	}
+}
+
+func TestClockSkewTolerance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	configure := func(tolerance time.Duration) func(*CacheConfig) {
+		return func(config *CacheConfig) {
+			config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+			config.ClockSkewTolerance = tolerance
+		}
+	}
+	strict := newRedisTestCache(t, mr, configure(0))
+	tolerant := newRedisTestCache(t, mr, configure(10*time.Second))
+
+	// 写入方时钟偏快：按本机时间已过期2秒，Redis中仍然有效
+	now := time.Now().Unix()
+	data, err := strict.marshalItem("k", &CacheItem{Value: "v", ExpireTime: now - 2, CreateTime: now - 60, AccessTime: now - 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("k", string(data))
+	mr.SetTTL("k", time.Minute)
+
+	if v, ok := strict.Get("k"); ok {
+		t.Errorf("Get without tolerance = %v, want miss", v)
+	}
+	if v, ok := tolerant.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get within tolerance = %v, %v; want v", v, ok)
+	}
+	// 容忍范围内的值不升级到L1
+	if _, ok := tolerant.shardFor("k").load("k"); ok {
+		t.Error("value within the skew tolerance was promoted to L1")
+	}
+}
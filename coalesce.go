@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pendingWrite 等待合并写入L2的缓存项
+type pendingWrite struct {
+	item     *CacheItem
+	snapshot *CacheItem // 入队时复制的缓存项，窗口结束时序列化，避免与L1读取更新访问信息竞争
+	ttl      int64
+	ctx      context.Context // 最后一次写入的调用方context(已脱离取消)
+}
+
+// writeCoalescer 合并同一键在时间窗口内的多次L2写入，只有窗口内最后一次写入会到达Redis
+type writeCoalescer struct {
+	mu      sync.Mutex
+	window  time.Duration
+	pending map[string]*pendingWrite
+}
+
+// newWriteCoalescer 创建写入合并器
+func newWriteCoalescer(window time.Duration) *writeCoalescer {
+	return &writeCoalescer{
+		window:  window,
+		pending: make(map[string]*pendingWrite),
+	}
+}
+
+// queueL2Write 将L2写入加入合并队列，窗口结束时写入最新的值
+func (c *MultiLevelCache) queueL2Write(ctx context.Context, key string, item *CacheItem, ttl int64) {
+	snapshot := item.clone()
+	wc := c.coalescer
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	if pw, ok := wc.pending[key]; ok {
+		pw.item = item
+		pw.snapshot = snapshot
+		pw.ttl = ttl
+		pw.ctx = ctx
+		return
+	}
+	wc.pending[key] = &pendingWrite{item: item, snapshot: snapshot, ttl: ttl, ctx: ctx}
+	time.AfterFunc(wc.window, func() {
+		c.flushL2Write(key)
+	})
+}
+
+// cancelL2Write 取消键尚未写入的合并写入，删除或直接写入L2前调用，避免旧值覆盖
+func (c *MultiLevelCache) cancelL2Write(key string) {
+	if c.coalescer == nil {
+		return
+	}
+	c.coalescer.mu.Lock()
+	delete(c.coalescer.pending, key)
+	c.coalescer.mu.Unlock()
+}
+
+// flushL2Write 将键最新的值写入L2，带写入序号的值已被更晚的写入或删除取代时撤销L1中的值
+func (c *MultiLevelCache) flushL2Write(key string) {
+	c.coalescer.mu.Lock()
+	pw, ok := c.coalescer.pending[key]
+	delete(c.coalescer.pending, key)
+	c.coalescer.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ttl := pw.snapshot.ExpireTime - c.nowUnix()
+	if ttl <= 0 {
+		return
+	}
+	jsonData, err := c.marshalItem(key, pw.snapshot)
+	written := true
+	if err == nil {
+		if seq := pw.item.l2Seq; seq > 0 && c.sequenced() {
+			written, err = c.setL2Sequenced(pw.ctx, key, jsonData, time.Duration(ttl)*time.Second, seq)
+		} else {
+			err = c.setL2(pw.ctx, key, jsonData, time.Duration(ttl)*time.Second).Err()
+		}
+	}
+	if err != nil {
+		c.logf("dancache: coalesced write %q failed: %v", key, err)
+		return
+	}
+	if !written {
+		if c.shardFor(key).removeIf(key, pw.item) {
+			c.recordL1Removal(pw.item)
+		}
+		return
+	}
+	pw.item.markL2Synced()
+}
+
+// cancelAllL2Writes 取消所有等待中的合并写入
+func (c *MultiLevelCache) cancelAllL2Writes() {
+	if c.coalescer == nil {
+		return
+	}
+	c.coalescer.mu.Lock()
+	c.coalescer.pending = make(map[string]*pendingWrite)
+	c.coalescer.mu.Unlock()
+}
+
+// flushAllL2Writes 立即写入所有等待中的合并写入，关闭缓存前调用
+func (c *MultiLevelCache) flushAllL2Writes() {
+	if c.coalescer == nil {
+		return
+	}
+	c.coalescer.mu.Lock()
+	keys := make([]string, 0, len(c.coalescer.pending))
+	for key := range c.coalescer.pending {
+		keys = append(keys, key)
+	}
+	c.coalescer.mu.Unlock()
+
+	for _, key := range keys {
+		c.flushL2Write(key)
+	}
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestWriteCoalescingKeepsLastValue(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.WriteCoalesceWindow = 30 * time.Millisecond })
+	other := newRedisTestCache(t, mr, func(config *CacheConfig) { config.EnableL1Cache = false })
+
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := c.Set("k", v, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 窗口内的写入只到达L1
+	if mr.Exists("k") {
+		t.Fatal("write reached L2 before the coalesce window ended")
+	}
+	if v, _ := c.Get("k"); v != "v3" {
+		t.Errorf("local Get = %v, want v3", v)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !mr.Exists("k") {
+		if time.Now().After(deadline) {
+			t.Fatal("coalesced write never reached L2")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if v, found := other.Get("k"); !found || v != "v3" {
+		t.Errorf("L2 value = %v, %v; want v3", v, found)
+	}
+}
+
+func TestDeleteCancelsCoalescedWrite(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.WriteCoalesceWindow = 10 * time.Millisecond })
+
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if mr.Exists("k") {
+		t.Error("coalesced write landed after Delete")
+	}
+}
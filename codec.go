@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Codec L2中缓存项的编解码器
+// 编码格式可以只包含值而不包含元数据：解码后ExpireTime为0时从Redis的TTL恢复，CreateTime为0时视为当前时间
+type Codec interface {
+	Marshal(item *CacheItem) ([]byte, error)
+	Unmarshal(data []byte, item *CacheItem) error
+}
+
+// JSONCodec 默认编解码器，值和元数据一起编码为JSON
+type JSONCodec struct{}
+
+// Marshal 编码缓存项
+func (JSONCodec) Marshal(item *CacheItem) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+// Unmarshal 解码缓存项
+func (JSONCodec) Unmarshal(data []byte, item *CacheItem) error {
+	return json.Unmarshal(data, item)
+}
+
+// codec 返回配置的编解码器
+func (c *MultiLevelCache) codec() Codec {
+	if codec := c.config().Codec; codec != nil {
+		return codec
+	}
+	return JSONCodec{}
+}
+
+// unmarshalItem 解码从L2读取的缓存项，补全编码格式中缺失的元数据
+// 返回nil且无错误时按未命中处理；解码失败按DecodePolicy处理
+func (c *MultiLevelCache) unmarshalItem(key string, data []byte, now int64) (*CacheItem, error) {
+	var item CacheItem
+	if err := c.decodeItem(key, data, &item); err != nil {
+		return nil, c.decodeFailed(key, data, err)
+	}
+	if item.CreateTime == 0 {
+		item.CreateTime = now
+	}
+	if item.ExpireTime == 0 {
+		ttl, err := c.l2().TTL(c.ctx, key).Result()
+		if err != nil {
+			return nil, nil
+		}
+		if ttl > 0 {
+			item.ExpireTime = now + int64(ttl/time.Second)
+		}
+	}
+	return &item, nil
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// valueOnlyCodec 只编码字符串值、不编码元数据的编解码器
+type valueOnlyCodec struct{}
+
+func (valueOnlyCodec) Marshal(item *CacheItem) ([]byte, error) {
+	return []byte(item.Value.(string)), nil
+}
+
+func (valueOnlyCodec) Unmarshal(data []byte, item *CacheItem) error {
+	item.Value = string(data)
+	return nil
+}
+
+func TestCodecWithoutMetadataUsesRedisTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	configure := func(config *CacheConfig) {
+		config.SequencedWrites = false
+		config.Codec = valueOnlyCodec{}
+	}
+	writer := newRedisTestCache(t, mr, configure)
+	reader := newRedisTestCache(t, mr, configure)
+
+	if err := writer.Set("k", "plain", 60); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := mr.Get("k"); err != nil || got != "plain" {
+		t.Fatalf("L2 value = %q, %v; want the codec output", got, err)
+	}
+
+	// 编码格式中没有过期时间时从Redis的TTL恢复
+	mr.SetTTL("k", 30*time.Second)
+	v, ttl, found := reader.GetWithTTL("k")
+	if !found || v != "plain" {
+		t.Fatalf("GetWithTTL = %v, %v; want plain, true", v, found)
+	}
+	if ttl <= 0 || ttl > 30 {
+		t.Errorf("remaining TTL = %d, want about 30 from Redis", ttl)
+	}
+}
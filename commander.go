@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisCommander 缓存使用的Redis命令，*redis.Client、*redis.ClusterClient和*redis.Ring都实现了该接口
+// 通过CacheConfig.RedisClient可以传入自定义的实现，例如连接Twemproxy/Envoy等代理或记录命令的包装
+type RedisCommander interface {
+	redis.Cmdable
+	AddHook(hook redis.Hook)
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+	Do(ctx context.Context, args ...interface{}) *redis.Cmd
+	Close() error
+}
+
+// ErrProxyUnsupported 启用RedisProxyMode时调用的操作依赖代理不支持的命令(SCAN、pub/sub、MULTI/EXEC、FLUSHDB等)
+var ErrProxyUnsupported = errors.New("通过代理访问Redis时不支持该操作")
+
+// proxyMode 判断是否通过代理访问Redis，只能使用代理支持的命令
+func (c *MultiLevelCache) proxyMode() bool {
+	return c.config().RedisProxyMode
+}
+
+// l2Pipelined 在事务中执行fn中的命令；代理模式下不支持MULTI/EXEC，改为普通pipeline，不保证原子性
+func (c *MultiLevelCache) l2Pipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	if c.proxyMode() {
+		return c.l2().Pipelined(ctx, fn)
+	}
+	return c.l2().TxPipelined(ctx, fn)
+}
+
+// rangeSetMembers 分批遍历Redis集合的成员；代理模式下不支持SSCAN，改为一次SMEMBERS
+func (c *MultiLevelCache) rangeSetMembers(ctx context.Context, setKey string, fn func(members []string) error) error {
+	if c.proxyMode() {
+		members, err := c.l2().SMembers(ctx, setKey).Result()
+		if err != nil || len(members) == 0 {
+			return err
+		}
+		return fn(members)
+	}
+	var cursor uint64
+	for {
+		members, next, err := c.l2().SScan(ctx, setKey, cursor, "", tagScanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			if err := fn(members); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// redisDB 返回Redis数据库编号，自定义客户端无法取得时为0
+func (c *MultiLevelCache) redisDB() int {
+	if opts := c.config().RedisOptions; opts != nil {
+		return opts.DB
+	}
+	if client, ok := c.redisClient.(*redis.Client); ok {
+		return client.Options().DB
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// commandRecorder 记录经过客户端的Redis命令名
+type commandRecorder struct {
+	mu    sync.Mutex
+	names map[string]int
+}
+
+var _ redis.Hook = (*commandRecorder)(nil)
+
+func (r *commandRecorder) record(cmds ...redis.Cmder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names == nil {
+		r.names = make(map[string]int)
+	}
+	for _, cmd := range cmds {
+		r.names[cmd.Name()]++
+	}
+}
+
+func (r *commandRecorder) count(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.names[name]
+}
+
+func (r *commandRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	r.record(cmd)
+	return ctx, nil
+}
+
+func (r *commandRecorder) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (r *commandRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	r.record(cmds...)
+	return ctx, nil
+}
+
+func (r *commandRecorder) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+// newCommanderTestCache 使用带命令记录的自定义客户端创建缓存
+func newCommanderTestCache(t *testing.T, mr *miniredis.Miniredis, configure func(*CacheConfig)) (*MultiLevelCache, *commandRecorder) {
+	t.Helper()
+	recorder := &commandRecorder{}
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	client.AddHook(recorder)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.RedisOptions = nil
+		config.RedisClient = client
+		if configure != nil {
+			configure(config)
+		}
+	})
+	return c, recorder
+}
+
+func TestCustomRedisClient(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, recorder := newCommanderTestCache(t, mr, nil)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if !mr.Exists("k") {
+		t.Fatal("k was not written through the custom client")
+	}
+	c.deleteL1("k")
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get = %v, %v; want v from L2", v, ok)
+	}
+	if recorder.count("get") == 0 {
+		t.Error("L2 read did not go through the custom client")
+	}
+	// 自定义客户端可以复制，数据库编号取自客户端
+	if found, err := c.Copy("k", "k2"); err != nil || !found {
+		t.Fatalf("Copy = %v, %v; want true", found, err)
+	}
+	if !mr.Exists("k2") {
+		t.Error("k2 was not copied in L2")
+	}
+}
+
+func TestProxyModeAvoidsUnsupportedCommands(t *testing.T) {
+	mr := miniredis.RunT(t)
+	logs := &logRecorder{}
+	c, recorder := newCommanderTestCache(t, mr, func(config *CacheConfig) {
+		config.RedisProxyMode = true
+		config.BroadcastChannel = "updates"
+		config.TimeSource = TimeRedis
+		config.Logger = logs
+	})
+	if !logs.contains("BroadcastChannel") || !logs.contains("TimeSource") {
+		t.Errorf("proxy mode warnings were not logged: %v", logs.lines)
+	}
+
+	if err := c.SetWithTags("a", "v", 60, "t"); err != nil {
+		t.Fatalf("SetWithTags: %v", err)
+	}
+	if err := c.SetWithTags("b", "v", 60, "t"); err != nil {
+		t.Fatalf("SetWithTags: %v", err)
+	}
+	if err := c.InvalidateTags("t"); err != nil {
+		t.Fatalf("InvalidateTags: %v", err)
+	}
+	if mr.Exists("a") || mr.Exists("b") {
+		t.Error("tagged keys survived InvalidateTags")
+	}
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("k"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	stats := c.GetStats()
+	if _, ok := stats["redis_info"]; ok {
+		t.Error("GetStats sent INFO through the proxy")
+	}
+
+	// 依赖代理不支持的命令的操作返回ErrProxyUnsupported
+	if err := c.Clear(ClearOptions{IAcceptDataLoss: true}); !errors.Is(err, ErrProxyUnsupported) {
+		t.Errorf("Clear = %v, want ErrProxyUnsupported", err)
+	}
+	if _, err := c.Copy("a", "c"); !errors.Is(err, ErrProxyUnsupported) {
+		t.Errorf("Copy = %v, want ErrProxyUnsupported", err)
+	}
+	if _, err := c.Rename("a", "c"); !errors.Is(err, ErrProxyUnsupported) {
+		t.Errorf("Rename = %v, want ErrProxyUnsupported", err)
+	}
+	if err := c.Set("l1", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	report, err := c.PurgeByPredicate(context.Background(), func(string, ItemMeta) bool { return true })
+	if !errors.Is(err, ErrProxyUnsupported) || report == nil || len(report.Purged) != 1 {
+		t.Errorf("PurgeByPredicate = %+v, %v; want L1 purged and ErrProxyUnsupported", report, err)
+	}
+	if err := NewRedisPublisher(c, "events").Publish(context.Background(), []byte("m")); !errors.Is(err, ErrProxyUnsupported) {
+		t.Errorf("Publish = %v, want ErrProxyUnsupported", err)
+	}
+
+	c.Close()
+	for _, name := range []string{"multi", "exec", "scan", "sscan", "flushdb", "info", "dbsize", "time", "subscribe", "publish", "copy"} {
+		if n := recorder.count(name); n > 0 {
+			t.Errorf("proxy mode sent %d %s commands", n, name)
+		}
+	}
+}
+
+func TestRedisClientSatisfiesValidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	config := CacheConfig{EnableL1Cache: true, MaxL1Size: 10, EnableL2Cache: true, RedisClient: client}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate with RedisClient = %v, want nil", err)
+	}
+	config.RedisClient = nil
+	if err := config.Validate(); err == nil {
+		t.Error("Validate accepted L2 without RedisOptions or RedisClient")
+	}
+	client.Close()
+}
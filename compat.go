@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"strings"
+)
+
+// 服务端类型
+const (
+	ServerRedis     = "redis"
+	ServerValkey    = "valkey"
+	ServerKeyDB     = "keydb"
+	ServerDragonfly = "dragonfly"
+)
+
+// ServerInfo 检测到的L2服务端类型和支持的功能
+type ServerInfo struct {
+	Flavor         string // 服务端类型，如ServerRedis、ServerValkey
+	Version        string // 服务端版本
+	Copy           bool   // 支持COPY命令，不支持时Copy改为读取后写入
+	KeyspaceEvents bool   // 已开启过期事件通知，未开启时不启动OnExpire的监听
+}
+
+// DetectServer 检测服务端类型和支持的功能，可以在CI中针对Redis、Valkey、KeyDB、Dragonfly等分别运行以确认兼容性
+// 无法确定是否支持的功能(如托管Redis禁止CONFIG命令)按支持处理
+func DetectServer(ctx context.Context, client RedisCommander) (ServerInfo, error) {
+	info := ServerInfo{Flavor: ServerRedis, KeyspaceEvents: true}
+	text, err := client.Info(ctx, "server").Result()
+	if err != nil {
+		return info, err
+	}
+	fields := parseInfo(text)
+	switch {
+	case fields["dragonfly_version"] != "":
+		info.Flavor, info.Version = ServerDragonfly, fields["dragonfly_version"]
+	case fields["valkey_version"] != "":
+		info.Flavor, info.Version = ServerValkey, fields["valkey_version"]
+	case strings.Contains(strings.ToLower(text), "keydb"):
+		info.Flavor, info.Version = ServerKeyDB, fields["redis_version"]
+	default:
+		info.Version = fields["redis_version"]
+	}
+
+	// COMMAND INFO对不存在的命令返回nil
+	if reply, err := client.Do(ctx, "COMMAND", "INFO", "copy").Slice(); err == nil {
+		info.Copy = len(reply) == 1 && reply[0] != nil
+	}
+
+	if flags, err := client.ConfigGet(ctx, "notify-keyspace-events").Result(); err == nil && len(flags) == 2 {
+		value, _ := flags[1].(string)
+		info.KeyspaceEvents = strings.Contains(value, "E") && (strings.Contains(value, "x") || strings.Contains(value, "A"))
+	}
+	return info, nil
+}
+
+// parseInfo 解析INFO命令返回的key:value行
+func parseInfo(text string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			fields[line[:i]] = line[i+1:]
+		}
+	}
+	return fields
+}
+
+// detectServer 启用CompatibilityMode时在创建缓存时检测服务端，并记录降级的功能
+func (c *MultiLevelCache) detectServer() {
+	config := c.config()
+	if config.EnableKeyspaceEvents && config.OnExpire != nil {
+		// 先尝试开启过期事件通知，检测结果反映开启后的状态
+		if err := c.redisClient.ConfigSet(c.ctx, "notify-keyspace-events", "Ex").Err(); err != nil {
+			c.logf("dancache: enable keyspace events: %v", err)
+		}
+	}
+	info, err := DetectServer(c.ctx, c.redisClient)
+	if err != nil {
+		c.logf("dancache: detect redis server failed, assuming full redis support: %v", err)
+		return
+	}
+	c.server = &info
+	c.logf("dancache: detected %s %s", info.Flavor, info.Version)
+	if !info.Copy {
+		c.logf("dancache: %s does not support COPY, Copy falls back to GET and SET", info.Flavor)
+	}
+	if !info.KeyspaceEvents && config.OnExpire != nil {
+		c.logf("dancache: keyspace events are disabled on %s, OnExpire will not be called", info.Flavor)
+	}
+}
+
+// Server 返回检测到的服务端信息，未启用CompatibilityMode或检测失败时ok为false
+func (c *MultiLevelCache) Server() (info ServerInfo, ok bool) {
+	if c.server == nil {
+		return ServerInfo{}, false
+	}
+	return *c.server, true
+}
+
+// serverCopy 判断服务端是否支持COPY，未检测时按支持处理
+func (c *MultiLevelCache) serverCopy() bool {
+	return c.server == nil || c.server.Copy
+}
+
+// serverKeyspaceEvents 判断服务端是否开启了过期事件通知，未检测时按开启处理
+func (c *MultiLevelCache) serverKeyspaceEvents() bool {
+	return c.server == nil || c.server.KeyspaceEvents
+}
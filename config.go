@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ConfigPatch 运行时配置修改，字段为nil表示保持原值
+// 只包含写入和读取路径实际使用的配置，过期时间由每次写入的ttl决定，不能在运行时修改
+type ConfigPatch struct {
+	MaxL1Size    *int // 本地缓存最大条目数
+	MaxValueSize *int // 缓存项序列化后的最大字节数
+
+	PromotionStrategy PromotionStrategy // 缓存升级策略
+	DemotionStrategy  DemotionStrategy  // 缓存降级策略
+
+	CircuitFailureThreshold *int           // 连续失败多少次后打开L2熔断器
+	CircuitOpenDuration     *time.Duration // 熔断器打开后的冷却时间
+}
+
+// config 返回当前配置，返回值只读
+func (c *MultiLevelCache) config() *CacheConfig {
+	return c.cfg.Load().(*CacheConfig)
+}
+
+// UpdateConfig 在运行时校验并原子地应用配置修改
+// 校验失败时不修改任何配置；缩小MaxL1Size后会立即淘汰超出的本地缓存项
+func (c *MultiLevelCache) UpdateConfig(patch ConfigPatch) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	config := *c.config()
+	if patch.MaxL1Size != nil {
+		if *patch.MaxL1Size < 0 {
+			return errors.New("MaxL1Size不能为负数")
+		}
+		config.MaxL1Size = *patch.MaxL1Size
+	}
+	if patch.MaxValueSize != nil {
+		if *patch.MaxValueSize < 0 {
+			return errors.New("MaxValueSize不能为负数")
+		}
+		config.MaxValueSize = *patch.MaxValueSize
+	}
+	if patch.PromotionStrategy != nil {
+		config.PromotionStrategy = patch.PromotionStrategy
+	}
+	if patch.DemotionStrategy != nil {
+		config.DemotionStrategy = patch.DemotionStrategy
+	}
+	if patch.CircuitFailureThreshold != nil || patch.CircuitOpenDuration != nil {
+		if c.circuit == nil {
+			return errors.New("未启用L2熔断器，无法修改熔断配置")
+		}
+		if patch.CircuitFailureThreshold != nil {
+			if *patch.CircuitFailureThreshold <= 0 {
+				return errors.New("CircuitFailureThreshold必须大于0")
+			}
+			config.CircuitFailureThreshold = *patch.CircuitFailureThreshold
+		}
+		if patch.CircuitOpenDuration != nil {
+			if *patch.CircuitOpenDuration <= 0 {
+				return errors.New("CircuitOpenDuration必须大于0")
+			}
+			config.CircuitOpenDuration = *patch.CircuitOpenDuration
+		}
+		c.circuit.configure(config.CircuitFailureThreshold, config.CircuitOpenDuration)
+	}
+
+	c.cfg.Store(&config)
+
+	// 缩小容量后立即淘汰超出的项
+	if count := c.l1Count(); config.EnableL1Cache && config.MaxL1Size > 0 && count > config.MaxL1Size {
+		c.evictLRU(count-config.MaxL1Size, EvictCapacity)
+	}
+	return nil
+}
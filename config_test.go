@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateConfig(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if err := c.Set(key, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 校验失败时不修改任何配置
+	size, negative := 2, -1
+	if err := c.UpdateConfig(ConfigPatch{MaxL1Size: &size, MaxValueSize: &negative}); err == nil {
+		t.Fatal("UpdateConfig accepted a negative MaxValueSize")
+	}
+	if got := c.config().MaxL1Size; got != 10 {
+		t.Errorf("MaxL1Size after a rejected patch = %d, want 10", got)
+	}
+	open := time.Second
+	if err := c.UpdateConfig(ConfigPatch{CircuitOpenDuration: &open}); err == nil {
+		t.Error("UpdateConfig changed circuit settings without a circuit breaker")
+	}
+
+	// 缩小容量后立即淘汰超出的项
+	if err := c.UpdateConfig(ConfigPatch{MaxL1Size: &size}); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.config().MaxL1Size; got != 2 {
+		t.Errorf("MaxL1Size = %d, want 2", got)
+	}
+	if n := c.l1Count(); n != 2 {
+		t.Errorf("L1 holds %d items after shrinking MaxL1Size, want 2", n)
+	}
+}
+
+// logRecorder 记录日志的Logger
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logRecorder) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+	l.mu.Unlock()
+}
+
+func (l *logRecorder) contains(substr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateReportsErrorsAndWarnings(t *testing.T) {
+	config := CacheConfig{
+		EnableL1Cache:    true,
+		MaxL1Size:        -1,
+		BypassPercent:    150,
+		BroadcastChannel: "updates",
+	}
+	var configErr *ConfigError
+	if err := config.Validate(); !errors.As(err, &configErr) {
+		t.Fatalf("Validate = %v, want *ConfigError", err)
+	}
+	fields := make(map[string]bool)
+	for _, issue := range configErr.Issues {
+		fields[issue.Field] = issue.Warning
+	}
+	if warning, ok := fields["MaxL1Size"]; !ok || warning {
+		t.Errorf("MaxL1Size issue = %v, %v; want an error", warning, ok)
+	}
+	if warning, ok := fields["BypassPercent"]; !ok || warning {
+		t.Errorf("BypassPercent issue = %v, %v; want an error", warning, ok)
+	}
+	if warning, ok := fields["BroadcastChannel"]; !ok || !warning {
+		t.Errorf("BroadcastChannel issue = %v, %v; want a warning without L2", warning, ok)
+	}
+
+	// 创建缓存时只有错误导致失败
+	if _, err := NewMultiLevelCache(config); !errors.As(err, &configErr) || len(configErr.Issues) != 2 {
+		t.Errorf("NewMultiLevelCache = %v, want the two errors only", err)
+	}
+	// 只有警告时创建成功，警告通过Logger输出
+	logs := &logRecorder{}
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10, BroadcastChannel: "updates", Logger: logs})
+	if err != nil {
+		t.Fatalf("NewMultiLevelCache with warnings only: %v", err)
+	}
+	defer c.Close()
+	if !logs.contains("BroadcastChannel") {
+		t.Errorf("warning was not logged: %v", logs.lines)
+	}
+
+	if err := (CacheConfig{EnableL1Cache: true, MaxL1Size: 10}).Validate(); err != nil {
+		t.Errorf("Validate of a clean config = %v, want nil", err)
+	}
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// LoaderFuncContext 缓存未命中时加载数据，ctx来自调用方(保留追踪ID等值，但不随调用方取消)
+type LoaderFuncContext func(ctx context.Context) (interface{}, error)
+
+// LoaderFuncWithTTLContext 缓存未命中时加载数据并返回过期时间(秒)，ctx来自调用方
+type LoaderFuncWithTTLContext func(ctx context.Context) (interface{}, int64, error)
+
+// MultiLoaderFuncContext 批量加载缓存未命中的键，ctx来自调用方
+type MultiLoaderFuncContext func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+// detachedContext 保留父context的值，但不继承取消和截止时间
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline 没有截止时间
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done 永远不会取消
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err 永远返回nil
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value 返回父context中的值
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// detach 返回用于后台工作的context：保留调用方context中的追踪ID、认证信息等值，
+// 但调用方返回或取消后后台工作(合并加载、异步写入、合并写入)仍能完成
+func detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	if _, ok := ctx.(detachedContext); ok {
+		return ctx
+	}
+	return detachedContext{parent: ctx}
+}
+
+// GetOrLoadContext 与GetOrLoad相同，loader和钩子收到由ctx派生的context
+// 合并的并发加载共享第一个调用方的context值；ctx取消时当前调用立即返回ctx.Err()，进行中的加载继续完成并回填
+func (c *MultiLevelCache) GetOrLoadContext(ctx context.Context, key string, ttl int64, loader LoaderFuncContext) (interface{}, error) {
+	return c.getOrLoad(ctx, key, ttl, nil, loader)
+}
+
+// GetOrLoadWithTTLContext 与GetOrLoadWithTTL相同，loader和钩子收到由ctx派生的context
+func (c *MultiLevelCache) GetOrLoadWithTTLContext(ctx context.Context, key string, loader LoaderFuncWithTTLContext) (interface{}, error) {
+	return c.getOrLoadTTL(ctx, key, nil, loader)
+}
+
+// GetOrLoadMultiContext 与GetOrLoadMulti相同，loader和钩子收到由ctx派生的context
+func (c *MultiLevelCache) GetOrLoadMultiContext(ctx context.Context, keys []string, ttl int64, loader MultiLoaderFuncContext) (map[string]interface{}, error) {
+	return c.getOrLoadMulti(ctx, keys, ttl, nil, loader)
+}
+
+// SetContext 与Set相同，L2写入(包括合并写入)使用由ctx派生的context，调用方取消不会中断已开始的写入
+func (c *MultiLevelCache) SetContext(ctx context.Context, key string, value interface{}, ttl int64) error {
+	return c.set(detach(ctx), key, value, ttl, nil, false, callOptions{})
+}
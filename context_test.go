@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// ctxKey 测试用的context键
+type ctxKey struct{}
+
+func TestDetachKeepsValuesWithoutCancellation(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "trace"), time.Millisecond)
+	cancel()
+	ctx := detach(parent)
+	if ctx.Err() != nil || ctx.Done() != nil {
+		t.Error("detached context inherited cancellation")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("detached context inherited the deadline")
+	}
+	if v := ctx.Value(ctxKey{}); v != "trace" {
+		t.Errorf("Value = %v, want trace", v)
+	}
+	if detach(ctx) != ctx {
+		t.Error("detaching twice wrapped the context again")
+	}
+}
+
+func TestGetOrLoadContextPassesValuesAndOutlivesCaller(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "trace"))
+	release := make(chan struct{})
+	seen := make(chan interface{}, 1)
+	loader := func(ctx context.Context) (interface{}, error) {
+		seen <- ctx.Value(ctxKey{})
+		<-release
+		return "loaded", ctx.Err()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoadContext(ctx, "k", 60, loader)
+		done <- err
+	}()
+	if v := <-seen; v != "trace" {
+		t.Errorf("loader saw ctx value %v, want trace", v)
+	}
+	// 调用方取消后立即返回，进行中的加载继续完成并回填
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("GetOrLoadContext = %v after cancel, want context.Canceled", err)
+	}
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if v, ok := c.Get("k"); ok {
+			if v != "loaded" {
+				t.Errorf("backfilled value = %v, want loaded", v)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("load was not backfilled after the caller cancelled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSetContextIgnoresCancellation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.SetContext(ctx, "k", "v", 60); err != nil {
+		t.Fatalf("SetContext with a cancelled context: %v", err)
+	}
+	if !mr.Exists("k") {
+		t.Error("SetContext did not write L2")
+	}
+	if err := c.SetAsyncContext(ctx, "a", "v", 60).Wait(); err != nil {
+		t.Fatalf("SetAsyncContext: %v", err)
+	}
+	if !mr.Exists("a") {
+		t.Error("SetAsyncContext did not write L2")
+	}
+}
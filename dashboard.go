@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// dashboardTopKeys 仪表盘展示的热点键数量
+const dashboardTopKeys = 20
+
+// DashboardHandler 返回内嵌的缓存仪表盘，页面不依赖外部资源，每2秒自动刷新
+// 请求带format=json时返回当前快照，可用于脚本采集
+// 仪表盘会暴露键名，应挂载在仅内部可访问的管理端口上
+func (c *MultiLevelCache) DashboardHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(c.dashboardSnapshot())
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(dashboardHTML))
+	})
+}
+
+// dashboardSnapshot 仪表盘数据快照
+func (c *MultiLevelCache) dashboardSnapshot() map[string]interface{} {
+	stats := c.GetStats()
+	delete(stats, "redis_info")
+
+	snapshot := map[string]interface{}{
+		"time":       time.Now().UnixNano() / int64(time.Millisecond),
+		"stats":      stats,
+		"heap_bytes": heapInUse(),
+		"top_keys":   []KeyCount{},
+		"slow_log":   c.SlowLog(dashboardTopKeys),
+	}
+	if c.topKeys != nil {
+		snapshot["top_keys"] = c.topKeys.top(dashboardTopKeys)
+	}
+	return snapshot
+}
+
+// dashboardHTML 仪表盘页面，速率由页面根据相邻两次快照计算
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>DanCache</title>
+<style>
+body { font-family: monospace; margin: 20px; background: #fafafa; }
+h2 { margin-top: 24px; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+.open { color: #c00; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>DanCache</h1>
+<div id="summary"></div>
+<h2>热点键</h2>
+<table id="keys"><tr><th>键</th><th>访问次数(估计)</th></tr></table>
+<h2>慢操作</h2>
+<table id="slow"><tr><th>时间</th><th>操作</th><th>键</th><th>耗时(ms)</th><th>级别</th><th>大小</th></tr></table>
+<h2>全部统计</h2>
+<table id="stats"></table>
+<script>
+var last = null;
+
+function row(cells, tag) {
+  var tr = document.createElement("tr");
+  cells.forEach(function (c) {
+    var td = document.createElement(tag || "td");
+    td.textContent = c;
+    tr.appendChild(td);
+  });
+  return tr;
+}
+
+function render(s) {
+  var st = s.stats;
+  var lookups = (st.l1_hits || 0) + (st.l2_hits || 0) + (st.misses || 0);
+  var rate = "-", evictRate = "-";
+  if (last) {
+    var secs = (s.time - last.time) / 1000;
+    var dl = lookups - last.lookups;
+    var dh = (st.l1_hits + st.l2_hits) - last.hits;
+    if (dl > 0) rate = (100 * dh / dl).toFixed(1) + "%";
+    if (secs > 0) evictRate = ((st.l1_evictions - last.evictions) / secs).toFixed(1) + "/s";
+  }
+  last = { time: s.time, lookups: lookups, hits: st.l1_hits + st.l2_hits, evictions: st.l1_evictions };
+
+  var circuit = st.l2_circuit_open ? '<span class="open">OPEN</span>' : "closed";
+  document.getElementById("summary").innerHTML =
+    "命中率(累计): " + (100 * st.hit_ratio).toFixed(1) + "% &nbsp; 命中率(最近): " + rate +
+    "<br>L1项数: " + (st.l1_item_count || 0) + " / " + (st.l1_max_size || 0) +
+    " &nbsp; 淘汰速率: " + evictRate +
+    "<br>堆内存: " + (s.heap_bytes / 1048576).toFixed(1) + " MB" +
+    " &nbsp; L2熔断器: " + (st.l2_circuit_open === undefined ? "-" : circuit);
+
+  var keys = document.getElementById("keys");
+  while (keys.rows.length > 1) keys.deleteRow(1);
+  s.top_keys.forEach(function (k) { keys.appendChild(row([k.key, k.count])); });
+
+  var slow = document.getElementById("slow");
+  while (slow.rows.length > 1) slow.deleteRow(1);
+  (s.slow_log || []).forEach(function (e) {
+    slow.appendChild(row([e.time, e.op, e.key, (e.duration / 1e6).toFixed(2), e.level, e.size]));
+  });
+
+  var table = document.getElementById("stats");
+  table.innerHTML = "";
+  Object.keys(st).sort().forEach(function (k) { table.appendChild(row([k, JSON.stringify(st[k])])); });
+}
+
+function refresh() {
+  fetch("?format=json").then(function (r) { return r.json(); }).then(render).catch(function () {});
+}
+refresh();
+setInterval(refresh, 2000);
+</script>
+</body>
+</html>
+`
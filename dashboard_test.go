@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTopKeysKeepsHeavyHitters(t *testing.T) {
+	tk := newTopKeys(3)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100*topKeysSampleEvery; i++ {
+		switch n := r.Intn(4); {
+		case n < 2:
+			tk.record("hot")
+		case n == 2:
+			tk.record("warm")
+		default:
+			tk.record(fmt.Sprint("cold", i))
+		}
+	}
+	top := tk.top(2)
+	if len(top) != 2 || top[0].Key != "hot" {
+		t.Fatalf("top = %v, want hot first", top)
+	}
+	if top[0].Count < 40*topKeysSampleEvery {
+		t.Errorf("hot count = %d, want about half of the accesses", top[0].Count)
+	}
+}
+
+func TestDashboardHandler(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) { config.TopKeys = 5 })
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3*topKeysSampleEvery; i++ {
+		c.Get("k")
+	}
+	c.Get("missing")
+
+	rec := httptest.NewRecorder()
+	c.DashboardHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/?format=json", nil))
+	var snapshot struct {
+		Stats   map[string]interface{} `json:"stats"`
+		TopKeys []KeyCount             `json:"top_keys"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if snapshot.Stats["l1_hits"] != float64(3*topKeysSampleEvery) || snapshot.Stats["misses"] != float64(1) {
+		t.Errorf("stats = %v", snapshot.Stats)
+	}
+	if len(snapshot.TopKeys) == 0 || snapshot.TopKeys[0].Key != "k" {
+		t.Errorf("top_keys = %v, want k first", snapshot.TopKeys)
+	}
+
+	rec = httptest.NewRecorder()
+	c.DashboardHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") || !strings.Contains(rec.Body.String(), "<title>DanCache</title>") {
+		t.Errorf("dashboard page Content-Type = %q", ct)
+	}
+}
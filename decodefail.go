@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// DecodePolicy L2中的值无法解码时的处理策略
+type DecodePolicy int
+
+const (
+	DecodeMiss          DecodePolicy = iota // 按未命中处理，值留在Redis中直到过期
+	DecodeDeleteAndMiss                     // 删除Redis中的值并按未命中处理，下次加载时自愈
+	DecodeReturnError                       // 按未命中处理，GetWithError返回*DecodeError
+	DecodeQuarantine                        // 同一键多次解码失败后将值移入隔离区供排查，按未命中处理
+)
+
+// DecodeError L2中的值无法解码
+type DecodeError struct {
+	Key string
+	Err error
+}
+
+// Error 实现error接口
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("缓存值解码失败: 键%q: %v", e.Key, e.Err)
+}
+
+// Unwrap 返回解码错误
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeFailed 按DecodePolicy处理解码失败的值，返回需要报告给调用方的错误
+// 校验和失败总是返回ErrCorrupted
+func (c *MultiLevelCache) decodeFailed(key string, data []byte, err error) error {
+	atomic.AddInt64(&c.decodeFailures, 1)
+	if err == ErrCorrupted {
+		atomic.AddInt64(&c.corruptedItems, 1)
+	}
+	c.logf("dancache: decode L2 value for %q: %v", key, err)
+
+	policy := c.config().DecodePolicy
+	switch policy {
+	case DecodeDeleteAndMiss:
+		if _, delErr := c.deleteL2(c.ctx, key); delErr != nil {
+			c.logf("dancache: delete undecodable %q: %v", key, delErr)
+		}
+	case DecodeQuarantine:
+		c.recordDecodeFailure(key, data, err)
+	}
+
+	if err == ErrCorrupted {
+		return err
+	}
+	if policy == DecodeReturnError {
+		return &DecodeError{Key: key, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestDecodePolicies(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  DecodePolicy
+		kept    bool
+		wantErr bool
+	}{
+		{"miss", DecodeMiss, true, false},
+		{"delete", DecodeDeleteAndMiss, false, false},
+		{"error", DecodeReturnError, true, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.DecodePolicy = tc.policy })
+			mr.Set("k", "{not json")
+
+			_, found, err := c.GetWithError("k")
+			if found {
+				t.Fatal("undecodable value was served")
+			}
+			var decodeErr *DecodeError
+			if got := errors.As(err, &decodeErr); got != tc.wantErr {
+				t.Errorf("GetWithError error = %v, want DecodeError %v", err, tc.wantErr)
+			} else if got && decodeErr.Key != "k" {
+				t.Errorf("DecodeError.Key = %q, want k", decodeErr.Key)
+			}
+			if mr.Exists("k") != tc.kept {
+				t.Errorf("value kept in L2 = %v, want %v", mr.Exists("k"), tc.kept)
+			}
+			if n := c.GetStats()["l2_decode_failures"]; n != int64(1) {
+				t.Errorf("l2_decode_failures = %v, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DegradedState 缓存级别的故障组合
+type DegradedState int32
+
+const (
+	StateHealthy      DegradedState = iota // 启用的级别都正常
+	StateL2Degraded                        // L2不可用(熔断器打开或L2探测失败)，只剩L1
+	StateL1Degraded                        // L1不可用(L1探测失败或正在因内存压力收缩)，只剩L2
+	StateBothDegraded                      // 两级都不可用
+)
+
+// String 返回状态的名称
+func (s DegradedState) String() string {
+	switch s {
+	case StateHealthy:
+		return "healthy"
+	case StateL2Degraded:
+		return "l2_degraded"
+	case StateL1Degraded:
+		return "l1_degraded"
+	case StateBothDegraded:
+		return "both_degraded"
+	default:
+		return "unknown"
+	}
+}
+
+// DegradedAction 故障期间GetOrLoad的行为
+type DegradedAction int
+
+const (
+	DegradeUseAvailable DegradedAction = iota // 使用仍可用的级别，未命中时调用loader(默认)
+	DegradeServeStale                         // 额外返回已过期不超过DegradedStaleFor的L1项，以及超过L1停留期限、本应从L2重新读取的项
+	DegradeErrorFast                          // 未命中时返回ErrDegraded，不调用loader，保护源数据库
+	DegradeBypass                             // 不读写缓存，直接调用loader
+	DegradeQueue                              // 未命中的加载排队执行，同时最多DegradedLoadConcurrency个loader
+)
+
+// defaultDegradedStaleFor DegradeServeStale默认返回过期多久以内的L1项(秒)
+const defaultDegradedStaleFor = 60
+
+// defaultDegradedLoadConcurrency DegradeQueue默认同时执行的loader数
+const defaultDegradedLoadConcurrency = 4
+
+// defaultDegradedQueueTimeout DegradeQueue默认的排队超时
+const defaultDegradedQueueTimeout = 5 * time.Second
+
+// ErrDegraded 缓存处于故障状态，按DegradeErrorFast配置拒绝调用loader
+var ErrDegraded = errors.New("缓存处于降级状态，拒绝加载")
+
+// DegradedStatus 当前的故障状态
+type DegradedStatus struct {
+	State   DegradedState
+	Action  DegradedAction // 当前状态下GetOrLoad的行为
+	Reasons []string       // 判定为故障的原因
+	Since   time.Time      // 进入当前状态的时间
+}
+
+// degradedTracker 记录故障状态的变化
+type degradedTracker struct {
+	state       int32 // DegradedState
+	since       int64 // 进入当前状态的时间(Unix纳秒)
+	transitions int64 // 状态变化次数
+	bypassed    int64 // DegradeBypass直接调用loader的次数
+	rejected    int64 // DegradeErrorFast拒绝加载的次数
+	queueOnce   sync.Once
+	queue       *loadLimiter // DegradeQueue的加载排队
+}
+
+// evaluateDegraded 根据熔断器、探测结果和内存压力判断当前的故障组合
+func (c *MultiLevelCache) evaluateDegraded() (DegradedState, []string) {
+	config := c.config()
+	var reasons []string
+	l1Down, l2Down := false, false
+
+	c.canary.mu.RLock()
+	canary := c.canary.results
+	c.canary.mu.RUnlock()
+	if config.EnableL1Cache {
+		if r, ok := canary[CanaryL1]; ok && !r.OK {
+			l1Down = true
+			reasons = append(reasons, "l1 canary failed: "+r.Err)
+		}
+		if last := atomic.LoadInt64(&c.lastPressure); last > 0 && config.MemoryLimitRatio > 0 {
+			interval := config.MemoryCheckInterval
+			if interval <= 0 {
+				interval = 5 * time.Second
+			}
+			if time.Since(time.Unix(0, last)) < 2*interval {
+				l1Down = true
+				reasons = append(reasons, "l1 shrinking under memory pressure")
+			}
+		}
+	}
+	if config.EnableL2Cache {
+		if c.circuitOpen() {
+			l2Down = true
+			reasons = append(reasons, "l2 circuit open")
+		}
+		if r, ok := canary[CanaryL2]; ok && !r.OK {
+			l2Down = true
+			reasons = append(reasons, "l2 canary failed: "+r.Err)
+		}
+	}
+
+	switch {
+	case l1Down && l2Down:
+		return StateBothDegraded, reasons
+	case l2Down:
+		return StateL2Degraded, reasons
+	case l1Down:
+		return StateL1Degraded, reasons
+	}
+	return StateHealthy, nil
+}
+
+// degradedAction 返回当前状态配置的行为，未配置DegradedModes时不检查状态
+func (c *MultiLevelCache) degradedAction() DegradedAction {
+	if len(c.config().DegradedModes) == 0 {
+		return DegradeUseAvailable
+	}
+	return c.updateDegraded().Action
+}
+
+// updateDegraded 判断当前状态，状态变化时记录日志并调用OnDegradedChange
+func (c *MultiLevelCache) updateDegraded() DegradedStatus {
+	state, reasons := c.evaluateDegraded()
+	t := &c.degraded
+	prev := DegradedState(atomic.LoadInt32(&t.state))
+	status := DegradedStatus{State: state, Action: c.config().DegradedModes[state], Reasons: reasons}
+	if state != prev && atomic.CompareAndSwapInt32(&t.state, int32(prev), int32(state)) {
+		now := time.Now()
+		atomic.StoreInt64(&t.since, now.UnixNano())
+		atomic.AddInt64(&t.transitions, 1)
+		status.Since = now
+		c.logf("dancache: degraded state %s -> %s %v", prev, state, reasons)
+		if onChange := c.config().OnDegradedChange; onChange != nil {
+			c.protect("OnDegradedChange", func() { onChange(status) })
+		}
+		return status
+	}
+	if since := atomic.LoadInt64(&t.since); since > 0 {
+		status.Since = time.Unix(0, since)
+	}
+	return status
+}
+
+// DegradedStatus 返回当前的故障状态和对应的行为；未配置DegradedModes时同样判断状态，行为为DegradeUseAvailable
+func (c *MultiLevelCache) DegradedStatus() DegradedStatus {
+	return c.updateDegraded()
+}
+
+// servesStale 判断已失效的L1项在当前故障状态下是否仍可返回
+// 在持有epoch读锁时调用，只判断状态，不记录状态变化，避免在锁内调用OnDegradedChange
+func (c *MultiLevelCache) servesStale(item *CacheItem, now int64) bool {
+	modes := c.config().DegradedModes
+	if len(modes) == 0 {
+		return false
+	}
+	if state, _ := c.evaluateDegraded(); modes[state] != DegradeServeStale {
+		return false
+	}
+	grace := c.config().DegradedStaleFor
+	if grace <= 0 {
+		grace = defaultDegradedStaleFor
+	}
+	return item.ExpireTime+grace > now
+}
+
+// degradedQueue 返回DegradeQueue的加载排队
+func (c *MultiLevelCache) degradedQueue() *loadLimiter {
+	t := &c.degraded
+	t.queueOnce.Do(func() {
+		config := c.config()
+		concurrency := config.DegradedLoadConcurrency
+		if concurrency <= 0 {
+			concurrency = defaultDegradedLoadConcurrency
+		}
+		timeout := config.DegradedQueueTimeout
+		if timeout <= 0 {
+			timeout = defaultDegradedQueueTimeout
+		}
+		t.queue = newLoadLimiter(concurrency, timeout)
+	})
+	return t.queue
+}
+
+// bypassDegraded DegradeBypass时直接调用loader，不读写缓存
+func (c *MultiLevelCache) bypassDegraded(ctx context.Context, loader LoaderFuncWithTTLContext) (interface{}, error) {
+	atomic.AddInt64(&c.degraded.bypassed, 1)
+	return c.callLoader(func() (interface{}, error) {
+		val, _, err := loader(ctx)
+		return val, err
+	})
+}
+
+// admitDegradedLoad DegradeQueue时排队执行加载，排队超时返回ErrOverloaded
+func (c *MultiLevelCache) admitDegradedLoad(load func() (interface{}, error)) (interface{}, error) {
+	q := c.degradedQueue()
+	if !q.acquire() {
+		atomic.AddInt64(&c.shedLoads, 1)
+		return nil, ErrOverloaded
+	}
+	defer q.release()
+	return c.callLoader(load)
+}
+
+// degradedStatsMap 返回故障状态统计
+func (c *MultiLevelCache) degradedStatsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"degraded_state":       DegradedState(atomic.LoadInt32(&c.degraded.state)).String(),
+		"degraded_transitions": atomic.LoadInt64(&c.degraded.transitions),
+		"degraded_bypassed":    atomic.LoadInt64(&c.degraded.bypassed),
+		"degraded_rejected":    atomic.LoadInt64(&c.degraded.rejected),
+	}
+}
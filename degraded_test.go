@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// failCanary 模拟探测结果，用于触发故障状态
+func failCanary(c *MultiLevelCache, components ...string) {
+	results := make(map[string]CanaryResult, len(components))
+	for _, component := range components {
+		results[component] = CanaryResult{OK: false, Err: "down"}
+	}
+	c.canary.mu.Lock()
+	c.canary.results = results
+	c.canary.mu.Unlock()
+}
+
+func TestDegradedStatusTransitions(t *testing.T) {
+	mr := miniredis.RunT(t)
+	var changes []DegradedStatus
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.DegradedModes = map[DegradedState]DegradedAction{StateL2Degraded: DegradeErrorFast}
+		config.OnDegradedChange = func(s DegradedStatus) { changes = append(changes, s) }
+	})
+	if s := c.DegradedStatus(); s.State != StateHealthy || len(s.Reasons) != 0 {
+		t.Fatalf("status = %+v, want healthy", s)
+	}
+
+	failCanary(c, CanaryL2)
+	s := c.DegradedStatus()
+	if s.State != StateL2Degraded || s.Action != DegradeErrorFast || len(s.Reasons) != 1 || !strings.Contains(s.Reasons[0], "l2 canary failed") {
+		t.Fatalf("status = %+v, want l2_degraded with ErrorFast", s)
+	}
+	if s.Since.IsZero() {
+		t.Error("status has no Since")
+	}
+	// 状态不变时不重复回调
+	c.DegradedStatus()
+
+	failCanary(c, CanaryL1, CanaryL2)
+	if s := c.DegradedStatus(); s.State != StateBothDegraded || s.Action != DegradeUseAvailable || len(s.Reasons) != 2 {
+		t.Errorf("status = %+v, want both_degraded with the default action", s)
+	}
+	failCanary(c)
+	c.DegradedStatus()
+
+	if len(changes) != 3 || changes[0].State != StateL2Degraded || changes[1].State != StateBothDegraded || changes[2].State != StateHealthy {
+		t.Errorf("OnDegradedChange calls = %+v, want l2_degraded, both_degraded, healthy", changes)
+	}
+	stats := c.GetStats()
+	if stats["degraded_state"] != "healthy" || stats["degraded_transitions"] != int64(3) {
+		t.Errorf("degraded stats = %v, %v; want healthy, 3", stats["degraded_state"], stats["degraded_transitions"])
+	}
+}
+
+func TestDegradedErrorFast(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.DegradedModes = map[DegradedState]DegradedAction{StateL2Degraded: DegradeErrorFast}
+	})
+	if err := c.Set("cached", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	failCanary(c, CanaryL2)
+
+	called := false
+	loader := func() (interface{}, error) { called = true; return "loaded", nil }
+	// 已缓存的值照常返回
+	if v, err := c.GetOrLoad("cached", 60, loader); err != nil || v != "v" {
+		t.Errorf("GetOrLoad(cached) = %v, %v; want v", v, err)
+	}
+	if _, err := c.GetOrLoad("missing", 60, loader); !errors.Is(err, ErrDegraded) {
+		t.Errorf("GetOrLoad(missing) = %v, want ErrDegraded", err)
+	}
+	if called {
+		t.Error("loader called while degraded")
+	}
+	if n := c.GetStats()["degraded_rejected"]; n != int64(1) {
+		t.Errorf("degraded_rejected = %v, want 1", n)
+	}
+}
+
+func TestDegradedBypass(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.DegradedModes = map[DegradedState]DegradedAction{StateL2Degraded: DegradeBypass}
+	})
+	if err := c.Set("k", "cached", 60); err != nil {
+		t.Fatal(err)
+	}
+	failCanary(c, CanaryL2)
+
+	// 不读取缓存，加载结果也不回填
+	v, err := c.GetOrLoad("k", 60, func() (interface{}, error) { return "loaded", nil })
+	if err != nil || v != "loaded" {
+		t.Fatalf("GetOrLoad = %v, %v; want loaded", v, err)
+	}
+	if v, ok := c.Get("k"); !ok || v != "cached" {
+		t.Errorf("Get = %v, %v; want the cached value untouched", v, ok)
+	}
+	if n := c.GetStats()["degraded_bypassed"]; n != int64(1) {
+		t.Errorf("degraded_bypassed = %v, want 1", n)
+	}
+}
+
+func TestDegradedServeStale(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.DegradedModes = map[DegradedState]DegradedAction{StateL2Degraded: DegradeServeStale}
+		config.DegradedStaleFor = 30
+	})
+	now := time.Now().Unix()
+	for key, expiredFor := range map[string]int64{"recent": 5, "old": 60} {
+		if err := c.Set(key, key, 60, SkipL2()); err != nil {
+			t.Fatal(err)
+		}
+		item, _ := c.shardFor(key).load(key)
+		item.ExpireTime = now - expiredFor
+	}
+
+	// 正常状态下过期项不返回
+	if _, ok := c.Get("recent"); ok {
+		t.Fatal("expired item returned while healthy")
+	}
+	if err := c.Set("recent", "recent", 60, SkipL2()); err != nil {
+		t.Fatal(err)
+	}
+	item, _ := c.shardFor("recent").load("recent")
+	item.ExpireTime = now - 5
+
+	failCanary(c, CanaryL2)
+	if v, ok := c.Get("recent"); !ok || v != "recent" {
+		t.Errorf("Get(recent) = %v, %v; want the stale value", v, ok)
+	}
+	if _, ok := c.Get("old"); ok {
+		t.Error("item expired beyond DegradedStaleFor was returned")
+	}
+}
+
+func TestDegradedQueue(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.DegradedModes = map[DegradedState]DegradedAction{StateL2Degraded: DegradeQueue}
+		config.DegradedLoadConcurrency = 1
+		config.DegradedQueueTimeout = 50 * time.Millisecond
+	})
+	failCanary(c, CanaryL2)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad("a", 60, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "a", nil
+		})
+		done <- err
+	}()
+	<-started
+
+	// 排队超时的加载返回ErrOverloaded
+	if _, err := c.GetOrLoad("b", 60, func() (interface{}, error) { return "b", nil }); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("queued GetOrLoad = %v, want ErrOverloaded", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first GetOrLoad: %v", err)
+	}
+	if v, err := c.GetOrLoad("b", 60, func() (interface{}, error) { return "b", nil }); err != nil || v != "b" {
+		t.Errorf("GetOrLoad after the queue drained = %v, %v; want b", v, err)
+	}
+}
+
+func TestDegradedStateString(t *testing.T) {
+	for state, want := range map[DegradedState]string{
+		StateHealthy:      "healthy",
+		StateL2Degraded:   "l2_degraded",
+		StateL1Degraded:   "l1_degraded",
+		StateBothDegraded: "both_degraded",
+		DegradedState(9):  "unknown",
+	} {
+		if got := state.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", state, got, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// keyedItem 带键的缓存项
+type keyedItem struct {
+	key  string
+	item *CacheItem
+}
+
+// markL2Synced 标记缓存项与L2副本一致
+func (item *CacheItem) markL2Synced() {
+	atomic.StoreInt32(&item.l2Synced, 1)
+}
+
+// isL2Synced 判断缓存项是否与L2副本一致
+func (item *CacheItem) isL2Synced() bool {
+	return atomic.LoadInt32(&item.l2Synced) == 1
+}
+
+// markDirty 记录一次尚未回写L2的访问
+func (item *CacheItem) markDirty() {
+	atomic.AddInt64(&item.dirty, 1)
+}
+
+// needsWriteBack 判断累计的访问信息是否需要回写L2
+func (c *MultiLevelCache) needsWriteBack(item *CacheItem) bool {
+	every := c.config().AccessWriteBackEvery
+	return every > 0 && atomic.LoadInt64(&item.dirty) >= every
+}
+
+// writeBackAccess 回写从L2读取后更新的访问信息
+// 只在升级时或平均每AccessWriteBackEvery次访问写入一次，避免每次读取都产生一次Redis写入
+// 从L2读取的项每次都是新解码的，本地没有累计的访问次数，因此按1/N的概率抽样回写
+// 启用SequencedWrites时经过rewriteL2，写入序号未知的项不回写
+func (c *MultiLevelCache) writeBackAccess(key string, item *CacheItem, promoted bool, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	every := c.config().AccessWriteBackEvery
+	if !promoted && (every <= 0 || rand.Int63n(every) != 0) {
+		return
+	}
+
+	jsonData, err := c.marshalItem(key, item)
+	if err != nil {
+		return
+	}
+	if written, err := c.rewriteL2(c.ctx, key, item, jsonData, ttl); err != nil || !written {
+		return
+	}
+	atomic.StoreInt64(&item.dirty, 0)
+	item.markL2Synced()
+}
+
+// demotion 等待通过管道写入L2的降级项
+type demotion struct {
+	key  string
+	data []byte
+	ttl  time.Duration
+	seq  uint64 // 写入序号，0表示不检查
+}
+
+// demoteBatch 通过管道将一批缓存项降级到L2
+// 与L2一致、没有待回写访问信息且L2中仍存在的项直接跳过，单个管道的数据量不超过MaxDemotionBatchBytes
+// 启用SequencedWrites时带写入该项时登记的序号写入，序号未知的项不写入
+func (c *MultiLevelCache) demoteBatch(items []keyedItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	pending := c.skipSyncedInL2(items)
+
+	maxBytes := c.config().MaxDemotionBatchBytes
+	if maxBytes <= 0 {
+		maxBytes = 1 << 20
+	}
+
+	sequenced := c.sequenced()
+	var batch []demotion
+	batchBytes := 0
+	now := c.nowUnix()
+	for _, ki := range pending {
+		ttl := ki.item.ExpireTime - now
+		if ttl <= 0 || (sequenced && ki.item.l2Seq == 0) {
+			continue
+		}
+
+		// 大值交给工作池单独写入
+		if c.usePool(ki.item.Value) {
+			c.demoteToL2(ki.key, ki.item)
+			continue
+		}
+
+		jsonData, err := c.marshalItem(ki.key, ki.item)
+		if err != nil {
+			continue
+		}
+		if batchBytes > 0 && batchBytes+len(jsonData) > maxBytes {
+			c.execDemotion(batch)
+			batch, batchBytes = batch[:0], 0
+		}
+		limited, err := c.admitQuota(ki.key, len(jsonData), c.l2TTL(time.Duration(ttl)*time.Second))
+		if err != nil {
+			continue
+		}
+		d := demotion{key: ki.key, data: jsonData, ttl: limited}
+		if sequenced {
+			d.seq = ki.item.l2Seq
+		}
+		batch = append(batch, d)
+		batchBytes += len(jsonData)
+	}
+	if len(batch) > 0 {
+		c.execDemotion(batch)
+	}
+}
+
+// skipSyncedInL2 过滤掉与L2一致且L2中仍然存在的项，返回需要写入的项
+func (c *MultiLevelCache) skipSyncedInL2(items []keyedItem) []keyedItem {
+	pipe := c.pipeline()
+	defer pipe.release()
+	checks := make(map[int]*redis.IntCmd)
+	for i, ki := range items {
+		if ki.item.isL2Synced() && !c.needsWriteBack(ki.item) {
+			checks[i] = pipe.Exists(c.ctx, ki.key)
+		}
+	}
+	if len(checks) == 0 {
+		return items
+	}
+	// 检查失败时按不存在处理，全部重新写入
+	_, _ = pipe.Exec(c.ctx)
+
+	pending := make([]keyedItem, 0, len(items))
+	for i, ki := range items {
+		if cmd, ok := checks[i]; ok && cmd.Val() > 0 {
+			continue
+		}
+		pending = append(pending, ki)
+	}
+	return pending
+}
+
+// execDemotion 通过一个管道写入一批降级项，失败时记录日志
+func (c *MultiLevelCache) execDemotion(batch []demotion) {
+	err := c.pipelinedScripts(c.ctx, func(pipe redis.Pipeliner, eval func(*redis.Script, []string, ...interface{}) *redis.Cmd) {
+		for _, d := range batch {
+			if d.seq > 0 {
+				eval(sequencedSet, []string{c.sequenceKey(d.key), d.key}, d.seq, d.data, d.ttl.Milliseconds(), c.sequenceTTL(d.ttl))
+			} else {
+				pipe.Set(c.ctx, d.key, d.data, d.ttl)
+			}
+		}
+	})
+	if err != nil {
+		c.logf("dancache: demotion pipeline failed: %v", err)
+	}
+}
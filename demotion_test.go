@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// pipelineCounter 统计执行的管道数
+type pipelineCounter struct {
+	pipelines int64
+}
+
+func (h *pipelineCounter) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *pipelineCounter) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *pipelineCounter) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	atomic.AddInt64(&h.pipelines, 1)
+	return ctx, nil
+}
+
+func (h *pipelineCounter) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestDemoteBatchSkipsSyncedItems(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.SequencedWrites = false })
+
+	unsynced := c.newCacheItem("new", 60)
+	synced := c.newCacheItem("same", 60)
+	synced.markL2Synced()
+	syncedMissing := c.newCacheItem("lost", 60)
+	syncedMissing.markL2Synced()
+	mr.Set("synced", "left alone")
+
+	c.demoteBatch([]keyedItem{{"unsynced", unsynced}, {"synced", synced}, {"synced-missing", syncedMissing}})
+
+	if !mr.Exists("unsynced") {
+		t.Error("unsynced item was not demoted")
+	}
+	if v, _ := mr.Get("synced"); v != "left alone" {
+		t.Error("item already in sync with L2 was rewritten")
+	}
+	if !mr.Exists("synced-missing") {
+		t.Error("synced item that is missing from L2 was not demoted")
+	}
+}
+
+func TestDemoteBatchCapsPipelineBytes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.SequencedWrites = false
+		config.MaxDemotionBatchBytes = 250
+	})
+	counter := &pipelineCounter{}
+	c.redisClient.AddHook(counter)
+
+	items := make([]keyedItem, 10)
+	for i := range items {
+		items[i] = keyedItem{fmt.Sprintf("k%d", i), c.newCacheItem(strings.Repeat("x", 50), 60)}
+	}
+	c.demoteBatch(items)
+
+	for _, ki := range items {
+		if !mr.Exists(ki.key) {
+			t.Errorf("%s was not demoted", ki.key)
+		}
+	}
+	// 每项编码后超过100字节，每个管道最多两项
+	if n := atomic.LoadInt64(&counter.pipelines); n < 5 {
+		t.Errorf("demotion used %d pipelines, want at least 5 with a 250-byte cap", n)
+	}
+}
+
+func TestDemoteBatchRespectsSequences(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	other := newRedisTestCache(t, mr, nil)
+
+	seq, err := c.reserveSequence(c.ctx, "current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := c.newCacheItem("v", 60)
+	current.l2Seq = seq
+
+	superseded := c.newCacheItem("old", 60)
+	if superseded.l2Seq, err = c.reserveSequence(c.ctx, "superseded"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Delete("superseded"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.demoteBatch([]keyedItem{{"current", current}, {"superseded", superseded}, {"unknown", c.newCacheItem("x", 60)}})
+	if !mr.Exists("current") {
+		t.Error("item with the latest sequence was not demoted")
+	}
+	if mr.Exists("superseded") {
+		t.Error("demotion overwrote a later delete")
+	}
+	if mr.Exists("unknown") {
+		t.Error("item without a sequence was demoted")
+	}
+}
+
+func TestReadsWriteBackAccessOnlyEveryN(t *testing.T) {
+	mr := miniredis.RunT(t)
+	configure := func(every int64) func(*CacheConfig) {
+		return func(config *CacheConfig) {
+			config.EnableL1Cache = false
+			config.SequencedWrites = false
+			config.AccessWriteBackEvery = every
+		}
+	}
+	accessCount := func(c *MultiLevelCache) int64 {
+		raw, err := mr.Get("k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var item CacheItem
+		if err := c.decodeItem("k", []byte(raw), &item); err != nil {
+			t.Fatal(err)
+		}
+		return item.AccessCount
+	}
+
+	c := newRedisTestCache(t, mr, configure(0))
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := mr.Get("k")
+	for i := 0; i < 5; i++ {
+		if _, ok := c.Get("k"); !ok {
+			t.Fatal("Get missed")
+		}
+	}
+	if after, _ := mr.Get("k"); after != before {
+		t.Error("reads rewrote the L2 value with AccessWriteBackEvery disabled")
+	}
+
+	// 每次读取都回写时，L2中的访问次数逐次累加
+	c = newRedisTestCache(t, mr, configure(1))
+	for i := 1; i <= 3; i++ {
+		c.Get("k")
+		if n := accessCount(c); n != int64(i) {
+			t.Errorf("L2 AccessCount after %d reads = %d, want %d", i, n, i)
+		}
+	}
+
+	// 按1/N抽样：1000次读取回写约100次
+	c = newRedisTestCache(t, mr, configure(10))
+	start := accessCount(c)
+	for i := 0; i < 1000; i++ {
+		c.Get("k")
+	}
+	if n := accessCount(c) - start; n < 30 || n > 300 {
+		t.Errorf("AccessCount advanced by %d over 1000 reads with AccessWriteBackEvery=10", n)
+	}
+}
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// KeyProvider 为写入L2的值选择加密密钥，多租户部署可以按租户或键前缀返回不同的密钥，实现可以对接KMS
+// 同一个密钥编号必须始终对应同一个密钥，缓存按编号复用cipher；轮换密钥时使用新的编号
+// 解密时值的密钥编号必须与EncryptionKey为该键返回的编号一致，轮换后用旧密钥加密的值按未命中处理
+type KeyProvider interface {
+	// EncryptionKey 返回加密key的值时使用的密钥编号(最多255字节)和AES密钥(16、24或32字节)
+	// 每次读取加密的值时也会调用以确认密钥编号，对接KMS的实现应缓存结果
+	EncryptionKey(ctx context.Context, key string) (keyID string, secret []byte, err error)
+	// DecryptionKey 返回编号对应的AES密钥，轮换后旧编号在旧值过期前仍需可用
+	DecryptionKey(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// PrefixKeys 按键前缀选择密钥的KeyProvider，密钥保存在进程内存中
+type PrefixKeys struct {
+	Keys     map[string][]byte // 密钥编号到AES密钥
+	Prefixes map[string]string // 键前缀到密钥编号，匹配最长的前缀
+	Default  string            // 没有匹配的前缀时使用的密钥编号(空表示拒绝写入)
+}
+
+// EncryptionKey 返回键匹配的最长前缀对应的密钥
+func (p *PrefixKeys) EncryptionKey(ctx context.Context, key string) (string, []byte, error) {
+	keyID, matched := p.Default, -1
+	for prefix, id := range p.Prefixes {
+		if len(prefix) > matched && strings.HasPrefix(key, prefix) {
+			keyID, matched = id, len(prefix)
+		}
+	}
+	if keyID == "" {
+		return "", nil, fmt.Errorf("键%q没有匹配的加密密钥", truncateKey(key))
+	}
+	secret, err := p.DecryptionKey(ctx, keyID)
+	return keyID, secret, err
+}
+
+// DecryptionKey 返回编号对应的密钥
+func (p *PrefixKeys) DecryptionKey(_ context.Context, keyID string) ([]byte, error) {
+	secret, ok := p.Keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("未知的密钥编号%q", keyID)
+	}
+	return secret, nil
+}
+
+// errNoKeyProvider 值已加密但没有配置Encryption
+var errNoKeyProvider = errors.New("值已加密但未配置Encryption")
+
+// maxCachedCiphers 按密钥编号缓存的cipher数量上限，超出时清空重新创建
+const maxCachedCiphers = 10000
+
+// cipherCache 按密钥编号缓存的AES-GCM cipher
+type cipherCache struct {
+	mu    sync.RWMutex
+	aeads map[string]cipher.AEAD
+}
+
+// get 返回编号对应的cipher，没有缓存时用secret返回的密钥创建
+func (cc *cipherCache) get(keyID string, secret func() ([]byte, error)) (cipher.AEAD, error) {
+	cc.mu.RLock()
+	aead, ok := cc.aeads[keyID]
+	cc.mu.RUnlock()
+	if ok {
+		return aead, nil
+	}
+	key, err := secret()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("密钥%q无效: %w", keyID, err)
+	}
+	if aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	cc.mu.Lock()
+	if cc.aeads == nil || len(cc.aeads) >= maxCachedCiphers {
+		cc.aeads = make(map[string]cipher.AEAD)
+	}
+	cc.aeads[keyID] = aead
+	cc.mu.Unlock()
+	return aead, nil
+}
+
+// encryptionAAD 返回参与认证的附加数据：信封头和缓存键，密文被放到其他键下时认证失败
+func encryptionAAD(header []byte, key string) []byte {
+	aad := make([]byte, 0, len(header)+len(key))
+	return append(append(aad, header...), key...)
+}
+
+// encryptionKey 调用KeyProvider选择key的密钥
+func (c *MultiLevelCache) encryptionKey(provider KeyProvider, key string) (keyID string, secret []byte, err error) {
+	if perr := c.protect("KeyProvider", func() { keyID, secret, err = provider.EncryptionKey(c.ctx, key) }); perr != nil {
+		err = perr
+	}
+	return keyID, secret, err
+}
+
+// sameEncryptionKey 判断两个键的值是否使用同一个密钥加密，未配置Encryption时总是相同
+func (c *MultiLevelCache) sameEncryptionKey(a, b string) bool {
+	provider := c.config().Encryption
+	if provider == nil {
+		return true
+	}
+	idA, _, errA := c.encryptionKey(provider, a)
+	idB, _, errB := c.encryptionKey(provider, b)
+	return errA == nil && errB == nil && idA == idB
+}
+
+// encrypt 用KeyProvider为key选择的密钥加密载荷，header和key作为附加数据参与认证
+// 结果格式: 1字节密钥编号长度、密钥编号、nonce、密文(含认证标签)
+func (c *MultiLevelCache) encrypt(provider KeyProvider, key string, header, payload []byte) ([]byte, error) {
+	keyID, secret, err := c.encryptionKey(provider, key)
+	if err != nil {
+		return nil, err
+	}
+	if keyID == "" || len(keyID) > 255 {
+		return nil, fmt.Errorf("密钥编号长度必须在1到255字节之间: %q", keyID)
+	}
+	aead, err := c.ciphers.get(keyID, func() ([]byte, error) { return secret, nil })
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 1+len(keyID)+aead.NonceSize(), 1+len(keyID)+aead.NonceSize()+len(payload)+aead.Overhead())
+	out[0] = byte(len(keyID))
+	copy(out[1:], keyID)
+	nonce := out[1+len(keyID):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, payload, encryptionAAD(header, key)), nil
+}
+
+// decrypt 解密key的值，密钥编号必须是KeyProvider为key选择的编号，其他租户的密钥加密的值不会被接受
+// 密钥编号不符或认证失败(值被移到其他键下或被篡改)时返回ErrCorrupted
+func (c *MultiLevelCache) decrypt(provider KeyProvider, key string, header, data []byte) ([]byte, error) {
+	if provider == nil {
+		return nil, errNoKeyProvider
+	}
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, ErrCorrupted
+	}
+	keyID := string(data[1 : 1+data[0]])
+	data = data[1+len(keyID):]
+	expected, _, err := c.encryptionKey(provider, key)
+	if err != nil {
+		return nil, err
+	}
+	if keyID != expected {
+		return nil, ErrCorrupted
+	}
+	aead, err := c.ciphers.get(keyID, func() (secret []byte, err error) {
+		if perr := c.protect("KeyProvider", func() { secret, err = provider.DecryptionKey(c.ctx, keyID) }); perr != nil {
+			err = perr
+		}
+		return secret, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取密钥%q失败: %w", keyID, err)
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCorrupted
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, ciphertext, encryptionAAD(header, key))
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return payload, nil
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testTenantKeys 两个租户各自使用一个密钥
+func testTenantKeys() *PrefixKeys {
+	return &PrefixKeys{
+		Keys:     map[string][]byte{"acme": bytes.Repeat([]byte{1}, 32), "globex": bytes.Repeat([]byte{2}, 32)},
+		Prefixes: map[string]string{"acme:": "acme", "globex:": "globex"},
+	}
+}
+
+// newEncryptedTestCache 创建以miniredis为L2、按租户加密的缓存
+func newEncryptedTestCache(t *testing.T, mr *miniredis.Miniredis) *MultiLevelCache {
+	return newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.SequencedWrites = false
+		config.Envelope = true
+		config.Encryption = testTenantKeys()
+	})
+}
+
+func TestEncryptedValueBoundToKey(t *testing.T) {
+	c := newEncryptedTestCache(t, miniredis.RunT(t))
+	data, err := c.encodeItem("acme:1", &CacheItem{Value: "secret", ExpireTime: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatal("encoded value contains the plaintext")
+	}
+
+	var item CacheItem
+	if err := c.decodeItem("acme:1", data, &item); err != nil || item.Value != "secret" {
+		t.Fatalf("decodeItem under its own key = %v, %v; want secret", item.Value, err)
+	}
+	// 同一租户的其他键：密钥相同但附加数据不同
+	if err := c.decodeItem("acme:2", data, &item); err != ErrCorrupted {
+		t.Errorf("decodeItem under another key of the same tenant = %v, want ErrCorrupted", err)
+	}
+	// 其他租户的键：密钥编号不是该键的密钥
+	if err := c.decodeItem("globex:1", data, &item); err != ErrCorrupted {
+		t.Errorf("decodeItem under another tenant's key = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestEncryptedValueMovedInRedisIsNotServed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newEncryptedTestCache(t, mr)
+	other := newEncryptedTestCache(t, mr)
+
+	if err := c.Set("acme:1", "acme data", 60); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := mr.Get("acme:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("globex:1", raw)
+	if v, ok := other.Get("globex:1"); ok {
+		t.Errorf("value encrypted for acme:1 was served under globex:1: %v", v)
+	}
+}
+
+func TestCopyAndRenameReencrypt(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newEncryptedTestCache(t, mr)
+	other := newEncryptedTestCache(t, mr)
+
+	if err := c.Set("acme:src", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := c.Copy("acme:src", "globex:dst"); err != nil || !found {
+		t.Fatalf("Copy = %v, %v; want true", found, err)
+	}
+	if v, ok := other.Get("globex:dst"); !ok || v != "v" {
+		t.Errorf("copied value from another instance = %v, %v; want v, true", v, ok)
+	}
+
+	if found, err := c.Rename("globex:dst", "acme:moved"); err != nil || !found {
+		t.Fatalf("Rename = %v, %v; want true", found, err)
+	}
+	if mr.Exists("globex:dst") {
+		t.Error("old key still exists after Rename")
+	}
+	if v, ok := other.Get("acme:moved"); !ok || v != "v" {
+		t.Errorf("renamed value from another instance = %v, %v; want v, true", v, ok)
+	}
+}
+
+func TestRenameReencodesL1AcrossKeys(t *testing.T) {
+	c := newEncryptedTestCache(t, miniredis.RunT(t))
+	if err := c.Set("acme:doc", map[string]interface{}{"n": float64(1)}, 60); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := c.shardFor("acme:doc").load("acme:doc")
+
+	if found, err := c.Rename("acme:doc", "globex:doc"); err != nil || !found {
+		t.Fatalf("Rename = %v, %v; want true", found, err)
+	}
+	after, ok := c.shardFor("globex:doc").load("globex:doc")
+	if !ok {
+		t.Fatal("renamed item is not in L1")
+	}
+	if after == before {
+		t.Error("item was moved to a key with a different encryption key without re-encoding")
+	}
+	if v, ok := c.Get("globex:doc"); !ok || v.(map[string]interface{})["n"] != float64(1) {
+		t.Errorf("Get after Rename = %v, %v; want n=1", v, ok)
+	}
+
+	// 同一个密钥下直接移动
+	if _, err := c.Rename("globex:doc", "globex:moved"); err != nil {
+		t.Fatal(err)
+	}
+	if moved, _ := c.shardFor("globex:moved").load("globex:moved"); moved != after {
+		t.Error("item was re-encoded although both keys use the same encryption key")
+	}
+}
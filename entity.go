@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// EntityCache "ID列表+逐个对象"模式的缓存
+// 列表只缓存对象ID，对象单独缓存并批量加载；列表按其中的成员打标签，成员失效时包含它的列表一并失效
+type EntityCache struct {
+	cache     *MultiLevelCache
+	name      string
+	listTTL   int64
+	objectTTL int64
+	newObject func() interface{}
+
+	ListKey   func(listID string) string // 列表的缓存键，默认"entity:<name>:list:<listID>"
+	ObjectKey func(id string) string     // 对象的缓存键，默认"entity:<name>:obj:<id>"
+}
+
+// NewEntityCache 创建实体缓存
+// 对象以JSON缓存，读取时解码到newObject返回的指针中
+func NewEntityCache(cache *MultiLevelCache, name string, listTTL, objectTTL int64, newObject func() interface{}) *EntityCache {
+	e := &EntityCache{
+		cache:     cache,
+		name:      name,
+		listTTL:   listTTL,
+		objectTTL: objectTTL,
+		newObject: newObject,
+	}
+	e.ListKey = func(listID string) string {
+		return fmt.Sprintf("entity:%s:list:%s", name, listID)
+	}
+	e.ObjectKey = func(id string) string {
+		return fmt.Sprintf("entity:%s:obj:%s", name, id)
+	}
+	return e
+}
+
+// IDs 获取列表中的对象ID，未命中时调用loadIDs
+func (e *EntityCache) IDs(listID string, loadIDs func() ([]string, error)) ([]string, error) {
+	key, err := e.cache.normalizeKey(e.ListKey(listID))
+	if err != nil {
+		return nil, err
+	}
+	if val, found := e.cache.Get(key); found {
+		return decodeIDs(val)
+	}
+
+	val, err := e.cache.loads.do(e.cache.ctx, key, func(context.Context) (interface{}, error) {
+		if val, found := e.cache.Get(key); found {
+			return val, nil
+		}
+		ids, err := loadIDs()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(ids)
+		if err != nil {
+			return nil, err
+		}
+		if e.cache.loadBlocked(key) {
+			return string(data), nil
+		}
+		// 列表按成员和实体打标签，任一成员失效时列表随之失效
+		tags := make([]string, 0, len(ids)+1)
+		tags = append(tags, e.entityTag())
+		for _, id := range ids {
+			tags = append(tags, e.memberTag(id))
+		}
+		if err := e.cache.SetWithTags(key, string(data), e.listTTL, tags...); err != nil {
+			e.cache.logf("dancache: cache entity list %q failed: %v", key, err)
+		}
+		return string(data), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeIDs(val)
+}
+
+// Objects 批量获取对象，未命中的ID一次性交给loadObjects
+// 返回结果以ID为键，loadObjects未返回的ID视为不存在
+func (e *EntityCache) Objects(ids []string, loadObjects func(ids []string) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	keys := make([]string, len(ids))
+	idByKey := make(map[string]string, len(ids))
+	for i, id := range ids {
+		keys[i] = e.ObjectKey(id)
+		idByKey[keys[i]] = id
+	}
+
+	values, err := e.cache.getOrLoadMulti(e.cache.ctx, keys, e.objectTTL, []string{e.entityTag()}, func(_ context.Context, missing []string) (map[string]interface{}, error) {
+		missingIDs := make([]string, len(missing))
+		for i, key := range missing {
+			missingIDs[i] = idByKey[key]
+		}
+		loaded, err := loadObjects(missingIDs)
+		if err != nil {
+			return nil, err
+		}
+		encoded := make(map[string]interface{}, len(loaded))
+		for id, obj := range loaded {
+			data, err := json.Marshal(obj)
+			if err != nil {
+				return nil, err
+			}
+			encoded[e.ObjectKey(id)] = string(data)
+		}
+		return encoded, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		obj, err := e.decodeObject(val)
+		if err != nil {
+			return nil, err
+		}
+		result[idByKey[key]] = obj
+	}
+	return result, nil
+}
+
+// List 获取列表并批量加载其中的对象，按列表顺序返回，不存在的对象被跳过
+func (e *EntityCache) List(listID string, loadIDs func() ([]string, error), loadObjects func(ids []string) (map[string]interface{}, error)) ([]interface{}, error) {
+	ids, err := e.IDs(listID, loadIDs)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := e.Objects(ids, loadObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if obj, ok := objects[id]; ok {
+			result = append(result, obj)
+		}
+	}
+	return result, nil
+}
+
+// Invalidate 删除对象以及包含这些对象的列表
+func (e *EntityCache) Invalidate(ids ...string) error {
+	tags := make([]string, len(ids))
+	for i, id := range ids {
+		if err := e.cache.Delete(e.ObjectKey(id)); err != nil {
+			return err
+		}
+		tags[i] = e.memberTag(id)
+	}
+	return e.cache.InvalidateTags(tags...)
+}
+
+// InvalidateList 删除列表，列表中的对象保留
+// 新增对象后应调用此方法，因为新对象尚不在任何已缓存列表的成员标签中
+func (e *EntityCache) InvalidateList(listIDs ...string) error {
+	for _, listID := range listIDs {
+		if err := e.cache.Delete(e.ListKey(listID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateAll 删除该实体的所有列表和对象
+func (e *EntityCache) InvalidateAll() error {
+	return e.cache.InvalidateTags(e.entityTag())
+}
+
+// decodeObject 将JSON字符串解码为对象
+func (e *EntityCache) decodeObject(val interface{}) (interface{}, error) {
+	data, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("缓存的对象类型错误: %T", val)
+	}
+	out := e.newObject()
+	if err := json.Unmarshal([]byte(data), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// entityTag 实体的标签
+func (e *EntityCache) entityTag() string {
+	return "entity:" + e.name
+}
+
+// memberTag 包含某个对象的列表的标签
+func (e *EntityCache) memberTag(id string) string {
+	return fmt.Sprintf("entity:%s:member:%s", e.name, id)
+}
+
+// decodeIDs 解析缓存的ID列表
+func decodeIDs(val interface{}) ([]string, error) {
+	data, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("缓存的ID列表类型错误: %T", val)
+	}
+	var ids []string
+	if err := json.Unmarshal([]byte(data), &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type testUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// entityLoaders 返回统计调用次数的列表和对象加载函数
+func entityLoaders(ids []string, names map[string]string) (func() ([]string, error), func([]string) (map[string]interface{}, error), *int, *[]string) {
+	listLoads := 0
+	var objectLoads []string
+	loadIDs := func() ([]string, error) {
+		listLoads++
+		return ids, nil
+	}
+	loadObjects := func(missing []string) (map[string]interface{}, error) {
+		objectLoads = append(objectLoads, missing...)
+		objects := make(map[string]interface{}, len(missing))
+		for _, id := range missing {
+			if name, ok := names[id]; ok {
+				objects[id] = testUser{ID: id, Name: name}
+			}
+		}
+		return objects, nil
+	}
+	return loadIDs, loadObjects, &listLoads, &objectLoads
+}
+
+func TestEntityCacheList(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	e := NewEntityCache(c, "user", 60, 60, func() interface{} { return &testUser{} })
+
+	names := map[string]string{"1": "alice", "2": "bob"}
+	loadIDs, loadObjects, listLoads, objectLoads := entityLoaders([]string{"2", "3", "1"}, names)
+	for i := 0; i < 2; i++ {
+		users, err := e.List("team-a", loadIDs, loadObjects)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 按列表顺序返回，不存在的对象被跳过
+		if len(users) != 2 || users[0].(*testUser).Name != "bob" || users[1].(*testUser).Name != "alice" {
+			t.Fatalf("List = %v, want bob, alice", users)
+		}
+	}
+	if *listLoads != 1 {
+		t.Errorf("list loaded %d times, want 1", *listLoads)
+	}
+	// 不存在的对象不缓存，之后只重新加载它
+	if got := *objectLoads; len(got) != 4 || got[3] != "3" {
+		t.Errorf("objects loaded %v, want [2 3 1] then only [3]", got)
+	}
+
+	// 成员失效时对象和包含它的列表一并失效
+	names["1"] = "alice2"
+	if err := e.Invalidate("1"); err != nil {
+		t.Fatal(err)
+	}
+	*objectLoads = nil
+	users, err := e.List("team-a", loadIDs, loadObjects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *listLoads != 2 {
+		t.Errorf("list loads after Invalidate = %d, want 2", *listLoads)
+	}
+	if users[1].(*testUser).Name != "alice2" {
+		t.Errorf("List after Invalidate = %v, want the reloaded object", users)
+	}
+	for _, id := range *objectLoads {
+		if id == "2" {
+			t.Errorf("unaffected object was reloaded: %v", *objectLoads)
+		}
+	}
+}
+
+func TestEntityCacheInvalidateListAndAll(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	e := NewEntityCache(c, "user", 60, 60, func() interface{} { return &testUser{} })
+	loadIDs, loadObjects, listLoads, objectLoads := entityLoaders([]string{"1"}, map[string]string{"1": "alice"})
+
+	if _, err := e.List("all", loadIDs, loadObjects); err != nil {
+		t.Fatal(err)
+	}
+	// InvalidateList只删除列表，对象保留
+	if err := e.InvalidateList("all"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.List("all", loadIDs, loadObjects); err != nil {
+		t.Fatal(err)
+	}
+	if *listLoads != 2 || len(*objectLoads) != 1 {
+		t.Errorf("after InvalidateList: %d list loads, objects %v; want 2 and [1]", *listLoads, *objectLoads)
+	}
+
+	if err := e.InvalidateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.List("all", loadIDs, loadObjects); err != nil {
+		t.Fatal(err)
+	}
+	if *listLoads != 3 || len(*objectLoads) != 2 {
+		t.Errorf("after InvalidateAll: %d list loads, objects %v; want 3 and [1 1]", *listLoads, *objectLoads)
+	}
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// CodecID 信封中标识编解码器的编号
+type CodecID uint8
+
+const (
+	// CodecIDJSON 内置的JSONCodec
+	CodecIDJSON CodecID = 1
+)
+
+// Compression 信封中载荷的压缩方式
+type Compression uint8
+
+const (
+	// CompressionNone 不压缩
+	CompressionNone Compression = iota
+	// CompressionGzip gzip压缩
+	CompressionGzip
+)
+
+// 信封格式: 2字节魔数、1字节版本、1字节编解码器编号、1字节压缩方式、1字节标志位，之后是载荷
+// 设置校验和标志时，载荷前是4字节的CRC32C校验和；设置加密标志时载荷先压缩再加密，校验和针对加密后的载荷
+// 不以魔数开头的值按未加信封的旧格式直接交给配置的编解码器解码
+const (
+	envelopeMagic0     = 0xDA
+	envelopeMagic1     = 0xCC
+	envelopeVersion    = 1
+	envelopeHeaderSize = 6
+
+	envelopeFlagChecksum  = 1 << 0 // 载荷前附带校验和
+	envelopeFlagEncrypted = 1 << 1 // 载荷经过加密
+	checksumSize          = 4
+)
+
+// crc32cTable CRC32C(Castagnoli)查找表
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// defaultCompressThreshold 载荷超过该大小才压缩(字节)
+const defaultCompressThreshold = 1024
+
+// isEnvelope 判断值是否带信封
+func isEnvelope(data []byte) bool {
+	return len(data) >= envelopeHeaderSize && data[0] == envelopeMagic0 && data[1] == envelopeMagic1
+}
+
+// encodeItem 编码写入L2的缓存项，启用信封时附带编解码器和压缩信息，配置Encryption时按key选择的密钥加密
+func (c *MultiLevelCache) encodeItem(key string, item *CacheItem) ([]byte, error) {
+	config := c.config()
+	var payload []byte
+	var err error
+	if perr := c.protect("Codec", func() { payload, err = c.codec().Marshal(tagItemType(item)) }); perr != nil {
+		err = perr
+	}
+	if err != nil || !config.Envelope {
+		return payload, err
+	}
+
+	codecID := config.CodecID
+	if isJSONCodec(config.Codec) {
+		codecID = CodecIDJSON
+	}
+	compression := CompressionNone
+	threshold := config.CompressThreshold
+	if threshold <= 0 {
+		threshold = defaultCompressThreshold
+	}
+	if config.Compression != CompressionNone && len(payload) >= threshold {
+		if payload, err = compress(config.Compression, payload); err != nil {
+			return nil, err
+		}
+		compression = config.Compression
+	}
+
+	header := []byte{envelopeMagic0, envelopeMagic1, envelopeVersion, byte(codecID), byte(compression), 0}
+	if config.Checksum {
+		header[5] |= envelopeFlagChecksum
+	}
+	if config.Encryption != nil {
+		header[5] |= envelopeFlagEncrypted
+		if payload, err = c.encrypt(config.Encryption, key, header, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	data := make([]byte, 0, envelopeHeaderSize+checksumSize+len(payload))
+	data = append(data, header...)
+	if config.Checksum {
+		data = binary.BigEndian.AppendUint32(data, crc32.Checksum(payload, crc32cTable))
+	}
+	return append(data, payload...), nil
+}
+
+// decodeItem 解码从L2读取的key的缓存项，值带有已注册的类型名时转换回具体类型
+func (c *MultiLevelCache) decodeItem(key string, data []byte, item *CacheItem) error {
+	if err := c.decodePayload(key, data, item); err != nil {
+		return err
+	}
+	return c.restoreItemType(item)
+}
+
+// decodePayload 解码缓存项，同时兼容带信封和不带信封的值；加密的值只能在加密时的键下解密
+func (c *MultiLevelCache) decodePayload(key string, data []byte, item *CacheItem) error {
+	if !isEnvelope(data) {
+		return c.unmarshalChecked(c.codec(), data, item)
+	}
+	if data[2] > envelopeVersion {
+		return fmt.Errorf("不支持的信封版本%d", data[2])
+	}
+	codec, err := c.codecByID(CodecID(data[3]))
+	if err != nil {
+		return err
+	}
+	payload := data[envelopeHeaderSize:]
+	if data[5]&envelopeFlagChecksum != 0 {
+		if len(payload) < checksumSize {
+			return ErrCorrupted
+		}
+		sum := binary.BigEndian.Uint32(payload)
+		payload = payload[checksumSize:]
+		if crc32.Checksum(payload, crc32cTable) != sum {
+			return ErrCorrupted
+		}
+	}
+	if data[5]&envelopeFlagEncrypted != 0 {
+		if payload, err = c.decrypt(c.config().Encryption, key, data[:envelopeHeaderSize], payload); err != nil {
+			return err
+		}
+	}
+	payload, err = decompress(Compression(data[4]), payload, c.maxDecodedSize())
+	if err != nil {
+		return err
+	}
+	return c.unmarshalChecked(codec, payload, item)
+}
+
+// unmarshalChecked 检查JSON载荷的嵌套深度后解码，其他编解码器自行负责输入检查
+func (c *MultiLevelCache) unmarshalChecked(codec Codec, data []byte, item *CacheItem) error {
+	if isJSONCodec(codec) {
+		if err := c.checkJSONDepth(data); err != nil {
+			return err
+		}
+	}
+	return c.unmarshalWith(codec, data, item)
+}
+
+// unmarshalWith 用指定的编解码器解码，编解码器发生panic时返回*PanicError
+func (c *MultiLevelCache) unmarshalWith(codec Codec, data []byte, item *CacheItem) (err error) {
+	if perr := c.protect("Codec", func() { err = codec.Unmarshal(data, item) }); perr != nil {
+		return perr
+	}
+	return err
+}
+
+// codecByID 返回编号对应的编解码器
+func (c *MultiLevelCache) codecByID(id CodecID) (Codec, error) {
+	config := c.config()
+	if config.Codec != nil && id == config.CodecID {
+		return config.Codec, nil
+	}
+	if codec, ok := config.Codecs[id]; ok {
+		return codec, nil
+	}
+	if id == CodecIDJSON {
+		return JSONCodec{}, nil
+	}
+	return nil, fmt.Errorf("未知的编解码器编号%d", id)
+}
+
+// compress 压缩载荷
+func compress(compression Compression, payload []byte) ([]byte, error) {
+	switch compression {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("未知的压缩方式%d", compression)
+	}
+}
+
+// decompress 解压载荷，解压后超过limit字节时返回错误
+func decompress(compression Compression, payload []byte, limit int64) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return payload, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readLimited(r, limit)
+	default:
+		return nil, fmt.Errorf("未知的压缩方式%d", compression)
+	}
+}
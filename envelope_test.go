@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestEnvelopeCompressesAndStaysReadable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	plain := newRedisTestCache(t, mr, func(config *CacheConfig) { config.EnableL1Cache = false })
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.EnableL1Cache = false
+		config.Envelope = true
+		config.Compression = CompressionGzip
+		config.CompressThreshold = 256
+	})
+
+	big := strings.Repeat("dancache", 100)
+	if err := c.Set("big", big, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("small", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := mr.Get("big")
+	if !isEnvelope([]byte(raw)) || raw[3] != byte(CodecIDJSON) || raw[4] != byte(CompressionGzip) {
+		t.Fatalf("header = %v, want JSON codec and gzip", []byte(raw[:envelopeHeaderSize]))
+	}
+	if len(raw) >= len(big) {
+		t.Errorf("compressed size %d is not smaller than the value", len(raw))
+	}
+	if raw, _ := mr.Get("small"); raw[4] != byte(CompressionNone) {
+		t.Error("value below CompressThreshold was compressed")
+	}
+
+	// 读取时总是兼容带信封和不带信封的值
+	if v, found := plain.Get("big"); !found || v != big {
+		t.Error("reader without Envelope could not read an enveloped value")
+	}
+	if err := plain.Set("legacy", "old", 60); err != nil {
+		t.Fatal(err)
+	}
+	if v, found := c.Get("legacy"); !found || v != "old" {
+		t.Errorf("Get of a value without envelope = %v, %v; want old, true", v, found)
+	}
+}
+
+func TestEnvelopeSelectsCodecByID(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.EnableL1Cache = false
+		config.Envelope = true
+		config.Codec = valueOnlyCodec{}
+		config.CodecID = 7
+	})
+	reader := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.EnableL1Cache = false
+		config.Codecs = map[CodecID]Codec{7: valueOnlyCodec{}}
+	})
+	unknown := newRedisTestCache(t, mr, func(config *CacheConfig) { config.EnableL1Cache = false })
+
+	if err := writer.Set("k", "raw", 60); err != nil {
+		t.Fatal(err)
+	}
+	if v, found := reader.Get("k"); !found || v != "raw" {
+		t.Errorf("Get with the codec registered by ID = %v, %v; want raw, true", v, found)
+	}
+	if _, found := unknown.Get("k"); found {
+		t.Error("reader without the codec decoded the value")
+	}
+}
+
+func TestChecksumDetectsCorruption(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.EnableL1Cache = false
+		config.Envelope = true
+		config.Checksum = true
+	})
+
+	if err := c.Set("k", "value", 60); err != nil {
+		t.Fatal(err)
+	}
+	if v, found, err := c.GetWithError("k"); err != nil || !found || v != "value" {
+		t.Fatalf("GetWithError = %v, %v, %v; want value, true, nil", v, found, err)
+	}
+
+	// 篡改载荷的最后一个字节
+	raw, _ := mr.Get("k")
+	tampered := []byte(raw)
+	tampered[len(tampered)-1] ^= 0xff
+	mr.Set("k", string(tampered))
+	if _, found, err := c.GetWithError("k"); found || err != ErrCorrupted {
+		t.Errorf("GetWithError of a tampered value = %v, %v; want false, ErrCorrupted", found, err)
+	}
+	mr.Set("k", raw[:envelopeHeaderSize+2])
+	if _, found, err := c.GetWithError("k"); found || err != ErrCorrupted {
+		t.Errorf("GetWithError of a truncated value = %v, %v; want false, ErrCorrupted", found, err)
+	}
+	if n := c.GetStats()["l2_corrupted_items"]; n != int64(2) {
+		t.Errorf("l2_corrupted_items = %v, want 2", n)
+	}
+}
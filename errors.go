@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTombstoned 键刚被删除，在墓碑窗口内拒绝写入
+var ErrTombstoned = errors.New("键刚被删除，暂时不允许写入")
+
+// ErrCircuitOpen L2熔断器处于打开状态，请求被快速拒绝
+var ErrCircuitOpen = errors.New("Redis熔断器已打开")
+
+// ErrOverloaded 加载并发已满，为保护源数据库拒绝本次加载
+var ErrOverloaded = errors.New("缓存加载过载，请求被拒绝")
+
+// ErrCorrupted L2中的值校验失败，可能被代理篡改或截断
+var ErrCorrupted = errors.New("缓存数据校验失败")
+
+// ErrDisabled 调用的API依赖的功能未启用，可通过errors.Is判断所有的*DisabledError
+var ErrDisabled = errors.New("功能未启用")
+
+// DisabledError 调用的API依赖的功能未启用，而不是静默地不做任何操作
+type DisabledError struct {
+	Feature string // 未启用的功能
+}
+
+// Error 实现error接口
+func (e *DisabledError) Error() string {
+	return fmt.Sprintf("%s未启用", e.Feature)
+}
+
+// Is 使errors.Is(err, ErrDisabled)成立
+func (e *DisabledError) Is(target error) bool {
+	return target == ErrDisabled
+}
+
+// ErrL2Disabled 调用的API需要Redis缓存，但未启用L2
+var ErrL2Disabled error = &DisabledError{Feature: "L2缓存"}
+
+// ErrNoCacheLevel L1和L2均未启用，写入和删除没有任何效果
+var ErrNoCacheLevel error = &DisabledError{Feature: "L1和L2缓存均"}
+
+// ErrClosed 缓存已关闭，Close之后的操作不再访问Redis
+var ErrClosed = errors.New("缓存已关闭")
+
+// ErrDuplicateKey 批量写入中的两个键规范化后相同，无法确定应写入哪个值
+var ErrDuplicateKey = errors.New("批量写入中的键规范化后重复")
+
+// BatchError 批量加载中部分键失败，Errors为失败的键及其错误
+// 批量loader返回*BatchError时其余键的结果照常使用；GetOrLoadMulti返回的*BatchError只包含调用方请求的失败键
+type BatchError struct {
+	Errors map[string]error
+}
+
+// Error 实现error接口
+func (e *BatchError) Error() string {
+	if len(e.Errors) == 1 {
+		for key, err := range e.Errors {
+			return fmt.Sprintf("键%q加载失败: %v", key, err)
+		}
+	}
+	return fmt.Sprintf("%d个键加载失败", len(e.Errors))
+}
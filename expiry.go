@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// expiredChannel 返回当前数据库的过期事件频道
+// 只订阅本库而不是__keyevent@*__，避免同一Redis上其他库的键触发回调
+func (c *MultiLevelCache) expiredChannel() string {
+	return fmt.Sprintf("__keyevent@%d__:expired", c.redisDB())
+}
+
+// expiryListenerRoutine 监听Redis过期事件，对只存在于L2的键调用OnExpire
+func (c *MultiLevelCache) expiryListenerRoutine(stop <-chan struct{}) {
+	config := c.config()
+	if config.EnableKeyspaceEvents {
+		// 托管Redis通常禁止CONFIG命令，失败时只记录日志，需要由运维开启
+		if err := c.redisClient.ConfigSet(c.ctx, "notify-keyspace-events", "Ex").Err(); err != nil {
+			c.logf("dancache: enable keyspace events: %v", err)
+		}
+	}
+
+	pubsub := c.redisClient.Subscribe(c.ctx, c.expiredChannel())
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.handleExpired(msg.Payload)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// handleExpired 处理一个过期键，跳过命名空间外的键、内部键和仍在L1中有效的键
+func (c *MultiLevelCache) handleExpired(key string) {
+	config := c.config()
+	if !strings.HasPrefix(key, config.ExpireKeyPrefix) {
+		return
+	}
+	if c.isInternalKey(key) {
+		return
+	}
+	if config.EnableL1Cache {
+		if _, ok := c.shardFor(key).load(key); ok {
+			return
+		}
+	}
+	c.protect("OnExpire", func() {
+		config.OnExpire(key)
+	})
+}
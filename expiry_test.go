@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestOnExpireForL2OnlyKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	expired := make(chan string, 10)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.ExpireKeyPrefix = "user:"
+		config.OnExpire = func(key string) { expired <- key }
+	})
+	if err := c.Set("user:2", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// 等待订阅建立后再发布过期事件
+	channel := c.expiredChannel()
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub(channel)[channel] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expiry listener did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// 前缀外的键、内部键和仍在L1中的键不回调
+	for _, key := range []string{"order:1", c.internalKey(tagKeyPrefix, "user:x"), "user:2", "user:1"} {
+		mr.Publish(channel, key)
+	}
+
+	select {
+	case key := <-expired:
+		if key != "user:1" {
+			t.Errorf("OnExpire(%q), want user:1", key)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnExpire was not called")
+	}
+	select {
+	case key := <-expired:
+		t.Errorf("unexpected OnExpire(%q)", key)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,51 @@
+package cache
+
+// EvictionReason 缓存项被移出L1的原因
+type EvictionReason int
+
+const (
+	EvictCapacity       EvictionReason = iota // 超过MaxL1Size被LRU淘汰
+	EvictMemoryPressure                       // 内存压力下被主动淘汰
+	EvictDemotion                             // 降级策略要求移出L1
+)
+
+// String 返回原因名称
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictMemoryPressure:
+		return "memory_pressure"
+	case EvictDemotion:
+		return "demotion"
+	}
+	return "unknown"
+}
+
+// EvictionExporter 接收被永久淘汰的缓存项(未启用L2，淘汰后数据不再保存在任何级别)
+// 可用于写入更慢的存储、记录日志或送入分析管道，在清理流程中同步调用，应尽快返回
+type EvictionExporter interface {
+	Export(key string, item *CacheItem, reason EvictionReason)
+}
+
+// EvictionExporterFunc 函数形式的EvictionExporter
+type EvictionExporterFunc func(key string, item *CacheItem, reason EvictionReason)
+
+// Export 实现EvictionExporter
+func (f EvictionExporterFunc) Export(key string, item *CacheItem, reason EvictionReason) {
+	f(key, item, reason)
+}
+
+// exportEvicted 将永久淘汰的缓存项交给导出器
+func (c *MultiLevelCache) exportEvicted(items []keyedItem, reason EvictionReason) {
+	if c.config().EvictionExporter == nil || c.config().EnableL2Cache {
+		return
+	}
+	exporter := c.config().EvictionExporter
+	for _, ki := range items {
+		ki := ki
+		c.protect("EvictionExporter", func() {
+			exporter.Export(ki.key, ki.item, reason)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestEvictionExporterReceivesCapacityEvictions(t *testing.T) {
+	exported := map[string]EvictionReason{}
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.MaxL1Size = 2
+		config.EvictionExporter = EvictionExporterFunc(func(key string, item *CacheItem, reason EvictionReason) {
+			exported[key] = reason
+			if item.Value != "v-"+key {
+				t.Errorf("exported %q with value %v", key, item.Value)
+			}
+			// 导出器发生panic不影响淘汰
+			panic("exporter failed")
+		})
+	})
+
+	for i := 0; i < 4; i++ {
+		key := fmt.Sprint(i)
+		if err := c.Set(key, "v-"+key, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(exported) != 2 {
+		t.Fatalf("exported %v, want two evicted keys", exported)
+	}
+	for key, reason := range exported {
+		if reason != EvictCapacity || reason.String() != "capacity" {
+			t.Errorf("%q exported with reason %v, want capacity", key, reason)
+		}
+		if _, found := c.Get(key); found {
+			t.Errorf("exported key %q is still cached", key)
+		}
+	}
+}
+
+func TestEvictionExporterSkippedWithL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+	exported := 0
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.MaxL1Size = 1
+		config.EvictionExporter = EvictionExporterFunc(func(key string, item *CacheItem, reason EvictionReason) {
+			exported++
+		})
+	})
+
+	// 启用L2时淘汰的项仍保存在L2，不是永久淘汰
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.Set(key, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exported != 0 {
+		t.Errorf("exporter called %d times with L2 enabled, want 0", exported)
+	}
+}
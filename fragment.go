@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"strings"
+)
+
+// holePlaceholder 缓存片段中动态区域(洞)的占位符格式
+const holePlaceholder = "<!--dancache:hole:%s-->"
+
+// HoleFunc 渲染动态区域的函数，每次请求都会调用
+type HoleFunc func() (template.HTML, error)
+
+// FragmentCache html/template渲染结果缓存
+// 以模板名和数据哈希为键缓存渲染输出，支持在缓存片段中留出每次请求单独渲染的动态区域(donut-hole)
+type FragmentCache struct {
+	cache *MultiLevelCache
+	tmpl  *template.Template
+	ttl   int64
+}
+
+// FragmentFuncs 返回片段缓存使用的模板函数，需要在解析模板前通过Funcs注册
+//
+//	{{fragment "sidebar" .}}  嵌套渲染并缓存另一个模板
+//	{{hole "cart"}}           留出动态区域，由RenderWithHoles在每次请求时填充
+func FragmentFuncs() template.FuncMap {
+	return template.FuncMap{
+		"fragment": func(name string, data interface{}) (template.HTML, error) {
+			return "", fmt.Errorf("模板%s未绑定片段缓存", name)
+		},
+		"hole": holeMarker,
+	}
+}
+
+// NewFragmentCache 创建片段缓存并为模板绑定片段函数
+func NewFragmentCache(cache *MultiLevelCache, tmpl *template.Template, ttl int64) *FragmentCache {
+	f := &FragmentCache{
+		cache: cache,
+		tmpl:  tmpl,
+		ttl:   ttl,
+	}
+	tmpl.Funcs(template.FuncMap{
+		"fragment": f.Render,
+		"hole":     holeMarker,
+	})
+	return f
+}
+
+// Key 生成模板渲染结果的缓存键
+func (f *FragmentCache) Key(name string, data interface{}) string {
+	return "tpl:" + name + ":" + hashJSON(data)
+}
+
+// Render 渲染模板并缓存输出，输出中的动态区域保持占位符
+func (f *FragmentCache) Render(name string, data interface{}) (template.HTML, error) {
+	val, err := f.cache.GetOrLoad(f.Key(name, data), f.ttl, func() (interface{}, error) {
+		var buf bytes.Buffer
+		if err := f.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	html, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("缓存的片段类型错误: %T", val)
+	}
+	return template.HTML(html), nil
+}
+
+// RenderWithHoles 渲染并缓存模板，然后用holes填充其中的动态区域
+// 动态区域本身可以再调用Render使用各自的缓存键和过期时间
+func (f *FragmentCache) RenderWithHoles(name string, data interface{}, holes map[string]HoleFunc) (template.HTML, error) {
+	html, err := f.Render(name, data)
+	if err != nil {
+		return "", err
+	}
+
+	out := string(html)
+	for holeName, render := range holes {
+		placeholder := fmt.Sprintf(holePlaceholder, holeName)
+		if !strings.Contains(out, placeholder) {
+			continue
+		}
+		content, err := render()
+		if err != nil {
+			return "", err
+		}
+		out = strings.ReplaceAll(out, placeholder, string(content))
+	}
+	return template.HTML(out), nil
+}
+
+// Invalidate 删除模板指定数据的渲染结果
+func (f *FragmentCache) Invalidate(name string, data interface{}) error {
+	return f.cache.Delete(f.Key(name, data))
+}
+
+// holeMarker 输出动态区域占位符
+func holeMarker(name string) template.HTML {
+	return template.HTML(fmt.Sprintf(holePlaceholder, name))
+}
+
+// hashJSON 计算值的规范JSON哈希，结构体与内容相同的map得到相同的结果
+func hashJSON(v interface{}) string {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%#v", v))
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf("%x", h.Sum64())
+}
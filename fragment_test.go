@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"html/template"
+	"io"
+	"testing"
+)
+
+func TestFragmentCacheRendersOnce(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	renders := map[string]int{}
+	funcs := FragmentFuncs()
+	funcs["count"] = func(name string) string {
+		renders[name]++
+		return ""
+	}
+	tmpl := template.Must(template.New("page").Funcs(funcs).Parse(
+		`{{define "page"}}{{count "page"}}<h1>{{.Title}}</h1>{{fragment "sidebar" .User}}{{hole "cart"}}{{end}}` +
+			`{{define "sidebar"}}{{count "sidebar"}}<aside>{{.}}</aside>{{end}}`))
+	f := NewFragmentCache(c, tmpl, 60)
+
+	data := map[string]string{"Title": "Home", "User": "alice"}
+	items := 0
+	holes := map[string]HoleFunc{"cart": func() (template.HTML, error) {
+		items++
+		return template.HTML("<b>" + string(rune('0'+items)) + "</b>"), nil
+	}}
+	for i, want := range []template.HTML{
+		"<h1>Home</h1><aside>alice</aside><b>1</b>",
+		"<h1>Home</h1><aside>alice</aside><b>2</b>",
+	} {
+		got, err := f.RenderWithHoles("page", data, holes)
+		if err != nil {
+			t.Fatalf("RenderWithHoles: %v", err)
+		}
+		if got != want {
+			t.Errorf("render %d = %q, want %q", i, got, want)
+		}
+	}
+	if renders["page"] != 1 || renders["sidebar"] != 1 {
+		t.Errorf("template executions = %v, want page and sidebar once", renders)
+	}
+
+	// 不同数据使用不同的键，失效后重新渲染
+	if _, err := f.Render("page", map[string]string{"Title": "About", "User": "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if renders["page"] != 2 || renders["sidebar"] != 1 {
+		t.Errorf("template executions = %v, want page twice and sidebar once", renders)
+	}
+	if err := f.Invalidate("page", data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Render("page", data); err != nil {
+		t.Fatal(err)
+	}
+	if renders["page"] != 3 {
+		t.Errorf("page executions after Invalidate = %d, want 3", renders["page"])
+	}
+}
+
+func TestFragmentFuncsRequireBinding(t *testing.T) {
+	tmpl := template.Must(template.New("page").Funcs(FragmentFuncs()).Parse(`{{fragment "page" .}}`))
+	if err := tmpl.Execute(io.Discard, nil); err == nil {
+		t.Error("fragment without a FragmentCache did not fail")
+	}
+}
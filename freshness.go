@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"time"
+)
+
+// FreshnessState 缓存值的新鲜度
+type FreshnessState int
+
+const (
+	Fresh FreshnessState = iota // 新鲜，可直接使用
+	Stale                       // 陈旧，仍可使用但应尽快重新验证
+)
+
+// Freshness 由上游(如HTTP Cache-Control)计算出的新鲜度信息
+type Freshness struct {
+	FreshUntil time.Time // 在此之前值是新鲜的
+	StaleUntil time.Time // 在此之前值可作为陈旧数据使用，之后过期
+}
+
+// validInL1 判断缓存项能否继续留在本地缓存
+// 带新鲜度的项只在新鲜期内留在L1，陈旧期内只由L2提供；从L2升级的项超过PromotionTTL后需重新从L2读取
+func (item *CacheItem) validInL1(now int64) bool {
+	if item.ExpireTime <= now {
+		return false
+	}
+	if item.revalidateAt > 0 && item.revalidateAt <= now {
+		return false
+	}
+	return item.FreshUntil == 0 || item.FreshUntil > now
+}
+
+// isStale 判断缓存项是否已过新鲜期
+func (item *CacheItem) isStale(now int64) bool {
+	return item.FreshUntil > 0 && item.FreshUntil <= now
+}
+
+// SetWithFreshness 按新鲜度信息设置缓存
+// L1只保留到FreshUntil，L2保留到StaleUntil；StaleUntil早于FreshUntil时按FreshUntil处理
+func (c *MultiLevelCache) SetWithFreshness(key string, value interface{}, freshness Freshness) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return err
+	}
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return err
+	}
+	if c.hasTombstone(key) {
+		return ErrTombstoned
+	}
+	now := c.nowUnix()
+	freshUntil := freshness.FreshUntil.Unix()
+	staleUntil := freshness.StaleUntil.Unix()
+	if staleUntil < freshUntil {
+		staleUntil = freshUntil
+	}
+
+	// 如果已经完全过期，不设置缓存
+	if staleUntil <= now {
+		return nil
+	}
+
+	// 已经陈旧的值不进入本地缓存，只写入L2
+	o := callOptions{freshUntil: freshUntil, skipL1: freshUntil <= now}
+	if o.skipL1 && !c.config().EnableL2Cache {
+		return nil
+	}
+	return c.set(c.ctx, key, value, staleUntil-now, nil, false, o)
+}
+
+// GetWithFreshness 获取缓存并返回新鲜度，调用方在Stale时应重新验证并回写
+func (c *MultiLevelCache) GetWithFreshness(key string) (interface{}, FreshnessState, bool) {
+	item, found := c.getItem(key)
+	if !found {
+		return nil, Fresh, false
+	}
+	if item.isStale(c.nowUnix()) {
+		return c.readValue(item), Stale, true
+	}
+	return c.readValue(item), Fresh, true
+}
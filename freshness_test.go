@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSetWithFreshnessAfterClose(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	now := time.Now()
+	err = c.SetWithFreshness("k", "v", Freshness{FreshUntil: now.Add(time.Minute), StaleUntil: now.Add(time.Hour)})
+	if err != ErrClosed {
+		t.Errorf("SetWithFreshness after Close = %v, want ErrClosed", err)
+	}
+	if err := c.Publish("k", "v", 60); err != ErrClosed {
+		t.Errorf("Publish after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestSetWithFreshnessStates(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	now := time.Now()
+	if err := c.SetWithFreshness("fresh", "v", Freshness{FreshUntil: now.Add(time.Minute), StaleUntil: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if v, state, found := c.GetWithFreshness("fresh"); !found || v != "v" || state != Fresh {
+		t.Errorf("GetWithFreshness = %v, %v, %v; want v, Fresh, true", v, state, found)
+	}
+	// 只启用L1时已经陈旧的值不进入本地缓存
+	if err := c.SetWithFreshness("stale", "v", Freshness{FreshUntil: now.Add(-time.Minute), StaleUntil: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, found := c.GetWithFreshness("stale"); found {
+		t.Error("stale value was stored in L1")
+	}
+}
+
+func TestValidInL1HonoursFreshUntil(t *testing.T) {
+	now := time.Now().Unix()
+	cases := []struct {
+		name  string
+		item  CacheItem
+		valid bool
+		stale bool
+	}{
+		{"no freshness", CacheItem{ExpireTime: now + 60}, true, false},
+		{"fresh", CacheItem{ExpireTime: now + 60, FreshUntil: now + 10}, true, false},
+		{"stale", CacheItem{ExpireTime: now + 60, FreshUntil: now}, false, true},
+		{"expired", CacheItem{ExpireTime: now, FreshUntil: now + 10}, false, false},
+	}
+	for _, tc := range cases {
+		if got := tc.item.validInL1(now); got != tc.valid {
+			t.Errorf("%s: validInL1 = %v, want %v", tc.name, got, tc.valid)
+		}
+		if got := tc.item.isStale(now); got != tc.stale {
+			t.Errorf("%s: isStale = %v, want %v", tc.name, got, tc.stale)
+		}
+	}
+}
+
+func TestSetWithFreshnessTiersTTLs(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+
+	now := time.Now()
+	freshness := Freshness{FreshUntil: now.Add(time.Minute), StaleUntil: now.Add(time.Hour)}
+	if err := c.SetWithFreshness("k", "v", freshness); err != nil {
+		t.Fatal(err)
+	}
+	// L1只保留到FreshUntil，L2保留到StaleUntil
+	item, ok := c.shardFor("k").load("k")
+	if !ok {
+		t.Fatal("fresh value was not stored in L1")
+	}
+	if item.FreshUntil != freshness.FreshUntil.Unix() {
+		t.Errorf("L1 FreshUntil = %d, want %d", item.FreshUntil, freshness.FreshUntil.Unix())
+	}
+	if ttl := mr.TTL("k"); ttl <= 50*time.Minute || ttl > time.Hour {
+		t.Errorf("L2 TTL = %v, want about StaleUntil", ttl)
+	}
+
+	// 新鲜期过后L1中的项失效，从L2读取的陈旧值不升级到L1
+	if err := c.SetWithFreshness("stale", "v", Freshness{FreshUntil: now.Add(-time.Second), StaleUntil: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if v, state, found := c.GetWithFreshness("stale"); !found || v != "v" || state != Stale {
+			t.Fatalf("GetWithFreshness = %v, %v, %v; want v, Stale, true", v, state, found)
+		}
+	}
+	if _, ok := c.shardFor("stale").load("stale"); ok {
+		t.Error("stale value read from L2 was promoted to L1")
+	}
+	item.FreshUntil = now.Add(-time.Second).Unix()
+	if _, _, found := c.GetWithFreshness("k"); !found {
+		t.Fatal("value past FreshUntil in L1 was not served from L2")
+	}
+	if promoted, ok := c.shardFor("k").load("k"); ok && promoted == item {
+		t.Error("item past FreshUntil stayed in L1")
+	}
+}
+
+func TestPromotionTTLBoundsL1Residency(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newRedisTestCache(t, mr, nil)
+	reader := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+		config.PromotionTTL = 5
+	})
+	if err := writer.Set("k", "v1", 600); err != nil {
+		t.Fatal(err)
+	}
+	reader.Get("k")
+	item, ok := reader.shardFor("k").load("k")
+	if !ok {
+		t.Fatal("k was not promoted")
+	}
+	if want := time.Now().Unix() + 5; item.revalidateAt < want-1 || item.revalidateAt > want {
+		t.Errorf("revalidateAt = %d, want about %d", item.revalidateAt, want)
+	}
+
+	// 停留期限到期后重新从L2读取，看到其他实例的写入
+	if err := writer.Set("k", "v2", 600); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := reader.Get("k"); v != "v1" {
+		t.Errorf("Get within PromotionTTL = %v, want the L1 copy v1", v)
+	}
+	item.revalidateAt = time.Now().Unix() - 1
+	if v, _ := reader.Get("k"); v != "v2" {
+		t.Errorf("Get after PromotionTTL = %v, want v2 from L2", v)
+	}
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// 原生模糊测试，只使用L1，不需要Redis，例如:
+//
+//	go test -run '^$' -fuzz FuzzDecode -fuzztime 30s .
+//
+// 不带-fuzz运行时只执行种子语料，作为普通测试的一部分
+
+// newFuzzCache 创建只启用L1的缓存，键按最严格的规则规范化，读取的值可能带信封、校验和、压缩和加密
+func newFuzzCache(tb testing.TB) *MultiLevelCache {
+	tb.Helper()
+	c, err := NewMultiLevelCache(CacheConfig{
+		EnableL1Cache:     true,
+		L1TTL:             60,
+		MaxL1Size:         1000,
+		MaxKeyLength:      250,
+		KeyLength:         KeyLengthHash,
+		KeyChars:          KeyCharsStrip,
+		Envelope:          true,
+		Checksum:          true,
+		Compression:       CompressionGzip,
+		CompressThreshold: 16,
+		MaxDecodedSize:    1 << 20,
+		Encryption: &PrefixKeys{
+			Keys:    map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)},
+			Default: "k1",
+		},
+	})
+	if err != nil {
+		tb.Fatalf("NewMultiLevelCache: %v", err)
+	}
+	tb.Cleanup(func() { c.Close() })
+	return c
+}
+
+// FuzzDecode 把任意字节当作从共享Redis读取的值解码，覆盖信封、校验和、压缩、解密和JSON载荷
+func FuzzDecode(f *testing.F) {
+	c := newFuzzCache(f)
+	for _, value := range []interface{}{"v", strings.Repeat("x", 100), map[string]interface{}{"a": []interface{}{1.0, "b"}}} {
+		data, err := c.encodeItem("seed", &CacheItem{Value: value, ExpireTime: 1})
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+		f.Add(data[:len(data)/2])
+	}
+	f.Add([]byte(`{"value":"v","expire_time":1}`))
+	f.Add([]byte(strings.Repeat("[", 2000)))
+	f.Add([]byte{envelopeMagic0, envelopeMagic1, envelopeVersion, byte(CodecIDJSON), byte(CompressionGzip), envelopeFlagChecksum | envelopeFlagEncrypted})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var item CacheItem
+		_ = c.decodeItem("seed", data, &item)
+	})
+}
+
+// FuzzKey 规范化任意键，结果必须是合法的UTF-8、不超过MaxKeyLength且再次规范化保持不变
+func FuzzKey(f *testing.F) {
+	c := newFuzzCache(f)
+	for _, seed := range []string{
+		"user:1",
+		" user:\t1\n",
+		strings.Repeat("a", 300),
+		strings.Repeat("缓", 100),
+		"x" + strings.Repeat("😀", 80),
+		"\xff\xfe" + strings.Repeat("é", 200),
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, key string) {
+		normalized, err := c.normalizeKey(key)
+		if err != nil {
+			return
+		}
+		if len(normalized) > 250 {
+			t.Fatalf("normalizeKey(%q) has length %d, want <= 250", key, len(normalized))
+		}
+		again, err := c.normalizeKey(normalized)
+		if err != nil || again != normalized {
+			t.Fatalf("normalizeKey not idempotent: %q -> %q -> %q, %v", key, normalized, again, err)
+		}
+	})
+}
+
+// FuzzSetGet 写入L1后读取，读到的值必须与写入的相同
+func FuzzSetGet(f *testing.F) {
+	c := newFuzzCache(f)
+	f.Add("user:1", "v")
+	f.Add(strings.Repeat("键", 100), "")
+	f.Add("a\x00b", "\xff")
+	f.Fuzz(func(t *testing.T, key, value string) {
+		if err := c.Set(key, value, 60); err != nil {
+			return
+		}
+		got, ok := c.Get(key)
+		if !ok {
+			t.Fatalf("Get(%q) missed right after Set", key)
+		}
+		if s, _ := got.(string); s != value {
+			t.Fatalf("Get(%q) = %q, want %q", key, got, value)
+		}
+	})
+}
+
+// FuzzEncodeDecode 编码后再解码，值必须保持不变；JSON会替换无效的UTF-8，只检查有效的字符串
+func FuzzEncodeDecode(f *testing.F) {
+	c := newFuzzCache(f)
+	f.Add("tenant:1", "v")
+	f.Add("", strings.Repeat("压缩", 50))
+	f.Fuzz(func(t *testing.T, key, value string) {
+		if !utf8.ValidString(value) {
+			return
+		}
+		data, err := c.encodeItem(key, &CacheItem{Value: value, ExpireTime: 1})
+		if err != nil {
+			t.Fatalf("encodeItem: %v", err)
+		}
+		var item CacheItem
+		if err := c.decodeItem(key, data, &item); err != nil {
+			t.Fatalf("decodeItem: %v", err)
+		}
+		if item.Value != value {
+			t.Fatalf("decoded %q, want %q", item.Value, value)
+		}
+	})
+}
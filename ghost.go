@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ghostEntry 影子列表中的记录
+type ghostEntry struct {
+	hash      uint64
+	evicted   time.Time // 淘汰时间
+	requested bool      // 淘汰后是否再次被请求
+}
+
+// ghostList 最近被淘汰键的影子列表
+// 只保存键的哈希，不保存值，用很少的内存记录"如果本地缓存再大一些就能命中"的请求
+type ghostList struct {
+	mu       sync.Mutex
+	capacity int
+	window   time.Duration            // 淘汰后多久内再次请求算作命中(0表示只受容量限制)
+	order    *list.List               // 按淘汰时间排序，最近淘汰的在前
+	index    map[uint64]*list.Element // 键哈希->列表元素
+}
+
+// newGhostList 创建影子列表
+func newGhostList(capacity int, window time.Duration) *ghostList {
+	return &ghostList{
+		capacity: capacity,
+		window:   window,
+		order:    list.New(),
+		index:    make(map[uint64]*list.Element),
+	}
+}
+
+// add 记录被淘汰的键，超出容量时丢弃最早的记录
+func (g *ghostList) add(key string) {
+	h := ghostHash(key)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if e, ok := g.index[h]; ok {
+		e.Value = &ghostEntry{hash: h, evicted: time.Now()}
+		g.order.MoveToFront(e)
+		return
+	}
+	g.index[h] = g.order.PushFront(&ghostEntry{hash: h, evicted: time.Now()})
+	for g.order.Len() > g.capacity {
+		g.remove(g.order.Back())
+	}
+}
+
+// hit 判断键是否在窗口内被淘汰过，命中时标记为待重新准入
+// 同一次淘汰只算一次命中
+func (g *ghostList) hit(key string) bool {
+	h := ghostHash(key)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e, ok := g.index[h]
+	if !ok {
+		return false
+	}
+	entry := e.Value.(*ghostEntry)
+	if g.window > 0 && time.Since(entry.evicted) > g.window {
+		g.remove(e)
+		return false
+	}
+	if entry.requested {
+		return false
+	}
+	entry.requested = true
+	return true
+}
+
+// readmit 判断键是否待重新准入，是则移除记录
+func (g *ghostList) readmit(key string) bool {
+	h := ghostHash(key)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e, ok := g.index[h]
+	if !ok || !e.Value.(*ghostEntry).requested {
+		return false
+	}
+	g.remove(e)
+	return true
+}
+
+// remove 删除记录，调用方需持有锁
+func (g *ghostList) remove(e *list.Element) {
+	g.order.Remove(e)
+	delete(g.index, e.Value.(*ghostEntry).hash)
+}
+
+// ghostHash 计算键的哈希
+func ghostHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestGhostListHitOncePerEviction(t *testing.T) {
+	g := newGhostList(2, 0)
+	for i := 0; i < 3; i++ {
+		g.add(fmt.Sprint(i))
+	}
+	// 超出容量时丢弃最早的记录
+	if g.hit("0") {
+		t.Error("hit on a key dropped from the ghost list")
+	}
+	if !g.hit("1") || g.hit("1") {
+		t.Error("want exactly one hit per eviction")
+	}
+	if g.readmit("2") {
+		t.Error("readmit on a key that was not requested")
+	}
+	if !g.readmit("1") || g.readmit("1") {
+		t.Error("want readmit once after a hit")
+	}
+
+	windowed := newGhostList(10, 10*time.Millisecond)
+	windowed.add("k")
+	time.Sleep(20 * time.Millisecond)
+	if windowed.hit("k") {
+		t.Error("hit after GhostWindow elapsed")
+	}
+}
+
+func TestGhostListReadmitsEvictedKey(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.MaxL1Size = 1
+		config.GhostListSize = 10
+	})
+
+	if err := c.Set("a", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("b", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.shardFor("a").load("a"); ok {
+		t.Fatal("a was not evicted")
+	}
+
+	// 默认升级策略不会升级只访问一次的项，淘汰后很快被再次请求的键直接升级
+	if _, found := c.Get("a"); !found {
+		t.Fatal("Get(a) missed")
+	}
+	if _, ok := c.shardFor("a").load("a"); !ok {
+		t.Error("recently evicted key was not readmitted to L1")
+	}
+	if n := c.GetStats()["ghost_readmissions"]; n != int64(1) {
+		t.Errorf("ghost_readmissions = %v, want 1", n)
+	}
+}
@@ -0,0 +1,111 @@
+// Package grpccache 为gRPC一元调用提供基于多级缓存的响应缓存拦截器
+package grpccache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	cache "github.com/losanming/DanCache"
+	"github.com/losanming/DanCache/protocache"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// MethodConfig 单个方法的缓存配置，只应为幂等方法配置
+type MethodConfig struct {
+	TTL int64 // 缓存时间(秒)
+	// Key 从请求消息提取缓存键，返回false时不缓存本次调用
+	// 为空时使用请求消息确定性序列化后的哈希
+	Key func(req interface{}) (string, bool)
+}
+
+// UnaryClientInterceptor 创建客户端拦截器，命中缓存时不发起调用
+// methods以完整方法名(如"/pkg.Service/Method")为键
+func UnaryClientInterceptor(c *cache.MultiLevelCache, methods map[string]MethodConfig) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key, cfg, ok := cacheKey(methods, method, req)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		msg, isProto := reply.(proto.Message)
+		if !isProto {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if val, found := c.Get(key); found {
+			if err := protocache.DecodeInto(val, msg); err == nil {
+				return nil
+			}
+		}
+
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+		store(c, key, cfg.TTL, msg)
+		return nil
+	}
+}
+
+// UnaryServerInterceptor 创建服务端拦截器，命中缓存时不调用处理函数
+// 响应消息类型需要在protoregistry.GlobalTypes中注册(生成的代码会自动注册)
+func UnaryServerInterceptor(c *cache.MultiLevelCache, methods map[string]MethodConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key, cfg, ok := cacheKey(methods, info.FullMethod, req)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		if val, found := c.Get(key); found {
+			if msg, err := protocache.Decode(val); err == nil {
+				return msg, nil
+			}
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if msg, isProto := resp.(proto.Message); isProto {
+			store(c, key, cfg.TTL, msg)
+		}
+		return resp, nil
+	}
+}
+
+// cacheKey 生成调用的缓存键，方法未配置或无法生成键时返回false
+func cacheKey(methods map[string]MethodConfig, method string, req interface{}) (string, MethodConfig, bool) {
+	cfg, ok := methods[method]
+	if !ok || cfg.TTL <= 0 {
+		return "", cfg, false
+	}
+
+	if cfg.Key != nil {
+		key, ok := cfg.Key(req)
+		if !ok {
+			return "", cfg, false
+		}
+		return "grpc:" + method + ":" + key, cfg, true
+	}
+
+	msg, isProto := req.(proto.Message)
+	if !isProto {
+		return "", cfg, false
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", cfg, false
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf("grpc:%s:%x", method, h.Sum64()), cfg, true
+}
+
+// store 序列化响应并写入缓存，失败时忽略
+func store(c *cache.MultiLevelCache, key string, ttl int64, msg proto.Message) {
+	encoded, err := protocache.Encode(msg)
+	if err != nil {
+		return
+	}
+	c.Set(key, encoded, ttl)
+}
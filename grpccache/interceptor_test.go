@@ -0,0 +1,110 @@
+package grpccache
+
+import (
+	"context"
+	"testing"
+
+	cache "github.com/losanming/DanCache"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testMethod = "/test.Service/Echo"
+
+func newTestCache(t *testing.T) *cache.MultiLevelCache {
+	t.Helper()
+	c, err := cache.NewMultiLevelCache(cache.CacheConfig{EnableL1Cache: true, MaxL1Size: 100})
+	if err != nil {
+		t.Fatalf("NewMultiLevelCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestUnaryServerInterceptorCachesResponses(t *testing.T) {
+	c := newTestCache(t)
+	interceptor := UnaryServerInterceptor(c, map[string]MethodConfig{testMethod: {TTL: 60}})
+	info := &grpc.UnaryServerInfo{FullMethod: testMethod}
+
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("echo:" + req.(*wrapperspb.StringValue).GetValue()), nil
+	}
+	for i := 0; i < 3; i++ {
+		resp, err := interceptor(context.Background(), wrapperspb.String("a"), info, handler)
+		if err != nil {
+			t.Fatalf("interceptor: %v", err)
+		}
+		if got := resp.(*wrapperspb.StringValue).GetValue(); got != "echo:a" {
+			t.Errorf("response = %q, want echo:a", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+
+	// 不同请求使用不同的键
+	if _, err := interceptor(context.Background(), wrapperspb.String("b"), info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times after a new request, want 2", calls)
+	}
+
+	// 未配置的方法不缓存
+	other := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Other"}
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(context.Background(), wrapperspb.String("a"), other, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("handler called %d times for an unconfigured method, want 4", calls)
+	}
+}
+
+func TestUnaryClientInterceptorCachesReplies(t *testing.T) {
+	c := newTestCache(t)
+	methods := map[string]MethodConfig{testMethod: {
+		TTL: 60,
+		Key: func(req interface{}) (string, bool) {
+			v := req.(*wrapperspb.StringValue).GetValue()
+			return v, v != "skip"
+		},
+	}}
+	interceptor := UnaryClientInterceptor(c, methods)
+
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		proto.Merge(reply.(proto.Message), wrapperspb.String("reply"))
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		reply := &wrapperspb.StringValue{}
+		if err := interceptor(context.Background(), testMethod, wrapperspb.String("a"), reply, nil, invoker); err != nil {
+			t.Fatalf("interceptor: %v", err)
+		}
+		if reply.GetValue() != "reply" {
+			t.Errorf("reply = %q, want reply", reply.GetValue())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("invoker called %d times, want 1", calls)
+	}
+	if _, found := c.Get("grpc:" + testMethod + ":a"); !found {
+		t.Error("reply was not cached under the custom key")
+	}
+
+	// Key返回false时不缓存
+	for i := 0; i < 2; i++ {
+		if err := interceptor(context.Background(), testMethod, wrapperspb.String("skip"), &wrapperspb.StringValue{}, nil, invoker); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("invoker called %d times for skipped keys, want 3", calls)
+	}
+}
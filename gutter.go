@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultGutterTTL 写入gutter的缓存项默认最长保留时间(秒)
+const defaultGutterTTL = 10
+
+// gutterProbeTimeout 使用gutter期间探测主Redis的超时
+const gutterProbeTimeout = 2 * time.Second
+
+// circuitOpen 判断L2熔断器是否打开
+func (c *MultiLevelCache) circuitOpen() bool {
+	return c.circuit != nil && c.circuit.isOpen()
+}
+
+// l2 返回当前使用的L2客户端，熔断器打开且配置了gutter时切换到gutter
+// 配置了L2RateLimit时每次调用扣除一个令牌，正常请求不等待，预热等后台任务据此让出额度；每次调用计入l2_call速率
+func (c *MultiLevelCache) l2() RedisCommander {
+	client, _ := c.l2Client()
+	return client
+}
+
+// l2Client 与l2相同，同时返回客户端在scriptCache中的下标
+func (c *MultiLevelCache) l2Client() (RedisCommander, int) {
+	c.ensureMaintenance(L2Cache)
+	recordRate(&c.rates.l2Calls)
+	if c.l2Limiter != nil {
+		c.l2Limiter.take()
+	}
+	if c.gutterClient != nil && c.circuitOpen() {
+		c.probePrimary()
+		return c.gutterClient, gutterClientIndex
+	}
+	return c.redisClient, primaryClient
+}
+
+// probePrimary 使用gutter期间冷却结束后异步向主Redis发送一次PING
+// 熔断器只接在主Redis上，切换到gutter后没有请求经过熔断器，需要探测请求使其关闭；PING成功后L2请求回到主Redis
+func (c *MultiLevelCache) probePrimary() {
+	if !c.circuit.probeDue() || !atomic.CompareAndSwapInt32(&c.gutterProbing, 0, 1) {
+		return
+	}
+	if !c.enter() {
+		atomic.StoreInt32(&c.gutterProbing, 0)
+		return
+	}
+	go func() {
+		defer c.exit()
+		defer atomic.StoreInt32(&c.gutterProbing, 0)
+		ctx, cancel := context.WithTimeout(c.ctx, gutterProbeTimeout)
+		defer cancel()
+		if err := c.redisClient.Ping(ctx).Err(); err != nil && err != ErrCircuitOpen {
+			c.logf("dancache: probe primary redis failed, staying on gutter: %v", err)
+		}
+	}()
+}
+
+// gutterTTL 写入gutter的缓存项最长保留时间
+func (c *MultiLevelCache) gutterTTL() int64 {
+	if c.config().GutterTTL > 0 {
+		return c.config().GutterTTL
+	}
+	return defaultGutterTTL
+}
+
+// l2TTL 返回写入当前L2客户端的过期时间，gutter中的项使用较短的过期时间
+func (c *MultiLevelCache) l2TTL(ttl time.Duration) time.Duration {
+	if c.gutterClient != nil && c.circuitOpen() {
+		if limit := time.Duration(c.gutterTTL()) * time.Second; ttl > limit {
+			return limit
+		}
+	}
+	return ttl
+}
+
+// setL2 将序列化后的缓存项写入当前L2客户端，超过前缀的硬配额时返回*QuotaExceededError或缩短过期时间
+func (c *MultiLevelCache) setL2(ctx context.Context, key string, value interface{}, ttl time.Duration) *redis.StatusCmd {
+	ttl = c.l2TTL(ttl)
+	if data, ok := value.([]byte); ok {
+		var err error
+		if ttl, err = c.admitQuota(key, len(data), ttl); err != nil {
+			cmd := redis.NewStatusCmd(ctx, "set", key)
+			cmd.SetErr(err)
+			return cmd
+		}
+	}
+	return c.l2().Set(ctx, key, value, ttl)
+}
+
+// useL1Gutter 判断是否应使用本地缓存作为gutter
+// 熔断器打开且未配置gutter Redis时，写入只保存在L1并使用较短的过期时间
+func (c *MultiLevelCache) useL1Gutter() bool {
+	return c.config().EnableL1Cache && c.gutterClient == nil && c.config().GutterTTL > 0 && c.circuitOpen()
+}
+
+// storeL1Gutter 将缓存项以gutter过期时间写入本地缓存
+func (c *MultiLevelCache) storeL1Gutter(key string, item *CacheItem) {
+	if limit := c.nowUnix() + c.gutterTTL(); item.ExpireTime > limit {
+		item.ExpireTime = limit
+	}
+	c.storeL1(key, item)
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// hardMaxKeyLength 未配置MaxKeyLength时键的最大字节数，超过时总是拒绝
+// Redis允许512MB的键，但这样的键只会来自错误或恶意的输入
+const hardMaxKeyLength = 64 << 10
+
+// defaultMaxDecodedSize 解压后载荷的默认最大字节数
+const defaultMaxDecodedSize = 64 << 20
+
+// defaultMaxJSONDepth JSON载荷默认的最大嵌套深度
+const defaultMaxJSONDepth = 1000
+
+// maxErrorKeyLength 错误信息中保留的键的最大字节数
+const maxErrorKeyLength = 256
+
+// errDecodedTooLarge 解压后的载荷超过MaxDecodedSize
+var errDecodedTooLarge = errors.New("解压后的载荷超过上限")
+
+// maxDecodedSize 返回解压后载荷的最大字节数
+func (c *MultiLevelCache) maxDecodedSize() int64 {
+	if size := c.config().MaxDecodedSize; size > 0 {
+		return size
+	}
+	return defaultMaxDecodedSize
+}
+
+// readLimited 读取r的全部内容，超过limit字节时返回errDecodedTooLarge，防止压缩炸弹耗尽内存
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errDecodedTooLarge
+	}
+	return data, nil
+}
+
+// checkJSONDepth 检查JSON载荷的嵌套深度，超过MaxJSONDepth时返回错误
+// 只扫描字节不解析，字符串中的括号和转义字符会被跳过；格式错误留给解码器报告
+func (c *MultiLevelCache) checkJSONDepth(data []byte) error {
+	limit := c.config().MaxJSONDepth
+	if limit < 0 {
+		return nil
+	}
+	if limit == 0 {
+		limit = defaultMaxJSONDepth
+	}
+	depth := 0
+	inString, escaped := false, false
+	for _, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			if depth++; depth > limit {
+				return fmt.Errorf("JSON嵌套深度超过上限%d", limit)
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// truncateKey 截断过长的键，用于错误信息和日志
+func truncateKey(key string) string {
+	if len(key) <= maxErrorKeyLength {
+		return key
+	}
+	return fmt.Sprintf("%s...(%d字节)", key[:maxErrorKeyLength], len(key))
+}
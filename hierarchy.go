@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// generationKeyPrefix Redis中路径前缀的代数计数器的键前缀
+const generationKeyPrefix = "gen:"
+
+// defaultPathSeparator 分层键默认的分隔符
+const defaultPathSeparator = "/"
+
+// defaultGenerationCacheTTL 本地缓存代数的默认时长
+const defaultGenerationCacheTTL = time.Second
+
+// maxCachedGenerations 本地缓存的代数数量上限，超出时清空重新读取
+const maxCachedGenerations = 100000
+
+// defaultGenerationTTL L2中代数计数器的默认过期时间，配置的L2MaxTTL更长时使用L2MaxTTL
+const defaultGenerationTTL = 30 * 24 * time.Hour
+
+// bumpGenerationScript 递增前缀的代数并设置过期时间
+// 计数器不存在(从未失效或已过期)时从Redis的当前时间(毫秒)起算，过期前用过的代数不会被再次使用
+const bumpGenerationScript = `
+local gen = redis.call('INCR', KEYS[1])
+if gen == 1 then
+	local now = redis.call('TIME')
+	gen = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+	redis.call('SET', KEYS[1], gen)
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return gen
+`
+
+// generationEntry 本地缓存的一个前缀的代数
+type generationEntry struct {
+	gen     int64
+	fetched int64 // 读取时间(Unix纳秒)
+}
+
+// generations 路径前缀的代数，失效子树时递增，分层键的实际键包含各级前缀的代数
+type generations struct {
+	mu          sync.RWMutex
+	entries     map[string]generationEntry
+	invalidated int64 // InvalidateSubtree的调用次数
+}
+
+// pathSeparator 返回分层键的分隔符
+func (c *MultiLevelCache) pathSeparator() string {
+	if sep := c.config().PathSeparator; sep != "" {
+		return sep
+	}
+	return defaultPathSeparator
+}
+
+// pathPrefixes 返回路径的各级前缀(包括路径本身)，如a/b/c返回a、a/b、a/b/c
+func (c *MultiLevelCache) pathPrefixes(path string) ([]string, error) {
+	sep := c.pathSeparator()
+	path = strings.Trim(path, sep)
+	if path == "" {
+		return nil, errors.New("分层键为空")
+	}
+	segments := strings.Split(path, sep)
+	prefixes := make([]string, len(segments))
+	for i := range segments {
+		if segments[i] == "" {
+			return nil, &InvalidKeyError{Key: path, Reason: "包含空的层级"}
+		}
+		prefixes[i] = strings.Join(segments[:i+1], sep)
+	}
+	return prefixes, nil
+}
+
+// generationCacheTTL 返回本地缓存代数的时长，负数表示每次从L2读取
+func (c *MultiLevelCache) generationCacheTTL() time.Duration {
+	if ttl := c.config().GenerationCacheTTL; ttl != 0 {
+		return ttl
+	}
+	return defaultGenerationCacheTTL
+}
+
+// generationTTL 返回L2中代数计数器的过期时间，不短于写入的最长过期时间
+// 计数器过期前，失效前写入的键都已过期，过期后重新起算的代数不会让它们重新可达
+func (c *MultiLevelCache) generationTTL() time.Duration {
+	if max := time.Duration(c.config().L2MaxTTL) * time.Second; max > defaultGenerationTTL {
+		return max
+	}
+	return defaultGenerationTTL
+}
+
+// errGenerationUnavailable L2不可用且本地没有缓存前缀的代数
+var errGenerationUnavailable = errors.New("无法读取分层键的代数")
+
+// storeLocked 记录前缀的代数，调用方需持有mu
+// 代数保存在L2时本地只是缓存，超过上限时清空重新读取；只启用L1时本地记录是唯一的副本，不清空
+func (g *generations) storeLocked(prefix string, entry generationEntry, cached bool) {
+	if g.entries == nil || (cached && len(g.entries) >= maxCachedGenerations) {
+		g.entries = make(map[string]generationEntry)
+	}
+	g.entries[prefix] = entry
+}
+
+// loadGenerations 返回各级前缀当前的代数，本地缓存过期或缺少的前缀通过一次MGET从L2读取
+// 读取L2失败时使用本地已过期的代数；本地没有某个前缀的代数时返回错误，避免读到失效前的值
+func (c *MultiLevelCache) loadGenerations(prefixes []string) ([]int64, error) {
+	g := &c.generations
+	gens := make([]int64, len(prefixes))
+	now := time.Now().UnixNano()
+	ttl := int64(c.generationCacheTTL())
+	useL2 := c.config().EnableL2Cache
+
+	var missing []int
+	absent := false
+	g.mu.RLock()
+	for i, prefix := range prefixes {
+		entry, ok := g.entries[prefix]
+		gens[i] = entry.gen
+		if useL2 && (!ok || ttl < 0 || now-entry.fetched > ttl) {
+			missing = append(missing, i)
+			absent = absent || !ok
+		}
+	}
+	g.mu.RUnlock()
+	if len(missing) == 0 {
+		return gens, nil
+	}
+	fallback := func(err error) ([]int64, error) {
+		if absent {
+			return nil, errGenerationUnavailable
+		}
+		c.logf("dancache: load path generations failed, using cached: %v", err)
+		return gens, nil
+	}
+	if c.circuitOpen() {
+		return fallback(ErrCircuitOpen)
+	}
+
+	keys := make([]string, len(missing))
+	for j, i := range missing {
+		keys[j] = c.internalKey(generationKeyPrefix, prefixes[i])
+	}
+	values, err := c.l2().MGet(c.ctx, keys...).Result()
+	if err != nil {
+		return fallback(err)
+	}
+	g.mu.Lock()
+	for j, i := range missing {
+		var gen int64
+		if s, ok := values[j].(string); ok {
+			gen, _ = strconv.ParseInt(s, 10, 64)
+		}
+		gens[i] = gen
+		g.storeLocked(prefixes[i], generationEntry{gen: gen, fetched: now}, true)
+	}
+	g.mu.Unlock()
+	return gens, nil
+}
+
+// pathKey 返回分层键在缓存中的实际键：路径后附加各级前缀的代数，任一级失效后实际键随之改变
+func (c *MultiLevelCache) pathKey(path string) (string, error) {
+	prefixes, err := c.pathPrefixes(path)
+	if err != nil {
+		return "", err
+	}
+	gens, err := c.loadGenerations(prefixes)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(prefixes[len(prefixes)-1])
+	b.WriteByte('@')
+	for i, gen := range gens {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(strconv.FormatInt(gen, 10))
+	}
+	return b.String(), nil
+}
+
+// SetPath 写入分层键，如org/123/project/456/doc/789，之后可以通过InvalidateSubtree失效任一级前缀下的全部键
+func (c *MultiLevelCache) SetPath(path string, value interface{}, ttl int64, opts ...CallOption) error {
+	key, err := c.pathKey(path)
+	if err != nil {
+		return err
+	}
+	return c.Set(key, value, ttl, opts...)
+}
+
+// GetPath 读取分层键，所在的任一级前缀失效后按未命中处理
+func (c *MultiLevelCache) GetPath(path string, opts ...CallOption) (interface{}, bool) {
+	key, err := c.pathKey(path)
+	if err != nil {
+		return nil, false
+	}
+	return c.Get(key, opts...)
+}
+
+// DeletePath 删除分层键本身，不影响其下层的键
+func (c *MultiLevelCache) DeletePath(path string) error {
+	key, err := c.pathKey(path)
+	if err != nil {
+		return err
+	}
+	return c.Delete(key)
+}
+
+// InvalidateSubtree 失效前缀本身及其下层的全部分层键，只递增前缀的代数，与键的数量无关
+// 旧的键不再可达，留在L1和L2中直到过期或被淘汰；其他实例在GenerationCacheTTL内可能仍读到旧值
+// L2中的计数器在最后一次失效后generationTTL过期
+func (c *MultiLevelCache) InvalidateSubtree(prefix string) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return err
+	}
+	prefixes, err := c.pathPrefixes(prefix)
+	if err != nil {
+		return err
+	}
+	prefix = prefixes[len(prefixes)-1]
+
+	g := &c.generations
+	useL2 := c.config().EnableL2Cache
+	var gen int64
+	if useL2 {
+		key := c.internalKey(generationKeyPrefix, prefix)
+		if gen, err = bumpGeneration.Run(c.ctx, c.l2(), []string{key}, c.generationTTL().Milliseconds()).Int64(); err != nil {
+			return err
+		}
+	}
+	g.mu.Lock()
+	if !useL2 {
+		gen = g.entries[prefix].gen + 1
+	}
+	g.storeLocked(prefix, generationEntry{gen: gen, fetched: time.Now().UnixNano()}, useL2)
+	g.mu.Unlock()
+	atomic.AddInt64(&g.invalidated, 1)
+	return nil
+}
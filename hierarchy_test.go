@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestInvalidateSubtree(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.GenerationCacheTTL = -1 })
+
+	for _, path := range []string{"org/1/project/1/doc/1", "org/1/project/1/doc/2", "org/1/project/2/doc/1"} {
+		if err := c.SetPath(path, path, 600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.InvalidateSubtree("org/1/project/1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"org/1/project/1/doc/1", "org/1/project/1/doc/2"} {
+		if v, ok := c.GetPath(path); ok {
+			t.Errorf("GetPath(%q) = %v after InvalidateSubtree, want miss", path, v)
+		}
+	}
+	if v, ok := c.GetPath("org/1/project/2/doc/1"); !ok || v != "org/1/project/2/doc/1" {
+		t.Errorf("sibling subtree GetPath = %v, %v; want hit", v, ok)
+	}
+}
+
+func TestGenerationCounterExpires(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.GenerationCacheTTL = -1
+		config.InternalKeyPrefix = "__dc:"
+	})
+	genKey := "__dc:gen:org/1"
+
+	if err := c.InvalidateSubtree("org/1"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(genKey); ttl < defaultGenerationTTL-time.Minute || ttl > defaultGenerationTTL {
+		t.Errorf("generation counter TTL = %v, want %v", ttl, defaultGenerationTTL)
+	}
+	if err := c.SetPath("org/1/doc", "before expiry", 600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 计数器过期后重新起算的代数不会让过期前写入的键重新可达
+	// 过期发生在最后一次递增的过期时间之后，固定Redis的时间，避免与第一次递增落在同一毫秒
+	mr.Del(genKey)
+	mr.SetTime(time.Now().Add(defaultGenerationTTL))
+	if err := c.InvalidateSubtree("org/1"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := c.GetPath("org/1/doc"); ok {
+		t.Errorf("GetPath = %v after the counter expired and was bumped again, want miss", v)
+	}
+
+	long := newRedisTestCache(t, mr, func(config *CacheConfig) { config.L2MaxTTL = int64(90 * 24 * time.Hour / time.Second) })
+	if err := long.InvalidateSubtree("org/2"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("gen:org/2"); ttl < 89*24*time.Hour {
+		t.Errorf("generation counter TTL with L2MaxTTL of 90 days = %v, want 90 days", ttl)
+	}
+}
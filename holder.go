@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// holderValue Holder中保存的值，包装一层以便原子替换任意类型(包括nil)
+type holderValue struct {
+	value interface{}
+}
+
+// Holder 将整份配置/多语言资源包缓存在一个键下，后台定期刷新并原子替换
+// 读取方拿到的总是某个完整版本，不会看到更新到一半的资源包
+type Holder struct {
+	cache   *MultiLevelCache
+	key     string
+	ttl     int64
+	loader  LoaderFunc
+	newVal  func() interface{}
+	current atomic.Value
+	stop    chan struct{}
+}
+
+// NewHolder 创建Holder并同步加载初始值，interval大于0时启动后台刷新
+// 多个实例共享缓存中的资源包，缓存过期后只有一个实例调用loader重新加载
+// newValue不为空时资源包以JSON缓存，读取时解码到newValue返回的指针中
+func NewHolder(cache *MultiLevelCache, key string, ttl int64, interval time.Duration, loader LoaderFunc, newValue func() interface{}) (*Holder, error) {
+	h := &Holder{
+		cache:  cache,
+		key:    key,
+		ttl:    ttl,
+		loader: loader,
+		newVal: newValue,
+		stop:   make(chan struct{}),
+	}
+	if err := h.reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go h.refreshRoutine(interval)
+	}
+	return h, nil
+}
+
+// Get 返回当前的资源包
+func (h *Holder) Get() interface{} {
+	return h.current.Load().(*holderValue).value
+}
+
+// Refresh 立即从loader重新加载资源包，写入缓存并替换当前版本
+func (h *Holder) Refresh() error {
+	val, err := h.loader()
+	if err != nil {
+		return err
+	}
+	encoded, err := h.encode(val)
+	if err != nil {
+		return err
+	}
+	if err := h.cache.Set(h.key, encoded, h.ttl); err != nil {
+		return err
+	}
+	decoded, err := h.decode(encoded)
+	if err != nil {
+		return err
+	}
+	h.current.Store(&holderValue{value: decoded})
+	return nil
+}
+
+// Close 停止后台刷新
+func (h *Holder) Close() {
+	close(h.stop)
+}
+
+// refreshRoutine 定期刷新资源包
+func (h *Holder) refreshRoutine(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.reload(); err != nil {
+				h.cache.logf("dancache: refresh holder %q failed: %v", h.key, err)
+			}
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// reload 从缓存读取资源包(未命中时加载)，成功后原子替换当前版本
+// 失败时保留当前版本
+func (h *Holder) reload() error {
+	val, err := h.cache.GetOrLoad(h.key, h.ttl, func() (interface{}, error) {
+		val, err := h.loader()
+		if err != nil {
+			return nil, err
+		}
+		return h.encode(val)
+	})
+	if err != nil {
+		return err
+	}
+	decoded, err := h.decode(val)
+	if err != nil {
+		return err
+	}
+	h.current.Store(&holderValue{value: decoded})
+	return nil
+}
+
+// encode 配置了newValue时将资源包编码为JSON字符串
+func (h *Holder) encode(val interface{}) (interface{}, error) {
+	if h.newVal == nil {
+		return val, nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// decode 配置了newValue时将JSON字符串解码为具体类型
+func (h *Holder) decode(val interface{}) (interface{}, error) {
+	if h.newVal == nil {
+		return val, nil
+	}
+	data, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("缓存的资源包类型错误: %T", val)
+	}
+	out := h.newVal()
+	if err := json.Unmarshal([]byte(data), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
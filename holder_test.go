@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type testBundle struct {
+	Locale   string            `json:"locale"`
+	Messages map[string]string `json:"messages"`
+}
+
+func TestHolderSharesBundleAcrossInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newRedisTestCache(t, mr, nil)
+	b := newRedisTestCache(t, mr, nil)
+
+	var loads int32
+	loader := func() (interface{}, error) {
+		n := atomic.AddInt32(&loads, 1)
+		return testBundle{Locale: "zh", Messages: map[string]string{"hello": "你好", "v": string(rune('0' + n))}}, nil
+	}
+	newBundle := func() interface{} { return &testBundle{} }
+
+	ha, err := NewHolder(a, "bundle:zh", 60, 0, loader, newBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ha.Close()
+	hb, err := NewHolder(b, "bundle:zh", 60, 0, loader, newBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hb.Close()
+
+	// 第二个实例从L2读取，不再调用loader，并解码为具体类型
+	if loads != 1 {
+		t.Errorf("loader called %d times, want 1", loads)
+	}
+	bundle, ok := hb.Get().(*testBundle)
+	if !ok || bundle.Messages["hello"] != "你好" || bundle.Messages["v"] != "1" {
+		t.Errorf("Get = %#v, want decoded bundle v1", hb.Get())
+	}
+
+	// Refresh立即替换当前版本并写入缓存
+	if err := ha.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ha.Get().(*testBundle).Messages["v"]; got != "2" {
+		t.Errorf("version after Refresh = %q, want 2", got)
+	}
+	if got := hb.Get().(*testBundle).Messages["v"]; got != "1" {
+		t.Errorf("other instance changed before its refresh: %q", got)
+	}
+}
+
+func TestHolderKeepsCurrentOnFailure(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	var fail atomic.Bool
+	var loads int32
+	loader := func() (interface{}, error) {
+		if fail.Load() {
+			return nil, errors.New("source down")
+		}
+		return atomic.AddInt32(&loads, 1), nil
+	}
+	h, err := NewHolder(c, "bundle", 60, 5*time.Millisecond, loader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.Get() != int32(1) {
+		t.Fatalf("Get = %v, want 1", h.Get())
+	}
+
+	// 后台刷新在缓存过期后重新加载；加载失败时保留当前版本
+	fail.Store(true)
+	if err := c.Delete("bundle"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Refresh(); err == nil {
+		t.Error("Refresh with a failing loader returned nil")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if h.Get() != int32(1) {
+		t.Errorf("Get after failed reloads = %v, want 1", h.Get())
+	}
+
+	fail.Store(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for h.Get() == int32(1) {
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not pick up the new bundle")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if h.Get() != int32(2) {
+		t.Errorf("Get after recovery = %v, want 2", h.Get())
+	}
+}
+
+func TestNewHolderFailsWithoutInitialValue(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	_, err := NewHolder(c, "bundle", 60, 0, func() (interface{}, error) {
+		return nil, errors.New("source down")
+	}, nil)
+	if err == nil {
+		t.Error("NewHolder with a failing loader returned nil error")
+	}
+}
@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cachedResponse 缓存的HTTP响应
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// CachingTransport 缓存GET响应的http.RoundTripper
+// 遵循Cache-Control/Expires确定新鲜期，带ETag或Last-Modified的响应过期后通过条件请求重新验证
+// 缓存存放在多级缓存中，因此可以在多个实例之间共享，不缓存标记为private的响应；
+// 按共享缓存的规则(RFC 9111 §3.5)，带Authorization的请求的响应只在标记为public、s-maxage或must-revalidate时缓存
+type CachingTransport struct {
+	Cache     *MultiLevelCache
+	Transport http.RoundTripper // 实际发送请求的Transport(为空时使用http.DefaultTransport)
+	KeyPrefix string            // 缓存键前缀(默认"http:")
+	StaleTTL  time.Duration     // 带校验器的响应在新鲜期后保留用于重新验证的时间(默认1小时)
+}
+
+// NewCachingTransport 创建新的缓存Transport
+func NewCachingTransport(cache *MultiLevelCache, transport http.RoundTripper) *CachingTransport {
+	return &CachingTransport{
+		Cache:     cache,
+		Transport: transport,
+	}
+}
+
+// RoundTrip 实现http.RoundTripper
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isCacheableRequest(req) {
+		return t.transport().RoundTrip(req)
+	}
+
+	key := t.cacheKey(req)
+	cached, state, found := t.load(key)
+	reqDirectives := parseCacheControl(req.Header.Get("Cache-Control"))
+	if found && state == Fresh && !reqDirectives.has("no-cache") {
+		return cached.toResponse(req), nil
+	}
+
+	// 有可用的校验器时发送条件请求
+	outReq := req
+	if found {
+		etag := cached.Header.Get("ETag")
+		lastModified := cached.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			outReq = req.Clone(req.Context())
+			if etag != "" {
+				outReq.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				outReq.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	resp, err := t.transport().RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重新验证成功，合并新的响应头并刷新新鲜期
+	if found && outReq != req && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		for name, values := range resp.Header {
+			cached.Header[name] = values
+		}
+		t.store(key, cached)
+		return cached.toResponse(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || !isStorableResponse(req, resp) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.store(key, &cachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	})
+	return resp, nil
+}
+
+// transport 返回实际发送请求的Transport
+func (t *CachingTransport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+// cacheKey 生成请求的缓存键
+func (t *CachingTransport) cacheKey(req *http.Request) string {
+	prefix := t.KeyPrefix
+	if prefix == "" {
+		prefix = "http:"
+	}
+	return prefix + req.URL.String()
+}
+
+// load 从缓存读取响应
+func (t *CachingTransport) load(key string) (*cachedResponse, FreshnessState, bool) {
+	val, state, found := t.Cache.GetWithFreshness(key)
+	if !found {
+		return nil, state, false
+	}
+	// 响应以JSON字符串缓存，保证经过L2后仍能还原
+	data, ok := val.(string)
+	if !ok {
+		return nil, state, false
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal([]byte(data), &cached); err != nil {
+		return nil, state, false
+	}
+	return &cached, state, true
+}
+
+// store 根据响应头计算新鲜度并写入缓存
+func (t *CachingTransport) store(key string, cached *cachedResponse) {
+	now := time.Now()
+	freshUntil, ok := freshnessLifetime(cached.Header, now)
+	hasValidator := cached.Header.Get("ETag") != "" || cached.Header.Get("Last-Modified") != ""
+	if !ok && !hasValidator {
+		return
+	}
+	if !ok {
+		freshUntil = now
+	}
+
+	staleUntil := freshUntil
+	if hasValidator {
+		staleTTL := t.StaleTTL
+		if staleTTL <= 0 {
+			staleTTL = time.Hour
+		}
+		staleUntil = freshUntil.Add(staleTTL)
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	t.Cache.SetWithFreshness(key, string(data), Freshness{
+		FreshUntil: freshUntil,
+		StaleUntil: staleUntil,
+	})
+}
+
+// toResponse 根据缓存内容构造响应
+func (cached *cachedResponse) toResponse(req *http.Request) *http.Response {
+	header := cached.Header.Clone()
+	header.Set("X-From-Cache", "1")
+	return &http.Response{
+		Status:        strconv.Itoa(cached.StatusCode) + " " + http.StatusText(cached.StatusCode),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
+
+// isCacheableRequest 判断请求是否可以使用缓存
+func isCacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return false
+	}
+	return !parseCacheControl(req.Header.Get("Cache-Control")).has("no-store")
+}
+
+// isStorableResponse 判断响应是否可以写入共享缓存
+func isStorableResponse(req *http.Request, resp *http.Response) bool {
+	cc := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if cc.has("no-store") || cc.has("private") {
+		return false
+	}
+	// 带凭据的请求的响应默认只属于该用户，源站明确允许时才能共享
+	if req.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return false
+	}
+	// 不支持按请求头区分的响应
+	for _, vary := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, "Accept-Encoding") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// freshnessLifetime 根据s-maxage、max-age或Expires计算新鲜截止时间
+// 响应在上游缓存中已经停留的时间(Age头)从新鲜期中扣除；Expires与Date同时存在时按两者之差计算新鲜期
+func freshnessLifetime(header http.Header, now time.Time) (time.Time, bool) {
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	if cc.has("no-cache") {
+		return now, true
+	}
+	age := responseAge(header)
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			seconds, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || seconds < 0 {
+				return now, true
+			}
+			return freshFor(now, time.Duration(seconds)*time.Second-age), true
+		}
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return now, true
+		}
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			return freshFor(now, t.Sub(date)-age), true
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// freshFor 返回剩余新鲜期为lifetime的截止时间，已经过期时返回now
+func freshFor(now time.Time, lifetime time.Duration) time.Time {
+	if lifetime <= 0 {
+		return now
+	}
+	return now.Add(lifetime)
+}
+
+// responseAge 解析Age头(秒)，缺失或无效时为0
+func responseAge(header http.Header) time.Duration {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(header.Get("Age")), 10, 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// cacheControl 解析后的Cache-Control指令
+type cacheControl map[string]string
+
+// has 判断是否包含指定指令
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// parseCacheControl 解析Cache-Control头
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(val), `"`)
+	}
+	return cc
+}
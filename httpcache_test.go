@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestTransport 创建缓存到L1的HTTP客户端，源站按header设置响应头并统计请求次数
+func newTestTransport(t *testing.T, header http.Header) (*http.Client, string, *int32) {
+	t.Helper()
+	var hits int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.Write([]byte("body"))
+	}))
+	t.Cleanup(origin.Close)
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return &http.Client{Transport: NewCachingTransport(c, http.DefaultTransport)}, origin.URL, &hits
+}
+
+func fetch(t *testing.T, client *http.Client, url, authorization string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestCachingTransportAuthorization(t *testing.T) {
+	for _, tc := range []struct {
+		cacheControl string
+		wantHits     int32
+	}{
+		{"max-age=60", 2},
+		{"public, max-age=60", 1},
+		{"s-maxage=60", 1},
+		{"max-age=60, must-revalidate", 1},
+	} {
+		t.Run(tc.cacheControl, func(t *testing.T) {
+			client, url, hits := newTestTransport(t, http.Header{"Cache-Control": {tc.cacheControl}})
+			fetch(t, client, url, "Bearer alice")
+			fetch(t, client, url, "Bearer alice")
+			if n := atomic.LoadInt32(hits); n != tc.wantHits {
+				t.Errorf("origin hits = %d, want %d", n, tc.wantHits)
+			}
+		})
+	}
+
+	// 不带凭据的请求不受影响
+	client, url, hits := newTestTransport(t, http.Header{"Cache-Control": {"max-age=60"}})
+	fetch(t, client, url, "")
+	fetch(t, client, url, "")
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("origin hits without Authorization = %d, want 1", n)
+	}
+}
+
+func TestCachingTransportSubtractsAge(t *testing.T) {
+	client, url, hits := newTestTransport(t, http.Header{"Cache-Control": {"max-age=60"}, "Age": {"60"}})
+	fetch(t, client, url, "")
+	fetch(t, client, url, "")
+	if n := atomic.LoadInt32(hits); n != 2 {
+		t.Errorf("origin hits for a response whose Age equals max-age = %d, want 2", n)
+	}
+
+	now := time.Now()
+	for _, tc := range []struct {
+		header http.Header
+		want   time.Duration
+	}{
+		{http.Header{"Cache-Control": {"max-age=60"}, "Age": {"50"}}, 10 * time.Second},
+		{http.Header{"Cache-Control": {"s-maxage=60, max-age=600"}, "Age": {"20"}}, 40 * time.Second},
+		{http.Header{"Cache-Control": {"max-age=60"}, "Age": {"90"}}, 0},
+		{http.Header{"Cache-Control": {"max-age=60"}, "Age": {"bogus"}}, time.Minute},
+		{http.Header{
+			"Date":    {now.UTC().Format(http.TimeFormat)},
+			"Expires": {now.Add(time.Minute).UTC().Format(http.TimeFormat)},
+			"Age":     {"30"},
+		}, 30 * time.Second},
+	} {
+		freshUntil, ok := freshnessLifetime(tc.header, now)
+		if !ok {
+			t.Errorf("freshnessLifetime(%v) reported no freshness information", tc.header)
+			continue
+		}
+		if got := freshUntil.Sub(now); got < tc.want-time.Second || got > tc.want+time.Second {
+			t.Errorf("freshnessLifetime(%v) = now+%v, want now+%v", tc.header, got, tc.want)
+		}
+	}
+}
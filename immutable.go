@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+)
+
+// CopyPolicy 写入和读取时复制值的方式
+// L1中保存的是值本身，不复制时调用方修改写入的值或读取到的值都会直接修改缓存
+type CopyPolicy int
+
+const (
+	// CopyNone 不复制，调用方需保证不修改写入和读取到的值(默认)
+	CopyNone CopyPolicy = iota
+	// CopyDeep 通过反射深拷贝指针、map、切片和结构体的导出字段
+	CopyDeep
+	// CopyCodec 通过JSON编码再解码为同一类型复制，未导出字段和JSON忽略的字段会丢失
+	CopyCodec
+)
+
+// cloneValue 按CloneFunc或CopyPolicy复制值，不可变类型直接返回
+func (c *MultiLevelCache) cloneValue(v interface{}) interface{} {
+	config := c.config()
+	if !isMutable(v) {
+		return v
+	}
+	if config.CloneFunc != nil {
+		cloned := v
+		if err := c.protect("CloneFunc", func() { cloned = config.CloneFunc(v) }); err != nil {
+			return deepCopy(v)
+		}
+		return cloned
+	}
+	switch config.CopyPolicy {
+	case CopyDeep:
+		return deepCopy(v)
+	case CopyCodec:
+		return codecCopy(v)
+	}
+	return v
+}
+
+// copyEnabled 判断是否配置了复制
+func (c *MultiLevelCache) copyEnabled() bool {
+	config := c.config()
+	return config.CloneFunc != nil || config.CopyPolicy != CopyNone
+}
+
+// cloneOnWrite 写入时复制值，调用方之后修改自己的值不会影响缓存
+func (c *MultiLevelCache) cloneOnWrite(v interface{}) interface{} {
+	if !c.copyEnabled() {
+		return v
+	}
+	return c.cloneValue(v)
+}
+
+// copyValue 为另一个键复制值，之后修改其中一个键的值不会影响另一个
+// 配置了复制时按CloneFunc或CopyPolicy复制，否则可变的值深拷贝
+func (c *MultiLevelCache) copyValue(v interface{}) interface{} {
+	if c.copyEnabled() {
+		return c.cloneValue(v)
+	}
+	if isMutable(v) {
+		return deepCopy(v)
+	}
+	return v
+}
+
+// readValue 返回缓存项的值
+// 配置了复制时返回副本；未配置但值与其他键共享时返回深拷贝，调用方修改返回值不会影响其他键
+func (c *MultiLevelCache) readValue(item *CacheItem) interface{} {
+	if c.copyEnabled() {
+		return c.cloneValue(item.Value)
+	}
+	if item.internKey != "" {
+		return deepCopy(item.Value)
+	}
+	return item.Value
+}
+
+// codecCopy 通过JSON编码再解码为同一类型复制值，失败时退回深拷贝
+func codecCopy(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return deepCopy(v)
+	}
+	p := reflect.New(reflect.TypeOf(v))
+	if err := json.Unmarshal(data, p.Interface()); err != nil {
+		return deepCopy(v)
+	}
+	return p.Elem().Interface()
+}
+
+// valueChecksum 返回值的规范JSON编码的哈希，无法编码时返回空字符串
+func valueChecksum(v interface{}) string {
+	data, err := CanonicalJSON(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return string(sum[:])
+}
+
+// sealItem VerifyImmutable时记录写入L1的值的内容哈希
+func (c *MultiLevelCache) sealItem(item *CacheItem) {
+	if !c.config().VerifyImmutable || !isMutable(item.Value) {
+		item.checksum = ""
+		return
+	}
+	item.checksum = valueChecksum(item.Value)
+}
+
+// unmutated 判断L1中的值是否未被修改，被修改时记录日志，调用方按未命中处理并删除该项
+func (c *MultiLevelCache) unmutated(key string, item *CacheItem) bool {
+	if item.checksum == "" || !c.config().VerifyImmutable {
+		return true
+	}
+	if valueChecksum(item.Value) == item.checksum {
+		return true
+	}
+	atomic.AddInt64(&c.mutatedValues, 1)
+	c.logf("dancache: cached value of %q was mutated by the caller, dropping it from L1", key)
+	return false
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"testing"
+)
+
+// copyTarget 复制策略测试用的值，unexported不参与JSON编码
+type copyTarget struct {
+	Tags       []string
+	unexported int
+}
+
+func TestCopyPolicies(t *testing.T) {
+	for _, policy := range []CopyPolicy{CopyDeep, CopyCodec} {
+		c := newL1TestCache(t, func(config *CacheConfig) {
+			config.CopyPolicy = policy
+		})
+		written := &copyTarget{Tags: []string{"a"}, unexported: 1}
+		if err := c.Set("k", written, 60); err != nil {
+			t.Fatal(err)
+		}
+		// 写入和读取都复制，修改任一方的值不影响缓存
+		written.Tags[0] = "written"
+		got, _ := c.Get("k")
+		got.(*copyTarget).Tags[0] = "read"
+		again, _ := c.Get("k")
+		if tags := again.(*copyTarget).Tags; tags[0] != "a" {
+			t.Errorf("policy %d: cached Tags = %v, want [a]", policy, tags)
+		}
+		// JSON复制丢失未导出字段，深拷贝按值保留
+		want := 1
+		if policy == CopyCodec {
+			want = 0
+		}
+		if n := again.(*copyTarget).unexported; n != want {
+			t.Errorf("policy %d: unexported = %d, want %d", policy, n, want)
+		}
+	}
+}
+
+func TestVerifyImmutableDropsMutatedValues(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.VerifyImmutable = true
+	})
+	if err := c.Set("k", map[string]int{"n": 1}, 60); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := c.Get("k")
+	got.(map[string]int)["n"] = 2
+
+	if v, ok := c.Get("k"); ok {
+		t.Errorf("Get = %v after the caller mutated the cached value, want miss", v)
+	}
+	if n := c.GetStats()["l1_mutated_values"]; n != int64(1) {
+		t.Errorf("l1_mutated_values = %v, want 1", n)
+	}
+}
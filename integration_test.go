@@ -0,0 +1,240 @@
+//go:build integration
+
+package cache
+
+// 针对真实服务端的兼容性测试，按环境变量中的地址分别运行：
+//
+//	DANCACHE_REDIS_ADDR=localhost:6379 DANCACHE_VALKEY_ADDR=localhost:6380 \
+//	DANCACHE_KEYDB_ADDR=localhost:6381 DANCACHE_DRAGONFLY_ADDR=localhost:6382 \
+//	go test -tags integration -run Integration ./...
+//
+// 未设置地址的服务端跳过；测试只使用带本次运行前缀的键，不清空数据库
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// integrationServers 服务端类型和对应的地址环境变量
+var integrationServers = []struct {
+	flavor string
+	env    string
+}{
+	{ServerRedis, "DANCACHE_REDIS_ADDR"},
+	{ServerValkey, "DANCACHE_VALKEY_ADDR"},
+	{ServerKeyDB, "DANCACHE_KEYDB_ADDR"},
+	{ServerDragonfly, "DANCACHE_DRAGONFLY_ADDR"},
+}
+
+// forEachServer 对每个配置了地址的服务端运行fn，prefix为本次运行使用的键前缀
+func forEachServer(t *testing.T, fn func(t *testing.T, flavor, addr, prefix string)) {
+	ran := false
+	for _, server := range integrationServers {
+		addr := os.Getenv(server.env)
+		if addr == "" {
+			continue
+		}
+		ran = true
+		t.Run(server.flavor, func(t *testing.T) {
+			prefix := fmt.Sprintf("dancache-it:%d:", time.Now().UnixNano())
+			t.Cleanup(func() { cleanupPrefix(t, addr, prefix) })
+			fn(t, server.flavor, addr, prefix)
+		})
+	}
+	if !ran {
+		t.Skip("未设置任何DANCACHE_*_ADDR环境变量")
+	}
+}
+
+// cleanupPrefix 删除测试写入的键和对应的写入序号
+func cleanupPrefix(t *testing.T, addr, prefix string) {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+	ctx := context.Background()
+	for _, pattern := range []string{prefix + "*", sequenceKeyPrefix + "*" + prefix + "*", tagKeyPrefix + prefix + "*"} {
+		iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			client.Del(ctx, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			t.Logf("cleanup %q: %v", pattern, err)
+		}
+	}
+}
+
+// newIntegrationCache 创建启用CompatibilityMode和写入序号的缓存
+func newIntegrationCache(t *testing.T, addr string) *MultiLevelCache {
+	t.Helper()
+	c, err := NewMultiLevelCache(CacheConfig{
+		EnableL1Cache:     true,
+		MaxL1Size:         100,
+		EnableL2Cache:     true,
+		RedisOptions:      &redis.Options{Addr: addr},
+		CompatibilityMode: true,
+		SequencedWrites:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewMultiLevelCache(%s): %v", addr, err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestIntegrationDetectServer(t *testing.T) {
+	forEachServer(t, func(t *testing.T, flavor, addr, _ string) {
+		c := newIntegrationCache(t, addr)
+		info, ok := c.Server()
+		if !ok {
+			t.Fatal("server was not detected")
+		}
+		if info.Flavor != flavor {
+			t.Errorf("Flavor = %q, want %q", info.Flavor, flavor)
+		}
+		t.Logf("%s %s: COPY=%v keyspace events=%v", info.Flavor, info.Version, info.Copy, info.KeyspaceEvents)
+	})
+}
+
+func TestIntegrationSetGetAcrossInstances(t *testing.T) {
+	forEachServer(t, func(t *testing.T, _, addr, prefix string) {
+		c, other := newIntegrationCache(t, addr), newIntegrationCache(t, addr)
+		key := prefix + "user:1"
+		if err := c.Set(key, "alice", 60); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := other.Get(key); !ok || v != "alice" {
+			t.Errorf("Get from another instance = %v, %v; want alice, true", v, ok)
+		}
+		if err := other.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := other.Get(key); ok {
+			t.Error("key still readable after Delete")
+		}
+	})
+}
+
+func TestIntegrationSequencedWrites(t *testing.T) {
+	forEachServer(t, func(t *testing.T, _, addr, prefix string) {
+		c := newIntegrationCache(t, addr)
+		key := prefix + "seq"
+		seq, err := c.Sequence(key)
+		if err != nil {
+			t.Fatalf("Sequence: %v", err)
+		}
+		if err := c.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Set(key, "stale", 60, WithSequence(seq)); !errors.Is(err, ErrSuperseded) {
+			t.Errorf("Set with a sequence reserved before Delete = %v, want ErrSuperseded", err)
+		}
+		if err := c.SetMulti(map[string]ItemOptions{key: {Value: "v", TTL: 60}}); err != nil {
+			t.Errorf("SetMulti: %v", err)
+		}
+	})
+}
+
+func TestIntegrationCopyAndRename(t *testing.T) {
+	forEachServer(t, func(t *testing.T, _, addr, prefix string) {
+		c, other := newIntegrationCache(t, addr), newIntegrationCache(t, addr)
+		src, dst, moved := prefix+"{k}src", prefix+"{k}dst", prefix+"{k}moved"
+		if err := c.Set(src, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := c.Copy(src, dst); err != nil || !found {
+			t.Fatalf("Copy = %v, %v; want true", found, err)
+		}
+		if v, ok := other.Get(dst); !ok || v != "v" {
+			t.Errorf("copied value from another instance = %v, %v; want v, true", v, ok)
+		}
+		if found, err := c.Rename(dst, moved); err != nil || !found {
+			t.Fatalf("Rename = %v, %v; want true", found, err)
+		}
+		if v, ok := other.Get(moved); !ok || v != "v" {
+			t.Errorf("renamed value from another instance = %v, %v; want v, true", v, ok)
+		}
+	})
+}
+
+func TestIntegrationTagsAndPrefix(t *testing.T) {
+	forEachServer(t, func(t *testing.T, _, addr, prefix string) {
+		c, other := newIntegrationCache(t, addr), newIntegrationCache(t, addr)
+		tag := prefix + "tag"
+		for i := 0; i < 3; i++ {
+			if err := c.SetWithTags(fmt.Sprintf("%stagged:%d", prefix, i), i, 60, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := c.InvalidateTags(tag); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, ok := other.Get(fmt.Sprintf("%stagged:%d", prefix, i)); ok {
+				t.Errorf("tagged key %d survived InvalidateTags", i)
+			}
+		}
+
+		if err := c.Set(prefix+"scan:a", "a", 60); err != nil {
+			t.Fatal(err)
+		}
+		n, err := c.InvalidatePrefix(context.Background(), prefix+"scan:")
+		if err != nil || n != 1 {
+			t.Errorf("InvalidatePrefix = %d, %v; want 1", n, err)
+		}
+	})
+}
+
+func TestIntegrationClientTracking(t *testing.T) {
+	forEachServer(t, func(t *testing.T, flavor, addr, prefix string) {
+		if flavor != ServerRedis && flavor != ServerValkey {
+			t.Skipf("%s不支持CLIENT TRACKING REDIRECT", flavor)
+		}
+		tracked, err := NewMultiLevelCache(CacheConfig{
+			EnableL1Cache:     true,
+			MaxL1Size:         100,
+			EnableL2Cache:     true,
+			RedisOptions:      &redis.Options{Addr: addr},
+			ClientTracking:    true,
+			PromotionStrategy: NewFrequencyBasedStrategy(1, 60, 0),
+		})
+		if err != nil {
+			t.Fatalf("NewMultiLevelCache with ClientTracking: %v", err)
+		}
+		t.Cleanup(func() { tracked.Close() })
+		other := newIntegrationCache(t, addr)
+
+		key := prefix + "tracked"
+		if err := other.Set(key, "old", 60); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := tracked.Get(key); !ok || v != "old" {
+			t.Fatalf("Get = %v, %v; want old", v, ok)
+		}
+		if _, ok := tracked.shardFor(key).load(key); !ok {
+			t.Fatal("read value was not promoted to L1")
+		}
+
+		// 其他实例修改后Redis通知本实例删除L1中的项
+		if err := other.Set(key, "new", 60); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if _, ok := tracked.shardFor(key).load(key); !ok {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("L1 entry survived a write from another instance")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if v, ok := tracked.Get(key); !ok || v != "new" {
+			t.Errorf("Get after invalidation = %v, %v; want new", v, ok)
+		}
+	})
+}
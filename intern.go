@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultInternMinSize 参与去重的值编码后的默认最小字节数
+const defaultInternMinSize = 64
+
+// internTable L1中内容相同的值共享的对象，按规范JSON编码的SHA-256索引
+type internTable struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	hits   int64
+}
+
+// newInternTable 创建去重表
+func newInternTable() *internTable {
+	return &internTable{values: make(map[string]interface{})}
+}
+
+// internItem 写入L1前将值替换为内容相同的共享对象
+// 第一次出现的值保存一份深拷贝作为共享对象，调用方之后修改自己的值不会影响缓存
+func (c *MultiLevelCache) internItem(item *CacheItem) {
+	if c.interned == nil || !isMutable(item.Value) {
+		return
+	}
+	data, err := CanonicalJSON(item.Value)
+	if err != nil {
+		return
+	}
+	minSize := c.config().InternMinSize
+	if minSize <= 0 {
+		minSize = defaultInternMinSize
+	}
+	if len(data) < minSize {
+		return
+	}
+	sum := sha256.Sum256(data)
+	h := string(sum[:])
+
+	t := c.interned
+	t.mu.Lock()
+	v, ok := t.values[h]
+	if !ok {
+		v = deepCopy(item.Value)
+		t.values[h] = v
+	}
+	t.mu.Unlock()
+	if ok {
+		atomic.AddInt64(&t.hits, 1)
+	}
+	item.Value = v
+	item.internKey = h
+}
+
+// pruneInterned 删除L1中已经没有键引用的共享对象
+func (c *MultiLevelCache) pruneInterned() {
+	if c.interned == nil {
+		return
+	}
+	used := make(map[string]struct{})
+	c.rangeL1(func(key string, item *CacheItem) bool {
+		if item.internKey != "" {
+			used[item.internKey] = struct{}{}
+		}
+		return true
+	})
+	t := c.interned
+	t.mu.Lock()
+	for h := range t.values {
+		if _, ok := used[h]; !ok {
+			delete(t.values, h)
+		}
+	}
+	t.mu.Unlock()
+}
+
+// internStatsMap 返回去重统计
+func (c *MultiLevelCache) internStatsMap() map[string]interface{} {
+	t := c.interned
+	t.mu.Lock()
+	n := len(t.values)
+	t.mu.Unlock()
+	return map[string]interface{}{
+		"l1_interned_values": n,
+		"l1_intern_hits":     atomic.LoadInt64(&t.hits),
+	}
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInternValuesSharesEqualValues(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.InternValues = true
+	})
+	value := func() map[string]string {
+		return map[string]string{"description": strings.Repeat("x", 100)}
+	}
+	original := value()
+	if err := c.Set("a", original, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("b", value(), 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("small", map[string]string{"n": "1"}, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := c.shardFor("a").load("a")
+	b, _ := c.shardFor("b").load("b")
+	if a.internKey == "" || a.internKey != b.internKey {
+		t.Fatal("equal values were not interned")
+	}
+	if small, _ := c.shardFor("small").load("small"); small.internKey != "" {
+		t.Error("value below InternMinSize was interned")
+	}
+	stats := c.GetStats()
+	if stats["l1_interned_values"] != 1 || stats["l1_intern_hits"] != int64(1) {
+		t.Errorf("intern stats = %v, %v; want 1, 1", stats["l1_interned_values"], stats["l1_intern_hits"])
+	}
+
+	// 写入后修改自己的值、修改读取到的值都不影响共享对象
+	original["description"] = "changed"
+	got, _ := c.Get("a")
+	got.(map[string]string)["description"] = "changed"
+	if v, _ := c.Get("b"); v.(map[string]string)["description"] == "changed" {
+		t.Error("modifying one key's value changed another key sharing it")
+	}
+
+	// 没有键引用的共享对象在清理时删除
+	c.Delete("a")
+	c.Delete("b")
+	c.pruneInterned()
+	if n := c.GetStats()["l1_interned_values"]; n != 0 {
+		t.Errorf("l1_interned_values after deleting both keys = %v, want 0", n)
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// InvalidationEvent 实体变更事件，指定需要失效的键和标签
+type InvalidationEvent struct {
+	Keys []string `json:"keys,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// PublishedAt 发布时间(Unix纳秒)，用于统计传播延迟；OutboxPublisher在为0时填入当前时间，
+	// 自定义Decode可以填入CDC记录的提交时间以统计从数据库提交到缓存失效的总延迟
+	PublishedAt int64 `json:"published_at,omitempty"`
+}
+
+// InvalidationSource 失效事件来源，如Kafka主题或NATS主题的订阅
+type InvalidationSource interface {
+	// Next 阻塞直到收到下一条消息或ctx结束
+	Next(ctx context.Context) ([]byte, error)
+}
+
+// InvalidationConsumer 消费实体变更事件并转换为键/标签失效，使缓存与源数据库保持一致
+type InvalidationConsumer struct {
+	cache  *MultiLevelCache
+	source InvalidationSource
+
+	// Decode 将消息解码为失效事件(默认按JSON格式的InvalidationEvent解码)
+	// 可以替换为将CDC记录映射到缓存键的函数，返回nil表示忽略该消息
+	Decode func(msg []byte) (*InvalidationEvent, error)
+	// RetryInterval 读取消息失败后的重试间隔(默认1秒)
+	RetryInterval time.Duration
+	// Name 统计传播延迟时的传输名称，如"kafka"或"nats"(默认"consumer")
+	Name string
+}
+
+// NewInvalidationConsumer 创建新的失效事件消费者
+func NewInvalidationConsumer(cache *MultiLevelCache, source InvalidationSource) *InvalidationConsumer {
+	return &InvalidationConsumer{
+		cache:  cache,
+		source: source,
+	}
+}
+
+// Run 持续消费失效事件直到ctx结束
+func (ic *InvalidationConsumer) Run(ctx context.Context) error {
+	retry := ic.RetryInterval
+	if retry <= 0 {
+		retry = time.Second
+	}
+
+	for {
+		msg, err := ic.source.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ic.cache.logf("dancache: receive invalidation failed: %v", err)
+			select {
+			case <-time.After(retry):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		event, err := ic.decode(msg)
+		if err != nil {
+			ic.cache.logf("dancache: decode invalidation failed: %v", err)
+			continue
+		}
+		if event == nil {
+			continue
+		}
+		if err := ic.cache.applyInvalidation(event); err != nil {
+			ic.cache.logf("dancache: apply invalidation failed: %v", err)
+			continue
+		}
+		ic.cache.observeLag(ic.transport(), "", event.PublishedAt)
+	}
+}
+
+// transport 返回统计传播延迟时的传输名称
+func (ic *InvalidationConsumer) transport() string {
+	if ic.Name != "" {
+		return ic.Name
+	}
+	return TransportConsumer
+}
+
+// decode 解码消息，自定义的Decode发生panic时返回*PanicError
+func (ic *InvalidationConsumer) decode(msg []byte) (event *InvalidationEvent, err error) {
+	if ic.Decode != nil {
+		if perr := ic.cache.protect("InvalidationConsumer.Decode", func() { event, err = ic.Decode(msg) }); perr != nil {
+			return nil, perr
+		}
+		return event, err
+	}
+	event = &InvalidationEvent{}
+	if err := json.Unmarshal(msg, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// applyInvalidation 执行失效事件中的键和标签失效
+func (c *MultiLevelCache) applyInvalidation(event *InvalidationEvent) error {
+	for _, key := range event.Keys {
+		if err := c.Delete(key); err != nil {
+			return err
+		}
+	}
+	if len(event.Tags) > 0 {
+		return c.InvalidateTags(event.Tags...)
+	}
+	return nil
+}
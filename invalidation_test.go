@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// chanSource 从通道读取消息的失效事件来源，通道中的error作为读取错误返回
+type chanSource chan interface{}
+
+func (s chanSource) Next(ctx context.Context) ([]byte, error) {
+	select {
+	case msg := <-s:
+		if err, ok := msg.(error); ok {
+			return nil, err
+		}
+		return msg.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runConsumer 在后台运行消费者，返回停止并等待其退出的函数
+func runConsumer(t *testing.T, ic *InvalidationConsumer) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ic.Run(ctx) }()
+	return func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	}
+}
+
+// waitMissing 等待键被失效
+func waitMissing(t *testing.T, c *MultiLevelCache, key string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, found := c.Get(key); !found {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("key %q was not invalidated", key)
+}
+
+func TestInvalidationConsumerAppliesEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	if err := c.Set("user:1", "alice", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetWithTags("post:1", "hello", 60, "user:1:posts"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("user:2", "bob", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	source := make(chanSource, 4)
+	ic := NewInvalidationConsumer(c, source)
+	ic.RetryInterval = time.Millisecond
+	stop := runConsumer(t, ic)
+	defer stop()
+
+	// 读取错误和无法解码的消息都不会中断消费
+	source <- errors.New("broker unavailable")
+	source <- []byte("not json")
+	source <- []byte(`{"keys":["user:1"],"tags":["user:1:posts"]}`)
+	waitMissing(t, c, "user:1")
+	waitMissing(t, c, "post:1")
+	if _, found := c.Get("user:2"); !found {
+		t.Error("unrelated key was invalidated")
+	}
+}
+
+func TestInvalidationConsumerCustomDecode(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	for _, key := range []string{"a", "b"} {
+		if err := c.Set(key, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := make(chanSource, 3)
+	ic := NewInvalidationConsumer(c, source)
+	ic.Decode = func(msg []byte) (*InvalidationEvent, error) {
+		switch string(msg) {
+		case "panic":
+			panic("bad record")
+		case "ignore":
+			return nil, nil
+		}
+		return &InvalidationEvent{Keys: []string{string(msg)}}, nil
+	}
+	stop := runConsumer(t, ic)
+	defer stop()
+
+	// Decode发生panic或返回nil时跳过该消息
+	source <- []byte("panic")
+	source <- []byte("ignore")
+	source <- []byte("b")
+	waitMissing(t, c, "b")
+	if _, found := c.Get("a"); !found {
+		t.Error("key a was invalidated")
+	}
+}
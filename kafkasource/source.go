@@ -0,0 +1,53 @@
+// Package kafkasource 将Kafka消费者适配为缓存失效事件来源
+package kafkasource
+
+import (
+	"context"
+
+	cache "github.com/losanming/DanCache"
+	"github.com/segmentio/kafka-go"
+)
+
+// Source 基于kafka.Reader的失效事件来源
+// 配置了GroupID的Reader在读取后自动提交位移
+type Source struct {
+	reader *kafka.Reader
+}
+
+var _ cache.InvalidationSource = (*Source)(nil)
+
+// New 创建失效事件来源
+func New(reader *kafka.Reader) *Source {
+	return &Source{reader: reader}
+}
+
+// Next 阻塞直到收到下一条消息或ctx结束
+func (s *Source) Next(ctx context.Context) ([]byte, error) {
+	msg, err := s.reader.ReadMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Value, nil
+}
+
+// Close 关闭Reader
+func (s *Source) Close() error {
+	return s.reader.Close()
+}
+
+// Publisher 通过Kafka发布失效事件，可配合cache.OutboxPublisher使用
+type Publisher struct {
+	writer *kafka.Writer
+}
+
+var _ cache.InvalidationPublisher = (*Publisher)(nil)
+
+// NewPublisher 创建发布器，writer需要配置Topic
+func NewPublisher(writer *kafka.Writer) *Publisher {
+	return &Publisher{writer: writer}
+}
+
+// Publish 同步写入一条消息
+func (p *Publisher) Publish(ctx context.Context, msg []byte) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{Value: msg})
+}
@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Copy 将源键的值和剩余TTL复制到目标键，目标键已存在时覆盖，返回源键是否存在
+// L2通过Redis COPY(需要Redis 6.2+)在服务端复制，不经过应用重新序列化，CompatibilityMode检测到不支持时改为读取后写入；
+// L1中的源项复制一份写入目标键，值按CloneFunc/CopyPolicy复制(未配置时深拷贝可变的值)，两个键不共享同一个值
+// 配置了Encryption时值与键绑定，改为读取后用目标键重新加密写入
+// 源键关联的标签不会复制到目标键；启用RedisProxyMode时不支持，返回ErrProxyUnsupported
+// 启用SequencedWrites时复制递增目标键的写入序号，之前登记的目标键写入不会覆盖复制的值
+func (c *MultiLevelCache) Copy(srcKey, dstKey string) (bool, error) {
+	if !c.enter() {
+		return false, ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return false, err
+	}
+	src, err := c.normalizeKey(srcKey)
+	if err != nil {
+		return false, err
+	}
+	dst, err := c.normalizeKey(dstKey)
+	if err != nil {
+		return false, err
+	}
+	if src == dst {
+		return false, errors.New("源键和目标键相同")
+	}
+	if c.hasTombstone(dst) {
+		return false, ErrTombstoned
+	}
+
+	config := c.config()
+	if config.EnableL2Cache && config.RedisProxyMode {
+		return false, ErrProxyUnsupported
+	}
+	found := false
+
+	// 复制L2，等待合并的源键写入先落到Redis，目标键等待合并的旧值作废
+	if config.EnableL2Cache {
+		if c.coalescer != nil {
+			c.flushL2Write(src)
+		}
+		c.cancelL2Write(dst)
+		copied, err := c.copyL2(src, dst)
+		if err != nil {
+			return false, err
+		}
+		found = copied
+	}
+
+	// 复制L1，源键不在L1中时删除目标键的旧值，下次读取从L2获取
+	if config.EnableL1Cache {
+		if item, ok := c.shardFor(src).load(src); ok && item.validInL1(c.nowUnix()) {
+			copied := item.clone()
+			copied.Value = c.copyValue(item.Value)
+			if found || !config.EnableL2Cache {
+				copied.markL2Synced()
+			}
+			c.storeL1(dst, copied)
+			found = true
+		} else {
+			c.deleteL1(dst)
+		}
+	}
+
+	return found, nil
+}
+
+// clone 复制缓存项的值和元数据，不复制内部的同步状态
+func (item *CacheItem) clone() *CacheItem {
+	return &CacheItem{
+		Value:       item.Value,
+		ExpireTime:  item.ExpireTime,
+		CreateTime:  item.CreateTime,
+		AccessTime:  item.AccessTime,
+		AccessCount: item.AccessCount,
+		FreshUntil:  item.FreshUntil,
+		Version:     item.Version,
+	}
+}
+
+// copyL2 在L2中复制键，服务端不支持COPY或配置了Encryption时读取值和剩余TTL后写入目标键(非原子)
+// 加密的值与键绑定，需要用源键解密后按目标键重新加密
+func (c *MultiLevelCache) copyL2(src, dst string) (bool, error) {
+	sequenced := c.sequenced()
+	encrypted := c.config().Encryption != nil
+	if c.serverCopy() && !encrypted {
+		if sequenced {
+			n, err := sequencedCopy.Run(c.ctx, c.l2(), []string{c.sequenceKey(dst), src, dst}, c.sequenceTTL(0)).Int64()
+			return n > 0, err
+		}
+		n, err := c.l2().Copy(c.ctx, src, dst, c.redisDB(), true).Result()
+		return n > 0, err
+	}
+	// 读取源键之前为目标键登记序号，读取期间目标键的写入或删除胜出
+	var seq uint64
+	if sequenced {
+		var err error
+		if seq, err = c.reserveSequence(c.ctx, dst); err != nil {
+			return false, err
+		}
+	}
+	data, err := c.l2().Get(c.ctx, src).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ttl, err := c.l2().PTTL(c.ctx, src).Result()
+	if err != nil {
+		return false, err
+	}
+	if ttl < 0 {
+		ttl = 0 // 没有过期时间
+	}
+	if encrypted {
+		if data, err = c.reencode(src, dst, data); err != nil {
+			return false, err
+		}
+	}
+	if sequenced {
+		written, err := sequencedSet.Run(c.ctx, c.l2(), []string{c.sequenceKey(dst), dst},
+			seq, data, ttl.Milliseconds(), c.sequenceTTL(ttl)).Int64()
+		if err == nil && written == 0 {
+			atomic.AddInt64(&c.supersededWrites, 1)
+		}
+		return true, err
+	}
+	return true, c.l2().Set(c.ctx, dst, data, ttl).Err()
+}
+
+// Rename 将键重命名为新键，保留值、剩余TTL和访问信息，新键已存在时覆盖，返回旧键是否存在
+// L2通过Redis RENAME移动(配置了Encryption时按新键重新加密写入后删除旧键)，L1中的项直接移动到新键；旧键随后进入墓碑窗口，阻止进行中的加载回填
+// 配置了Encryption且新键使用不同的密钥时，L1中的项按新键重新编码，新键无法加密时不保留在L1
+// 旧键关联的标签不会转移到新键；启用SequencedWrites时递增两个键的写入序号，之前登记的写入都不再生效
+func (c *MultiLevelCache) Rename(oldKey, newKey string) (bool, error) {
+	if !c.enter() {
+		return false, ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return false, err
+	}
+	from, err := c.normalizeKey(oldKey)
+	if err != nil {
+		return false, err
+	}
+	to, err := c.normalizeKey(newKey)
+	if err != nil {
+		return false, err
+	}
+	if from == to {
+		return false, errors.New("旧键和新键相同")
+	}
+	if c.hasTombstone(to) {
+		return false, ErrTombstoned
+	}
+
+	config := c.config()
+	if config.EnableL2Cache && config.RedisProxyMode {
+		return false, ErrProxyUnsupported
+	}
+	found := false
+
+	// 移动L2，等待合并的旧键写入先落到Redis
+	if config.EnableL2Cache {
+		if c.coalescer != nil {
+			c.flushL2Write(from)
+		}
+		c.cancelL2Write(to)
+		renamed, err := c.renameL2(from, to)
+		if err != nil {
+			return false, err
+		}
+		found = renamed
+	}
+
+	// 移动L1，旧键不在L1中时删除新键的旧值，下次读取从L2获取
+	if config.EnableL1Cache {
+		shard := c.shardFor(from)
+		if item, ok := shard.load(from); ok && item.validInL1(c.nowUnix()) && shard.removeIf(from, item) {
+			found = true
+			switch {
+			case !c.sameEncryptionKey(from, to):
+				// 新键使用不同的密钥，按新键重新编码，与其他实例从L2读取到的项一致
+				item = c.reencodeL1(to, item)
+			case c.sequenced():
+				// 项的写入序号属于旧键，移动后按序号未知处理，不再回写到新键
+				moved := item.clone()
+				if item.isL2Synced() {
+					moved.markL2Synced()
+				}
+				item = moved
+			}
+			if item != nil {
+				c.storeL1(to, item)
+			} else {
+				c.deleteL1(to)
+			}
+		} else {
+			c.deleteL1(to)
+		}
+	}
+
+	c.addTombstone(from)
+	return found, nil
+}
+
+// reencodeL1 按key编码L1中的项再解码，得到与从L2读取key相同的新项；无法编码或解码时返回nil
+// 新项不带写入序号，按序号未知处理
+func (c *MultiLevelCache) reencodeL1(key string, item *CacheItem) *CacheItem {
+	data, err := c.encodeItem(key, item)
+	if err != nil {
+		c.logf("dancache: re-encode %q for rename failed: %v", key, err)
+		return nil
+	}
+	moved := &CacheItem{}
+	if err := c.decodeItem(key, data, moved); err != nil {
+		c.logf("dancache: re-encode %q for rename failed: %v", key, err)
+		return nil
+	}
+	if item.isL2Synced() {
+		moved.markL2Synced()
+	}
+	return moved
+}
+
+// reencode 把src的L2值解码后按dst重新编码，用于加密的值在键之间复制
+func (c *MultiLevelCache) reencode(src, dst string, data []byte) ([]byte, error) {
+	var item CacheItem
+	if err := c.decodeItem(src, data, &item); err != nil {
+		return nil, err
+	}
+	return c.encodeItem(dst, &item)
+}
+
+// renameL2 在L2中重命名键，返回旧键是否存在
+// 配置了Encryption时先按新键重新加密写入再删除旧键(非原子)
+func (c *MultiLevelCache) renameL2(from, to string) (bool, error) {
+	if c.config().Encryption != nil {
+		found, err := c.copyL2(from, to)
+		if err != nil || !found {
+			return found, err
+		}
+		_, err = c.deleteL2(c.ctx, from)
+		return true, err
+	}
+	if c.sequenced() {
+		n, err := sequencedRename.Run(c.ctx, c.l2(), []string{c.sequenceKey(from), c.sequenceKey(to), from, to}, c.sequenceTTL(0)).Int64()
+		return n > 0, err
+	}
+	err := c.l2().Rename(c.ctx, from, to).Err()
+	if err != nil && !isNoSuchKey(err) {
+		return false, err
+	}
+	return err == nil, nil
+}
+
+// isNoSuchKey 判断是否为RENAME的键不存在错误
+func isNoSuchKey(err error) bool {
+	return strings.Contains(err.Error(), "no such key")
+}
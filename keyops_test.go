@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+)
+
+// newL1TestCache 创建只启用L1的缓存，configure可以修改默认配置
+func newL1TestCache(t *testing.T, configure func(*CacheConfig)) *MultiLevelCache {
+	t.Helper()
+	config := CacheConfig{EnableL1Cache: true, MaxL1Size: 100}
+	if configure != nil {
+		configure(&config)
+	}
+	c, err := NewMultiLevelCache(config)
+	if err != nil {
+		t.Fatalf("NewMultiLevelCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCopyDoesNotShareL1Value(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	if err := c.Set("src", map[string]int{"n": 1}, 60); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := c.Copy("src", "dst"); err != nil || !found {
+		t.Fatalf("Copy = %v, %v; want true", found, err)
+	}
+	// 未配置复制时可变的值深拷贝，两个键不共享同一个map
+	src, _ := c.shardFor("src").load("src")
+	dst, _ := c.shardFor("dst").load("dst")
+	src.Value.(map[string]int)["n"] = 2
+	if n := dst.Value.(map[string]int)["n"]; n != 1 {
+		t.Errorf("dst n = %d after modifying src, want 1", n)
+	}
+}
+
+func TestCopyUsesCloneFunc(t *testing.T) {
+	clones := 0
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.CloneFunc = func(v interface{}) interface{} {
+			clones++
+			m := v.(map[string]int)
+			cloned := make(map[string]int, len(m))
+			for k, n := range m {
+				cloned[k] = n
+			}
+			return cloned
+		}
+	})
+	if err := c.Set("src", map[string]int{"n": 1}, 60); err != nil {
+		t.Fatal(err)
+	}
+	before := clones
+	if _, err := c.Copy("src", "dst"); err != nil {
+		t.Fatal(err)
+	}
+	if clones != before+1 {
+		t.Errorf("Copy called CloneFunc %d times, want 1", clones-before)
+	}
+	src, _ := c.shardFor("src").load("src")
+	dst, _ := c.shardFor("dst").load("dst")
+	src.Value.(map[string]int)["n"] = 2
+	if dst.Value.(map[string]int)["n"] != 1 {
+		t.Error("Copy stored the source's value in the destination")
+	}
+}
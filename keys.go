@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeyLengthPolicy 键超过MaxKeyLength时的处理策略
+type KeyLengthPolicy int
+
+const (
+	KeyLengthReject KeyLengthPolicy = iota // 拒绝并返回InvalidKeyError
+	KeyLengthHash                          // 保留前缀，超出部分替换为整个键的SHA-256
+)
+
+// KeyCharsPolicy 键中包含空白或控制字符时的处理策略
+type KeyCharsPolicy int
+
+const (
+	KeyCharsAllow  KeyCharsPolicy = iota // 不检查
+	KeyCharsReject                       // 拒绝并返回InvalidKeyError
+	KeyCharsStrip                        // 删除这些字符
+)
+
+// InvalidKeyError 键不符合校验规则
+type InvalidKeyError struct {
+	Key    string
+	Reason string
+}
+
+// Error 实现error接口
+func (e *InvalidKeyError) Error() string {
+	return fmt.Sprintf("无效的缓存键%q: %s", truncateKey(e.Key), e.Reason)
+}
+
+// hashedKeySuffixLen 哈希后缀的长度(分隔符加64位十六进制)
+const hashedKeySuffixLen = 1 + sha256.Size*2
+
+// normalizeKey 按配置校验并规范化键，结果再次规范化保持不变
+// 规范化后落在内部键命名空间中的键被拒绝，避免覆盖写入序号、墓碑、标签索引等内部键
+func (c *MultiLevelCache) normalizeKey(key string) (string, error) {
+	key, err := c.cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if c.isInternalKey(key) {
+		return "", &InvalidKeyError{Key: key, Reason: "使用了内部键的前缀"}
+	}
+	return key, nil
+}
+
+// cleanKey 按KeyChars和MaxKeyLength规范化键
+func (c *MultiLevelCache) cleanKey(key string) (string, error) {
+	config := c.config()
+	if config.MaxKeyLength <= 0 && len(key) > hardMaxKeyLength {
+		return "", &InvalidKeyError{Key: key, Reason: fmt.Sprintf("长度%d超过上限%d", len(key), hardMaxKeyLength)}
+	}
+	if config.KeyChars == KeyCharsAllow && config.MaxKeyLength <= 0 {
+		return key, nil
+	}
+
+	if config.KeyChars != KeyCharsAllow && strings.IndexFunc(key, badKeyRune) >= 0 {
+		if config.KeyChars == KeyCharsReject {
+			return "", &InvalidKeyError{Key: key, Reason: "包含空白或控制字符"}
+		}
+		key = strings.Map(func(r rune) rune {
+			if badKeyRune(r) {
+				return -1
+			}
+			return r
+		}, key)
+	}
+	if config.KeyChars != KeyCharsAllow && key == "" {
+		return "", &InvalidKeyError{Key: key, Reason: "键为空"}
+	}
+
+	if config.MaxKeyLength > 0 && len(key) > config.MaxKeyLength {
+		if config.KeyLength == KeyLengthReject || config.MaxKeyLength < hashedKeySuffixLen {
+			return "", &InvalidKeyError{Key: key, Reason: fmt.Sprintf("长度%d超过上限%d", len(key), config.MaxKeyLength)}
+		}
+		// 保留的前缀在字符边界截断，截断多字节字符会产生无效的UTF-8，KeyCharsStrip再次规范化时会删除它
+		cut := config.MaxKeyLength - hashedKeySuffixLen
+		for cut > 0 && !utf8.RuneStart(key[cut]) {
+			cut--
+		}
+		sum := sha256.Sum256([]byte(key))
+		key = key[:cut] + "#" + hex.EncodeToString(sum[:])
+	}
+	return key, nil
+}
+
+// badKeyRune 判断字符是否会影响redis-cli等工具处理键
+func badKeyRune(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r) || r == unicode.ReplacementChar
+}
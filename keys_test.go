@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// newKeyTestCache 创建只启用L1、超长键替换为哈希的缓存
+func newKeyTestCache(t *testing.T, chars KeyCharsPolicy) *MultiLevelCache {
+	t.Helper()
+	c, err := NewMultiLevelCache(CacheConfig{
+		EnableL1Cache: true,
+		MaxL1Size:     100,
+		MaxKeyLength:  100,
+		KeyLength:     KeyLengthHash,
+		KeyChars:      chars,
+	})
+	if err != nil {
+		t.Fatalf("NewMultiLevelCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestNormalizeKeyHashKeepsRuneBoundary(t *testing.T) {
+	for _, chars := range []KeyCharsPolicy{KeyCharsAllow, KeyCharsReject, KeyCharsStrip} {
+		c := newKeyTestCache(t, chars)
+		// 前缀长度35字节，每个字符3字节，按字节截断会切开一个字符
+		for _, key := range []string{
+			strings.Repeat("缓", 60),
+			"a" + strings.Repeat("缓", 60),
+			"ab" + strings.Repeat("😀", 40),
+		} {
+			got, err := c.normalizeKey(key)
+			if err != nil {
+				t.Fatalf("policy %d: normalizeKey(%q): %v", chars, key, err)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("policy %d: normalizeKey(%q) = %q, not valid UTF-8", chars, key, got)
+			}
+			if len(got) > 100 {
+				t.Errorf("policy %d: normalizeKey(%q) has length %d, want <= 100", chars, key, len(got))
+			}
+			again, err := c.normalizeKey(got)
+			if err != nil || again != got {
+				t.Errorf("policy %d: normalizeKey not idempotent: %q -> %q, %v", chars, got, again, err)
+			}
+		}
+	}
+}
+
+func TestNormalizeKeyDistinctLongKeys(t *testing.T) {
+	c := newKeyTestCache(t, KeyCharsStrip)
+	prefix := strings.Repeat("键", 40)
+	a, err := c.normalizeKey(prefix + "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.normalizeKey(prefix + "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Errorf("long keys sharing a prefix normalized to the same key %q", a)
+	}
+}
+
+func TestNormalizeKeyStripAndReject(t *testing.T) {
+	c := newKeyTestCache(t, KeyCharsStrip)
+	if got, err := c.normalizeKey("user: 1\n"); err != nil || got != "user:1" {
+		t.Errorf("normalizeKey = %q, %v; want %q", got, err, "user:1")
+	}
+	if _, err := c.normalizeKey(" \t"); err == nil {
+		t.Error("key consisting only of whitespace was accepted")
+	}
+
+	r := newKeyTestCache(t, KeyCharsReject)
+	if _, err := r.normalizeKey("user 1"); err == nil {
+		t.Error("KeyCharsReject accepted a key with a space")
+	}
+}
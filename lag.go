@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 失效传播的传输方式，用于LagSample.Transport和统计键名
+const (
+	TransportBroadcast = "broadcast" // BroadcastChannel广播的新值
+	TransportRegion    = "region"    // 其他区域发来的失效
+	TransportConsumer  = "consumer"  // InvalidationConsumer消费的失效事件(未设置Name时)
+)
+
+// LagSample 一次失效传播延迟的观测
+type LagSample struct {
+	Transport string        // 传输方式
+	Source    string        // 发送方：广播为实例标识，跨区域失效为区域名，消费者为空
+	Lag       time.Duration // 从发布到本实例应用的时间
+}
+
+// LagStat 一种传输方式的传播延迟统计
+type LagStat struct {
+	Count   int64         // 观测次数
+	AvgLag  time.Duration // 平均延迟
+	MaxLag  time.Duration // 最大延迟
+	LastLag time.Duration // 最近一次延迟
+}
+
+// lagStats 传播延迟的累计统计(纳秒)
+type lagStats struct {
+	count int64
+	total int64
+	max   int64
+	last  int64
+}
+
+// record 记录一次延迟
+func (s *lagStats) record(lag int64) {
+	atomic.AddInt64(&s.count, 1)
+	atomic.AddInt64(&s.total, lag)
+	atomic.StoreInt64(&s.last, lag)
+	storeMax(&s.max, lag)
+}
+
+// stat 返回统计快照
+func (s *lagStats) stat() LagStat {
+	stat := LagStat{
+		Count:   atomic.LoadInt64(&s.count),
+		MaxLag:  time.Duration(atomic.LoadInt64(&s.max)),
+		LastLag: time.Duration(atomic.LoadInt64(&s.last)),
+	}
+	if stat.Count > 0 {
+		stat.AvgLag = time.Duration(atomic.LoadInt64(&s.total) / stat.Count)
+	}
+	return stat
+}
+
+// storeMax 将v存入addr，只在v更大时替换
+func storeMax(addr *int64, v int64) {
+	for {
+		old := atomic.LoadInt64(addr)
+		if v <= old || atomic.CompareAndSwapInt64(addr, old, v) {
+			return
+		}
+	}
+}
+
+// invalidationLag 各传输方式的传播延迟
+type invalidationLag struct {
+	transports sync.Map // 传输方式->*lagStats
+	windowMax  int64    // 当前告警窗口内的最大延迟(纳秒)，-1表示窗口内没有观测
+}
+
+// observeLag 记录一次失效传播延迟，publishedAt为发布方写入消息的时间(Unix纳秒，0表示发布方未记录)
+// 延迟按本实例与发布方的时钟计算，配置TimeRedis时各实例使用同一时钟
+func (c *MultiLevelCache) observeLag(transport, source string, publishedAt int64) {
+	if publishedAt <= 0 {
+		return
+	}
+	lag := c.now().UnixNano() - publishedAt
+	if lag < 0 {
+		lag = 0
+	}
+	v, _ := c.lag.transports.LoadOrStore(transport, &lagStats{})
+	v.(*lagStats).record(lag)
+	storeMax(&c.lag.windowMax, lag)
+
+	if onLag := c.config().OnInvalidationLag; onLag != nil {
+		sample := LagSample{Transport: transport, Source: source, Lag: time.Duration(lag)}
+		c.protect("OnInvalidationLag", func() { onLag(sample) })
+	}
+}
+
+// takeLagWindow 返回告警窗口内的最大延迟并开始新窗口，窗口内没有观测时返回false
+func (c *MultiLevelCache) takeLagWindow() (time.Duration, bool) {
+	max := atomic.SwapInt64(&c.lag.windowMax, -1)
+	return time.Duration(max), max >= 0
+}
+
+// InvalidationLag 返回各传输方式的失效传播延迟统计，只包含发布方记录了发布时间的消息
+func (c *MultiLevelCache) InvalidationLag() map[string]LagStat {
+	stats := make(map[string]LagStat)
+	c.lag.transports.Range(func(k, v interface{}) bool {
+		stats[k.(string)] = v.(*lagStats).stat()
+		return true
+	})
+	return stats
+}
+
+// lagStatsMap 返回GetStats中的传播延迟统计
+func (c *MultiLevelCache) lagStatsMap() map[string]interface{} {
+	stats := make(map[string]interface{})
+	for transport, s := range c.InvalidationLag() {
+		prefix := "invalidation_lag_" + transport
+		stats[prefix+"_count"] = s.Count
+		stats[prefix+"_avg_ms"] = s.AvgLag.Milliseconds()
+		stats[prefix+"_max_ms"] = s.MaxLag.Milliseconds()
+		stats[prefix+"_last_ms"] = s.LastLag.Milliseconds()
+	}
+	return stats
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestObserveLagRecordsPerTransport(t *testing.T) {
+	var mu sync.Mutex
+	var samples []LagSample
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.OnInvalidationLag = func(s LagSample) {
+			mu.Lock()
+			samples = append(samples, s)
+			mu.Unlock()
+		}
+	})
+	now := time.Now()
+	c.observeLag(TransportBroadcast, "peer", now.Add(-300*time.Millisecond).UnixNano())
+	c.observeLag(TransportBroadcast, "peer", now.Add(-100*time.Millisecond).UnixNano())
+	// 发布方未记录时间时忽略，时钟偏差导致的负延迟按0计
+	c.observeLag(TransportRegion, "eu", 0)
+	c.observeLag(TransportRegion, "eu", now.Add(time.Hour).UnixNano())
+
+	lags := c.InvalidationLag()
+	broadcast := lags[TransportBroadcast]
+	if broadcast.Count != 2 || broadcast.MaxLag < 300*time.Millisecond || broadcast.LastLag >= broadcast.MaxLag {
+		t.Errorf("broadcast lag = %+v, want 2 samples with max >= 300ms", broadcast)
+	}
+	if broadcast.AvgLag < 200*time.Millisecond || broadcast.AvgLag > broadcast.MaxLag {
+		t.Errorf("broadcast avg lag = %v, want about 200ms", broadcast.AvgLag)
+	}
+	if region := lags[TransportRegion]; region.Count != 1 || region.MaxLag != 0 {
+		t.Errorf("region lag = %+v, want one sample of 0", region)
+	}
+
+	mu.Lock()
+	if len(samples) != 3 || samples[0].Transport != TransportBroadcast || samples[0].Source != "peer" {
+		t.Errorf("OnInvalidationLag samples = %+v", samples)
+	}
+	mu.Unlock()
+	if n := c.GetStats()["invalidation_lag_broadcast_count"]; n != int64(2) {
+		t.Errorf("invalidation_lag_broadcast_count = %v, want 2", n)
+	}
+}
+
+func TestConsumerLagUsesName(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	source := make(chanSource, 1)
+	ic := NewInvalidationConsumer(c, source)
+	ic.Name = "kafka"
+	stop := runConsumer(t, ic)
+	defer stop()
+
+	data, err := json.Marshal(InvalidationEvent{Keys: []string{"k"}, PublishedAt: time.Now().Add(-time.Second).UnixNano()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source <- data
+	waitMissing(t, c, "k")
+	deadline := time.Now().Add(2 * time.Second)
+	for c.InvalidationLag()["kafka"].Count == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("lag was not recorded under the consumer name: %v", c.InvalidationLag())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if lag := c.InvalidationLag()["kafka"]; lag.MaxLag < time.Second {
+		t.Errorf("kafka lag = %+v, want at least 1s", lag)
+	}
+}
+
+func TestInvalidationLagAlarm(t *testing.T) {
+	var alarms []Alarm
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.InvalidationLagAlarm = 500 * time.Millisecond
+		config.OnAlarm = func(a Alarm) { alarms = append(alarms, a) }
+	})
+	// 停止告警协程，由测试控制检查的时机
+	c.PauseMaintenance(L1Cache)
+	c.snapshotAlarms()
+
+	c.observeLag(TransportBroadcast, "peer", time.Now().Add(-2*time.Second).UnixNano())
+	c.checkAlarms(time.Second)
+	if len(alarms) != 1 || alarms[0].Kind != AlarmInvalidationLag || !alarms[0].Firing || alarms[0].Value < 2 {
+		t.Fatalf("alarms = %+v, want the lag alarm firing at about 2s", alarms)
+	}
+
+	// 窗口内没有观测时保持告警，延迟恢复正常后解除
+	c.checkAlarms(time.Second)
+	if len(alarms) != 1 {
+		t.Fatalf("alarms = %+v, want no change without samples", alarms)
+	}
+	c.observeLag(TransportBroadcast, "peer", time.Now().UnixNano())
+	c.checkAlarms(time.Second)
+	if len(alarms) != 2 || alarms[1].Firing {
+		t.Fatalf("alarms = %+v, want the lag alarm resolved", alarms)
+	}
+}
+
+func TestInvalidationLagAlarmValidated(t *testing.T) {
+	if _, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, InvalidationLagAlarm: -time.Second}); err == nil {
+		t.Error("NewMultiLevelCache accepted a negative InvalidationLagAlarm")
+	}
+}
+
+func TestOutboxStampsPublishedAt(t *testing.T) {
+	publisher := &recordingPublisher{}
+	o, err := NewOutboxPublisher(publisher, filepath.Join(t.TempDir(), "outbox"), time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	before := time.Now().UnixNano()
+	event := &InvalidationEvent{Keys: []string{"a"}}
+	if err := o.Publish(event); err != nil {
+		t.Fatal(err)
+	}
+	// 保留调用方设置的发布时间，如CDC记录的提交时间
+	if err := o.Publish(&InvalidationEvent{Keys: []string{"b"}, PublishedAt: 42}); err != nil {
+		t.Fatal(err)
+	}
+	waitDrained(t, o)
+	if event.PublishedAt != 0 {
+		t.Error("Publish modified the caller's event")
+	}
+
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+	var events []InvalidationEvent
+	for _, msg := range publisher.messages {
+		var e InvalidationEvent
+		if err := json.Unmarshal(msg, &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 2 || events[0].PublishedAt < before || events[1].PublishedAt != 42 {
+		t.Errorf("delivered events = %+v, want a stamped event and the original time", events)
+	}
+}
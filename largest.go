@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultLargestKeysSample LargestKeys默认抽样的L2键数
+const defaultLargestKeysSample = 1000
+
+// KeySize 占用内存较多的键
+type KeySize struct {
+	Key   string        `json:"key"`
+	Level CacheLevel    `json:"level"`
+	Size  int64         `json:"size"` // L1为值编码后的字节数(估计值)，L2为MEMORY USAGE返回的字节数
+	TTL   time.Duration `json:"ttl"`  // 剩余有效期，0表示不过期
+}
+
+// LargestKeysOptions LargestKeysContext的选项
+type LargestKeysOptions struct {
+	L2Sample int // 通过SCAN抽样的L2键数(默认1000，负数表示不检查L2)
+}
+
+// keySizeHeap 按Size排列的小顶堆，保留最大的n个键
+type keySizeHeap []KeySize
+
+func (h keySizeHeap) Len() int            { return len(h) }
+func (h keySizeHeap) Less(i, j int) bool  { return h[i].Size < h[j].Size }
+func (h keySizeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *keySizeHeap) Push(x interface{}) { *h = append(*h, x.(KeySize)) }
+func (h *keySizeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// offer 加入候选键，超过n个时移出最小的一个
+func (h *keySizeHeap) offer(ks KeySize, n int) {
+	if h.Len() < n {
+		heap.Push(h, ks)
+		return
+	}
+	if ks.Size > (*h)[0].Size {
+		(*h)[0] = ks
+		heap.Fix(h, 0)
+	}
+}
+
+// LargestKeys 返回L1中和L2抽样中占用内存最多的n个键，按大小降序排列，用于确定容量清理的目标
+// 同一个键可能同时出现在两级中
+func (c *MultiLevelCache) LargestKeys(n int) ([]KeySize, error) {
+	return c.LargestKeysContext(c.ctx, n, LargestKeysOptions{})
+}
+
+// LargestKeysContext 与LargestKeys相同，可以指定L2的抽样数量
+// L1逐项检查；L2通过SCAN抽样并用pipeline执行MEMORY USAGE和PTTL，受L2RateLimit限制
+// 启用RedisProxyMode时只检查L1并返回ErrProxyUnsupported；ctx取消时返回已检查部分的结果和错误
+func (c *MultiLevelCache) LargestKeysContext(ctx context.Context, n int, opts LargestKeysOptions) ([]KeySize, error) {
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	if n <= 0 {
+		return nil, nil
+	}
+	h := &keySizeHeap{}
+	c.largestL1(h, n)
+	err := c.largestL2(ctx, h, n, opts.L2Sample)
+
+	result := []KeySize(*h)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, err
+}
+
+// largestL1 检查L1中的项，大小按值编码后的字节数估计
+func (c *MultiLevelCache) largestL1(h *keySizeHeap, n int) {
+	if !c.config().EnableL1Cache {
+		return
+	}
+	now := c.nowUnix()
+	c.rangeL1(func(key string, item *CacheItem) bool {
+		if item.ExpireTime <= now {
+			return true
+		}
+		size := int64(estimateSize(item.Value))
+		if size < 0 {
+			data, err := json.Marshal(item.Value)
+			if err != nil {
+				return true
+			}
+			size = int64(len(data))
+		}
+		h.offer(KeySize{Key: key, Level: L1Cache, Size: size, TTL: time.Duration(item.ExpireTime-now) * time.Second}, n)
+		return true
+	})
+}
+
+// largestL2 通过SCAN抽样L2中的键，跳过标签、墓碑等内部键
+func (c *MultiLevelCache) largestL2(ctx context.Context, h *keySizeHeap, n, sample int) error {
+	if !c.config().EnableL2Cache || sample < 0 {
+		return nil
+	}
+	if c.proxyMode() {
+		return ErrProxyUnsupported
+	}
+	if sample == 0 {
+		sample = defaultLargestKeysSample
+	}
+
+	var cursor uint64
+	for checked := 0; checked < sample; {
+		if err := c.waitL2Budget(ctx); err != nil {
+			return err
+		}
+		keys, next, err := c.l2().Scan(ctx, cursor, "", purgeScanCount).Result()
+		if err != nil {
+			return err
+		}
+		batch := keys[:0]
+		for _, key := range keys {
+			if !c.isInternalKey(key) && checked < sample {
+				batch = append(batch, key)
+				checked++
+			}
+		}
+
+		if len(batch) > 0 {
+			if err := c.waitL2Budget(ctx); err != nil {
+				return err
+			}
+			pipe := c.pipeline()
+			sizes := make([]*redis.IntCmd, len(batch))
+			ttls := make([]*redis.DurationCmd, len(batch))
+			for i, key := range batch {
+				sizes[i] = pipe.MemoryUsage(ctx, key)
+				ttls[i] = pipe.PTTL(ctx, key)
+			}
+			_, err := pipe.Exec(ctx)
+			pipe.release()
+			if err != nil && err != redis.Nil && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			for i, key := range batch {
+				size, err := sizes[i].Result()
+				if err != nil {
+					continue // 扫描期间已删除或服务端不支持MEMORY USAGE
+				}
+				ttl := ttls[i].Val()
+				if ttl < 0 {
+					ttl = 0
+				}
+				h.offer(KeySize{Key: key, Level: L2Cache, Size: size, TTL: ttl}, n)
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestLargestKeysL1(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	for key, size := range map[string]int{"small": 10, "medium": 100, "large": 1000, "expired": 5000} {
+		if err := c.Set(key, strings.Repeat("x", size), 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 已过期但尚未清理的项不计入
+	item, _ := c.shardFor("expired").load("expired")
+	item.ExpireTime = time.Now().Unix() - 1
+
+	keys, err := c.LargestKeys(2)
+	if err != nil {
+		t.Fatalf("LargestKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Key != "large" || keys[1].Key != "medium" {
+		t.Fatalf("LargestKeys = %+v, want large then medium", keys)
+	}
+	if keys[0].Level != L1Cache || keys[0].Size != 1000 || keys[0].TTL <= 50*time.Second || keys[0].TTL > time.Minute {
+		t.Errorf("largest key = %+v, want 1000 bytes in L1 with about 60s left", keys[0])
+	}
+	if keys, _ := c.LargestKeys(0); keys != nil {
+		t.Errorf("LargestKeys(0) = %v, want nil", keys)
+	}
+}
+
+func TestLargestKeysSamplesL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.EnableL1Cache = false })
+	if err := c.SetWithTags("big", strings.Repeat("x", 4000), 60, "t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("small", "x", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := c.LargestKeysContext(context.Background(), 10, LargestKeysOptions{})
+	if err != nil {
+		t.Fatalf("LargestKeysContext: %v", err)
+	}
+	// 标签、写入序号等内部键不计入
+	if len(keys) != 2 || keys[0].Key != "big" || keys[1].Key != "small" {
+		t.Fatalf("LargestKeysContext = %+v, want big and small only", keys)
+	}
+	if keys[0].Level != L2Cache || keys[0].Size < 4000 || keys[0].TTL <= 0 {
+		t.Errorf("big = %+v, want at least 4000 bytes with a TTL", keys[0])
+	}
+
+	// 负数的抽样数不检查L2
+	if keys, err := c.LargestKeysContext(context.Background(), 10, LargestKeysOptions{L2Sample: -1}); err != nil || len(keys) != 0 {
+		t.Errorf("LargestKeysContext without L2 = %+v, %v; want none", keys, err)
+	}
+	if keys, err := c.LargestKeysContext(context.Background(), 10, LargestKeysOptions{L2Sample: 1}); err != nil || len(keys) != 1 {
+		t.Errorf("LargestKeysContext sampling 1 key = %+v, %v; want one", keys, err)
+	}
+}
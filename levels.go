@@ -0,0 +1,53 @@
+package cache
+
+// CallOption 单次Get/Set调用的选项
+type CallOption func(*callOptions)
+
+// callOptions 单次调用使用的缓存级别和写入序号
+type callOptions struct {
+	skipL1 bool
+	skipL2 bool
+	seq    uint64 // 写入前登记的序号(0表示写入时登记)
+	strict bool   // 写入被取代时返回ErrSuperseded
+
+	freshUntil int64   // 写入的项的新鲜截止时间戳(SetWithFreshness，0表示不区分新鲜与陈旧)
+	async      *Future // 非nil时L2写入在后台完成，结果通过该Future报告(SetAsync)
+}
+
+// SkipL1 本次调用不读写L1：Get直接读取Redis且不把结果升级到L1，Set只写入Redis并删除本实例L1中的旧值
+// 适合一次性导出等大量读取，避免挤出L1中的热点数据
+func SkipL1() CallOption {
+	return func(o *callOptions) { o.skipL1 = true }
+}
+
+// SkipL2 本次调用不访问Redis：Get只查询L1，Set只写入本实例的L1，Redis中已有的值保持不变
+// 适合只在本实例使用的小数据
+func SkipL2() CallOption {
+	return func(o *callOptions) { o.skipL2 = true }
+}
+
+// WithSequence Set使用预先通过Sequence登记的写入序号：登记之后该键有其他写入或删除时不写入并返回ErrSuperseded
+// 未启用SequencedWrites时忽略
+func WithSequence(seq uint64) CallOption {
+	return func(o *callOptions) {
+		o.seq = seq
+		o.strict = true
+	}
+}
+
+// applyCallOptions 合并调用选项
+func applyCallOptions(opts []CallOption) callOptions {
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// requireLevel 跳过指定级别后没有可用的缓存级别时返回ErrNoCacheLevel
+func (o callOptions) requireLevel(config *CacheConfig) error {
+	if (config.EnableL1Cache && !o.skipL1) || (config.EnableL2Cache && !o.skipL2) {
+		return nil
+	}
+	return ErrNoCacheLevel
+}
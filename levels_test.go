@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSkipL1(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+	})
+	if err := c.Set("k", "old", 60); err != nil {
+		t.Fatal(err)
+	}
+	// 只写入L2并删除L1中的旧值
+	if err := c.Set("k", "new", 60, SkipL1()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.shardFor("k").load("k"); ok {
+		t.Error("SkipL1 Set left the old value in L1")
+	}
+	if !mr.Exists("k") {
+		t.Fatal("SkipL1 Set did not write to L2")
+	}
+
+	// 直接读取L2且不升级
+	if v, ok := c.Get("k", SkipL1()); !ok || v != "new" {
+		t.Fatalf("Get(SkipL1) = %v, %v; want new", v, ok)
+	}
+	if _, ok := c.shardFor("k").load("k"); ok {
+		t.Error("SkipL1 Get promoted the value")
+	}
+	if err := c.Set("local", "l1", 60, SkipL2()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("local", SkipL1()); ok {
+		t.Error("SkipL1 Get read the L1-only value")
+	}
+}
+
+func TestSkipL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	if err := c.Set("k", "shared", 60); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := mr.Get("k")
+
+	// 只写入本实例的L1，Redis中的值不变
+	if err := c.Set("k", "local", 60, SkipL2()); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := mr.Get("k"); after != before {
+		t.Error("SkipL2 Set changed the L2 value")
+	}
+	if v, ok := c.Get("k", SkipL2()); !ok || v != "local" {
+		t.Errorf("Get(SkipL2) = %v, %v; want local", v, ok)
+	}
+
+	// 只在L2中的键按未命中处理
+	if err := c.Set("remote", "v", 60, SkipL1()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := c.GetWithError("remote", SkipL2()); ok || err != nil {
+		t.Errorf("GetWithError(SkipL2) = %v, %v; want a miss", ok, err)
+	}
+}
+
+func TestSkipAllLevels(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	if err := c.Set("k", "v", 60, SkipL1(), SkipL2()); !errors.Is(err, ErrNoCacheLevel) {
+		t.Errorf("Set skipping both levels = %v, want ErrNoCacheLevel", err)
+	}
+	// Get按未命中处理
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := c.GetWithError("k", SkipL1(), SkipL2()); ok || err != nil {
+		t.Errorf("GetWithError skipping both levels = %v, %v; want a miss", ok, err)
+	}
+
+	l1 := newL1TestCache(t, nil)
+	if err := l1.Set("k", "v", 60, SkipL1()); !errors.Is(err, ErrNoCacheLevel) {
+		t.Errorf("Set(SkipL1) on an L1-only cache = %v, want ErrNoCacheLevel", err)
+	}
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// closeDrainPoll Close等待进行中操作完成时的轮询间隔
+const closeDrainPoll = time.Millisecond
+
+// enter 登记一个进行中的操作，缓存已关闭时返回false，调用方应返回ErrClosed
+// 返回true时调用方必须在操作结束后调用exit
+func (c *MultiLevelCache) enter() bool {
+	atomic.AddInt64(&c.active, 1)
+	if atomic.LoadInt32(&c.closed) != 0 {
+		c.exit()
+		return false
+	}
+	return true
+}
+
+// exit 结束enter登记的操作
+func (c *MultiLevelCache) exit() {
+	atomic.AddInt64(&c.active, -1)
+}
+
+// retain 在已登记的操作内部启动异步任务前调用，Close会等待该任务结束，任务结束时调用exit
+func (c *MultiLevelCache) retain() {
+	atomic.AddInt64(&c.active, 1)
+}
+
+// Closed 判断缓存是否已关闭
+func (c *MultiLevelCache) Closed() bool {
+	return atomic.LoadInt32(&c.closed) != 0
+}
+
+// goBackground 启动由Close等待退出的后台协程
+func (c *MultiLevelCache) goBackground(fn func()) {
+	c.bg.Add(1)
+	go func() {
+		defer c.bg.Done()
+		fn()
+	}()
+}
+
+// drain 等待所有进行中的操作结束，调用前已设置关闭标记，之后不会有新的操作进入
+func (c *MultiLevelCache) drain() {
+	for atomic.LoadInt64(&c.active) > 0 {
+		time.Sleep(closeDrainPoll)
+	}
+}
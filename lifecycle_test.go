@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCloseDrainsInFlightOperations(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loaded := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad("k", 60, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "v", nil
+		})
+		loaded <- err
+	}()
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	// Close等待进行中的加载结束
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a load was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-loaded; err != nil {
+		t.Errorf("in-flight GetOrLoad = %v, want nil", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return after the load finished")
+	}
+
+	// 重复调用Close直接返回，之后的操作被拒绝
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+	if err := c.Set("k", "v", 60); err != ErrClosed {
+		t.Errorf("Set after Close = %v, want ErrClosed", err)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("Get after Close hit")
+	}
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoaderFunc 缓存未命中时加载数据
+type LoaderFunc func() (interface{}, error)
+
+// LoaderFuncWithTTL 缓存未命中时加载数据，同时返回该值的过期时间(秒)
+type LoaderFuncWithTTL func() (interface{}, int64, error)
+
+// MultiLoaderFunc 批量加载缓存未命中的键，返回结果中缺失的键视为不存在；部分键失败时返回*BatchError
+type MultiLoaderFunc func(keys []string) (map[string]interface{}, error)
+
+// loadCall 正在进行的一次加载
+type loadCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// loadGroup 合并同一键的并发加载，避免缓存击穿
+type loadGroup struct {
+	mu      sync.Mutex
+	calls   map[string]*loadCall
+	onPanic func(err *PanicError) // 加载发生panic时调用
+	onStart func()                // 启动后台加载协程前调用
+	onDone  func()                // 后台加载协程结束时调用
+}
+
+// do 执行加载，同一键的并发调用共享同一次结果
+// 加载在后台协程中以脱离取消的context执行；ctx取消时调用方立即返回，加载继续完成供其他调用方使用
+func (g *loadGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*loadCall)
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		call = &loadCall{done: make(chan struct{})}
+		g.calls[key] = call
+		if g.onStart != nil {
+			g.onStart()
+		}
+		go g.run(detach(ctx), key, call, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run 执行一次加载并唤醒所有等待的调用方，加载发生panic时所有调用方收到*PanicError
+func (g *loadGroup) run(ctx context.Context, key string, call *loadCall, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe := newPanicError("loader", r)
+			if g.onPanic != nil {
+				g.onPanic(pe)
+			}
+			call.val, call.err = nil, pe
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+		if g.onDone != nil {
+			g.onDone()
+		}
+	}()
+	call.val, call.err = fn(ctx)
+}
+
+// GetOrLoad 获取缓存，未命中时调用loader加载并写入缓存
+// 同一键的并发未命中只会调用一次loader
+func (c *MultiLevelCache) GetOrLoad(key string, ttl int64, loader LoaderFunc) (interface{}, error) {
+	return c.getOrLoad(c.ctx, key, ttl, nil, func(context.Context) (interface{}, error) {
+		return loader()
+	})
+}
+
+// GetOrLoadWithTTL 获取缓存，未命中时调用loader加载，按loader返回的TTL写入缓存
+// 过期时间可以取决于数据本身，例如已发布的文章缓存1小时、草稿缓存10秒；loader返回的TTL<=0时只返回值，不写入缓存
+func (c *MultiLevelCache) GetOrLoadWithTTL(key string, loader LoaderFuncWithTTL) (interface{}, error) {
+	return c.getOrLoadTTL(c.ctx, key, nil, func(context.Context) (interface{}, int64, error) {
+		return loader()
+	})
+}
+
+// getOrLoad GetOrLoad的实现，加载的值以固定TTL、带上指定标签写入
+// loader和钩子收到由ctx派生的context
+func (c *MultiLevelCache) getOrLoad(ctx context.Context, key string, ttl int64, tags []string, loader LoaderFuncContext) (interface{}, error) {
+	return c.getOrLoadTTL(ctx, key, tags, func(ctx context.Context) (interface{}, int64, error) {
+		val, err := loader(ctx)
+		return val, ttl, err
+	})
+}
+
+// getOrLoadTTL 合并加载并按loader返回的TTL回填，TTL<=0时不回填
+func (c *MultiLevelCache) getOrLoadTTL(ctx context.Context, key string, tags []string, loader LoaderFuncWithTTLContext) (interface{}, error) {
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	// 对比采样：绕过缓存直接加载，记录缓存本来会返回的值
+	if c.sampleBypass() {
+		return c.bypassLoad(ctx, key, loader)
+	}
+	// 故障期间按DegradedModes配置的行为处理
+	action := c.degradedAction()
+	if action == DegradeBypass {
+		return c.bypassDegraded(ctx, loader)
+	}
+	if val, found := c.Get(key); found {
+		return val, nil
+	}
+	if action == DegradeErrorFast {
+		atomic.AddInt64(&c.degraded.rejected, 1)
+		return nil, ErrDegraded
+	}
+	admit := c.admitLoad
+	if action == DegradeQueue {
+		admit = c.admitDegradedLoad
+	}
+
+	return c.loads.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		// 等待期间可能已被其他协程写入
+		if val, found := c.Get(key); found {
+			return val, nil
+		}
+		c.hookBeforeLoad(ctx, key)
+		seq := c.loadSequence(ctx, key)
+		start := time.Now()
+		var ttl int64
+		val, err := admit(func() (interface{}, error) {
+			var val interface{}
+			var err error
+			val, ttl, err = loader(ctx)
+			return val, err
+		})
+		if err != nil {
+			c.recordAccess(AccessLoad, key, start, nil, false)
+			return nil, err
+		}
+		c.recordSlow("load", key, start, "loader", val)
+		c.recordAccess(AccessLoad, key, start, nil, true)
+		c.hookBeforeBackfill(ctx, key)
+		// 加载期间键被删除、未启用任何缓存级别或loader要求不缓存时不回填，直接返回加载结果
+		if ttl <= 0 || c.requireLevel() != nil || c.loadBlocked(key) {
+			return val, nil
+		}
+		if err := c.setWithTags(ctx, key, val, ttl, tags, callOptions{seq: seq}); err != nil {
+			c.logf("dancache: cache loaded value %q failed: %v", key, err)
+		}
+		return val, nil
+	})
+}
+
+// GetOrLoadMulti 批量获取缓存，未命中的键一次性交给loader加载并写入缓存
+// L2中的键通过一次MGET读取，返回结果只包含命中或加载到的键
+// loader返回*BatchError表示部分键加载失败：其余键照常返回并回填，调用方收到只包含失败键的*BatchError和部分结果
+func (c *MultiLevelCache) GetOrLoadMulti(keys []string, ttl int64, loader MultiLoaderFunc) (map[string]interface{}, error) {
+	return c.getOrLoadMulti(c.ctx, keys, ttl, nil, func(_ context.Context, keys []string) (map[string]interface{}, error) {
+		return loader(keys)
+	})
+}
+
+// getOrLoadMulti GetOrLoadMulti的实现，加载的值带上指定标签写入
+// loader和钩子收到由ctx派生的context，回填使用脱离取消的context
+func (c *MultiLevelCache) getOrLoadMulti(ctx context.Context, keys []string, ttl int64, tags []string, loader MultiLoaderFuncContext) (map[string]interface{}, error) {
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	result := c.getMulti(keys)
+
+	missing := make([]string, 0, len(keys)-len(result))
+	for _, key := range keys {
+		if _, ok := result[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	for _, key := range missing {
+		c.hookBeforeLoad(ctx, key)
+	}
+	seqs := c.loadSequences(ctx, missing)
+	loadedVal, err := c.admitLoad(func() (interface{}, error) {
+		return loader(ctx, missing)
+	})
+	// loader返回*BatchError时只有其中的键失败，其余键照常返回和回填
+	var failed map[string]error
+	if err != nil {
+		batchErr, ok := err.(*BatchError)
+		if !ok {
+			return nil, err
+		}
+		failed = batchErr.Errors
+	}
+	loaded, _ := loadedVal.(map[string]interface{})
+	var batchErr *BatchError
+	for _, key := range missing {
+		if keyErr, ok := failed[key]; ok {
+			if batchErr == nil {
+				batchErr = &BatchError{Errors: make(map[string]error)}
+			}
+			batchErr.Errors[key] = keyErr
+			continue
+		}
+		val, ok := loaded[key]
+		if !ok {
+			continue
+		}
+		result[key] = val
+		c.hookBeforeBackfill(ctx, key)
+		if normalized, err := c.normalizeKey(key); err != nil || c.requireLevel() != nil || c.loadBlocked(normalized) {
+			continue
+		}
+		if err := c.setWithTags(detach(ctx), key, val, ttl, tags, callOptions{seq: seqs[key]}); err != nil {
+			c.logf("dancache: cache loaded value %q failed: %v", key, err)
+		}
+	}
+	if batchErr != nil {
+		return result, batchErr
+	}
+	return result, nil
+}
+
+// getMulti 批量获取缓存，先查本地缓存，剩余的键通过一次MGET从Redis读取
+func (c *MultiLevelCache) getMulti(keys []string) map[string]interface{} {
+	now := c.nowUnix()
+	result := make(map[string]interface{}, len(keys))
+
+	// 按规范化后的键查询，结果仍以调用方传入的键返回
+	// 持有读锁查询全部键，SetMulti写入的一组键要么全部可见要么全部不可见
+	remaining := make([]string, 0, len(keys))
+	originals := make([]string, 0, len(keys))
+	l1 := c.config().EnableL1Cache
+	if l1 {
+		c.epoch.RLock()
+	}
+	for _, original := range keys {
+		key, err := c.normalizeKey(original)
+		if err != nil {
+			continue
+		}
+		if l1 {
+			if item, ok := c.lookupL1(key, now); ok {
+				result[original] = c.readValue(item)
+				c.recordLookup(key, L1Cache, true)
+				continue
+			}
+		}
+		remaining = append(remaining, key)
+		originals = append(originals, original)
+	}
+	if l1 {
+		c.epoch.RUnlock()
+	}
+
+	if !c.config().EnableL2Cache || len(remaining) == 0 {
+		for _, key := range remaining {
+			c.recordLookup(key, L1Cache, false)
+		}
+		return result
+	}
+
+	for _, key := range remaining {
+		c.hookBeforeL2Get(key)
+	}
+	values, err := c.l2().MGet(c.ctx, remaining...).Result()
+	if err != nil {
+		// Redis错误，剩余的键按未命中处理
+		for _, key := range remaining {
+			c.recordLookup(key, L2Cache, false)
+		}
+		return result
+	}
+	// 命中的项读取完后统一判断升级，单次升级的键数有上限
+	candidates := make([]promotionCandidate, 0, len(values))
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			c.recordLookup(remaining[i], L2Cache, false)
+			continue
+		}
+		item, ok, settled, _ := c.readL2Item(remaining[i], []byte(data), now)
+		if ok {
+			result[originals[i]] = c.readValue(item)
+			if settled {
+				candidates = append(candidates, promotionCandidate{key: remaining[i], item: item})
+			}
+		}
+		c.recordLookup(remaining[i], L2Cache, ok)
+	}
+	c.promoteBatch(candidates, now)
+	return result
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestGetOrLoadWithTTLUsesLoaderTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	v, err := c.GetOrLoadWithTTL("published", func() (interface{}, int64, error) {
+		return "article", 3600, nil
+	})
+	if err != nil || v != "article" {
+		t.Fatalf("GetOrLoadWithTTL = %v, %v; want article", v, err)
+	}
+	if ttl := mr.TTL("published"); ttl < 59*time.Minute || ttl > time.Hour {
+		t.Errorf("L2 TTL = %v, want the loader's 1h", ttl)
+	}
+
+	// TTL<=0时只返回值，不写入缓存
+	loads := 0
+	draft := func() (interface{}, int64, error) {
+		loads++
+		return "draft", 0, nil
+	}
+	for i := 0; i < 2; i++ {
+		if v, err := c.GetOrLoadWithTTL("draft", draft); err != nil || v != "draft" {
+			t.Fatalf("GetOrLoadWithTTL = %v, %v; want draft", v, err)
+		}
+	}
+	if loads != 2 || mr.Exists("draft") {
+		t.Errorf("loads = %d, in L2 = %v; want every call to load and nothing cached", loads, mr.Exists("draft"))
+	}
+}
+
+func TestGetOrLoadMultiPartialFailure(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	errB := errors.New("b unavailable")
+	var requested [][]string
+	loader := func(keys []string) (map[string]interface{}, error) {
+		requested = append(requested, keys)
+		return map[string]interface{}{"a": "A"}, &BatchError{Errors: map[string]error{"b": errB, "other": errB}}
+	}
+
+	result, err := c.GetOrLoadMulti([]string{"a", "b"}, 60, loader)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("GetOrLoadMulti error = %v, want *BatchError", err)
+	}
+	// 只包含调用方请求的失败键，其余键照常返回
+	if len(batchErr.Errors) != 1 || batchErr.Errors["b"] != errB {
+		t.Errorf("BatchError.Errors = %v, want only b", batchErr.Errors)
+	}
+	if len(result) != 1 || result["a"] != "A" {
+		t.Errorf("result = %v, want a only", result)
+	}
+	if !strings.Contains(batchErr.Error(), "b unavailable") {
+		t.Errorf("Error() = %q, want the key's error", batchErr.Error())
+	}
+
+	// 成功的键已回填，失败的键下次重新加载
+	c.GetOrLoadMulti([]string{"a", "b"}, 60, loader)
+	if len(requested) != 2 || len(requested[1]) != 1 || requested[1][0] != "b" {
+		t.Errorf("second load requested %v, want [b]", requested[1:])
+	}
+
+	// 其他错误整体失败
+	if _, err := c.GetOrLoadMulti([]string{"c"}, 60, func([]string) (map[string]interface{}, error) {
+		return nil, errB
+	}); err != errB {
+		t.Errorf("GetOrLoadMulti with a plain error = %v, want errB", err)
+	}
+}
@@ -0,0 +1,13 @@
+package cache
+
+// Logger 日志接口，*log.Logger满足该接口
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// logf 输出日志，未配置Logger时忽略
+func (c *MultiLevelCache) logf(format string, v ...interface{}) {
+	if c.config().Logger != nil {
+		c.config().Logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maintenance 一个缓存级别的后台维护协程(清理、监听、时钟校准等)，可以暂停和恢复
+// 保持L1与其他实例一致的失效监听不随暂停停止，只在关闭缓存时退出
+type maintenance struct {
+	mu        sync.Mutex
+	requested int32        // 已请求启动时为1；LazyMaintenance时首次使用该级别才请求
+	paused    bool         // PauseMaintenance之后为true
+	routines  routineGroup // 可暂停的维护协程
+	listeners routineGroup // 失效监听协程，不随暂停停止
+}
+
+// routineGroup 一组一起启动和停止的协程
+type routineGroup struct {
+	stop chan struct{}   // 运行中时非nil，关闭后协程退出
+	wg   *sync.WaitGroup // 本次运行启动的协程
+}
+
+// start 启动协程，已在运行或没有协程时直接返回
+func (g *routineGroup) start(routines []func(stop <-chan struct{})) {
+	if g.stop != nil || len(routines) == 0 {
+		return
+	}
+	stop := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(len(routines))
+	for _, routine := range routines {
+		routine := routine
+		go func() {
+			defer wg.Done()
+			routine(stop)
+		}()
+	}
+	g.stop, g.wg = stop, wg
+}
+
+// detach 取出运行中的协程的停止信号，之后由调用方在锁外关闭并等待
+func (g *routineGroup) detach() (chan struct{}, *sync.WaitGroup) {
+	stop, wg := g.stop, g.wg
+	g.stop, g.wg = nil, nil
+	return stop, wg
+}
+
+// halt 关闭detach取出的停止信号并等待协程退出
+func halt(stop chan struct{}, wg *sync.WaitGroup) {
+	if stop != nil {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+// listenerRoutines 返回L2的失效监听协程：广播、客户端跟踪和跨区域失效
+// 这些协程停止后其他实例的写入和删除不再同步到本实例的L1，因此不随PauseMaintenance暂停
+func (c *MultiLevelCache) listenerRoutines(level CacheLevel) []func(stop <-chan struct{}) {
+	config := c.config()
+	if level != L2Cache || !config.EnableL2Cache {
+		return nil
+	}
+	var routines []func(stop <-chan struct{})
+	// 代理不支持pub/sub，不启动监听
+	if config.BroadcastChannel != "" && !config.RedisProxyMode {
+		routines = append(routines, c.broadcastListenerRoutine)
+	}
+	if c.tracking != nil {
+		routines = append(routines, c.trackingListenerRoutine)
+	}
+	if c.regions != nil && !config.RedisProxyMode {
+		routines = append(routines, c.regionListenerRoutine)
+	}
+	return routines
+}
+
+// maintenanceRoutines 返回级别需要的可暂停的后台维护协程，未启用该级别时为空
+func (c *MultiLevelCache) maintenanceRoutines(level CacheLevel) []func(stop <-chan struct{}) {
+	config := c.config()
+	var routines []func(stop <-chan struct{})
+	switch level {
+	case L1Cache:
+		if !config.EnableL1Cache {
+			return nil
+		}
+		routines = append(routines, c.cleanupRoutines(time.Minute)...) // 每个分片每分钟清理一次
+		if config.MemoryLimitRatio > 0 {
+			routines = append(routines, c.memoryMonitorRoutine)
+		}
+		if config.SweepInterval > 0 {
+			routines = append(routines, c.sweepRoutine)
+		}
+		if c.sizer != nil {
+			routines = append(routines, c.l1SizeRoutine)
+		}
+		if c.alarmsEnabled() {
+			routines = append(routines, c.alarmRoutine)
+		}
+		// 探测随L2的维护协程运行，未启用L2时随L1
+		if !config.EnableL2Cache && config.CanaryInterval > 0 {
+			routines = append(routines, c.canaryRoutine)
+		}
+	case L2Cache:
+		if !config.EnableL2Cache {
+			return nil
+		}
+		if config.TimeSource == TimeRedis && !config.RedisProxyMode {
+			routines = append(routines, c.clockSyncRoutine)
+		}
+		// 代理不支持TIME和pub/sub，不启动校准和监听
+		if config.OnExpire != nil && !config.RedisProxyMode && c.serverKeyspaceEvents() {
+			routines = append(routines, c.expiryListenerRoutine)
+		}
+		if config.CanaryInterval > 0 {
+			routines = append(routines, c.canaryRoutine)
+		}
+		// 告警检查随L1的维护协程运行，未启用L1时随L2
+		if !config.EnableL1Cache && c.alarmsEnabled() {
+			routines = append(routines, c.alarmRoutine)
+		}
+	}
+	return routines
+}
+
+// ensureMaintenance 请求启动级别的后台维护协程，已请求过时直接返回
+// 暂停中的级别只启动失效监听，其余协程在ResumeMaintenance时启动；缓存关闭后不再启动
+func (c *MultiLevelCache) ensureMaintenance(level CacheLevel) {
+	m := &c.maint[level]
+	if atomic.LoadInt32(&m.requested) != 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if atomic.LoadInt32(&m.requested) != 0 || c.Closed() {
+		return
+	}
+	atomic.StoreInt32(&m.requested, 1)
+	c.startMaintenanceLocked(level)
+}
+
+// startMaintenanceLocked 启动级别的失效监听，未暂停时同时启动其余维护协程，调用方持有m.mu
+func (c *MultiLevelCache) startMaintenanceLocked(level CacheLevel) {
+	m := &c.maint[level]
+	m.listeners.start(c.listenerRoutines(level))
+	if !m.paused {
+		m.routines.start(c.maintenanceRoutines(level))
+	}
+}
+
+// stopMaintenance 停止级别的全部后台维护协程(包括失效监听)并等待退出
+func (c *MultiLevelCache) stopMaintenance(level CacheLevel) {
+	m := &c.maint[level]
+	m.mu.Lock()
+	stop, wg := m.routines.detach()
+	listenStop, listenWG := m.listeners.detach()
+	m.mu.Unlock()
+	halt(stop, wg)
+	halt(listenStop, listenWG)
+}
+
+// maintenanceRunning 判断级别的可暂停的维护协程是否在运行
+func (c *MultiLevelCache) maintenanceRunning(level CacheLevel) bool {
+	m := &c.maint[level]
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.routines.stop != nil
+}
+
+// maintenanceLevels 未指定级别时返回全部级别
+func maintenanceLevels(levels []CacheLevel) []CacheLevel {
+	if len(levels) == 0 {
+		return []CacheLevel{L1Cache, L2Cache}
+	}
+	return levels
+}
+
+// PauseMaintenance 暂停指定级别(默认全部)的后台维护协程，返回时协程已退出
+// 暂停期间L1不再定期清理过期项和降级，L2的过期事件监听、时钟校准和探测停止；读写不受影响，定时失效照常触发
+// 广播、客户端跟踪和跨区域失效的监听继续运行，其他实例的写入和删除仍会同步到本实例的L1
+func (c *MultiLevelCache) PauseMaintenance(levels ...CacheLevel) {
+	for _, level := range maintenanceLevels(levels) {
+		if level != L1Cache && level != L2Cache {
+			continue
+		}
+		m := &c.maint[level]
+		m.mu.Lock()
+		m.paused = true
+		stop, wg := m.routines.detach()
+		m.mu.Unlock()
+		halt(stop, wg)
+	}
+}
+
+// ResumeMaintenance 恢复指定级别(默认全部)的后台维护协程
+// LazyMaintenance时尚未使用过的级别仍在首次使用时才启动
+func (c *MultiLevelCache) ResumeMaintenance(levels ...CacheLevel) {
+	for _, level := range maintenanceLevels(levels) {
+		if level != L1Cache && level != L2Cache {
+			continue
+		}
+		m := &c.maint[level]
+		m.mu.Lock()
+		m.paused = false
+		if atomic.LoadInt32(&m.requested) != 0 && !c.Closed() {
+			c.startMaintenanceLocked(level)
+		}
+		m.mu.Unlock()
+	}
+}
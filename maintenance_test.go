@@ -0,0 +1,66 @@
+package cache
+
+import "testing"
+
+func TestPauseMaintenanceKeepsListeners(t *testing.T) {
+	c, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, MaxL1Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 用计数的假协程代替失效监听，确认暂停只停止可暂停的维护协程
+	running := make(chan struct{})
+	exited := make(chan struct{})
+	m := &c.maint[L2Cache]
+	m.mu.Lock()
+	m.listeners.start([]func(stop <-chan struct{}){func(stop <-chan struct{}) {
+		close(running)
+		<-stop
+		close(exited)
+	}})
+	m.mu.Unlock()
+	<-running
+
+	c.PauseMaintenance()
+	if c.maintenanceRunning(L1Cache) {
+		t.Error("L1 maintenance still running after PauseMaintenance")
+	}
+	select {
+	case <-exited:
+		t.Fatal("invalidation listener stopped by PauseMaintenance")
+	default:
+	}
+
+	c.ResumeMaintenance()
+	if !c.maintenanceRunning(L1Cache) {
+		t.Error("L1 maintenance not running after ResumeMaintenance")
+	}
+	c.stopMaintenance(L2Cache)
+	<-exited
+}
+
+func TestLazyMaintenanceStartsOnFirstUse(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.LazyMaintenance = true
+	})
+	if c.maintenanceRunning(L1Cache) {
+		t.Fatal("L1 maintenance started before the first write")
+	}
+	// 暂停期间首次使用只登记请求，恢复时才启动
+	c.PauseMaintenance(L1Cache)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if c.maintenanceRunning(L1Cache) {
+		t.Error("L1 maintenance started while paused")
+	}
+	c.ResumeMaintenance(L2Cache)
+	if c.maintenanceRunning(L1Cache) {
+		t.Error("resuming L2 resumed L1 maintenance")
+	}
+	c.ResumeMaintenance(L1Cache)
+	if !c.maintenanceRunning(L1Cache) {
+		t.Error("L1 maintenance not running after the first write and resume")
+	}
+}
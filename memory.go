@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+// heapObjectsMetric 堆上存活对象占用字节数的运行时指标
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// memoryMonitorRoutine 定期检查堆内存，接近GOMEMLIMIT时主动收缩L1
+func (c *MultiLevelCache) memoryMonitorRoutine(stop <-chan struct{}) {
+	interval := c.config().MemoryCheckInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.checkMemoryPressure()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// checkMemoryPressure 堆内存超过阈值时按比例淘汰L1项(启用L2时降级到L2)
+func (c *MultiLevelCache) checkMemoryPressure() {
+	// 读取当前GOMEMLIMIT，未设置时为math.MaxInt64
+	limit := debug.SetMemoryLimit(-1)
+	if limit <= 0 || limit == math.MaxInt64 {
+		return
+	}
+
+	if float64(heapInUse()) < float64(limit)*c.config().MemoryLimitRatio {
+		return
+	}
+
+	shrinkRatio := c.config().MemoryShrinkRatio
+	if shrinkRatio <= 0 || shrinkRatio > 1 {
+		shrinkRatio = 0.25
+	}
+
+	count := int(float64(c.l1Count()) * shrinkRatio)
+	if count < 1 {
+		count = 1
+	}
+	c.evictLRU(count, EvictMemoryPressure)
+	atomic.AddInt64(&c.pressureShrinks, 1)
+	atomic.StoreInt64(&c.lastPressure, time.Now().UnixNano())
+}
+
+// heapInUse 返回堆上存活对象占用的字节数(不触发STW)
+func heapInUse() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
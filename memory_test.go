@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckMemoryPressureShrinksL1(t *testing.T) {
+	var reasons []EvictionReason
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.MemoryLimitRatio = 0.5
+		config.MemoryShrinkRatio = 0.25
+		config.EvictionExporter = EvictionExporterFunc(func(key string, item *CacheItem, reason EvictionReason) {
+			reasons = append(reasons, reason)
+		})
+	})
+	for i := 0; i < 40; i++ {
+		if err := c.Set(fmt.Sprintf("k%d", i), i, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 未设置GOMEMLIMIT时不收缩
+	old := debug.SetMemoryLimit(math.MaxInt64)
+	defer debug.SetMemoryLimit(old)
+	c.checkMemoryPressure()
+	if n := c.l1Count(); n != 40 {
+		t.Fatalf("L1 holds %d items without a memory limit, want 40", n)
+	}
+
+	// 堆内存超过GOMEMLIMIT的一半时淘汰四分之一
+	debug.SetMemoryLimit(int64(heapInUse()))
+	c.checkMemoryPressure()
+	debug.SetMemoryLimit(math.MaxInt64)
+	if n := c.l1Count(); n != 30 {
+		t.Errorf("L1 holds %d items after a pressure shrink, want 30", n)
+	}
+	if n := atomic.LoadInt64(&c.pressureShrinks); n != 1 {
+		t.Errorf("pressureShrinks = %d, want 1", n)
+	}
+	for _, reason := range reasons {
+		if reason != EvictMemoryPressure {
+			t.Errorf("exported with reason %v, want %v", reason, EvictMemoryPressure)
+		}
+	}
+	if len(reasons) != 10 {
+		t.Errorf("%d items exported, want 10", len(reasons))
+	}
+}
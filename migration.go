@@ -0,0 +1,59 @@
+package cache
+
+import "time"
+
+// MigrationFunc 将值从版本v升级到v+1，返回错误时该缓存项作废
+// 从L2读取的值经过JSON往返，通常是map[string]interface{}等通用类型
+type MigrationFunc func(key string, value interface{}) (interface{}, error)
+
+// migrateItem 将旧版本的缓存项逐级升级到当前版本并回写L2，返回缓存项是否可用
+// 缺少某一级迁移或迁移失败时删除L2中的值；比当前版本新的值(回滚期间由新版本写入)按未命中处理但不删除
+func (c *MultiLevelCache) migrateItem(key string, item *CacheItem, now int64) bool {
+	config := c.config()
+	if item.Version == config.ValueVersion {
+		return true
+	}
+	if item.Version > config.ValueVersion {
+		return false
+	}
+
+	from := item.Version
+	for item.Version < config.ValueVersion {
+		migrate, ok := config.Migrations[item.Version]
+		if !ok {
+			c.invalidateL2(key, from)
+			return false
+		}
+		var value interface{}
+		var err error
+		if perr := c.protect("MigrationFunc", func() { value, err = migrate(key, item.Value) }); perr != nil {
+			err = perr
+		}
+		if err != nil {
+			c.logf("dancache: migrate %q from version %d: %v", key, item.Version, err)
+			c.invalidateL2(key, from)
+			return false
+		}
+		item.Value = value
+		item.Version++
+	}
+
+	// 回写升级后的值，其他实例不必重复迁移(按本机时间已过期的值不回写，避免写入没有过期时间的键)
+	if item.ExpireTime <= now {
+		return true
+	}
+	// 启用SequencedWrites时从L2读取的值序号未知，rewriteL2不回写，由之后的写入替换旧版本
+	if data, err := c.marshalItem(key, item); err == nil {
+		if written, _ := c.rewriteL2(c.ctx, key, item, data, time.Duration(item.ExpireTime-now)*time.Second); written {
+			item.markL2Synced()
+		}
+	}
+	return true
+}
+
+// invalidateL2 删除无法迁移的旧版本值
+func (c *MultiLevelCache) invalidateL2(key string, version int) {
+	if _, err := c.deleteL2(c.ctx, key); err != nil {
+		c.logf("dancache: invalidate %q (version %d): %v", key, version, err)
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestMigrateOldValuesOnRead(t *testing.T) {
+	mr := miniredis.RunT(t)
+	v0 := newRedisTestCache(t, mr, func(config *CacheConfig) { config.SequencedWrites = false })
+	v2 := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.EnableL1Cache = false
+		config.SequencedWrites = false
+		config.ValueVersion = 2
+		config.Migrations = map[int]MigrationFunc{
+			0: func(key string, value interface{}) (interface{}, error) { return fmt.Sprint(value, "+1"), nil },
+			1: func(key string, value interface{}) (interface{}, error) {
+				if value == "bad+1" {
+					return nil, errors.New("cannot migrate")
+				}
+				return fmt.Sprint(value, "+2"), nil
+			},
+		}
+	})
+
+	for _, key := range []string{"k", "bad"} {
+		if err := v0.Set(key, key, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v, found := v2.Get("k"); !found || v != "k+1+2" {
+		t.Fatalf("Get = %v, %v; want k+1+2", v, found)
+	}
+	// 升级后的值回写L2，其他实例不必重复迁移
+	var item CacheItem
+	raw, _ := mr.Get("k")
+	if err := v2.decodeItem("k", []byte(raw), &item); err != nil || item.Version != 2 || item.Value != "k+1+2" {
+		t.Errorf("L2 value after migration = %+v, %v; want version 2", item, err)
+	}
+
+	// 迁移失败的值作废
+	if _, found := v2.Get("bad"); found {
+		t.Error("value that failed to migrate was served")
+	}
+	if mr.Exists("bad") {
+		t.Error("value that failed to migrate was not deleted from L2")
+	}
+
+	// 比当前版本新的值按未命中处理但不删除
+	if err := v2.Set("new", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := v0.Get("new"); found {
+		t.Error("old version served a newer value")
+	}
+	if !mr.Exists("new") {
+		t.Error("newer value was deleted by an old version")
+	}
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ItemOptions SetMulti中单个键的值和选项
+type ItemOptions struct {
+	Value interface{}
+	TTL   int64    // 过期时间(秒)
+	Tags  []string // 关联的标签
+}
+
+// SetMulti 原子地设置多个键，由多个键组成的复合对象不会出现部分更新
+// L2通过MULTI/EXEC事务一次写入，失败时不修改L1；L1在同一把写锁内写入，读取方要么看到全部新值要么看到全部旧值
+// 启用RedisProxyMode时L2改为普通pipeline写入，不保证L2中的原子性
+// Redis Cluster的事务只能包含同一个槽的键，ClusterClient按槽拆分为多个事务，不同槽的键之间不保证L2中的原子性；
+// 需要原子性时用哈希标签(如"order:{1001}"和"order:{1001}:items")把一组键放在同一个槽
+// 任一键无效、处于墓碑窗口或值超过MaxValueSize时不写入任何键；两个键规范化后相同时返回ErrDuplicateKey
+// 启用SequencedWrites时在一次pipeline中为全部键登记写入序号，已被更晚的写入或删除取代的键不写入，其余键照常写入
+func (c *MultiLevelCache) SetMulti(items map[string]ItemOptions) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return err
+	}
+	config := c.config()
+
+	// 按键排序，保证写入顺序确定
+	keys := make([]string, 0, len(items))
+	normalized := make(map[string]ItemOptions, len(items))
+	originals := make(map[string]string, len(items))
+	for key, opts := range items {
+		nk, err := c.normalizeKey(key)
+		if err != nil {
+			return err
+		}
+		if other, ok := originals[nk]; ok {
+			return fmt.Errorf("%w: %q和%q", ErrDuplicateKey, other, key)
+		}
+		originals[nk] = key
+		if c.hasTombstone(nk) {
+			return ErrTombstoned
+		}
+		keys = append(keys, nk)
+		normalized[nk] = opts
+	}
+	sort.Strings(keys)
+
+	// 启用写入序号时在写入开始时为全部键登记，登记失败的键按不带序号的写入处理
+	var seqs []uint64
+	if c.sequenced() {
+		var err error
+		if seqs, err = c.reserveSequences(c.ctx, keys); err != nil {
+			c.logf("dancache: reserve write sequences for %d keys failed: %v", len(keys), err)
+		}
+	}
+
+	cacheItems := make([]*CacheItem, len(keys))
+	encoded := make([][]byte, len(keys))
+	for i, key := range keys {
+		opts := normalized[key]
+		cacheItems[i] = c.newCacheItem(opts.Value, opts.TTL)
+		c.applyTagTTL(cacheItems[i], opts.Tags)
+		if seqs != nil {
+			cacheItems[i].l2Seq = seqs[i]
+		}
+		opts.TTL = cacheItems[i].lifetime()
+		normalized[key] = opts
+		if !config.EnableL2Cache && config.MaxValueSize <= 0 {
+			continue
+		}
+		data, err := c.marshalItem(key, cacheItems[i])
+		if err != nil {
+			return err
+		}
+		if config.MaxValueSize > 0 && len(data) > config.MaxValueSize {
+			return &ValueTooLargeError{Key: key, Size: len(data), Limit: config.MaxValueSize}
+		}
+		encoded[i] = data
+	}
+
+	// 先写入L2，失败时L1保持不变
+	if config.EnableL2Cache {
+		// 任何一个键超过硬配额时整批不写入
+		ttls := make([]time.Duration, len(keys))
+		for i, key := range keys {
+			ttl, err := c.admitQuota(key, len(encoded[i]), c.l2TTL(time.Duration(normalized[key].TTL)*time.Second))
+			if err != nil {
+				return err
+			}
+			ttls[i] = ttl
+		}
+		for _, key := range keys {
+			c.cancelL2Write(key)
+		}
+		written := make([]*redis.Cmd, len(keys))
+		_, err := c.l2Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				if seq := cacheItems[i].l2Seq; seq > 0 {
+					// 事务中的NOSCRIPT无法单独重试，使用EVAL
+					written[i] = sequencedSet.Eval(c.ctx, pipe, []string{c.sequenceKey(key), key},
+						seq, encoded[i], ttls[i].Milliseconds(), c.sequenceTTL(ttls[i]))
+				} else {
+					pipe.Set(c.ctx, key, encoded[i], ttls[i])
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for i, item := range cacheItems {
+			if written[i] != nil {
+				if n, _ := written[i].Int64(); n == 0 {
+					// 已被更晚的写入或删除取代，L1同样不写入
+					atomic.AddInt64(&c.supersededWrites, 1)
+					cacheItems[i] = nil
+					continue
+				}
+			}
+			item.markL2Synced()
+		}
+		c.replicate(keys...)
+	}
+
+	if config.EnableL1Cache {
+		// 写锁内只修改分片，超过容量时的淘汰(降级到L2、调用导出器)在释放写锁后进行
+		c.epoch.Lock()
+		for i, key := range keys {
+			if cacheItems[i] == nil {
+				continue
+			}
+			// 客户端跟踪不覆盖写入的键时删除旧值，下次读取从L2获取
+			if c.trackingBypassesWrites(key) {
+				c.deleteL1(key)
+			} else {
+				c.insertL1(key, cacheItems[i])
+			}
+		}
+		c.epoch.Unlock()
+		c.enforceL1Size()
+	}
+
+	// 记录标签索引
+	for _, key := range keys {
+		if tags := normalized[key].Tags; len(tags) > 0 {
+			if err := c.tagKey(c.ctx, key, normalized[key].TTL, tags); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSetMultiRejectsDuplicateNormalizedKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.KeyChars = KeyCharsStrip })
+
+	err := c.SetMulti(map[string]ItemOptions{
+		"a b": {Value: "1", TTL: 60},
+		"ab":  {Value: "2", TTL: 60},
+	})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("SetMulti = %v, want ErrDuplicateKey", err)
+	}
+	if mr.Exists("ab") {
+		t.Error("SetMulti with duplicate keys wrote to L2")
+	}
+	if _, ok := c.Get("ab"); ok {
+		t.Error("SetMulti with duplicate keys wrote to L1")
+	}
+}
+
+func TestSetMultiEvictsOutsideEpochLock(t *testing.T) {
+	var c *MultiLevelCache
+	exported := make(chan string, 10)
+	c, err := NewMultiLevelCache(CacheConfig{
+		EnableL1Cache: true,
+		MaxL1Size:     2,
+		// 导出器读取缓存需要epoch读锁，在写锁内调用会死锁
+		EvictionExporter: EvictionExporterFunc(func(key string, item *CacheItem, reason EvictionReason) {
+			c.Get("a")
+			exported <- key
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- c.SetMulti(map[string]ItemOptions{
+			"a": {Value: "1", TTL: 60},
+			"b": {Value: "2", TTL: 60},
+			"c": {Value: "3", TTL: 60},
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("SetMulti: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetMulti did not return when eviction called back into the cache")
+	}
+	if len(exported) != 1 {
+		t.Errorf("%d items exported, want 1", len(exported))
+	}
+	if n := c.l1Count(); n != 2 {
+		t.Errorf("L1 holds %d items after SetMulti, want MaxL1Size 2", n)
+	}
+}
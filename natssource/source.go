@@ -0,0 +1,68 @@
+// Package natssource 将NATS订阅适配为缓存失效事件来源
+package natssource
+
+import (
+	"context"
+
+	cache "github.com/losanming/DanCache"
+	"github.com/nats-io/nats.go"
+)
+
+// Source 基于NATS同步订阅的失效事件来源
+type Source struct {
+	sub *nats.Subscription
+}
+
+var _ cache.InvalidationSource = (*Source)(nil)
+
+// Subscribe 订阅主题并创建失效事件来源，queue不为空时以队列组方式订阅
+func Subscribe(nc *nats.Conn, subject, queue string) (*Source, error) {
+	var (
+		sub *nats.Subscription
+		err error
+	)
+	if queue != "" {
+		sub, err = nc.QueueSubscribeSync(subject, queue)
+	} else {
+		sub, err = nc.SubscribeSync(subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Source{sub: sub}, nil
+}
+
+// Next 阻塞直到收到下一条消息或ctx结束
+func (s *Source) Next(ctx context.Context) ([]byte, error) {
+	msg, err := s.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
+// Close 取消订阅
+func (s *Source) Close() error {
+	return s.sub.Unsubscribe()
+}
+
+// Publisher 通过NATS发布失效事件，可配合cache.OutboxPublisher使用
+type Publisher struct {
+	nc      *nats.Conn
+	subject string
+}
+
+var _ cache.InvalidationPublisher = (*Publisher)(nil)
+
+// NewPublisher 创建发布到指定主题的发布器
+func NewPublisher(nc *nats.Conn, subject string) *Publisher {
+	return &Publisher{nc: nc, subject: subject}
+}
+
+// Publish 发布消息并等待服务器确认已收到
+func (p *Publisher) Publish(ctx context.Context, msg []byte) error {
+	if err := p.nc.Publish(p.subject, msg); err != nil {
+		return err
+	}
+	return p.nc.FlushWithContext(ctx)
+}
@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// InvalidationPublisher 失效事件发布器，如Redis pub/sub、Kafka或NATS
+type InvalidationPublisher interface {
+	Publish(ctx context.Context, msg []byte) error
+}
+
+// RedisPublisher 通过Redis pub/sub发布失效事件
+type RedisPublisher struct {
+	cache   *MultiLevelCache
+	channel string
+}
+
+// NewRedisPublisher 创建使用缓存Redis连接的发布器
+func NewRedisPublisher(cache *MultiLevelCache, channel string) *RedisPublisher {
+	return &RedisPublisher{cache: cache, channel: channel}
+}
+
+// Publish 发布消息，缓存已关闭时返回ErrClosed
+func (p *RedisPublisher) Publish(ctx context.Context, msg []byte) error {
+	if !p.cache.enter() {
+		return ErrClosed
+	}
+	defer p.cache.exit()
+	if p.cache.proxyMode() {
+		return ErrProxyUnsupported
+	}
+	return p.cache.redisClient.Publish(ctx, p.channel, msg).Err()
+}
+
+// OutboxPublisher 带本地持久化发件箱的失效事件发布器
+// 事件先追加写入本地文件再异步投递，消息代理短暂不可用时按间隔重试，进程重启后继续投递未完成的事件
+type OutboxPublisher struct {
+	publisher     InvalidationPublisher
+	path          string
+	retryInterval time.Duration
+	logger        Logger
+
+	mutex   sync.Mutex
+	file    *os.File
+	pending [][]byte
+	notify  chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewOutboxPublisher 创建发件箱发布器，path为发件箱文件路径，文件中已有的事件会重新投递
+func NewOutboxPublisher(publisher InvalidationPublisher, path string, retryInterval time.Duration, logger Logger) (*OutboxPublisher, error) {
+	if retryInterval <= 0 {
+		retryInterval = time.Second
+	}
+
+	pending, err := readOutbox(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OutboxPublisher{
+		publisher:     publisher,
+		path:          path,
+		retryInterval: retryInterval,
+		logger:        logger,
+		file:          file,
+		pending:       pending,
+		notify:        make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go o.deliverRoutine()
+	if len(pending) > 0 {
+		o.wake()
+	}
+	return o, nil
+}
+
+// Publish 将失效事件写入发件箱并异步投递，只在写入发件箱失败时返回错误
+// 未设置PublishedAt时以写入发件箱的时间为发布时间，消费方统计的延迟包含在发件箱中等待投递的时间
+func (o *OutboxPublisher) Publish(event *InvalidationEvent) error {
+	if event.PublishedAt == 0 {
+		stamped := *event
+		stamped.PublishedAt = time.Now().UnixNano()
+		event = &stamped
+	}
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	if _, err := o.file.Write(append(msg, '\n')); err != nil {
+		o.mutex.Unlock()
+		return err
+	}
+	if err := o.file.Sync(); err != nil {
+		o.mutex.Unlock()
+		return err
+	}
+	o.pending = append(o.pending, msg)
+	o.mutex.Unlock()
+
+	o.wake()
+	return nil
+}
+
+// Pending 返回尚未投递成功的事件数量
+func (o *OutboxPublisher) Pending() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.pending)
+}
+
+// Close 停止投递，未投递的事件保留在发件箱文件中
+func (o *OutboxPublisher) Close() error {
+	close(o.stop)
+	<-o.done
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.file.Close()
+}
+
+// wake 通知投递协程有新事件
+func (o *OutboxPublisher) wake() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliverRoutine 按顺序投递发件箱中的事件，失败时等待后重试
+func (o *OutboxPublisher) deliverRoutine() {
+	defer close(o.done)
+	for {
+		select {
+		case <-o.notify:
+		case <-o.stop:
+			return
+		}
+
+		for !o.deliverPending() {
+			select {
+			case <-time.After(o.retryInterval):
+			case <-o.stop:
+				return
+			}
+		}
+	}
+}
+
+// deliverPending 投递当前所有待发事件并压缩发件箱，全部成功时返回true
+func (o *OutboxPublisher) deliverPending() bool {
+	o.mutex.Lock()
+	batch := o.pending
+	o.mutex.Unlock()
+
+	delivered := 0
+	for _, msg := range batch {
+		if err := o.publisher.Publish(context.Background(), msg); err != nil {
+			if o.logger != nil {
+				o.logger.Printf("dancache: publish invalidation failed, will retry: %v", err)
+			}
+			break
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return len(batch) == 0
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.pending = o.pending[delivered:]
+	if err := o.rewrite(); err != nil && o.logger != nil {
+		o.logger.Printf("dancache: compact outbox failed: %v", err)
+	}
+	return delivered == len(batch)
+}
+
+// rewrite 用剩余的待发事件重写发件箱文件，调用方需持有锁
+func (o *OutboxPublisher) rewrite() error {
+	tmpPath := o.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, msg := range o.pending {
+		w.Write(msg)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmpPath, o.path); err != nil {
+		return err
+	}
+
+	// 重新打开文件，后续事件追加到新文件
+	file, err := os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	o.file.Close()
+	o.file = file
+	return nil
+}
+
+// readOutbox 读取发件箱文件中的事件，文件不存在时返回空
+func readOutbox(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pending := make([][]byte, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// 忽略写入中断产生的不完整记录
+		if len(line) == 0 || !json.Valid(line) {
+			continue
+		}
+		pending = append(pending, append([]byte(nil), line...))
+	}
+	return pending, scanner.Err()
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingPublisher 记录收到的消息，failures大于0时先失败相应次数
+type recordingPublisher struct {
+	mutex    sync.Mutex
+	failures int
+	messages [][]byte
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, msg []byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.failures != 0 {
+		if p.failures > 0 {
+			p.failures--
+		}
+		return errors.New("broker unavailable")
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// keys 返回已收到事件中的键
+func (p *recordingPublisher) keys(t *testing.T) []string {
+	t.Helper()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var keys []string
+	for _, msg := range p.messages {
+		var event InvalidationEvent
+		if err := json.Unmarshal(msg, &event); err != nil {
+			t.Fatalf("unmarshal %q: %v", msg, err)
+		}
+		if event.PublishedAt == 0 {
+			t.Errorf("event %q has no PublishedAt", msg)
+		}
+		keys = append(keys, event.Keys...)
+	}
+	return keys
+}
+
+// waitDrained 等待发件箱中的事件全部投递
+func waitDrained(t *testing.T, o *OutboxPublisher) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for o.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox still has %d pending events", o.Pending())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOutboxPublisherRetriesInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox")
+	publisher := &recordingPublisher{failures: 3}
+	o, err := NewOutboxPublisher(publisher, path, time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := o.Publish(&InvalidationEvent{Keys: []string{key}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitDrained(t, o)
+	if got := publisher.keys(t); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("delivered keys = %v, want [a b c]", got)
+	}
+	// 投递成功后压缩发件箱文件
+	if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+		t.Errorf("outbox file = %q, %v; want empty", data, err)
+	}
+}
+
+func TestOutboxPublisherRedeliversAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox")
+	down := &recordingPublisher{failures: -1}
+	o, err := NewOutboxPublisher(down, path, time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if err := o.Publish(&InvalidationEvent{Keys: []string{key}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟写入中断留下的不完整记录
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"keys":["c"`)
+	f.Close()
+
+	up := &recordingPublisher{}
+	o, err = NewOutboxPublisher(up, path, time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	waitDrained(t, o)
+	if got := up.keys(t); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("redelivered keys = %v, want [a b]", got)
+	}
+}
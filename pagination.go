@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PageCache 分页查询结果缓存
+// 缓存键包含集合的代数，失效时只需将代数加一，旧代数下的所有分页和总数随TTL自然过期
+type PageCache struct {
+	cache      *MultiLevelCache
+	collection string
+	ttl        int64
+	newPage    func() interface{}
+
+	localGen int64 // 未启用L2时的本地代数
+}
+
+// NewPageCache 创建分页缓存
+// 分页结果以JSON缓存，读取时解码到newPage返回的指针中
+// 启用L2时代数保存在Redis中，所有实例共享；否则只在本实例内有效
+func NewPageCache(cache *MultiLevelCache, collection string, ttl int64, newPage func() interface{}) *PageCache {
+	return &PageCache{
+		cache:      cache,
+		collection: collection,
+		ttl:        ttl,
+		newPage:    newPage,
+	}
+}
+
+// PageKey 生成当前代数下某一页的缓存键，filter为查询条件(排序、筛选等)
+func (p *PageCache) PageKey(filter map[string]interface{}, page, size int) (string, error) {
+	gen, err := p.generation()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("page:%s:%d:%s:%d:%d", p.collection, gen, hashArgs(filter), page, size), nil
+}
+
+// CountKey 生成当前代数下总数的缓存键
+func (p *PageCache) CountKey(filter map[string]interface{}) (string, error) {
+	gen, err := p.generation()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("page:%s:%d:%s:count", p.collection, gen, hashArgs(filter)), nil
+}
+
+// Page 获取一页结果，未命中时调用load
+func (p *PageCache) Page(filter map[string]interface{}, page, size int, load func() (interface{}, error)) (interface{}, error) {
+	key, err := p.PageKey(filter, page, size)
+	if err != nil {
+		return nil, err
+	}
+	val, err := p.cache.GetOrLoad(key, p.ttl, func() (interface{}, error) {
+		result, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("缓存的分页结果类型错误: %T", val)
+	}
+	out := p.newPage()
+	if err := json.Unmarshal([]byte(data), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Count 获取总数，未命中时调用load
+func (p *PageCache) Count(filter map[string]interface{}, load func() (int64, error)) (int64, error) {
+	key, err := p.CountKey(filter)
+	if err != nil {
+		return 0, err
+	}
+	val, err := p.cache.GetOrLoad(key, p.ttl, func() (interface{}, error) {
+		count, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return strconv.FormatInt(count, 10), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return 0, fmt.Errorf("缓存的总数类型错误: %T", val)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Invalidate 使集合的所有分页和总数失效
+func (p *PageCache) Invalidate() error {
+	if !p.cache.config().EnableL2Cache {
+		atomic.AddInt64(&p.localGen, 1)
+		return nil
+	}
+	return p.cache.l2().Incr(p.cache.ctx, p.genKey()).Err()
+}
+
+// generation 返回集合当前的代数
+func (p *PageCache) generation() (int64, error) {
+	if !p.cache.config().EnableL2Cache {
+		return atomic.LoadInt64(&p.localGen), nil
+	}
+	gen, err := p.cache.l2().Get(p.cache.ctx, p.genKey()).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// genKey 代数在Redis中的键
+func (p *PageCache) genKey() string {
+	return "page:" + p.collection + ":gen"
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type testPage struct {
+	Items []string `json:"items"`
+}
+
+func TestPageCacheInvalidatesAcrossInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newPage := func() interface{} { return &testPage{} }
+	a := NewPageCache(newRedisTestCache(t, mr, nil), "posts", 60, newPage)
+	b := NewPageCache(newRedisTestCache(t, mr, nil), "posts", 60, newPage)
+
+	loads, counts := 0, 0
+	load := func() (interface{}, error) {
+		loads++
+		return testPage{Items: []string{"p1", "p2"}}, nil
+	}
+	count := func() (int64, error) {
+		counts++
+		return 42, nil
+	}
+	filter := map[string]interface{}{"sort": "new"}
+	for _, p := range []*PageCache{a, b} {
+		page, err := p.Page(filter, 1, 20, load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if items := page.(*testPage).Items; len(items) != 2 || items[0] != "p1" {
+			t.Errorf("Page = %v, want [p1 p2]", items)
+		}
+		if n, err := p.Count(filter, count); err != nil || n != 42 {
+			t.Errorf("Count = %d, %v; want 42", n, err)
+		}
+	}
+	if loads != 1 || counts != 1 {
+		t.Fatalf("loads = %d, counts = %d; want the second instance to hit", loads, counts)
+	}
+
+	// 不同的筛选条件和页码使用不同的键
+	if _, err := a.Page(filter, 2, 20, load); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Page(map[string]interface{}{"sort": "old"}, 1, 20, load); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 3 {
+		t.Errorf("loads = %d, want 3", loads)
+	}
+
+	// 一个实例使集合失效后，另一个实例读到新代数的键
+	if err := a.Invalidate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Page(filter, 1, 20, load); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Count(filter, count); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 4 || counts != 2 {
+		t.Errorf("after Invalidate loads = %d, counts = %d; want 4 and 2", loads, counts)
+	}
+}
+
+func TestPageCacheLocalGeneration(t *testing.T) {
+	p := NewPageCache(newL1TestCache(t, nil), "posts", 60, func() interface{} { return &testPage{} })
+	before, err := p.PageKey(nil, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Invalidate(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := p.PageKey(nil, 1, 10)
+	if before == after {
+		t.Errorf("PageKey unchanged after Invalidate: %q", after)
+	}
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// PanicError 用户回调或loader发生panic时返回的错误
+// panic被恢复后缓存继续工作，后台协程不会退出，锁也不会一直被持有
+type PanicError struct {
+	Callback string      // 发生panic的回调，如"loader"、"PromotionStrategy"
+	Value    interface{} // recover得到的值
+	Stack    []byte      // 发生panic时的调用栈
+}
+
+// Error 实现error接口
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s发生panic: %v", e.Callback, e.Value)
+}
+
+// newPanicError 根据recover得到的值创建PanicError
+func newPanicError(callback string, r interface{}) *PanicError {
+	return &PanicError{Callback: callback, Value: r, Stack: debug.Stack()}
+}
+
+// reportPanic 记录恢复的panic
+func (c *MultiLevelCache) reportPanic(err *PanicError) {
+	atomic.AddInt64(&c.recoveredPanics, 1)
+	c.logf("dancache: recovered panic in %s: %v\n%s", err.Callback, err.Value, err.Stack)
+}
+
+// protect 执行用户回调，发生panic时恢复并返回*PanicError
+func (c *MultiLevelCache) protect(callback string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe := newPanicError(callback, r)
+			c.reportPanic(pe)
+			err = pe
+		}
+	}()
+	fn()
+	return nil
+}
+
+// shouldPromote 调用升级策略，发生panic时不升级
+func (c *MultiLevelCache) shouldPromote(item *CacheItem) (promote bool) {
+	c.protect("PromotionStrategy", func() {
+		promote = c.config().PromotionStrategy.ShouldPromote(item)
+	})
+	return promote
+}
+
+// shouldDemote 调用降级策略，发生panic时不降级
+func (c *MultiLevelCache) shouldDemote(item *CacheItem) (demote bool) {
+	c.protect("DemotionStrategy", func() {
+		demote = c.config().DemotionStrategy.ShouldDemote(item)
+	})
+	return demote
+}
+
+// keyOwned 调用KeyOwner，发生panic时视为归本实例所有
+func (c *MultiLevelCache) keyOwned(owner func(key string) bool, key string) bool {
+	owned := true
+	c.protect("KeyOwner", func() {
+		owned = owner(key)
+	})
+	return owned
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestLoaderPanicIsRecovered(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	_, err := c.GetOrLoad("k", 60, func() (interface{}, error) { panic("boom") })
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Callback != "loader" || pe.Value != "boom" || len(pe.Stack) == 0 {
+		t.Fatalf("GetOrLoad error = %v, want *PanicError from loader", err)
+	}
+	// 发生panic的加载不会一直占用该键
+	v, err := c.GetOrLoad("k", 60, func() (interface{}, error) { return "v", nil })
+	if err != nil || v != "v" {
+		t.Errorf("GetOrLoad after a panic = %v, %v; want v", v, err)
+	}
+	if n := c.GetStats()["recovered_panics"]; n != int64(1) {
+		t.Errorf("recovered_panics = %v, want 1", n)
+	}
+}
+
+func TestStrategyPanicsAreRecovered(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newRedisTestCache(t, mr, nil)
+	reader := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.PromotionStrategy = promoteFunc(func(*CacheItem) bool { panic("promotion") })
+	})
+	if err := writer.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	// 升级策略panic时按不升级处理，读取照常返回L2中的值
+	if v, ok := reader.Get("k"); !ok || v != "v" {
+		t.Errorf("Get = %v, %v; want v", v, ok)
+	}
+	if _, ok := reader.shardFor("k").load("k"); ok {
+		t.Error("value was promoted although the strategy panicked")
+	}
+	if !reader.keyOwned(func(string) bool { panic("owner") }, "k") {
+		t.Error("KeyOwner panic was not treated as owned")
+	}
+	if n := reader.GetStats()["recovered_panics"]; n != int64(2) {
+		t.Errorf("recovered_panics = %v, want 2", n)
+	}
+}
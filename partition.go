@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// L1Partition 按键前缀划分的L1容量份额
+// 淘汰时优先从超出份额最多的分区中淘汰，某个分区的突发写入不会把其他分区的项全部挤出L1
+type L1Partition struct {
+	Prefix string  // 键前缀，多个分区匹配时取最长的前缀；空字符串匹配其余所有键
+	Weight float64 // 份额权重，各分区按权重比例分配MaxL1Size
+}
+
+// validatePartitions 校验L1分区配置
+func validatePartitions(partitions []L1Partition) error {
+	seen := make(map[string]bool, len(partitions))
+	for _, p := range partitions {
+		if p.Weight <= 0 {
+			return errors.New("L1分区的权重必须大于0")
+		}
+		if seen[p.Prefix] {
+			return errors.New("L1分区前缀重复: " + p.Prefix)
+		}
+		seen[p.Prefix] = true
+	}
+	return nil
+}
+
+// partitionOf 返回键所属的分区下标，不属于任何分区时返回-1
+func partitionOf(partitions []L1Partition, key string) int {
+	best := -1
+	for i, p := range partitions {
+		if strings.HasPrefix(key, p.Prefix) && (best < 0 || len(p.Prefix) > len(partitions[best].Prefix)) {
+			best = i
+		}
+	}
+	return best
+}
+
+// evictionVictims 从L1的所有项中选出最先淘汰的count项
+// 未配置分区时按LRU选择；配置分区时每次从超出份额最多的分区中选择LRU的项，不属于任何分区的键份额为0
+func (c *MultiLevelCache) evictionVictims(items []keyedItem, count int) []keyedItem {
+	config := c.config()
+	partitions := config.L1Partitions
+	if len(partitions) == 0 {
+		sortForEviction(items)
+		if count > len(items) {
+			count = len(items)
+		}
+		return items[:count]
+	}
+
+	// 按分区分组，最后一组为不属于任何分区的键
+	groups := make([][]keyedItem, len(partitions)+1)
+	for _, ki := range items {
+		i := partitionOf(partitions, ki.key)
+		if i < 0 {
+			i = len(partitions)
+		}
+		groups[i] = append(groups[i], ki)
+	}
+	total := 0.0
+	for _, p := range partitions {
+		total += p.Weight
+	}
+	quotas := make([]float64, len(groups))
+	for i, p := range partitions {
+		quotas[i] = p.Weight / total * float64(config.MaxL1Size)
+	}
+
+	// 逐个分配淘汰名额给超出份额最多的分区
+	remaining := make([]int, len(groups))
+	for i := range groups {
+		remaining[i] = len(groups[i])
+	}
+	picks := make([]int, len(groups))
+	for n := 0; n < count; n++ {
+		victim := -1
+		for i := range groups {
+			if remaining[i] == 0 {
+				continue
+			}
+			if victim < 0 || float64(remaining[i])-quotas[i] > float64(remaining[victim])-quotas[victim] {
+				victim = i
+			}
+		}
+		if victim < 0 {
+			break
+		}
+		remaining[victim]--
+		picks[victim]++
+	}
+
+	victims := make([]keyedItem, 0, count)
+	for i, group := range groups {
+		if picks[i] == 0 {
+			continue
+		}
+		sortForEviction(group)
+		victims = append(victims, group[:picks[i]]...)
+	}
+	return victims
+}
+
+// sortForEviction 按淘汰顺序排序(最早访问的在前面)，相同时按确定的顺序裁决
+func sortForEviction(items []keyedItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].item.evictsBefore(items[j].item)
+	})
+}
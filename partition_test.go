@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestPartitionOfPrefersLongestPrefix(t *testing.T) {
+	partitions := []L1Partition{{Prefix: "", Weight: 1}, {Prefix: "user:", Weight: 1}, {Prefix: "user:vip:", Weight: 1}}
+	tests := map[string]int{"order:1": 0, "user:1": 1, "user:vip:1": 2}
+	for key, want := range tests {
+		if got := partitionOf(partitions, key); got != want {
+			t.Errorf("partitionOf(%q) = %d, want %d", key, got, want)
+		}
+	}
+	if got := partitionOf(partitions[1:], "order:1"); got != -1 {
+		t.Errorf("partitionOf without a default partition = %d, want -1", got)
+	}
+}
+
+func TestL1PartitionsProtectOtherPrefixes(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.MaxL1Size = 4
+		config.L1Partitions = []L1Partition{{Prefix: "a:", Weight: 1}, {Prefix: "b:", Weight: 1}}
+	})
+	for _, key := range []string{"b:1", "b:2"} {
+		if err := c.Set(key, key, 60); err != nil {
+			t.Fatal(err)
+		}
+		item, _ := c.shardFor(key).load(key)
+		item.AccessTime -= 100
+	}
+	// a:的突发写入只淘汰a:自己超出份额的项，b:中更早访问的项保留
+	for _, key := range []string{"a:1", "a:2", "a:3", "a:4"} {
+		if err := c.Set(key, key, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"b:1", "b:2"} {
+		if _, ok := c.shardFor(key).load(key); !ok {
+			t.Errorf("%s was evicted by a burst in another partition", key)
+		}
+	}
+	if n := c.l1Count(); n > 4 {
+		t.Errorf("L1 holds %d items, want at most 4", n)
+	}
+}
+
+func TestValidatePartitions(t *testing.T) {
+	if err := validatePartitions([]L1Partition{{Prefix: "a:", Weight: 0}}); err == nil {
+		t.Error("accepted a zero weight")
+	}
+	if err := validatePartitions([]L1Partition{{Prefix: "a:", Weight: 1}, {Prefix: "a:", Weight: 2}}); err == nil {
+		t.Error("accepted a duplicate prefix")
+	}
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Sizer 可报告自身序列化后大致字节数的值，用于判断是否交给序列化工作池
+type Sizer interface {
+	Size() int
+}
+
+// Future 异步操作的结果
+type Future struct {
+	done  chan struct{}
+	value interface{}
+	found bool
+	err   error
+}
+
+// newFuture 创建未完成的Future
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// complete 设置结果并唤醒等待者，只能调用一次
+func (f *Future) complete(value interface{}, found bool, err error) {
+	f.value = value
+	f.found = found
+	f.err = err
+	close(f.done)
+}
+
+// Done 返回操作完成时关闭的通道
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait 等待操作完成并返回错误
+func (f *Future) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// Result 等待操作完成并返回值、是否命中和错误
+func (f *Future) Result() (interface{}, bool, error) {
+	<-f.done
+	return f.value, f.found, f.err
+}
+
+// serializePool 序列化工作池，避免大值的编码阻塞调用方和清理协程
+type serializePool struct {
+	jobs    chan func()
+	stop    <-chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.RWMutex // submit持有读锁入队，wait持有写锁标记停止，之后不会再有任务入队
+	stopped bool
+}
+
+// newSerializePool 创建并启动序列化工作池，stop关闭后工作协程退出
+func newSerializePool(workers int, stop <-chan struct{}) *serializePool {
+	p := &serializePool{
+		jobs: make(chan func(), workers*4),
+		stop: stop,
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// wait 在stop关闭后调用，等待所有工作协程退出并执行队列中剩余的任务
+// 之后提交的任务都在调用方协程执行，等待其结果的调用方不会永远阻塞
+func (p *serializePool) wait() {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+	p.wg.Wait()
+	p.drain()
+}
+
+// drain 执行队列中剩余的任务
+func (p *serializePool) drain() {
+	for {
+		select {
+		case job := <-p.jobs:
+			job()
+		default:
+			return
+		}
+	}
+}
+
+// worker 执行提交的任务直到工作池停止，退出前执行已入队的任务
+func (p *serializePool) worker() {
+	defer p.wg.Done()
+	for {
+		select {
+		case job := <-p.jobs:
+			job()
+		case <-p.stop:
+			p.drain()
+			return
+		}
+	}
+}
+
+// submit 提交任务，工作池已停止时在当前协程执行
+func (p *serializePool) submit(job func()) {
+	p.mu.RLock()
+	if !p.stopped {
+		select {
+		case <-p.stop:
+		default:
+			select {
+			case p.jobs <- job:
+				p.mu.RUnlock()
+				return
+			case <-p.stop:
+			}
+		}
+	}
+	p.mu.RUnlock()
+	job()
+}
+
+// estimateSize 估算值序列化后的字节数，无法估算时返回-1
+func estimateSize(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case json.RawMessage:
+		return len(v)
+	case Sizer:
+		return v.Size()
+	}
+	return -1
+}
+
+// usePool 判断该值是否应交给序列化工作池处理
+func (c *MultiLevelCache) usePool(value interface{}) bool {
+	if c.serializer == nil {
+		return false
+	}
+	threshold := c.config().SerializeThreshold
+	if threshold <= 0 {
+		threshold = 64 * 1024
+	}
+	return estimateSize(value) >= threshold
+}
+
+// marshalItemAsync 序列化缓存项，大值在工作池中执行，Future的值为[]byte
+func (c *MultiLevelCache) marshalItemAsync(key string, item *CacheItem) *Future {
+	f := newFuture()
+	encode := func() {
+		data, err := c.encodeItem(key, item)
+		f.complete(data, err == nil, err)
+	}
+	if c.usePool(item.Value) {
+		c.serializer.submit(encode)
+	} else {
+		encode()
+	}
+	return f
+}
+
+// marshalItem 同步序列化缓存项，大值同样经过工作池以限制并发编码
+func (c *MultiLevelCache) marshalItem(key string, item *CacheItem) ([]byte, error) {
+	data, _, err := c.marshalItemAsync(key, item).Result()
+	if err != nil {
+		return nil, err
+	}
+	return data.([]byte), nil
+}
+
+// demoteToL2 将缓存项写入L2，大值交给工作池异步完成，不阻塞清理流程
+func (c *MultiLevelCache) demoteToL2(key string, item *CacheItem) {
+	write := func() {
+		ttl := item.ExpireTime - c.nowUnix()
+		if ttl <= 0 {
+			return
+		}
+		jsonData, err := c.encodeItem(key, item)
+		if err == nil {
+			_, _ = c.rewriteL2(c.ctx, key, item, jsonData, time.Duration(ttl)*time.Second)
+		}
+	}
+	if c.usePool(item.Value) {
+		c.serializer.submit(write)
+		return
+	}
+	write()
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCloseFlushesCoalescedWritesThroughPool(t *testing.T) {
+	mr := miniredis.RunT(t)
+	for i := 0; i < 20; i++ {
+		c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+			config.SequencedWrites = false
+			config.SerializeWorkers = 1
+			config.SerializeThreshold = 10
+			config.WriteCoalesceWindow = time.Hour
+		})
+		if err := c.Set("big", strings.Repeat("x", 100), 60); err != nil {
+			t.Fatal(err)
+		}
+
+		closed := make(chan error)
+		go func() { closed <- c.Close() }()
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("iteration %d: Close did not return with a coalesced write pending", i)
+		}
+		if !mr.Exists("big") {
+			t.Fatalf("iteration %d: coalesced write was not flushed on Close", i)
+		}
+		mr.FlushAll()
+	}
+}
+
+func TestSerializePoolRunsJobsAfterStop(t *testing.T) {
+	stop := make(chan struct{})
+	p := newSerializePool(1, stop)
+
+	// 工作协程忙碌时入队的任务在停止后仍会执行
+	block := make(chan struct{})
+	p.submit(func() { <-block })
+	var ran int32
+	for i := 0; i < 3; i++ {
+		p.submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	close(stop)
+	close(block)
+	p.wait()
+	if n := atomic.LoadInt32(&ran); n != 3 {
+		t.Errorf("%d of 3 queued jobs ran before wait returned", n)
+	}
+
+	// 停止后提交的任务在调用方协程执行
+	done := false
+	p.submit(func() { done = true })
+	if !done {
+		t.Error("job submitted after stop did not run inline")
+	}
+}
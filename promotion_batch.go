@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// defaultMaxBatchPromotions 单次批量读取默认最多升级到L1的键数
+const defaultMaxBatchPromotions = 64
+
+// promotionCandidate 批量读取中从L2命中的项
+type promotionCandidate struct {
+	key  string
+	item *CacheItem
+}
+
+// maxBatchPromotions 返回单次批量读取最多升级的键数
+func (c *MultiLevelCache) maxBatchPromotions() int {
+	if limit := c.config().MaxBatchPromotions; limit > 0 {
+		return limit
+	}
+	return defaultMaxBatchPromotions
+}
+
+// promoteBatch 对批量读取中从L2命中的项统一判断升级，每次最多升级maxBatchPromotions个
+// 超出上限时优先升级访问次数最多的项，避免一次大批量读取把L1的工作集全部挤出
+func (c *MultiLevelCache) promoteBatch(candidates []promotionCandidate, now int64) {
+	if len(candidates) == 0 {
+		return
+	}
+	admitted := make([]promotionCandidate, 0, len(candidates))
+	for _, cand := range candidates {
+		if c.promotable(cand.key, cand.item) {
+			admitted = append(admitted, cand)
+		}
+	}
+	if limit := c.maxBatchPromotions(); len(admitted) > limit {
+		sort.SliceStable(admitted, func(i, j int) bool {
+			return admitted[i].item.AccessCount > admitted[j].item.AccessCount
+		})
+		atomic.AddInt64(&c.cappedPromotions, int64(len(admitted)-limit))
+		admitted = admitted[:limit]
+	}
+
+	promoted := make(map[string]bool, len(admitted))
+	for _, cand := range admitted {
+		c.applyPromotion(cand.key, cand.item)
+		promoted[cand.key] = true
+	}
+	for _, cand := range candidates {
+		c.writeBackAccess(cand.key, cand.item, promoted[cand.key], time.Duration(cand.item.ExpireTime-now)*time.Second)
+	}
+}
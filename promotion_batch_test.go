@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestBatchPromotionsAreCapped(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+		config.MaxBatchPromotions = 2
+	})
+	// 直接写入L2，各键的访问次数不同
+	now := time.Now().Unix()
+	keys := make([]string, 5)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+		data, err := c.marshalItem(keys[i], &CacheItem{Value: i, ExpireTime: now + 600, CreateTime: now, AccessTime: now, AccessCount: int64(i * 10)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mr.Set(keys[i], string(data))
+		mr.SetTTL(keys[i], 10*time.Minute)
+	}
+
+	result, err := c.GetOrLoadMulti(keys, 600, func([]string) (map[string]interface{}, error) {
+		return nil, errors.New("all keys should be in L2")
+	})
+	if err != nil || len(result) != len(keys) {
+		t.Fatalf("GetOrLoadMulti = %d results, %v; want all from L2", len(result), err)
+	}
+	// 超出上限时只升级访问次数最多的键
+	for i, key := range keys {
+		_, inL1 := c.shardFor(key).load(key)
+		if want := i >= 3; inL1 != want {
+			t.Errorf("%s in L1 = %v, want %v", key, inL1, want)
+		}
+	}
+	if n := c.GetStats()["capped_promotions"]; n != int64(3) {
+		t.Errorf("capped_promotions = %v, want 3", n)
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"sync/atomic"
+)
+
+// promotionStats 升级效果统计
+// 记录每个升级到L1的项在被降级或淘汰前是否在L1中再次命中，用于衡量升级策略是否值得占用的内存
+type promotionStats struct {
+	promotions int64 // 升级次数
+	useful     int64 // 升级后在L1中至少命中一次的次数
+	wasted     int64 // 升级后未命中即被移出L1的次数
+}
+
+// markPromoted 标记缓存项刚从L2升级
+func (item *CacheItem) markPromoted() {
+	atomic.StoreInt32(&item.promotion, promotionPending)
+}
+
+const (
+	promotionNone    int32 = iota // 不是升级来的项或已统计
+	promotionPending              // 升级后尚未在L1命中
+)
+
+// recordPromotion 记录一次升级
+func (c *MultiLevelCache) recordPromotion(item *CacheItem) {
+	item.markPromoted()
+	atomic.AddInt64(&c.promoStats.promotions, 1)
+}
+
+// recordL1Hit 升级来的项第一次在L1命中时记为有效升级
+func (c *MultiLevelCache) recordL1Hit(item *CacheItem) {
+	if atomic.CompareAndSwapInt32(&item.promotion, promotionPending, promotionNone) {
+		atomic.AddInt64(&c.promoStats.useful, 1)
+	}
+}
+
+// recordL1Removal 升级来的项未命中就被移出L1时记为无效升级
+func (c *MultiLevelCache) recordL1Removal(item *CacheItem) {
+	if atomic.CompareAndSwapInt32(&item.promotion, promotionPending, promotionNone) {
+		atomic.AddInt64(&c.promoStats.wasted, 1)
+	}
+}
+
+// promotionStatsMap 返回升级效果统计
+func (c *MultiLevelCache) promotionStatsMap() map[string]interface{} {
+	promotions := atomic.LoadInt64(&c.promoStats.promotions)
+	useful := atomic.LoadInt64(&c.promoStats.useful)
+	wasted := atomic.LoadInt64(&c.promoStats.wasted)
+
+	// 只按已有结论的升级计算有效率，仍在L1中等待命中的不计入
+	rate := 0.0
+	if useful+wasted > 0 {
+		rate = float64(useful) / float64(useful+wasted)
+	}
+	return map[string]interface{}{
+		"promotions":            promotions,
+		"useful_promotions":     useful,
+		"wasted_promotions":     wasted,
+		"useful_promotion_rate": rate,
+		"capped_promotions":     atomic.LoadInt64(&c.cappedPromotions),
+	}
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPromotionStats(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer := newRedisTestCache(t, mr, nil)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.MaxL1Size = 2
+		config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+	})
+	for _, key := range []string{"a", "b", "c"} {
+		if err := writer.Set(key, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a升级后再次命中记为有效；b未命中就被c挤出L1记为无效
+	for _, key := range []string{"a", "b", "a", "c"} {
+		if key == "c" {
+			// 访问时间精确到秒，让b成为最久未访问的项
+			b, _ := c.shardFor("b").load("b")
+			b.AccessTime -= 10
+		}
+		if _, found := c.Get(key); !found {
+			t.Fatalf("Get(%q) missed", key)
+		}
+	}
+	if _, ok := c.shardFor("b").load("b"); ok {
+		t.Fatal("b was not evicted from L1")
+	}
+
+	stats := c.GetStats()
+	want := map[string]interface{}{
+		"promotions":            int64(3),
+		"useful_promotions":     int64(1),
+		"wasted_promotions":     int64(1),
+		"useful_promotion_rate": 0.5,
+	}
+	for name, value := range want {
+		if stats[name] != value {
+			t.Errorf("%s = %v, want %v", name, stats[name], value)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package protocache
+
+import (
+	cache "github.com/losanming/DanCache"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoCache 存取固定类型protobuf消息的缓存
+type ProtoCache[T proto.Message] struct {
+	cache *cache.MultiLevelCache
+}
+
+// New 创建ProtoCache
+func New[T proto.Message](c *cache.MultiLevelCache) *ProtoCache[T] {
+	return &ProtoCache[T]{cache: c}
+}
+
+// Set 编码消息并写入缓存
+func (p *ProtoCache[T]) Set(key string, msg T, ttl int64) error {
+	encoded, err := Encode(msg)
+	if err != nil {
+		return err
+	}
+	return p.cache.Set(key, encoded, ttl)
+}
+
+// Get 获取并解码消息，缓存值无法按T解码时返回错误
+func (p *ProtoCache[T]) Get(key string) (T, bool, error) {
+	var zero T
+	val, found := p.cache.Get(key)
+	if !found {
+		return zero, false, nil
+	}
+	msg, err := p.decode(val)
+	if err != nil {
+		return zero, false, err
+	}
+	return msg, true, nil
+}
+
+// GetOrLoad 获取消息，未命中时调用loader加载并写入缓存
+func (p *ProtoCache[T]) GetOrLoad(key string, ttl int64, loader func() (T, error)) (T, error) {
+	var zero T
+	val, err := p.cache.GetOrLoad(key, ttl, func() (interface{}, error) {
+		msg, err := loader()
+		if err != nil {
+			return nil, err
+		}
+		return Encode(msg)
+	})
+	if err != nil {
+		return zero, err
+	}
+	return p.decode(val)
+}
+
+// Delete 删除消息
+func (p *ProtoCache[T]) Delete(key string) error {
+	return p.cache.Delete(key)
+}
+
+// decode 创建T类型的新消息并解码缓存值
+func (p *ProtoCache[T]) decode(val interface{}) (T, error) {
+	var zero T
+	msg := zero.ProtoReflect().Type().New().Interface().(T)
+	if err := DecodeInto(val, msg); err != nil {
+		return zero, err
+	}
+	return msg, nil
+}
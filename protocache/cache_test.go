@@ -0,0 +1,75 @@
+package protocache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	cache "github.com/losanming/DanCache"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtoCacheThroughL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newCache := func() *cache.MultiLevelCache {
+		c, err := cache.NewMultiLevelCache(cache.CacheConfig{
+			EnableL1Cache: true,
+			MaxL1Size:     100,
+			EnableL2Cache: true,
+			RedisOptions:  &redis.Options{Addr: mr.Addr()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	writer := New[*timestamppb.Timestamp](newCache())
+	reader := New[*timestamppb.Timestamp](newCache())
+
+	want := &timestamppb.Timestamp{Seconds: 1700000000, Nanos: 42}
+	if err := writer.Set("ts", want, 60); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := reader.Get("ts")
+	if err != nil || !found || got.GetSeconds() != want.Seconds || got.GetNanos() != want.Nanos {
+		t.Errorf("Get from another instance = %v, %v, %v; want %v", got, found, err, want)
+	}
+
+	loads := 0
+	loader := func() (*timestamppb.Timestamp, error) {
+		loads++
+		return timestamppb.New(want.AsTime()), nil
+	}
+	for i := 0; i < 2; i++ {
+		if got, err := reader.GetOrLoad("loaded", 60, loader); err != nil || got.GetNanos() != 42 {
+			t.Errorf("GetOrLoad = %v, %v", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loader called %d times, want 1", loads)
+	}
+
+	if err := reader.Delete("ts"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := reader.Get("ts"); found {
+		t.Error("Get after Delete found the message")
+	}
+}
+
+func TestProtoCacheTypeMismatch(t *testing.T) {
+	c, err := cache.NewMultiLevelCache(cache.CacheConfig{EnableL1Cache: true, MaxL1Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := New[*wrapperspb.StringValue](c).Set("k", wrapperspb.String("v"), 60); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := New[*timestamppb.Timestamp](c).Get("k"); found || !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Get as another type = %v, %v; want ErrTypeMismatch", found, err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package protocache 在多级缓存中存取protobuf消息
+// 消息以protobuf二进制格式序列化，并记录消息的完整名称用于解码，避免JSON往返破坏oneof和枚举
+package protocache
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ErrTypeMismatch 缓存的消息类型与期望的类型不一致
+var ErrTypeMismatch = errors.New("缓存的消息类型不匹配")
+
+// envelope 缓存的消息
+type envelope struct {
+	Type string `json:"type"` // 消息的完整名称
+	Data string `json:"data"` // base64编码的protobuf数据
+}
+
+// Encode 将消息编码为缓存值
+func Encode(msg proto.Message) (string, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(envelope{
+		Type: string(msg.ProtoReflect().Descriptor().FullName()),
+		Data: base64.StdEncoding.EncodeToString(data),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// DecodeInto 将缓存值解码到已有的消息中，消息类型必须与缓存的类型一致
+func DecodeInto(val interface{}, msg proto.Message) error {
+	env, data, err := unwrap(val)
+	if err != nil {
+		return err
+	}
+	if env.Type != string(msg.ProtoReflect().Descriptor().FullName()) {
+		return ErrTypeMismatch
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// Decode 根据缓存的消息名称从全局注册表创建消息并解码
+func Decode(val interface{}) (proto.Message, error) {
+	env, data, err := unwrap(val)
+	if err != nil {
+		return nil, err
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(env.Type))
+	if err != nil {
+		return nil, err
+	}
+	msg := mt.New().Interface()
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// unwrap 解析缓存值
+func unwrap(val interface{}) (*envelope, []byte, error) {
+	s, ok := val.(string)
+	if !ok {
+		return nil, nil, errors.New("缓存的消息格式错误")
+	}
+	var env envelope
+	if err := json.Unmarshal([]byte(s), &env); err != nil {
+		return nil, nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(env.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &env, data, nil
+}
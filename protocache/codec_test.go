@@ -0,0 +1,50 @@
+package protocache
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	// structpb.Value使用oneof，JSON往返会丢失具体的分支
+	msg, err := structpb.NewValue(map[string]interface{}{"n": 1.5, "ok": true, "tags": []interface{}{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := Encode(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !proto.Equal(decoded, msg) {
+		t.Errorf("Decode = %v, want %v", decoded, msg)
+	}
+	into := &structpb.Value{}
+	if err := DecodeInto(encoded, into); err != nil || !proto.Equal(into, msg) {
+		t.Errorf("DecodeInto = %v, %v; want %v", into, err, msg)
+	}
+}
+
+func TestDecodeIntoRejectsOtherTypes(t *testing.T) {
+	encoded, err := Encode(wrapperspb.String("v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeInto(encoded, &wrapperspb.Int64Value{}); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("DecodeInto with another type = %v, want ErrTypeMismatch", err)
+	}
+	if _, err := Decode(42); err == nil {
+		t.Error("Decode of a non-string value returned nil error")
+	}
+	if _, err := Decode(`{"type":"no.such.Message","data":""}`); err == nil {
+		t.Error("Decode of an unregistered type returned nil error")
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// maxWarmHints 同时保留的预热提示数量上限，超出时忽略新的提示
+const maxWarmHints = 10000
+
+// warmHints 其他实例Publish的键，下次从L2读取时直接升级到L1
+type warmHints struct {
+	mu       sync.Mutex
+	keys     map[string]int64 // 键->提示到期时间(Unix秒，0表示随键过期)
+	pending  int64            // keys中的提示数，为0时读取路径不加锁
+	received int64            // 收到的提示数
+	used     int64            // 因提示直接升级的次数
+}
+
+// Publish 只把值写入L2，并向其他实例广播预热提示：其他实例删除L1中的旧值，下次访问时从L2读取并直接升级到L1
+// 适合生产者只写不读的场景，值不占用生产者的L1；未配置BroadcastChannel时只写入L2，其他实例L1中的旧值保留到过期
+func (c *MultiLevelCache) Publish(key string, value interface{}, ttl int64) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireL2(); err != nil {
+		return err
+	}
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return err
+	}
+	if err := c.set(c.ctx, key, value, ttl, nil, false, callOptions{skipL1: true}); err != nil {
+		return err
+	}
+	var until int64
+	if ttl = c.boundTTL(ttl); ttl > 0 {
+		until = c.nowUnix() + ttl
+	}
+	c.publishHint(key, until)
+	return nil
+}
+
+// publishHint 向其他实例广播预热提示，失败时只记录日志，其他实例的L1保留旧值到过期
+func (c *MultiLevelCache) publishHint(key string, until int64) {
+	if c.config().BroadcastChannel == "" || c.proxyMode() {
+		return
+	}
+	data, err := json.Marshal(broadcastMessage{Origin: c.instanceID, Key: key, Hint: true, Until: until, At: c.now().UnixNano()})
+	if err == nil {
+		err = c.redisClient.Publish(c.ctx, c.config().BroadcastChannel, data).Err()
+	}
+	if err != nil {
+		c.logf("dancache: publish hint %q failed: %v", key, err)
+		return
+	}
+	atomic.AddInt64(&c.broadcastsSent, 1)
+}
+
+// handleWarmHint 删除L1中的旧值并记录提示
+func (c *MultiLevelCache) handleWarmHint(key string, until int64) {
+	c.deleteL1(key)
+	if until > 0 && until <= c.nowUnix() {
+		return
+	}
+	h := &c.hints
+	atomic.AddInt64(&h.received, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.keys == nil {
+		h.keys = make(map[string]int64)
+	}
+	if _, ok := h.keys[key]; !ok && len(h.keys) >= maxWarmHints {
+		h.pruneLocked(c.nowUnix())
+		if len(h.keys) >= maxWarmHints {
+			return
+		}
+	}
+	h.keys[key] = until
+	atomic.StoreInt64(&h.pending, int64(len(h.keys)))
+}
+
+// pruneLocked 删除已到期的提示，调用方需持有mu
+func (h *warmHints) pruneLocked(now int64) {
+	for key, until := range h.keys {
+		if until > 0 && until <= now {
+			delete(h.keys, key)
+		}
+	}
+}
+
+// takeWarmHint 取出键的预热提示，返回提示是否有效；每个提示只使用一次
+func (c *MultiLevelCache) takeWarmHint(key string) bool {
+	h := &c.hints
+	if atomic.LoadInt64(&h.pending) == 0 {
+		return false
+	}
+	h.mu.Lock()
+	until, ok := h.keys[key]
+	if ok {
+		delete(h.keys, key)
+		atomic.StoreInt64(&h.pending, int64(len(h.keys)))
+	}
+	h.mu.Unlock()
+	if !ok || (until > 0 && until <= c.nowUnix()) {
+		return false
+	}
+	atomic.AddInt64(&h.used, 1)
+	return true
+}
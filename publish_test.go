@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPublishWarmsOtherInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	const channel = "dancache:broadcast"
+	configure := func(config *CacheConfig) { config.BroadcastChannel = channel }
+	writer := newRedisTestCache(t, mr, configure)
+	reader := newRedisTestCache(t, mr, configure)
+	waitSubscribed(t, mr, channel, 2)
+
+	if err := reader.Set("k", "old", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Publish("k", "new", 60); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	// 生产者的L1不保留该值
+	if _, ok := writer.shardFor("k").load("k"); ok {
+		t.Error("Publish wrote to the publisher's L1")
+	}
+
+	// 其他实例删除旧值，第一次读取即升级，不必等到升级策略的访问次数
+	deadline := time.Now().Add(2 * time.Second)
+	for reader.GetStats()["warm_hints_received"] != int64(1) {
+		if time.Now().After(deadline) {
+			t.Fatal("warm hint was not received")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := reader.shardFor("k").load("k"); ok {
+		t.Fatal("old value still in the reader's L1")
+	}
+	if v, ok := reader.Get("k"); !ok || v != "new" {
+		t.Fatalf("Get = %v, %v; want new", v, ok)
+	}
+	if item, ok := reader.shardFor("k").load("k"); !ok || item.Value != "new" {
+		t.Error("hinted key was not promoted on the first read")
+	}
+	if n := reader.GetStats()["warm_hints_used"]; n != int64(1) {
+		t.Errorf("warm_hints_used = %v, want 1", n)
+	}
+}
+
+func TestWarmHintExpiresAndIsUsedOnce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	now := time.Now().Unix()
+	c.handleWarmHint("expired", now-1)
+	if c.takeWarmHint("expired") {
+		t.Error("expired hint was used")
+	}
+	c.handleWarmHint("k", now+60)
+	if !c.takeWarmHint("k") {
+		t.Fatal("hint was not used")
+	}
+	if c.takeWarmHint("k") {
+		t.Error("hint was used twice")
+	}
+}
+
+func TestPublishRequiresL2(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	if err := c.Publish("k", "v", 60); !errors.Is(err, ErrL2Disabled) {
+		t.Errorf("Publish on an L1-only cache = %v, want ErrL2Disabled", err)
+	}
+}
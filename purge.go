@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// purgeScanCount 清除时每批扫描的L2键数
+const purgeScanCount = 100
+
+// ItemMeta 清除时提供给判断函数的缓存项信息
+type ItemMeta struct {
+	Level       CacheLevel  // 所在的缓存级别
+	Value       interface{} // 缓存的值，L2中的值无法解码时为nil
+	CreateTime  time.Time
+	ExpireTime  time.Time
+	AccessTime  time.Time
+	AccessCount int64
+	Size        int   // L2中序列化后的字节数，L1中为0
+	DecodeErr   error // L2中的值解码失败的原因
+}
+
+// PurgedEntry 一个被清除的键
+type PurgedEntry struct {
+	Key      string
+	Level    CacheLevel // 匹配判断函数的级别，清除时两级都会删除
+	PurgedAt time.Time
+}
+
+// PurgeReport 清除的审计报告
+type PurgeReport struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	ScannedL1  int // 检查的L1项数
+	ScannedL2  int // 检查的L2键数
+	Purged     []PurgedEntry
+}
+
+// PurgeByPredicate 扫描L1和L2，删除pred返回true的缓存项并返回审计报告，用于执行GDPR等数据删除请求
+// 匹配的键通过Delete同时从两级删除并进入墓碑窗口，阻止进行中的加载回填旧数据
+// L2的扫描受PurgeRate和L2RateLimit限制，不会挤占正常请求；启用RedisProxyMode时只清除L1并返回ErrProxyUnsupported；ctx取消或删除失败时停止，返回已完成部分的报告和错误
+// pred在扫描过程中被调用，不应修改缓存；pred发生panic时视为不匹配；配置了Authorizer时需要其允许OpPurge
+func (c *MultiLevelCache) PurgeByPredicate(ctx context.Context, pred func(key string, meta ItemMeta) bool) (*PurgeReport, error) {
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return nil, err
+	}
+	if err := c.authorize(ctx, OpPurge, ""); err != nil {
+		return nil, err
+	}
+	report := &PurgeReport{StartedAt: time.Now()}
+	err := c.purgeL1(ctx, pred, report)
+	if err == nil {
+		err = c.purgeL2(ctx, pred, report)
+	}
+	report.FinishedAt = time.Now()
+	c.logf("dancache: purge scanned %d L1 items and %d L2 keys, removed %d keys", report.ScannedL1, report.ScannedL2, len(report.Purged))
+	return report, err
+}
+
+// purgeL1 扫描L1中的项
+func (c *MultiLevelCache) purgeL1(ctx context.Context, pred func(key string, meta ItemMeta) bool, report *PurgeReport) error {
+	if !c.config().EnableL1Cache {
+		return nil
+	}
+	var matched []string
+	for _, shard := range c.shards {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		shard.rangeItems(func(key string, item *CacheItem) bool {
+			report.ScannedL1++
+			meta := ItemMeta{
+				Level:       L1Cache,
+				Value:       c.readValue(item),
+				CreateTime:  time.Unix(item.CreateTime, 0),
+				ExpireTime:  time.Unix(item.ExpireTime, 0),
+				AccessTime:  time.Unix(item.AccessTime, 0),
+				AccessCount: item.AccessCount,
+			}
+			if c.purgeMatch(pred, key, meta) {
+				matched = append(matched, key)
+			}
+			return true
+		})
+	}
+	return c.purgeKeys(matched, L1Cache, report)
+}
+
+// purgeL2 通过SCAN分批扫描L2中的键，跳过标签、墓碑等内部键
+func (c *MultiLevelCache) purgeL2(ctx context.Context, pred func(key string, meta ItemMeta) bool, report *PurgeReport) error {
+	if !c.config().EnableL2Cache {
+		return nil
+	}
+	if c.proxyMode() {
+		return ErrProxyUnsupported
+	}
+	var limiter *rateLimiter
+	if rate := c.config().PurgeRate; rate > 0 {
+		limiter = newRateLimiter(rate)
+	}
+
+	var cursor uint64
+	for {
+		if err := c.waitL2Budget(ctx); err != nil {
+			return err
+		}
+		keys, next, err := c.l2().Scan(ctx, cursor, "", purgeScanCount).Result()
+		if err != nil {
+			return err
+		}
+		batch := keys[:0]
+		for _, key := range keys {
+			if !c.isInternalKey(key) {
+				batch = append(batch, key)
+			}
+		}
+		for range batch {
+			if limiter != nil {
+				if err := limiter.wait(ctx); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(batch) > 0 {
+			if err := c.waitL2Budget(ctx); err != nil {
+				return err
+			}
+			pipe := c.pipeline()
+			gets := make([]*redis.StringCmd, len(batch))
+			for i, key := range batch {
+				gets[i] = pipe.Get(ctx, key)
+			}
+			_, err := pipe.Exec(ctx)
+			pipe.release()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+
+			var matched []string
+			for i, key := range batch {
+				data, err := gets[i].Bytes()
+				if err != nil {
+					continue // 扫描期间已删除或过期
+				}
+				report.ScannedL2++
+				meta := ItemMeta{Level: L2Cache, Size: len(data)}
+				var item CacheItem
+				if err := c.decodeItem(key, data, &item); err != nil {
+					meta.DecodeErr = err
+				} else {
+					meta.Value = item.Value
+					meta.CreateTime = time.Unix(item.CreateTime, 0)
+					meta.ExpireTime = time.Unix(item.ExpireTime, 0)
+					meta.AccessTime = time.Unix(item.AccessTime, 0)
+					meta.AccessCount = item.AccessCount
+				}
+				if c.purgeMatch(pred, key, meta) {
+					matched = append(matched, key)
+				}
+			}
+			if err := c.purgeKeys(matched, L2Cache, report); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// purgeMatch 调用判断函数，发生panic时视为不匹配
+func (c *MultiLevelCache) purgeMatch(pred func(key string, meta ItemMeta) bool, key string, meta ItemMeta) bool {
+	matched := false
+	c.protect("PurgeByPredicate", func() { matched = pred(key, meta) })
+	return matched
+}
+
+// purgeKeys 从两级删除匹配的键并记录到报告
+func (c *MultiLevelCache) purgeKeys(keys []string, level CacheLevel, report *PurgeReport) error {
+	for _, key := range keys {
+		if err := c.Delete(key); err != nil {
+			return fmt.Errorf("清除键%q失败: %w", key, err)
+		}
+		report.Purged = append(report.Purged, PurgedEntry{Key: key, Level: level, PurgedAt: time.Now()})
+	}
+	return nil
+}
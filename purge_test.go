@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPurgeByPredicateRemovesMatchesFromBothLevels(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	for _, key := range []string{"user:1", "user:2", "order:1"} {
+		if err := c.Set(key, key, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.SetWithTags("order:2", "v", 60, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	// 只在L2中的键也要被清除
+	now := time.Now().Unix()
+	data, err := c.marshalItem("user:3", &CacheItem{Value: "user:3", ExpireTime: now + 60, CreateTime: now, AccessTime: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("user:3", string(data))
+
+	var seen []string
+	report, err := c.PurgeByPredicate(context.Background(), func(key string, meta ItemMeta) bool {
+		seen = append(seen, key)
+		return strings.HasPrefix(key, "user:")
+	})
+	if err != nil {
+		t.Fatalf("PurgeByPredicate: %v", err)
+	}
+	for _, key := range seen {
+		if c.isInternalKey(key) {
+			t.Errorf("predicate saw internal key %q", key)
+		}
+	}
+
+	var purged []string
+	levels := make(map[string]CacheLevel)
+	for _, entry := range report.Purged {
+		purged = append(purged, entry.Key)
+		levels[entry.Key] = entry.Level
+	}
+	sort.Strings(purged)
+	if strings.Join(purged, ",") != "user:1,user:2,user:3" {
+		t.Fatalf("purged %v, want user:1, user:2 and user:3", purged)
+	}
+	if levels["user:1"] != L1Cache || levels["user:3"] != L2Cache {
+		t.Errorf("purged levels = %v, want user:1 from L1 and user:3 from L2", levels)
+	}
+	// L1中匹配的键已从两级删除，L2只扫描剩下的三个键
+	if report.ScannedL1 != 4 || report.ScannedL2 != 3 {
+		t.Errorf("scanned %d L1 items and %d L2 keys, want 4 and 3", report.ScannedL1, report.ScannedL2)
+	}
+	if report.FinishedAt.Before(report.StartedAt) {
+		t.Errorf("report finished at %v before it started at %v", report.FinishedAt, report.StartedAt)
+	}
+	for _, key := range []string{"user:1", "user:2", "user:3"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("%s still cached after purge", key)
+		}
+		if mr.Exists(key) {
+			t.Errorf("%s still in L2 after purge", key)
+		}
+	}
+	for _, key := range []string{"order:1", "order:2"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was purged", key)
+		}
+	}
+}
+
+func TestPurgeByPredicateProvidesL2Meta(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.EnableL1Cache = false })
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("garbage", "not an item")
+
+	metas := make(map[string]ItemMeta)
+	if _, err := c.PurgeByPredicate(context.Background(), func(key string, meta ItemMeta) bool {
+		metas[key] = meta
+		return false
+	}); err != nil {
+		t.Fatalf("PurgeByPredicate: %v", err)
+	}
+	if meta := metas["k"]; meta.Level != L2Cache || meta.Value != "v" || meta.Size == 0 || meta.DecodeErr != nil {
+		t.Errorf("meta for k = %+v, want the decoded L2 item", meta)
+	}
+	if meta, ok := metas["garbage"]; !ok || meta.DecodeErr == nil || meta.Value != nil {
+		t.Errorf("meta for garbage = %+v, %v; want a decode error", meta, ok)
+	}
+}
+
+func TestPurgeByPredicatePanicDoesNotMatch(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	for _, key := range []string{"a", "b"} {
+		if err := c.Set(key, key, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err := c.PurgeByPredicate(context.Background(), func(key string, meta ItemMeta) bool {
+		if key == "a" {
+			panic("boom")
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("PurgeByPredicate: %v", err)
+	}
+	if len(report.Purged) != 1 || report.Purged[0].Key != "b" {
+		t.Errorf("purged %+v, want only b", report.Purged)
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a was purged although the predicate panicked")
+	}
+}
+
+func TestPurgeByPredicateStopsOnCancel(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := c.PurgeByPredicate(ctx, func(string, ItemMeta) bool { return true })
+	if !errors.Is(err, context.Canceled) || report == nil {
+		t.Fatalf("PurgeByPredicate = %v, %v; want a partial report and context.Canceled", report, err)
+	}
+	if _, ok := c.Get("k"); !ok {
+		t.Error("cancelled purge removed k")
+	}
+}
+
+func TestPurgeRateValidated(t *testing.T) {
+	if _, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, PurgeRate: -1}); err == nil {
+		t.Error("NewMultiLevelCache accepted a negative PurgeRate")
+	}
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// quarantineKeyPrefix 隔离区的键前缀
+const quarantineKeyPrefix = "quarantine:"
+
+// 隔离的默认参数
+const (
+	defaultQuarantineAfter  = 3
+	defaultQuarantineWindow = time.Minute
+	defaultQuarantineTTL    = 24 * time.Hour
+)
+
+// QuarantineEntry 隔离区中的一条记录
+type QuarantineEntry struct {
+	Key      string    // 原缓存键
+	Payload  []byte    // Redis中的原始值
+	Error    string    // 最后一次解码错误
+	Failures int       // 隔离前的解码失败次数
+	Time     time.Time // 隔离时间
+}
+
+// decodeFailureCount 一个键在窗口内的解码失败次数
+type decodeFailureCount struct {
+	mu    sync.Mutex
+	count int
+	first time.Time
+}
+
+// recordDecodeFailure 记录解码失败，窗口内失败次数达到QuarantineAfter时隔离该值
+func (c *MultiLevelCache) recordDecodeFailure(key string, data []byte, err error) {
+	config := c.config()
+	threshold := config.QuarantineAfter
+	if threshold <= 0 {
+		threshold = defaultQuarantineAfter
+	}
+	window := config.QuarantineWindow
+	if window <= 0 {
+		window = defaultQuarantineWindow
+	}
+
+	v, _ := c.failingKeys.LoadOrStore(key, &decodeFailureCount{first: time.Now()})
+	fc := v.(*decodeFailureCount)
+	fc.mu.Lock()
+	if time.Since(fc.first) > window {
+		fc.count, fc.first = 0, time.Now()
+	}
+	fc.count++
+	failures := fc.count
+	fc.mu.Unlock()
+	if failures < threshold {
+		return
+	}
+
+	c.failingKeys.Delete(key)
+	c.quarantine(key, data, err, failures)
+}
+
+// quarantine 将原始值连同错误信息移入隔离区，并在短时间内阻止该键被重新写入
+func (c *MultiLevelCache) quarantine(key string, data []byte, err error, failures int) {
+	ttl := c.config().QuarantineTTL
+	if ttl <= 0 {
+		ttl = defaultQuarantineTTL
+	}
+
+	qkey := c.internalKey(quarantineKeyPrefix, key)
+	_, txErr := c.l2Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(c.ctx, qkey,
+			"payload", data,
+			"error", err.Error(),
+			"failures", failures,
+			"time", time.Now().Unix(),
+		)
+		pipe.Expire(c.ctx, qkey, ttl)
+		if c.sequenced() {
+			sequencedDelete.Eval(c.ctx, pipe, []string{c.sequenceKey(key), key}, c.sequenceTTL(0))
+		} else {
+			pipe.Del(c.ctx, key)
+		}
+		return nil
+	})
+	if txErr != nil {
+		c.logf("dancache: quarantine %q: %v", key, txErr)
+		return
+	}
+	c.logf("dancache: quarantined %q after %d decode failures: %v", key, failures, err)
+
+	c.deleteL1(key)
+	c.addTombstoneFor(key, c.config().QuarantineBlock)
+}
+
+// Quarantined 读取隔离区中的记录，不存在时返回nil
+func (c *MultiLevelCache) Quarantined(key string) (*QuarantineEntry, error) {
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireL2(); err != nil {
+		return nil, err
+	}
+	fields, err := c.l2().HGetAll(c.ctx, c.internalKey(quarantineKeyPrefix, key)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	failures, _ := strconv.Atoi(fields["failures"])
+	ts, _ := strconv.ParseInt(fields["time"], 10, 64)
+	return &QuarantineEntry{
+		Key:      key,
+		Payload:  []byte(fields["payload"]),
+		Error:    fields["error"],
+		Failures: failures,
+		Time:     time.Unix(ts, 0),
+	}, nil
+}
+
+// cleanupDecodeFailures 清理窗口已过的失败计数
+func (c *MultiLevelCache) cleanupDecodeFailures() {
+	window := c.config().QuarantineWindow
+	if window <= 0 {
+		window = defaultQuarantineWindow
+	}
+	c.failingKeys.Range(func(key, value interface{}) bool {
+		fc := value.(*decodeFailureCount)
+		fc.mu.Lock()
+		expired := time.Since(fc.first) > window
+		fc.mu.Unlock()
+		if expired {
+			c.failingKeys.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestQuarantineAfterRepeatedFailures(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.DecodePolicy = DecodeQuarantine
+		config.QuarantineAfter = 2
+		config.QuarantineBlock = time.Minute
+	})
+	mr.Set("k", "{not json")
+
+	c.Get("k")
+	if entry, err := c.Quarantined("k"); err != nil || entry != nil {
+		t.Fatalf("Quarantined after one failure = %v, %v; want nil", entry, err)
+	}
+	c.Get("k")
+
+	entry, err := c.Quarantined("k")
+	if err != nil || entry == nil {
+		t.Fatalf("Quarantined = %v, %v; want an entry", entry, err)
+	}
+	if string(entry.Payload) != "{not json" || entry.Failures != 2 || entry.Error == "" {
+		t.Errorf("entry = %+v, want the raw payload after 2 failures", entry)
+	}
+	if mr.Exists("k") {
+		t.Error("quarantined value was left under the original key")
+	}
+	// 隔离后短时间内阻止写入，避免同一来源立即写回坏值
+	if err := c.Set("k", "v", 60); err != ErrTombstoned {
+		t.Errorf("Set after quarantine = %v, want ErrTombstoned", err)
+	}
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QuotaAction 写入超过硬配额时的处理方式
+type QuotaAction int
+
+const (
+	QuotaReject     QuotaAction = iota // 拒绝写入L2并返回*QuotaExceededError
+	QuotaShortenTTL                    // 照常写入，但L2中的过期时间不超过ShortTTL
+)
+
+// defaultQuotaShortTTL QuotaShortenTTL默认的过期时间上限
+const defaultQuotaShortTTL = time.Minute
+
+// quotaBucket 配额统计按过期时间分桶的宽度(秒)
+const quotaBucket = 60
+
+// PrefixQuota 一个键前缀写入L2的字节数配额，用量为本实例写入且尚未过期的载荷字节数(估计值)
+type PrefixQuota struct {
+	Prefix    string        // 键前缀，匹配规范化后的键，多个配额匹配时使用最长的前缀
+	SoftBytes int64         // 软配额：用量超过时记录日志并调用OnQuota，不影响写入(0表示不检查)
+	HardBytes int64         // 硬配额：写入后用量会超过时按Action处理(0表示不限制)
+	Action    QuotaAction   // 超过硬配额时的处理方式
+	ShortTTL  time.Duration // QuotaShortenTTL时L2中的过期时间上限(默认1分钟)
+}
+
+// QuotaEvent 软配额状态变化
+type QuotaEvent struct {
+	Prefix string
+	Firing bool  // true表示用量超过软配额，false表示回落到软配额以下
+	Usage  int64 // 当前用量(字节)
+	Limit  int64 // 软配额(字节)
+}
+
+// QuotaUsage 一个前缀的配额用量
+type QuotaUsage struct {
+	Bytes     int64 // 本实例写入且尚未过期的字节数(估计值)
+	SoftBytes int64
+	HardBytes int64
+	Rejected  int64 // 因超过硬配额被拒绝的写入次数
+	Shortened int64 // 因超过硬配额缩短过期时间的写入次数
+}
+
+// ErrQuotaExceeded 写入超过前缀的硬配额，可通过errors.Is判断所有的*QuotaExceededError
+var ErrQuotaExceeded = errors.New("超过L2配额")
+
+// QuotaExceededError 写入超过前缀的硬配额被拒绝
+type QuotaExceededError struct {
+	Key    string
+	Prefix string
+	Usage  int64
+	Limit  int64
+}
+
+// Error 实现error接口
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("键%q超过前缀%q的L2配额: 已用%d字节，上限%d字节", e.Key, e.Prefix, e.Usage, e.Limit)
+}
+
+// Is 使errors.Is(err, ErrQuotaExceeded)成立
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// quotaLedger 一个前缀写入L2的字节数，按过期时间分桶，到期的桶从用量中扣除
+// 同一个键重复写入或被删除时不会扣除，用量偏高，是保守的估计
+type quotaLedger struct {
+	mu        sync.Mutex
+	buckets   map[int64]int64 // 过期时间所在桶的结束时间(Unix秒)->字节数，不过期的写入记在math.MaxInt64
+	bytes     int64
+	firing    bool
+	rejected  int64
+	shortened int64
+}
+
+// usage 扣除已到期的桶后返回当前用量，调用方需持有mu
+func (l *quotaLedger) usage(now int64) int64 {
+	for end, n := range l.buckets {
+		if end <= now {
+			l.bytes -= n
+			delete(l.buckets, end)
+		}
+	}
+	return l.bytes
+}
+
+// add 记录一次写入，调用方需持有mu
+func (l *quotaLedger) add(size int64, now int64, ttl time.Duration) {
+	end := int64(math.MaxInt64)
+	if ttl > 0 {
+		expire := now + int64((ttl+time.Second-1)/time.Second)
+		end = (expire/quotaBucket + 1) * quotaBucket
+	}
+	l.buckets[end] += size
+	l.bytes += size
+}
+
+// matchQuota 返回键匹配的最长前缀的配额
+func (c *MultiLevelCache) matchQuota(key string) *PrefixQuota {
+	var match *PrefixQuota
+	quotas := c.config().L2Quotas
+	for i := range quotas {
+		q := &quotas[i]
+		if strings.HasPrefix(key, q.Prefix) && (match == nil || len(q.Prefix) > len(match.Prefix)) {
+			match = q
+		}
+	}
+	return match
+}
+
+// ledgerFor 返回前缀的用量记录
+func (c *MultiLevelCache) ledgerFor(prefix string) *quotaLedger {
+	if l, ok := c.quotaLedgers.Load(prefix); ok {
+		return l.(*quotaLedger)
+	}
+	l, _ := c.quotaLedgers.LoadOrStore(prefix, &quotaLedger{buckets: make(map[int64]int64)})
+	return l.(*quotaLedger)
+}
+
+// admitQuota 按键所在前缀的配额检查size字节的写入，返回写入L2应使用的过期时间
+// 超过硬配额时按Action拒绝或缩短过期时间；用量越过软配额时记录日志并调用OnQuota
+func (c *MultiLevelCache) admitQuota(key string, size int, ttl time.Duration) (time.Duration, error) {
+	q := c.matchQuota(key)
+	if q == nil {
+		return ttl, nil
+	}
+	l := c.ledgerFor(q.Prefix)
+	now := c.nowUnix()
+
+	l.mu.Lock()
+	usage := l.usage(now)
+	if q.HardBytes > 0 && usage+int64(size) > q.HardBytes {
+		if q.Action == QuotaReject {
+			l.rejected++
+			l.mu.Unlock()
+			return 0, &QuotaExceededError{Key: key, Prefix: q.Prefix, Usage: usage, Limit: q.HardBytes}
+		}
+		short := q.ShortTTL
+		if short <= 0 {
+			short = defaultQuotaShortTTL
+		}
+		if ttl <= 0 || ttl > short {
+			ttl = short
+		}
+		l.shortened++
+	}
+	l.add(int64(size), now, ttl)
+	usage = l.bytes
+	var event *QuotaEvent
+	if q.SoftBytes > 0 && (usage > q.SoftBytes) != l.firing {
+		l.firing = !l.firing
+		event = &QuotaEvent{Prefix: q.Prefix, Firing: l.firing, Usage: usage, Limit: q.SoftBytes}
+	}
+	l.mu.Unlock()
+
+	if event != nil {
+		c.quotaEvent(*event)
+	}
+	return ttl, nil
+}
+
+// quotaEvent 记录软配额状态变化并调用OnQuota
+func (c *MultiLevelCache) quotaEvent(event QuotaEvent) {
+	if event.Firing {
+		atomic.AddInt64(&c.quotaWarnings, 1)
+		c.logf("dancache: L2 usage of prefix %q is %d bytes, over soft quota %d", event.Prefix, event.Usage, event.Limit)
+	} else {
+		c.logf("dancache: L2 usage of prefix %q is back under soft quota %d", event.Prefix, event.Limit)
+	}
+	if onQuota := c.config().OnQuota; onQuota != nil {
+		c.protect("OnQuota", func() { onQuota(event) })
+	}
+}
+
+// QuotaUsage 返回每个配置了配额的前缀的用量
+// 用量按本实例写入L2的载荷字节数估计，不包括其他实例的写入和Redis的对象开销，重复写入的键在过期前重复计入
+func (c *MultiLevelCache) QuotaUsage() map[string]QuotaUsage {
+	now := c.nowUnix()
+	result := make(map[string]QuotaUsage)
+	for _, q := range c.config().L2Quotas {
+		u := QuotaUsage{SoftBytes: q.SoftBytes, HardBytes: q.HardBytes}
+		if v, ok := c.quotaLedgers.Load(q.Prefix); ok {
+			l := v.(*quotaLedger)
+			l.mu.Lock()
+			u.Bytes = l.usage(now)
+			u.Rejected = l.rejected
+			u.Shortened = l.shortened
+			l.mu.Unlock()
+		}
+		result[q.Prefix] = u
+	}
+	return result
+}
+
+// quotaStatsMap 返回配额统计
+func (c *MultiLevelCache) quotaStatsMap() map[string]interface{} {
+	stats := map[string]interface{}{
+		"l2_quota_warnings": atomic.LoadInt64(&c.quotaWarnings),
+	}
+	for prefix, u := range c.QuotaUsage() {
+		stats["l2_quota_bytes_"+prefix] = u.Bytes
+		stats["l2_quota_rejected_"+prefix] = u.Rejected
+		stats["l2_quota_shortened_"+prefix] = u.Shortened
+	}
+	return stats
+}
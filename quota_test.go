@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestQuotaRejectsOverHardLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	var events []QuotaEvent
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.L2Quotas = []PrefixQuota{
+			{Prefix: "big:", SoftBytes: 400, HardBytes: 700},
+			{Prefix: "big:free:"},
+		}
+		config.OnQuota = func(e QuotaEvent) { events = append(events, e) }
+	})
+	value := strings.Repeat("x", 200)
+	if err := c.Set("big:1", value, 60); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if err := c.Set("big:2", value, 60); err != nil {
+		t.Fatalf("second write: %v", err)
+	}
+	// 用量超过软配额只告警
+	if len(events) != 1 || !events[0].Firing || events[0].Prefix != "big:" || events[0].Limit != 400 {
+		t.Fatalf("quota events = %+v, want the soft quota firing", events)
+	}
+
+	err := c.Set("big:3", value, 60)
+	var quotaErr *QuotaExceededError
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &quotaErr) || quotaErr.Prefix != "big:" || quotaErr.Limit != 700 {
+		t.Fatalf("third write = %v, want a QuotaExceededError for big:", err)
+	}
+	if mr.Exists("big:3") {
+		t.Error("rejected write reached L2")
+	}
+	// 最长前缀的配额优先，其他前缀不受影响
+	if err := c.Set("big:free:1", value, 60); err != nil {
+		t.Errorf("write under an unlimited longer prefix: %v", err)
+	}
+	if err := c.Set("other", value, 60); err != nil {
+		t.Errorf("write without a quota: %v", err)
+	}
+	// 批量写入中任一键超过硬配额时整批不写入
+	if err := c.SetMulti(map[string]ItemOptions{"batch": {Value: "v", TTL: 60}, "big:4": {Value: value, TTL: 60}}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("SetMulti = %v, want ErrQuotaExceeded", err)
+	}
+	if mr.Exists("batch") {
+		t.Error("SetMulti wrote part of a rejected batch")
+	}
+
+	usage := c.QuotaUsage()["big:"]
+	if usage.Bytes <= 400 || usage.Bytes > 700 || usage.Rejected != 2 {
+		t.Errorf("usage = %+v, want between the soft and hard quota with 2 rejections", usage)
+	}
+	if n := c.GetStats()["l2_quota_warnings"]; n != int64(1) {
+		t.Errorf("l2_quota_warnings = %v, want 1", n)
+	}
+}
+
+func TestQuotaShortensTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.L2Quotas = []PrefixQuota{{Prefix: "p:", HardBytes: 400, Action: QuotaShortenTTL, ShortTTL: 10 * time.Second}}
+	})
+	value := strings.Repeat("x", 200)
+	if err := c.Set("p:1", value, 3600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("p:2", value, 3600); err != nil {
+		t.Fatalf("write over the hard quota: %v", err)
+	}
+	if ttl := mr.TTL("p:1"); ttl <= time.Hour-time.Minute {
+		t.Errorf("TTL under the quota = %v, want about 1h", ttl)
+	}
+	if ttl := mr.TTL("p:2"); ttl <= 0 || ttl > 10*time.Second {
+		t.Errorf("TTL over the quota = %v, want at most 10s", ttl)
+	}
+	if n := c.QuotaUsage()["p:"].Shortened; n != 1 {
+		t.Errorf("shortened = %d, want 1", n)
+	}
+}
+
+func TestQuotaLedgerExpires(t *testing.T) {
+	l := &quotaLedger{buckets: make(map[int64]int64)}
+	now := time.Now().Unix()
+	l.add(100, now, time.Minute)
+	l.add(50, now, 0)
+	if u := l.usage(now); u != 150 {
+		t.Fatalf("usage = %d, want 150", u)
+	}
+	// 过期时间所在的桶结束后扣除，不过期的写入保留
+	if u := l.usage(now + 2*quotaBucket + 60); u != 50 {
+		t.Errorf("usage after expiry = %d, want 50", u)
+	}
+}
+
+func TestQuotaValidated(t *testing.T) {
+	config := CacheConfig{EnableL1Cache: true, MaxL1Size: 10, L2Quotas: []PrefixQuota{{Prefix: "p:", HardBytes: -1}}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate accepted a negative quota")
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// rateSlots 滚动计数保留的秒数，多出的一格是正在累计的当前秒
+const rateSlots = 61
+
+// rateWindows 对外提供的滚动窗口(秒)及名称
+var rateWindows = []struct {
+	seconds int64
+	name    string
+}{
+	{1, "1s"},
+	{10, "10s"},
+	{60, "1m"},
+}
+
+// rateSlot 一秒内的计数
+type rateSlot struct {
+	sec int64
+	n   int64
+}
+
+// rateCounter 按秒分桶的滚动计数，无锁累加
+// 跨秒复用桶时与并发的累加存在竞争，个别计数可能丢失，速率是近似值
+type rateCounter struct {
+	slots [rateSlots]rateSlot
+}
+
+// add 在当前秒的桶中计数
+func (r *rateCounter) add(now int64) {
+	s := &r.slots[now%rateSlots]
+	if sec := atomic.LoadInt64(&s.sec); sec != now {
+		if atomic.CompareAndSwapInt64(&s.sec, sec, now) {
+			atomic.StoreInt64(&s.n, 0)
+		}
+	}
+	atomic.AddInt64(&s.n, 1)
+}
+
+// rate 返回最近seconds个完整秒的平均每秒次数，不含正在累计的当前秒
+func (r *rateCounter) rate(now, seconds int64) float64 {
+	var total int64
+	for sec := now - seconds; sec < now; sec++ {
+		s := &r.slots[sec%rateSlots]
+		if atomic.LoadInt64(&s.sec) == sec {
+			total += atomic.LoadInt64(&s.n)
+		}
+	}
+	return float64(total) / float64(seconds)
+}
+
+// opRates 各类操作的滚动计数
+type opRates struct {
+	gets    rateCounter // 查找次数(包括批量读取中的每个键)
+	sets    rateCounter // 写入次数
+	misses  rateCounter // 未命中次数
+	l2Calls rateCounter // Redis调用次数
+}
+
+// recordRate 在计数器中记录一次操作
+func recordRate(r *rateCounter) {
+	r.add(time.Now().Unix())
+}
+
+// Rates 返回最近1秒、10秒和1分钟内各类操作的平均每秒次数
+// 键为"<操作>_rate_<窗口>"，操作为get、set、miss和l2_call，例如get_rate_10s；只统计已结束的完整秒
+func (c *MultiLevelCache) Rates() map[string]float64 {
+	now := time.Now().Unix()
+	counters := []struct {
+		name    string
+		counter *rateCounter
+	}{
+		{"get", &c.rates.gets},
+		{"set", &c.rates.sets},
+		{"miss", &c.rates.misses},
+		{"l2_call", &c.rates.l2Calls},
+	}
+	rates := make(map[string]float64, len(counters)*len(rateWindows))
+	for _, counter := range counters {
+		for _, window := range rateWindows {
+			rates[fmt.Sprintf("%s_rate_%s", counter.name, window.name)] = counter.counter.rate(now, window.seconds)
+		}
+	}
+	return rates
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestRateCounterWindows(t *testing.T) {
+	var r rateCounter
+	const start = 1000
+	for sec := int64(start); sec < start+10; sec++ {
+		for i := int64(0); i < sec-start+1; i++ {
+			r.add(sec)
+		}
+	}
+	now := int64(start + 10)
+	// 最近1秒为10次，最近10秒共55次
+	if got := r.rate(now, 1); got != 10 {
+		t.Errorf("1s rate = %v, want 10", got)
+	}
+	if got := r.rate(now, 10); got != 5.5 {
+		t.Errorf("10s rate = %v, want 5.5", got)
+	}
+	// 正在累计的当前秒不计入
+	r.add(now)
+	if got := r.rate(now, 1); got != 10 {
+		t.Errorf("1s rate with the current second = %v, want 10", got)
+	}
+	// 复用一分钟前的桶时清零，旧计数不会混入
+	later := now + rateSlots
+	r.add(later - 1)
+	if got := r.rate(later, 1); got != 1 {
+		t.Errorf("1s rate after reusing a slot = %v, want 1", got)
+	}
+	if got := r.rate(later, 60); got != 1.0/60 {
+		t.Errorf("1m rate = %v, want one op over 60s", got)
+	}
+}
+
+func TestRatesKeys(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	c.Set("k", "v", 60)
+	c.Get("k")
+	rates := c.Rates()
+	for _, op := range []string{"get", "set", "miss", "l2_call"} {
+		for _, window := range []string{"1s", "10s", "1m"} {
+			if _, ok := rates[op+"_rate_"+window]; !ok {
+				t.Errorf("Rates missing %s_rate_%s", op, window)
+			}
+		}
+	}
+	if _, ok := c.GetStats()["get_rate_1m"]; !ok {
+		t.Error("GetStats missing get_rate_1m")
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ReadRepairPolicy Get命中L1后抽查L2，发现两级的项不一致时的处理方式
+type ReadRepairPolicy int
+
+const (
+	ReadRepairLog      ReadRepairPolicy = iota // 只记录日志和统计，返回L1中的值
+	ReadRepairRefresh                          // 用L2中的项替换L1并返回；L2中已没有该键时删除L1中的项并按未命中处理
+	ReadRepairFreshest                         // 返回写入较晚的一方，L2较新时替换L1；L2中已没有该键时删除L1中的项
+)
+
+// readRepairStats 读修复统计
+type readRepairStats struct {
+	checks     int64 // 抽查次数
+	mismatches int64 // 发现不一致的次数(包括L2中已没有该键)
+	repaired   int64 // 替换或删除L1中的项的次数
+}
+
+// sampleReadRepair 判断本次L1命中是否抽查L2
+func (c *MultiLevelCache) sampleReadRepair() bool {
+	config := c.config()
+	percent := config.ReadRepairPercent
+	return percent > 0 && config.EnableL2Cache && !c.circuitOpen() && rand.Float64()*100 < percent
+}
+
+// readRepair 读取L2中的项与L1命中的项比较，按ReadRepairPolicy返回应使用的项
+// L2读取失败或值在时钟偏差容忍范围内时返回L1中的项
+func (c *MultiLevelCache) readRepair(key string, item *CacheItem, now int64) (*CacheItem, CacheLevel, bool) {
+	s := &c.readRepairs
+	atomic.AddInt64(&s.checks, 1)
+	policy := c.config().ReadRepairPolicy
+
+	data, err := c.l2().Get(c.ctx, key).Bytes()
+	if err == redis.Nil {
+		atomic.AddInt64(&s.mismatches, 1)
+		if policy == ReadRepairLog {
+			c.logf("dancache: read repair %q: present in L1 but missing in L2", key)
+			return item, L1Cache, true
+		}
+		if c.shardFor(key).removeIf(key, item) {
+			c.recordL1Removal(item)
+			atomic.AddInt64(&s.repaired, 1)
+		}
+		return nil, L2Cache, false
+	}
+	if err != nil {
+		return item, L1Cache, true
+	}
+	current, found, settled, err := c.readL2Item(key, data, now)
+	if err != nil || !found || !settled {
+		return item, L1Cache, true
+	}
+	if sameGeneration(item, current) {
+		return item, L1Cache, true
+	}
+
+	atomic.AddInt64(&s.mismatches, 1)
+	switch {
+	case policy == ReadRepairLog:
+		c.logf("dancache: read repair %q: L1 created=%d expire=%d version=%d, L2 created=%d expire=%d version=%d",
+			key, item.CreateTime, item.ExpireTime, item.Version, current.CreateTime, current.ExpireTime, current.Version)
+		return item, L1Cache, true
+	case policy == ReadRepairFreshest && !newerThan(current, item):
+		return item, L1Cache, true
+	}
+	// L2中的项较新或按策略以L2为准，替换L1中的项，期间已被替换的项保留
+	if !c.shardFor(key).removeIf(key, item) {
+		return item, L1Cache, true
+	}
+	c.recordL1Removal(item)
+	current.markL2Synced()
+	c.applyPromotion(key, current)
+	atomic.AddInt64(&s.repaired, 1)
+	return current, L2Cache, true
+}
+
+// sameGeneration 判断两个缓存项是否来自同一次写入：创建时间、过期时间和值版本都相同
+func sameGeneration(a, b *CacheItem) bool {
+	return a.CreateTime == b.CreateTime && a.ExpireTime == b.ExpireTime && a.Version == b.Version
+}
+
+// newerThan 判断a是否比b写入得晚，依次比较创建时间、值版本和过期时间
+func newerThan(a, b *CacheItem) bool {
+	if a.CreateTime != b.CreateTime {
+		return a.CreateTime > b.CreateTime
+	}
+	if a.Version != b.Version {
+		return a.Version > b.Version
+	}
+	return a.ExpireTime > b.ExpireTime
+}
+
+// readRepairStatsMap 返回读修复统计
+func (c *MultiLevelCache) readRepairStatsMap() map[string]interface{} {
+	s := &c.readRepairs
+	return map[string]interface{}{
+		"read_repair_checks":     atomic.LoadInt64(&s.checks),
+		"read_repair_mismatches": atomic.LoadInt64(&s.mismatches),
+		"read_repair_repaired":   atomic.LoadInt64(&s.repaired),
+	}
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestReadRepairPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		policy   ReadRepairPolicy
+		l2Age    int64       // L2中的项相对L1的创建时间差(秒)，0表示从L2删除该键
+		want     interface{} // Get返回的值，nil表示未命中
+		repaired int64
+	}{
+		{"log newer", ReadRepairLog, 10, "l1", 0},
+		{"log missing", ReadRepairLog, 0, "l1", 0},
+		{"refresh older", ReadRepairRefresh, -10, "l2", 1},
+		{"refresh missing", ReadRepairRefresh, 0, nil, 1},
+		{"freshest older", ReadRepairFreshest, -10, "l1", 0},
+		{"freshest newer", ReadRepairFreshest, 10, "l2", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+				config.ReadRepairPercent = 100
+				config.ReadRepairPolicy = tc.policy
+			})
+			if err := c.Set("k", "l1", 60); err != nil {
+				t.Fatal(err)
+			}
+			item, _ := c.shardFor("k").load("k")
+			if tc.l2Age == 0 {
+				mr.Del("k")
+			} else {
+				data, err := c.marshalItem("k", &CacheItem{Value: "l2", CreateTime: item.CreateTime + tc.l2Age, ExpireTime: item.ExpireTime + tc.l2Age, AccessTime: item.AccessTime})
+				if err != nil {
+					t.Fatal(err)
+				}
+				mr.Set("k", string(data))
+			}
+
+			v, ok := c.Get("k")
+			if tc.want == nil {
+				if ok {
+					t.Errorf("Get = %v, want a miss", v)
+				}
+			} else if !ok || v != tc.want {
+				t.Errorf("Get = %v, %v; want %v", v, ok, tc.want)
+			}
+			// 修复后L1与返回的结果一致
+			l1, inL1 := c.shardFor("k").load("k")
+			if tc.want == nil && inL1 {
+				t.Error("L1 item kept after the key disappeared from L2")
+			} else if tc.want != nil && (!inL1 || l1.Value != tc.want) {
+				t.Errorf("L1 value = %v, %v; want %v", l1, inL1, tc.want)
+			}
+
+			stats := c.GetStats()
+			if stats["read_repair_checks"] != int64(1) || stats["read_repair_mismatches"] != int64(1) || stats["read_repair_repaired"] != tc.repaired {
+				t.Errorf("read repair stats = %v checks, %v mismatches, %v repaired; want 1, 1, %d",
+					stats["read_repair_checks"], stats["read_repair_mismatches"], stats["read_repair_repaired"], tc.repaired)
+			}
+		})
+	}
+}
+
+func TestReadRepairIgnoresSameGeneration(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.ReadRepairPercent = 100
+		config.ReadRepairPolicy = ReadRepairRefresh
+	})
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if v, ok := c.Get("k"); !ok || v != "v" {
+			t.Fatalf("Get = %v, %v; want v", v, ok)
+		}
+	}
+	stats := c.GetStats()
+	if stats["read_repair_checks"] != int64(3) || stats["read_repair_mismatches"] != int64(0) {
+		t.Errorf("read repair stats = %v checks, %v mismatches; want 3, 0", stats["read_repair_checks"], stats["read_repair_mismatches"])
+	}
+}
+
+func TestReadRepairPercentValidated(t *testing.T) {
+	for _, percent := range []float64{-1, 101} {
+		if _, err := NewMultiLevelCache(CacheConfig{EnableL1Cache: true, ReadRepairPercent: percent}); err == nil {
+			t.Errorf("NewMultiLevelCache accepted ReadRepairPercent %v", percent)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ReconcilePolicy 订阅连接重连后，对可能错过失效消息的L1项的处理策略
+type ReconcilePolicy int
+
+const (
+	ReconcileDrop       ReconcilePolicy = iota // 删除重连前写入L1的项，下次读取从L2获取
+	ReconcileRevalidate                        // 与L2中的值比较，只删除已被修改、删除或无法比较的项
+	ReconcileNone                              // 不处理，错过失效的项保留到过期
+)
+
+// subscribeHealthCheck 订阅连接空闲多久后发送PING检查连接
+const subscribeHealthCheck = 5 * time.Second
+
+// reconcileBatchSize 重新校验时每个pipeline读取的键数
+const reconcileBatchSize = 100
+
+// reconcileState 订阅重连后的对账状态
+type reconcileState struct {
+	mu          sync.Mutex
+	lastCutoff  time.Time // 最近一次对账的截止时间，之前重连的订阅已被覆盖
+	reconnects  int64     // 订阅连接的重连次数
+	dropped     int64     // 对账删除的L1项数
+	revalidated int64     // 重新校验后保留的L1项数
+}
+
+// subscribeRoutine 订阅channel并把消息交给handle，直到stop关闭
+// 连接断开后go-redis自动重连并重新订阅，期间发布的消息丢失；收到重新订阅的确认后按ReconcilePolicy处理L1
+func (c *MultiLevelCache) subscribeRoutine(channel string, handle func(payload []byte), stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	pubsub := c.redisClient.Subscribe(ctx, channel)
+	defer pubsub.Close()
+	// 取消ctx不会中断阻塞中的读取，停止时关闭订阅连接，避免等到健康检查超时才退出
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+			pubsub.Close()
+		case <-ctx.Done():
+		}
+	}()
+
+	subscribed := false
+	lastActive := time.Now()
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, subscribeHealthCheck)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// 空闲时PING，连接已断开时由go-redis在下次接收时重连
+				pubsub.Ping(ctx)
+				continue
+			}
+			c.logf("dancache: subscription %q receive failed: %v", channel, err)
+			select {
+			case <-time.After(time.Second):
+			case <-stop:
+				return
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// 第一次确认是初始订阅，之后的确认表示断开后重新订阅
+			if subscribed && m.Kind == "subscribe" {
+				c.reconcileL1(channel, lastActive, stop)
+			}
+			subscribed = true
+		case *redis.Message:
+			handle([]byte(m.Payload))
+		}
+		lastActive = time.Now()
+	}
+}
+
+// reconcileL1 订阅重连后处理断开期间可能错过失效消息的L1项，since为断开前最后一次收到数据的时间
+// 断开期间写入或升级的项同样可能错过之后的失效，截止点取重连的时刻而不是断开的时刻
+func (c *MultiLevelCache) reconcileL1(channel string, since time.Time, stop <-chan struct{}) {
+	s := &c.reconcile
+	reconnected := time.Now()
+	atomic.AddInt64(&s.reconnects, 1)
+	c.logf("dancache: subscription %q reconnected after %s gap", channel, reconnected.Sub(since).Round(time.Millisecond))
+
+	policy := c.config().ReconcilePolicy
+	if policy == ReconcileNone || !c.config().EnableL1Cache {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 多个订阅同时重连时，其他订阅在本次重连之后进行的对账已经覆盖
+	if !s.lastCutoff.Before(reconnected) {
+		return
+	}
+	s.lastCutoff = time.Now()
+	cutoff := atomic.LoadUint64(&c.insertSeq)
+
+	var stale []string
+	var items []*CacheItem
+	c.rangeL1(func(key string, item *CacheItem) bool {
+		if item.seq <= cutoff {
+			stale = append(stale, key)
+			items = append(items, item)
+		}
+		return true
+	})
+
+	if policy == ReconcileRevalidate {
+		for start := 0; start < len(stale); start += reconcileBatchSize {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			end := start + reconcileBatchSize
+			if end > len(stale) {
+				end = len(stale)
+			}
+			c.revalidateL1(stale[start:end], items[start:end])
+		}
+		return
+	}
+
+	dropped := 0
+	for i, key := range stale {
+		if c.dropReconciled(key, items[i]) {
+			dropped++
+		}
+	}
+	c.logf("dancache: reconcile after %q reconnect dropped %d local items", channel, dropped)
+}
+
+// revalidateL1 读取L2中的值，删除与L1不一致的项；读取失败时按不一致处理
+func (c *MultiLevelCache) revalidateL1(keys []string, items []*CacheItem) {
+	if err := c.waitL2Budget(c.ctx); err != nil {
+		return
+	}
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := c.l2().Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(c.ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		c.logf("dancache: revalidate local items failed: %v", err)
+	}
+
+	for i, key := range keys {
+		if c.sameAsL2(key, items[i], cmds[i]) {
+			atomic.AddInt64(&c.reconcile.revalidated, 1)
+			continue
+		}
+		c.dropReconciled(key, items[i])
+	}
+}
+
+// sameAsL2 判断L1项与L2中的值是否一致：过期时间相同且值的JSON形式相同
+func (c *MultiLevelCache) sameAsL2(key string, item *CacheItem, cmd *redis.StringCmd) bool {
+	data, err := cmd.Bytes()
+	if err != nil {
+		return false
+	}
+	var current CacheItem
+	if err := c.decodeItem(key, data, &current); err != nil {
+		return false
+	}
+	if current.ExpireTime != item.ExpireTime {
+		return false
+	}
+	a, errA := normalizeJSON(item.Value)
+	b, errB := normalizeJSON(current.Value)
+	return errA == nil && errB == nil && reflect.DeepEqual(a, b)
+}
+
+// dropReconciled 删除对账发现可能过期的L1项，期间已被替换的项保留，返回是否删除
+func (c *MultiLevelCache) dropReconciled(key string, item *CacheItem) bool {
+	if !c.shardFor(key).removeIf(key, item) {
+		return false
+	}
+	c.recordL1Removal(item)
+	atomic.AddInt64(&c.reconcile.dropped, 1)
+	return true
+}
+
+// reconcileStatsMap 返回订阅重连和对账的统计
+func (c *MultiLevelCache) reconcileStatsMap() map[string]interface{} {
+	s := &c.reconcile
+	return map[string]interface{}{
+		"subscribe_reconnects":  atomic.LoadInt64(&s.reconnects),
+		"reconcile_dropped":     atomic.LoadInt64(&s.dropped),
+		"reconcile_revalidated": atomic.LoadInt64(&s.revalidated),
+	}
+}
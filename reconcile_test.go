@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCloseStopsSubscriptionPromptly(t *testing.T) {
+	mr := miniredis.RunT(t)
+	const channel = "dancache:broadcast"
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.BroadcastChannel = channel })
+	waitSubscribed(t, mr, channel, 1)
+
+	// 阻塞在读取中的订阅随Close退出，而不是等到健康检查超时
+	start := time.Now()
+	c.Close()
+	if elapsed := time.Since(start); elapsed >= subscribeHealthCheck/2 {
+		t.Errorf("Close took %v with an idle subscription", elapsed)
+	}
+}
+
+func TestReconcilePolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy ReconcilePolicy
+		want   map[string]bool // 对账后各键是否仍在L1中
+	}{
+		{"drop", ReconcileDrop, map[string]bool{"same": false, "changed": false}},
+		{"revalidate", ReconcileRevalidate, map[string]bool{"same": true, "changed": false}},
+		{"none", ReconcileNone, map[string]bool{"same": true, "changed": true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.ReconcilePolicy = tc.policy })
+			for _, key := range []string{"same", "changed"} {
+				if err := c.Set(key, "v1", 60); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// 断开期间其他实例修改了L2中的值
+			item, _ := c.shardFor("changed").load("changed")
+			data, err := c.marshalItem("changed", &CacheItem{Value: "v2", ExpireTime: item.ExpireTime, CreateTime: item.CreateTime, AccessTime: item.AccessTime})
+			if err != nil {
+				t.Fatal(err)
+			}
+			mr.Set("changed", string(data))
+
+			c.reconcileL1("test", time.Now().Add(-time.Second), make(chan struct{}))
+			for key, want := range tc.want {
+				if _, ok := c.shardFor(key).load(key); ok != want {
+					t.Errorf("%s in L1 = %v, want %v", key, ok, want)
+				}
+			}
+			// 对账之后写入的项不受影响
+			if err := c.Set("later", "v", 60); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := c.shardFor("later").load("later"); !ok {
+				t.Error("item written after reconcile is missing")
+			}
+			stats := c.GetStats()
+			if stats["subscribe_reconnects"] != int64(1) {
+				t.Errorf("subscribe_reconnects = %v, want 1", stats["subscribe_reconnects"])
+			}
+			dropped, revalidated := 0, 0
+			for _, inL1 := range tc.want {
+				if !inL1 {
+					dropped++
+				} else if tc.policy == ReconcileRevalidate {
+					revalidated++
+				}
+			}
+			if stats["reconcile_dropped"] != int64(dropped) || stats["reconcile_revalidated"] != int64(revalidated) {
+				t.Errorf("reconcile stats = %v dropped, %v revalidated; want %d, %d", stats["reconcile_dropped"], stats["reconcile_revalidated"], dropped, revalidated)
+			}
+		})
+	}
+}
+
+func TestSubscriptionReconnectReconcilesL1(t *testing.T) {
+	mr := miniredis.RunT(t)
+	const channel = "dancache:broadcast"
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.BroadcastChannel = channel })
+	waitSubscribed(t, mr, channel, 1)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// 断开期间的广播丢失，重新订阅后删除之前写入L1的项
+	mr.Close()
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for c.GetStats()["subscribe_reconnects"] != int64(1) {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription did not reconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := c.shardFor("k").load("k"); ok {
+		t.Error("L1 item survived the subscription reconnect")
+	}
+}
@@ -0,0 +1,369 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newRedisTestCache 创建以miniredis为L2、启用写入序号的缓存，configure可以修改默认配置
+func newRedisTestCache(t *testing.T, mr *miniredis.Miniredis, configure func(*CacheConfig)) *MultiLevelCache {
+	t.Helper()
+	config := CacheConfig{
+		EnableL1Cache:   true,
+		MaxL1Size:       100,
+		EnableL2Cache:   true,
+		RedisOptions:    &redis.Options{Addr: mr.Addr()},
+		SequencedWrites: true,
+	}
+	if configure != nil {
+		configure(&config)
+	}
+	c, err := NewMultiLevelCache(config)
+	if err != nil {
+		t.Fatalf("NewMultiLevelCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestSequencedDeleteAsyncBeatsSlowLoad(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan interface{})
+	go func() {
+		val, err := c.GetOrLoad("k", 60, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "old", nil
+		})
+		if err != nil {
+			t.Errorf("GetOrLoad: %v", err)
+		}
+		done <- val
+	}()
+	<-started
+	if err := c.DeleteAsync("k").Wait(); err != nil {
+		t.Fatalf("DeleteAsync: %v", err)
+	}
+	close(release)
+	if val := <-done; val != "old" {
+		t.Errorf("GetOrLoad = %v, want old", val)
+	}
+
+	if mr.Exists("k") {
+		t.Error("value loaded before DeleteAsync was written to L2")
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("value loaded before DeleteAsync is still readable")
+	}
+}
+
+func TestSequencedWritesSupersededByEveryOverwrite(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		overwrite func(c *MultiLevelCache) error
+	}{
+		{"Delete", func(c *MultiLevelCache) error { return c.Delete("p:k") }},
+		{"DeleteAsync", func(c *MultiLevelCache) error { return c.DeleteAsync("p:k").Wait() }},
+		{"SetAsync", func(c *MultiLevelCache) error { return c.SetAsync("p:k", "new", 60).Wait() }},
+		{"InvalidateTags", func(c *MultiLevelCache) error { return c.InvalidateTags("t") }},
+		{"InvalidatePrefix", func(c *MultiLevelCache) error {
+			_, err := c.InvalidatePrefix(ctx, "p:")
+			return err
+		}},
+		{"SetMulti", func(c *MultiLevelCache) error {
+			return c.SetMulti(map[string]ItemOptions{"p:k": {Value: "new", TTL: 60}, "p:other": {Value: "x", TTL: 60}})
+		}},
+		{"Copy", func(c *MultiLevelCache) error {
+			if err := c.Set("p:src", "new", 60); err != nil {
+				return err
+			}
+			_, err := c.Copy("p:src", "p:k")
+			return err
+		}},
+		{"Rename", func(c *MultiLevelCache) error {
+			_, err := c.Rename("p:k", "p:moved")
+			return err
+		}},
+		{"SetWithFreshness", func(c *MultiLevelCache) error {
+			now := time.Now()
+			return c.SetWithFreshness("p:k", "new", Freshness{FreshUntil: now.Add(time.Minute), StaleUntil: now.Add(time.Hour)})
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			c := newRedisTestCache(t, mr, nil)
+			if err := c.SetWithTags("p:k", "v1", 60, "t"); err != nil {
+				t.Fatal(err)
+			}
+			seq, err := c.Sequence("p:k")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.overwrite(c); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			if err := c.Set("p:k", "stale", 60, WithSequence(seq)); !errors.Is(err, ErrSuperseded) {
+				t.Errorf("Set after %s = %v, want ErrSuperseded", tc.name, err)
+			}
+			if data, _ := mr.Get("p:k"); strings.Contains(data, "stale") {
+				t.Errorf("stale write landed in L2 after %s", tc.name)
+			}
+			if v, ok := c.Get("p:k"); ok && v == "stale" {
+				t.Errorf("stale write is readable from L1 after %s", tc.name)
+			}
+		})
+	}
+}
+
+func TestGetOrLoadMultiSequencesAllKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := c.GetOrLoadMulti([]string{"a", "b", "c"}, 60, func(keys []string) (map[string]interface{}, error) {
+			close(started)
+			<-release
+			values := make(map[string]interface{}, len(keys))
+			for _, key := range keys {
+				values[key] = "loaded-" + key
+			}
+			return values, nil
+		})
+		done <- err
+	}()
+	<-started
+	// 三个键的序号在调用loader之前登记
+	for _, key := range []string{"a", "b", "c"} {
+		if !mr.Exists(c.sequenceKey(key)) {
+			t.Errorf("no write sequence reserved for %q before the loader ran", key)
+		}
+	}
+	if err := c.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("GetOrLoadMulti: %v", err)
+	}
+
+	if !mr.Exists("a") || !mr.Exists("c") {
+		t.Error("loaded values were not backfilled")
+	}
+	if mr.Exists("b") {
+		t.Error("value loaded before Delete was backfilled")
+	}
+}
+
+func TestCoalescedWriteSupersededByOtherInstance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.WriteCoalesceWindow = 50 * time.Millisecond })
+	other := newRedisTestCache(t, mr, nil)
+
+	if err := c.Set("k", "v1", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	c.flushAllL2Writes()
+
+	if mr.Exists("k") {
+		t.Error("coalesced write landed after another instance deleted the key")
+	}
+	if _, ok := c.shardFor("k").load("k"); ok {
+		t.Error("superseded coalesced write is still in L1")
+	}
+}
+
+func TestRewriteL2RespectsSequences(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	other := newRedisTestCache(t, mr, nil)
+	ttl := time.Minute
+
+	if err := c.Set("k", "v1", 60); err != nil {
+		t.Fatal(err)
+	}
+	item, ok := c.shardFor("k").load("k")
+	if !ok || item.l2Seq == 0 {
+		t.Fatalf("L1 item has no write sequence: %+v", item)
+	}
+	data, err := c.marshalItem("k", item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written, err := c.rewriteL2(c.ctx, "k", item, data, ttl); err != nil || !written {
+		t.Fatalf("rewriteL2 before any other write = %v, %v; want true", written, err)
+	}
+
+	// 其他实例删除之后，降级和访问信息回写不能恢复该键
+	if err := other.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := c.rewriteL2(c.ctx, "k", item, data, ttl); err != nil || written {
+		t.Errorf("rewriteL2 after Delete = %v, %v; want false", written, err)
+	}
+	if mr.Exists("k") {
+		t.Error("rewrite restored a deleted key")
+	}
+
+	// 从L2读取的项序号未知，不回写
+	if written, err := c.rewriteL2(c.ctx, "k", &CacheItem{Value: "v1"}, data, ttl); err != nil || written {
+		t.Errorf("rewriteL2 without a sequence = %v, %v; want false", written, err)
+	}
+}
+
+func TestSetAsyncLosesToLaterDelete(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+
+	f := c.SetAsync("k", "v", 60)
+	if err := c.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Wait(); err != nil {
+		t.Fatalf("SetAsync: %v", err)
+	}
+	if mr.Exists("k") {
+		t.Error("SetAsync issued before Delete overwrote the delete")
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("value written by SetAsync is readable after Delete")
+	}
+}
+
+func TestSetWithFreshnessReachesL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	other := newRedisTestCache(t, mr, nil)
+
+	now := time.Now()
+	if err := c.SetWithFreshness("stale", "v", Freshness{FreshUntil: now.Add(-time.Minute), StaleUntil: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.shardFor("stale").load("stale"); ok {
+		t.Error("stale value was stored in L1")
+	}
+	if v, state, found := other.GetWithFreshness("stale"); !found || v != "v" || state != Stale {
+		t.Errorf("GetWithFreshness from another instance = %v, %v, %v; want v, Stale, true", v, state, found)
+	}
+	if ttl := mr.TTL("stale"); ttl <= 50*time.Minute {
+		t.Errorf("L2 TTL = %v, want about StaleUntil", ttl)
+	}
+}
+
+func TestGutterProbeClosesCircuit(t *testing.T) {
+	primary := miniredis.RunT(t)
+	gutter := miniredis.RunT(t)
+	c := newRedisTestCache(t, primary, func(config *CacheConfig) {
+		config.EnableL1Cache = false
+		config.SequencedWrites = false
+		config.CircuitFailureThreshold = 2
+		config.CircuitOpenDuration = 100 * time.Millisecond
+		config.GutterRedisOptions = &redis.Options{Addr: gutter.Addr()}
+	})
+
+	primary.Close()
+	for i := 0; i < 2; i++ {
+		_ = c.Set("k", "v", 60)
+	}
+	if !c.circuitOpen() {
+		t.Fatal("circuit did not open after consecutive failures")
+	}
+	if err := c.Set("on-gutter", "v", 60); err != nil {
+		t.Fatalf("Set while the circuit is open: %v", err)
+	}
+	if !gutter.Exists("on-gutter") {
+		t.Fatal("write did not go to the gutter while the circuit was open")
+	}
+
+	// 主Redis恢复后，使用gutter期间的探测请求关闭熔断器，写入回到主Redis
+	if err := primary.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	deadline := time.Now().Add(3 * time.Second)
+	for c.circuitOpen() && time.Now().Before(deadline) {
+		_ = c.Set("probe-trigger", "v", 60)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.circuitOpen() {
+		t.Fatal("circuit stayed open after the primary recovered")
+	}
+	if err := c.Set("back", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if !primary.Exists("back") || gutter.Exists("back") {
+		t.Error("writes did not return to the primary after the circuit closed")
+	}
+}
+
+func TestHashedKeySharedThroughL2(t *testing.T) {
+	mr := miniredis.RunT(t)
+	configure := func(config *CacheConfig) {
+		config.SequencedWrites = false
+		config.MaxKeyLength = 100
+		config.KeyLength = KeyLengthHash
+	}
+	c := newRedisTestCache(t, mr, configure)
+	other := newRedisTestCache(t, mr, configure)
+
+	key := "a" + strings.Repeat("缓", 60)
+	if err := c.Set(key, "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := other.Get(key); !ok || v != "v" {
+		t.Errorf("Get from another instance = %v, %v; want v, true", v, ok)
+	}
+	for _, stored := range mr.Keys() {
+		if !utf8.ValidString(stored) || len(stored) > 100 {
+			t.Errorf("L2 key %q is not a valid hashed key", stored)
+		}
+	}
+}
+
+func TestInternalKeysAreNamespaced(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	var invalid *InvalidKeyError
+	for _, key := range []string{"seq:{k}", "tag:t", "tombstone:k", "gen:org"} {
+		if err := c.Set(key, "v", 60); !errors.As(err, &invalid) {
+			t.Errorf("Set(%q) = %v, want *InvalidKeyError", key, err)
+		}
+	}
+
+	c = newRedisTestCache(t, mr, func(config *CacheConfig) { config.InternalKeyPrefix = "__dc:" })
+	if err := c.SetWithTags("k", "v", 60, "t"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"__dc:seq:{k}", "__dc:tag:t"} {
+		if !mr.Exists(key) {
+			t.Errorf("internal key %q was not written under InternalKeyPrefix", key)
+		}
+	}
+	if err := c.Set("tag:t", "user data", 60); err != nil {
+		t.Errorf("Set(tag:t) with InternalKeyPrefix = %v, want nil", err)
+	}
+	if err := c.Set("__dc:seq:{k}", "v", 60); !errors.As(err, &invalid) {
+		t.Errorf("Set inside InternalKeyPrefix = %v, want *InvalidKeyError", err)
+	}
+	if err := c.InvalidateTags("t"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("k") || !mr.Exists("tag:t") {
+		t.Error("InvalidateTags did not use the namespaced tag index")
+	}
+}
@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultRegionChannel 跨区域失效的默认频道
+const defaultRegionChannel = "dancache:region"
+
+// defaultRegionQueueSize 每个远端区域等待发送的失效消息默认上限
+const defaultRegionQueueSize = 1024
+
+// regionBatchSize 一次pipeline发往远端区域的最多消息数
+const regionBatchSize = 64
+
+// regionMessage 发往其他区域的失效消息，At为源区域写入的时间(Unix纳秒)，用于计算跨区域延迟
+type regionMessage struct {
+	Region string   `json:"r"`
+	Keys   []string `json:"k"`
+	At     int64    `json:"t"`
+}
+
+// regionPeer 一个远端区域的Redis客户端和发送队列
+type regionPeer struct {
+	name    string
+	client  *redis.Client
+	queue   chan regionMessage
+	sent    int64 // 发送成功的消息数
+	failed  int64 // 发送失败的消息数
+	dropped int64 // 队列已满被丢弃的消息数
+}
+
+// regionState 多区域部署的状态
+type regionState struct {
+	peers []*regionPeer
+	lags  sync.Map // 源区域->*lagStats
+}
+
+// RegionStat 与一个区域之间的跨区域失效统计
+type RegionStat struct {
+	Sent     int64         // 发往该区域的失效消息数
+	Failed   int64         // 发往该区域失败的消息数
+	Dropped  int64         // 因队列已满未发往该区域的消息数
+	Pending  int           // 等待发往该区域的消息数
+	Received int64         // 收到的来自该区域的失效消息数
+	AvgLag   time.Duration // 来自该区域的写入到本实例删除L1旧值的平均延迟，即本实例读到旧值的时间窗口
+	MaxLag   time.Duration // 来自该区域的最大延迟
+	LastLag  time.Duration // 来自该区域的最近一次延迟
+}
+
+// regionRedisOptions 返回本区域的Redis配置，未配置区域时使用RedisOptions
+func regionRedisOptions(config *CacheConfig) *redis.Options {
+	if opts := config.RegionRedisOptions[config.Region]; config.Region != "" && opts != nil {
+		return opts
+	}
+	return config.RedisOptions
+}
+
+// setupRegions 为其他区域创建Redis客户端和发送队列
+func (c *MultiLevelCache) setupRegions(config *CacheConfig) error {
+	if config.Region == "" {
+		return nil
+	}
+	size := config.RegionQueueSize
+	if size <= 0 {
+		size = defaultRegionQueueSize
+	}
+	names := make([]string, 0, len(config.RegionRedisOptions))
+	for name := range config.RegionRedisOptions {
+		if name != config.Region {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	state := &regionState{}
+	for _, name := range names {
+		opts, err := c.redisOptions(config, config.RegionRedisOptions[name])
+		if err != nil {
+			state.close()
+			return err
+		}
+		state.peers = append(state.peers, &regionPeer{
+			name:   name,
+			client: redis.NewClient(opts),
+			queue:  make(chan regionMessage, size),
+		})
+	}
+	c.regions = state
+	return nil
+}
+
+// regionChannel 返回跨区域失效频道
+func (c *MultiLevelCache) regionChannel() string {
+	if ch := c.config().RegionChannel; ch != "" {
+		return ch
+	}
+	return defaultRegionChannel
+}
+
+// replicate 将本区域对keys的写入或删除异步通知其他区域，队列已满时丢弃并计数，不阻塞调用方
+func (c *MultiLevelCache) replicate(keys ...string) {
+	if c.regions == nil || len(c.regions.peers) == 0 || len(keys) == 0 {
+		return
+	}
+	msg := regionMessage{
+		Region: c.config().Region,
+		Keys:   keys,
+		At:     c.now().UnixNano(),
+	}
+	for _, peer := range c.regions.peers {
+		select {
+		case peer.queue <- msg:
+		default:
+			atomic.AddInt64(&peer.dropped, 1)
+		}
+	}
+}
+
+// startRegionSenders 为每个远端区域启动发送协程，不随PauseMaintenance暂停，Close时发出剩余消息后退出
+func (c *MultiLevelCache) startRegionSenders() {
+	if c.regions == nil {
+		return
+	}
+	for _, peer := range c.regions.peers {
+		peer := peer
+		c.goBackground(func() {
+			c.regionSenderRoutine(peer, c.stopCleanup)
+		})
+	}
+}
+
+// regionSenderRoutine 将失效消息发往远端区域：删除该区域Redis中的键并发布消息，由该区域的实例删除各自L1中的旧值
+// 停止时发出队列中剩余的消息
+func (c *MultiLevelCache) regionSenderRoutine(peer *regionPeer, stop <-chan struct{}) {
+	for {
+		select {
+		case msg := <-peer.queue:
+			c.sendRegionBatch(peer, msg)
+		case <-stop:
+			for {
+				select {
+				case msg := <-peer.queue:
+					c.sendRegionBatch(peer, msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendRegionBatch 将msg和队列中已有的消息合并到一个pipeline中发送
+func (c *MultiLevelCache) sendRegionBatch(peer *regionPeer, first regionMessage) {
+	batch := []regionMessage{first}
+drain:
+	for len(batch) < regionBatchSize {
+		select {
+		case msg := <-peer.queue:
+			batch = append(batch, msg)
+		default:
+			break drain
+		}
+	}
+
+	channel := c.regionChannel()
+	_, err := peer.client.Pipelined(c.ctx, func(pipe redis.Pipeliner) error {
+		for _, msg := range batch {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if c.sequenced() {
+				// 远端区域的写入同样检查写入序号，删除需要递增远端的序号
+				for _, key := range msg.Keys {
+					sequencedDelete.Eval(c.ctx, pipe, []string{c.sequenceKey(key), key}, c.sequenceTTL(0))
+				}
+			} else {
+				pipe.Del(c.ctx, msg.Keys...)
+			}
+			pipe.Publish(c.ctx, channel, data)
+		}
+		return nil
+	})
+	if err != nil {
+		atomic.AddInt64(&peer.failed, int64(len(batch)))
+		c.logf("dancache: replicate %d invalidations to region %q failed: %v", len(batch), peer.name, err)
+		return
+	}
+	atomic.AddInt64(&peer.sent, int64(len(batch)))
+}
+
+// regionListenerRoutine 接收其他区域发来的失效消息
+func (c *MultiLevelCache) regionListenerRoutine(stop <-chan struct{}) {
+	c.subscribeRoutine(c.regionChannel(), c.handleRegionMessage, stop)
+}
+
+// handleRegionMessage 删除其他区域修改的键在L1中的旧值并记录延迟，L2中的键已由发送方删除
+func (c *MultiLevelCache) handleRegionMessage(payload []byte) {
+	var msg regionMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		c.logf("dancache: decode region invalidation failed: %v", err)
+		return
+	}
+	if msg.Region == c.config().Region {
+		return
+	}
+	for _, key := range msg.Keys {
+		c.cancelL2Write(key)
+		if c.config().EnableL1Cache {
+			c.deleteL1(key)
+		}
+	}
+
+	lag := c.now().UnixNano() - msg.At
+	if lag < 0 {
+		lag = 0
+	}
+	v, _ := c.regions.lags.LoadOrStore(msg.Region, &lagStats{})
+	v.(*lagStats).record(lag)
+	c.observeLag(TransportRegion, msg.Region, msg.At)
+}
+
+// RegionStats 返回与各区域之间的跨区域失效统计，未配置Region时返回nil
+func (c *MultiLevelCache) RegionStats() map[string]RegionStat {
+	if c.regions == nil {
+		return nil
+	}
+	stats := make(map[string]RegionStat)
+	for _, peer := range c.regions.peers {
+		stats[peer.name] = RegionStat{
+			Sent:    atomic.LoadInt64(&peer.sent),
+			Failed:  atomic.LoadInt64(&peer.failed),
+			Dropped: atomic.LoadInt64(&peer.dropped),
+			Pending: len(peer.queue),
+		}
+	}
+	c.regions.lags.Range(func(k, v interface{}) bool {
+		lag := v.(*lagStats).stat()
+		s := stats[k.(string)]
+		s.Received, s.AvgLag, s.MaxLag, s.LastLag = lag.Count, lag.AvgLag, lag.MaxLag, lag.LastLag
+		stats[k.(string)] = s
+		return true
+	})
+	return stats
+}
+
+// regionStatsMap 返回GetStats中的跨区域失效统计
+func (c *MultiLevelCache) regionStatsMap() map[string]interface{} {
+	if c.regions == nil {
+		return nil
+	}
+	stats := map[string]interface{}{"region": c.config().Region}
+	for name, s := range c.RegionStats() {
+		stats["region_sent_"+name] = s.Sent
+		stats["region_failed_"+name] = s.Failed
+		stats["region_dropped_"+name] = s.Dropped
+		stats["region_pending_"+name] = s.Pending
+		stats["region_received_"+name] = s.Received
+		stats["region_lag_avg_ms_"+name] = s.AvgLag.Milliseconds()
+		stats["region_lag_max_ms_"+name] = s.MaxLag.Milliseconds()
+	}
+	return stats
+}
+
+// close 关闭远端区域的Redis客户端
+func (s *regionState) close() {
+	for _, peer := range s.peers {
+		peer.client.Close()
+	}
+}
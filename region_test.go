@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newRegionTestCache 创建region区域的缓存，各区域的Redis由servers给出
+func newRegionTestCache(t *testing.T, region string, servers map[string]*miniredis.Miniredis) *MultiLevelCache {
+	t.Helper()
+	opts := make(map[string]*redis.Options)
+	for name, mr := range servers {
+		opts[name] = &redis.Options{Addr: mr.Addr()}
+	}
+	return newRedisTestCache(t, servers[region], func(config *CacheConfig) {
+		config.RedisOptions = nil
+		config.Region = region
+		config.RegionRedisOptions = opts
+	})
+}
+
+// waitSubscribed 等待频道上的订阅者达到n个
+func waitSubscribed(t *testing.T, mr *miniredis.Miniredis, channel string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for mr.PubSubNumSub(channel)[channel] < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d listeners did not subscribe to %s", n, channel)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegionWritesInvalidateOtherRegions(t *testing.T) {
+	servers := map[string]*miniredis.Miniredis{"us": miniredis.RunT(t), "eu": miniredis.RunT(t)}
+	us := newRegionTestCache(t, "us", servers)
+	eu := newRegionTestCache(t, "eu", servers)
+	waitSubscribed(t, servers["us"], defaultRegionChannel, 1)
+	waitSubscribed(t, servers["eu"], defaultRegionChannel, 1)
+
+	if err := eu.Set("k", "old", 60); err != nil {
+		t.Fatal(err)
+	}
+	if !servers["eu"].Exists("k") || servers["us"].Exists("k") {
+		t.Fatal("Set did not write only to the local region")
+	}
+	waitRegionStat(t, eu, "us", func(s RegionStat) bool { return s.Sent == 1 })
+
+	// 本区域的写入删除其他区域Redis中的键和L1中的旧值
+	if err := us.Set("k", "new", 60); err != nil {
+		t.Fatal(err)
+	}
+	waitMissing(t, eu, "k")
+	if servers["eu"].Exists("k") {
+		t.Error("k still in the eu Redis after a write in us")
+	}
+	if v, ok := us.Get("k"); !ok || v != "new" {
+		t.Errorf("us Get = %v, %v; want new", v, ok)
+	}
+
+	waitRegionStat(t, eu, "us", func(s RegionStat) bool { return s.Received == 1 })
+	if s := eu.RegionStats()["us"]; s.MaxLag <= 0 || s.LastLag > s.MaxLag {
+		t.Errorf("eu lag from us = %+v, want a recorded lag", s)
+	}
+	if stats := us.GetStats(); stats["region"] != "us" || stats["region_sent_eu"] != int64(1) {
+		t.Errorf("region stats = %v, %v", stats["region"], stats["region_sent_eu"])
+	}
+}
+
+// waitRegionStat 等待与区域name之间的统计满足ok
+func waitRegionStat(t *testing.T, c *MultiLevelCache, name string, ok func(RegionStat) bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !ok(c.RegionStats()[name]) {
+		if time.Now().After(deadline) {
+			t.Fatalf("region stat for %s = %+v", name, c.RegionStats()[name])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegionSendFailureIsCounted(t *testing.T) {
+	servers := map[string]*miniredis.Miniredis{"us": miniredis.RunT(t), "eu": miniredis.RunT(t)}
+	us := newRegionTestCache(t, "us", servers)
+	servers["eu"].Close()
+
+	if err := us.Delete("k"); err != nil {
+		t.Fatalf("Delete with an unreachable region = %v, want nil", err)
+	}
+	waitRegionStat(t, us, "eu", func(s RegionStat) bool { return s.Failed == 1 })
+	if s := us.RegionStats()["eu"]; s.Sent != 0 || s.Pending != 0 {
+		t.Errorf("eu stat = %+v, want only a failure", s)
+	}
+}
+
+func TestRegionValidated(t *testing.T) {
+	for name, config := range map[string]CacheConfig{
+		"proxy mode":  {EnableL2Cache: true, RedisOptions: &redis.Options{}, Region: "us", RedisProxyMode: true},
+		"nil options": {EnableL2Cache: true, RedisOptions: &redis.Options{}, Region: "us", RegionRedisOptions: map[string]*redis.Options{"eu": nil}},
+		"queue size":  {EnableL2Cache: true, RedisOptions: &redis.Options{}, Region: "us", RegionQueueSize: -1},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate accepted the region config", name)
+		}
+	}
+	// 本区域的Redis配置可以代替RedisOptions
+	config := CacheConfig{EnableL2Cache: true, Region: "us", RegionRedisOptions: map[string]*redis.Options{"us": {Addr: "us:6379"}}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate with only a region Redis = %v, want nil", err)
+	}
+	if opts := regionRedisOptions(&config); opts.Addr != "us:6379" {
+		t.Errorf("regionRedisOptions = %q, want the us Redis", opts.Addr)
+	}
+}
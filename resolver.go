@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// ResolverCache GraphQL解析结果缓存
+// 以实体类型、父对象ID和参数生成缓存键，并按实体类型打标签，便于实体变更时整体失效
+// 解析函数与gqlgen等框架无关，可直接在生成的resolver方法中使用
+type ResolverCache struct {
+	cache  *MultiLevelCache
+	ttl    int64
+	newVal func() interface{}
+}
+
+// NewResolverCache 创建新的解析结果缓存
+// newValue不为空时结果以JSON缓存，读取时解码到newValue返回的指针中，保证经过L2后类型不变
+func NewResolverCache(cache *MultiLevelCache, ttl int64, newValue func() interface{}) *ResolverCache {
+	return &ResolverCache{
+		cache:  cache,
+		ttl:    ttl,
+		newVal: newValue,
+	}
+}
+
+// Key 生成解析结果的缓存键
+// 实体类型和父对象ID带长度前缀，包含":"的片段不会与其他组合产生相同的键
+func (r *ResolverCache) Key(typeName, parentID string, args map[string]interface{}) string {
+	return fmt.Sprintf("gql:%d:%s:%d:%s:%s", len(typeName), typeName, len(parentID), parentID, hashArgs(args))
+}
+
+// Resolve 获取单个父对象的解析结果，未命中时调用resolve
+func (r *ResolverCache) Resolve(typeName, parentID string, args map[string]interface{}, resolve func() (interface{}, error)) (interface{}, error) {
+	val, err := r.cache.getOrLoad(r.cache.ctx, r.Key(typeName, parentID, args), r.ttl, []string{typeTag(typeName)}, func(context.Context) (interface{}, error) {
+		val, err := resolve()
+		if err != nil {
+			return nil, err
+		}
+		return r.encode(val)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.decode(val)
+}
+
+// ResolveMany 批量获取多个父对象的解析结果，未命中的父对象ID一次性交给resolve
+// 返回结果以父对象ID为键
+func (r *ResolverCache) ResolveMany(typeName string, parentIDs []string, args map[string]interface{}, resolve func(parentIDs []string) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	keys := make([]string, len(parentIDs))
+	idByKey := make(map[string]string, len(parentIDs))
+	for i, id := range parentIDs {
+		keys[i] = r.Key(typeName, id, args)
+		idByKey[keys[i]] = id
+	}
+
+	values, err := r.cache.getOrLoadMulti(r.cache.ctx, keys, r.ttl, []string{typeTag(typeName)}, func(_ context.Context, missing []string) (map[string]interface{}, error) {
+		ids := make([]string, len(missing))
+		for i, key := range missing {
+			ids[i] = idByKey[key]
+		}
+		resolved, err := resolve(ids)
+		if err != nil {
+			return nil, err
+		}
+		loaded := make(map[string]interface{}, len(resolved))
+		for id, val := range resolved {
+			encoded, err := r.encode(val)
+			if err != nil {
+				return nil, err
+			}
+			loaded[r.Key(typeName, id, args)] = encoded
+		}
+		return loaded, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		decoded, err := r.decode(val)
+		if err != nil {
+			return nil, err
+		}
+		result[idByKey[key]] = decoded
+	}
+	return result, nil
+}
+
+// InvalidateType 使某个实体类型的所有解析结果失效
+func (r *ResolverCache) InvalidateType(typeName string) error {
+	return r.cache.InvalidateTags(typeTag(typeName))
+}
+
+// encode 配置了newValue时将结果编码为JSON字符串
+func (r *ResolverCache) encode(val interface{}) (interface{}, error) {
+	if r.newVal == nil {
+		return val, nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// decode 配置了newValue时将JSON字符串解码为具体类型
+func (r *ResolverCache) decode(val interface{}) (interface{}, error) {
+	if r.newVal == nil {
+		return val, nil
+	}
+	data, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf("缓存的解析结果类型错误: %T", val)
+	}
+	out := r.newVal()
+	if err := json.Unmarshal([]byte(data), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// typeTag 实体类型对应的标签
+func typeTag(typeName string) string {
+	return "gql:type:" + typeName
+}
+
+// hashArgs 计算参数规范化JSON的SHA-256，不同参数得到相同键的概率可以忽略
+func hashArgs(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return "-"
+	}
+	data, err := CanonicalJSON(args)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%#v", args))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
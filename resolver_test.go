@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type testOrder struct {
+	ID    string
+	Total int
+}
+
+func TestResolverKeyDistinguishesSegments(t *testing.T) {
+	r := NewResolverCache(nil, 60, nil)
+	keys := map[string]string{}
+	for _, tc := range [][2]string{
+		{"User:orders", "1"},
+		{"User", "orders:1"},
+		{"User", "orders"},
+		{"User:", "orders"},
+	} {
+		key := r.Key(tc[0], tc[1], nil)
+		if other, ok := keys[key]; ok {
+			t.Errorf("Key(%q, %q) = %q, same as %s", tc[0], tc[1], key, other)
+		}
+		keys[key] = tc[0] + "/" + tc[1]
+	}
+
+	// 参数顺序不影响键，参数值不同时键不同
+	a := r.Key("User", "1", map[string]interface{}{"first": 10, "after": "x"})
+	b := r.Key("User", "1", map[string]interface{}{"after": "x", "first": 10})
+	if a != b {
+		t.Errorf("keys differ by argument order: %q, %q", a, b)
+	}
+	if c := r.Key("User", "1", map[string]interface{}{"first": 11, "after": "x"}); c == a {
+		t.Errorf("different arguments produced the same key %q", c)
+	}
+}
+
+func TestResolverCacheResolve(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	other := newRedisTestCache(t, mr, nil)
+	newOrders := func() interface{} { return new([]testOrder) }
+	r := NewResolverCache(c, 60, newOrders)
+
+	calls := 0
+	resolve := func() (interface{}, error) {
+		calls++
+		return []testOrder{{ID: "o1", Total: 5}}, nil
+	}
+	args := map[string]interface{}{"first": 1}
+	for i := 0; i < 2; i++ {
+		val, err := r.Resolve("Order", "u1", args, resolve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if orders, ok := val.(*[]testOrder); !ok || len(*orders) != 1 || (*orders)[0].ID != "o1" {
+			t.Fatalf("Resolve = %#v, want *[]testOrder with o1", val)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("resolve called %d times, want 1", calls)
+	}
+
+	// 其他实例从L2读取时解码为相同的类型
+	val, err := NewResolverCache(other, 60, newOrders).Resolve("Order", "u1", args, resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := val.(*[]testOrder); !ok || calls != 1 {
+		t.Errorf("Resolve from another instance = %T after %d calls, want *[]testOrder after 1", val, calls)
+	}
+
+	if err := r.InvalidateType("Order"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Resolve("Order", "u1", args, resolve); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("resolve called %d times after InvalidateType, want 2", calls)
+	}
+}
+
+func TestResolverCacheResolveMany(t *testing.T) {
+	c := newRedisTestCache(t, miniredis.RunT(t), nil)
+	r := NewResolverCache(c, 60, nil)
+
+	var requested [][]string
+	resolve := func(ids []string) (map[string]interface{}, error) {
+		sorted := append([]string(nil), ids...)
+		sort.Strings(sorted)
+		requested = append(requested, sorted)
+		result := make(map[string]interface{}, len(ids))
+		for _, id := range ids {
+			result[id] = "orders of " + id
+		}
+		return result, nil
+	}
+	if _, err := r.ResolveMany("Order", []string{"u1"}, nil, resolve); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.ResolveMany("Order", []string{"u1", "u2", "u:3"}, nil, resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"u1": "orders of u1", "u2": "orders of u2", "u:3": "orders of u:3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveMany = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(requested, [][]string{{"u1"}, {"u2", "u:3"}}) {
+		t.Errorf("resolve received %v, want only the missing parents", requested)
+	}
+}
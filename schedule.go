@@ -0,0 +1,380 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// scheduleLockPrefix Redis中定时失效锁的键前缀，同一次触发只由一个实例清理L2
+const scheduleLockPrefix = "schedule:"
+
+// scheduleScanCount 定时失效扫描L2时每批的键数
+const scheduleScanCount = 1000
+
+// cronSchedule 解析后的cron表达式，每个字段是允许取值的位集合
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// cronField cron表达式一个字段的取值范围
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"分钟", 0, 59},
+	{"小时", 0, 23},
+	{"日", 1, 31},
+	{"月", 1, 12},
+	{"星期", 0, 7},
+}
+
+// parseCron 解析五段式cron表达式(分 时 日 月 星期)，支持*、列表、范围和步长，星期中0和7都表示周日
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron表达式需要5个字段: %q", spec)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	// 7与0同为周日
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField 解析一个字段，返回允许取值的位集合
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("cron%s字段的步长无效: %q", f.name, part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := f.min, f.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("cron%s字段无效: %q", f.name, part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("cron%s字段无效: %q", f.name, part)
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("cron%s字段超出范围: %q", f.name, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// matchDay 判断日期是否匹配，日和星期都不是*时满足其一即可(与标准cron一致)
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next 返回t之后的下一次触发时间，5年内没有触发时间时返回零值
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// ScheduleInvalidation 按cron表达式定时失效匹配pattern的键，返回取消该计划的函数
+// pattern使用Redis的glob语法；cronSpec为五段式(分 时 日 月 星期)，按本地时区计算，例如"5 0 * * *"表示每天00:05
+// 每个实例清理自己的L1，L2中的键由最先触发的一个实例通过SCAN删除(启用RedisProxyMode时只清理L1)；配置TimeRedis时各实例在同一时刻触发
+// 配置了Authorizer时需要其允许OpScheduleInvalidation
+func (c *MultiLevelCache) ScheduleInvalidation(pattern, cronSpec string) (func(), error) {
+	return c.ScheduleInvalidationContext(c.ctx, pattern, cronSpec)
+}
+
+// ScheduleInvalidationContext 与ScheduleInvalidation相同，ctx传给Authorizer
+func (c *MultiLevelCache) ScheduleInvalidationContext(ctx context.Context, pattern, cronSpec string) (func(), error) {
+	if err := c.requireLevel(); err != nil {
+		return nil, err
+	}
+	if pattern == "" {
+		return nil, errors.New("失效模式不能为空")
+	}
+	schedule, err := parseCron(cronSpec)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.next(c.now()).IsZero() {
+		return nil, fmt.Errorf("cron表达式永远不会触发: %q", cronSpec)
+	}
+
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	if err := c.authorize(ctx, OpScheduleInvalidation, pattern); err != nil {
+		return nil, err
+	}
+
+	cancel := make(chan struct{})
+	c.goBackground(func() {
+		c.scheduledInvalidationRoutine(pattern, schedule, cancel)
+	})
+
+	var once int32
+	return func() {
+		if atomic.CompareAndSwapInt32(&once, 0, 1) {
+			close(cancel)
+		}
+	}, nil
+}
+
+// scheduledInvalidationRoutine 等待每次触发时间并执行失效
+func (c *MultiLevelCache) scheduledInvalidationRoutine(pattern string, schedule *cronSchedule, cancel chan struct{}) {
+	for {
+		fire := schedule.next(c.now())
+		if fire.IsZero() {
+			return
+		}
+		timer := time.NewTimer(fire.Sub(c.now()))
+		select {
+		case <-timer.C:
+			c.invalidatePattern(pattern, fire)
+		case <-cancel:
+			timer.Stop()
+			return
+		case <-c.stopCleanup:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// invalidatePattern 失效L1和L2中匹配pattern的键，fire用于保证同一次触发只有一个实例清理L2
+func (c *MultiLevelCache) invalidatePattern(pattern string, fire time.Time) {
+	config := c.config()
+	removed := 0
+
+	if config.EnableL1Cache {
+		c.rangeL1(func(key string, item *CacheItem) bool {
+			if globMatch(pattern, key) && c.shardFor(key).removeIf(key, item) {
+				c.recordL1Removal(item)
+				c.cancelL2Write(key)
+				removed++
+			}
+			return true
+		})
+	}
+
+	if config.EnableL2Cache {
+		lock := c.internalKey(scheduleLockPrefix, fmt.Sprintf("%s:%d", pattern, fire.Unix()))
+		acquired, err := c.l2().SetNX(c.ctx, lock, c.instanceID, time.Hour).Result()
+		if err != nil {
+			c.logf("dancache: scheduled invalidation %q failed: %v", pattern, err)
+		} else if acquired {
+			n, err := c.deletePatternL2(pattern)
+			if err != nil {
+				c.logf("dancache: scheduled invalidation %q failed: %v", pattern, err)
+			}
+			removed += n
+		}
+	}
+
+	atomic.AddInt64(&c.scheduledInvalidations, 1)
+	c.logf("dancache: scheduled invalidation %q removed %d keys", pattern, removed)
+}
+
+// deletePatternL2 通过SCAN删除L2中匹配pattern的键，跳过标签、墓碑等内部键
+func (c *MultiLevelCache) deletePatternL2(pattern string) (int, error) {
+	if c.proxyMode() {
+		return 0, ErrProxyUnsupported
+	}
+	deleted := 0
+	var cursor uint64
+	for {
+		keys, next, err := c.l2().Scan(c.ctx, cursor, pattern, scheduleScanCount).Result()
+		if err != nil {
+			return deleted, err
+		}
+		batch := keys[:0]
+		for _, key := range keys {
+			if !c.isInternalKey(key) {
+				batch = append(batch, key)
+			}
+		}
+		if len(batch) > 0 {
+			n, err := c.deleteL2(c.ctx, batch...)
+			if err != nil {
+				return deleted, err
+			}
+			deleted += int(n)
+		}
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
+
+// internalKeyPrefixes 缓存内部使用的Redis键的前缀，位于InternalKeyPrefix之后
+var internalKeyPrefixes = []string{tagKeyPrefix, tombstoneKeyPrefix, quarantineKeyPrefix, scheduleLockPrefix, canaryKeyPrefix, sequenceKeyPrefix, generationKeyPrefix}
+
+// internalKey 返回内部键在Redis中的键名
+func (c *MultiLevelCache) internalKey(prefix, name string) string {
+	return c.config().InternalKeyPrefix + prefix + name
+}
+
+// isInternalKey 判断是否为缓存内部使用的Redis键
+// 配置了InternalKeyPrefix时该前缀下的键都属于内部键
+func (c *MultiLevelCache) isInternalKey(key string) bool {
+	if ns := c.config().InternalKeyPrefix; ns != "" {
+		return strings.HasPrefix(key, ns)
+	}
+	for _, prefix := range internalKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch 按Redis的glob语法匹配键，支持*、?、[...]、[^...]和\转义
+func globMatch(pattern, s string) bool {
+	px, sx := 0, 0
+	starPx, starSx := -1, 0
+	for sx < len(s) {
+		if px < len(pattern) {
+			switch pattern[px] {
+			case '*':
+				starPx, starSx = px, sx
+				px++
+				continue
+			case '?':
+				px++
+				sx++
+				continue
+			case '[':
+				if end, ok := matchClass(pattern[px:], s[sx]); end > 0 {
+					if ok {
+						px += end
+						sx++
+						continue
+					}
+				} else if s[sx] == '[' {
+					px++
+					sx++
+					continue
+				}
+			case '\\':
+				if px+1 < len(pattern) && pattern[px+1] == s[sx] {
+					px += 2
+					sx++
+					continue
+				}
+			default:
+				if pattern[px] == s[sx] {
+					px++
+					sx++
+					continue
+				}
+			}
+		}
+		if starPx < 0 {
+			return false
+		}
+		starSx++
+		px, sx = starPx+1, starSx
+	}
+	for px < len(pattern) && pattern[px] == '*' {
+		px++
+	}
+	return px == len(pattern)
+}
+
+// matchClass 匹配[...]字符类，返回字符类的长度(未闭合时为0)和是否匹配
+func matchClass(class string, b byte) (int, bool) {
+	i := 1
+	negate := i < len(class) && class[i] == '^'
+	if negate {
+		i++
+	}
+	matched := false
+	for ; i < len(class) && class[i] != ']'; i++ {
+		lo := class[i]
+		if lo == '\\' && i+1 < len(class) {
+			i++
+			lo = class[i]
+		}
+		hi := lo
+		if i+2 < len(class) && class[i+1] == '-' && class[i+2] != ']' {
+			hi = class[i+2]
+			i += 2
+		}
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if b >= lo && b <= hi {
+			matched = true
+		}
+	}
+	if i >= len(class) {
+		return 0, false
+	}
+	return i + 1, matched != negate
+}
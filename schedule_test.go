@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2024, 1, 31, 23, 58, 30, 0, time.UTC) // 周三
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC)},
+		{"5 0 * * *", time.Date(2024, 2, 1, 0, 5, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 2, 4, 12, 0, 0, 0, time.UTC)},
+		// 日和星期都指定时满足其一即可
+		{"0 0 15 * 5", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.spec)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.next(from); !got.Equal(tt.want) {
+			t.Errorf("next(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q) accepted an invalid spec", spec)
+		}
+	}
+	never, _ := parseCron("0 0 31 2 *")
+	if got := never.next(from); !got.IsZero() {
+		t.Errorf("next for Feb 31 = %v, want zero", got)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"user:?", "user:12", false},
+		{"*:session:*", "u:session:9", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"[", "[", true},
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestScheduledInvalidationClearsMatchingKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newRedisTestCache(t, mr, nil)
+	b := newRedisTestCache(t, mr, nil)
+	for _, key := range []string{"report:1", "report:2", "user:1"} {
+		if err := a.Set(key, key, 600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Set("report:3", "report:3", 600); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetWithTags("report:4", "tagged", 600, "reports"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一次触发只有一个实例清理L2，每个实例清理自己的L1
+	fire := time.Now().Truncate(time.Minute)
+	a.invalidatePattern("*report*", fire)
+	b.invalidatePattern("*report*", fire)
+	for _, key := range []string{"report:1", "report:2", "report:3", "report:4"} {
+		if mr.Exists(key) {
+			t.Errorf("%s still in L2", key)
+		}
+	}
+	if _, ok := b.shardFor("report:3").load("report:3"); ok {
+		t.Error("report:3 still in the second instance's L1")
+	}
+	if v, ok := a.Get("user:1"); !ok || v != "user:1" {
+		t.Errorf("user:1 = %v, %v; want kept", v, ok)
+	}
+	// 标签索引等内部键不被删除
+	if !mr.Exists(a.internalKey(tagKeyPrefix, "reports")) {
+		t.Error("scheduled invalidation deleted the tag index")
+	}
+	if n := a.GetStats()["scheduled_invalidations"]; n != int64(1) {
+		t.Errorf("scheduled_invalidations = %v, want 1", n)
+	}
+}
+
+func TestScheduleInvalidationValidates(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	if _, err := c.ScheduleInvalidation("", "* * * * *"); err == nil {
+		t.Error("ScheduleInvalidation accepted an empty pattern")
+	}
+	if _, err := c.ScheduleInvalidation("k*", "0 0 31 2 *"); err == nil {
+		t.Error("ScheduleInvalidation accepted a spec that never fires")
+	}
+	cancel, err := c.ScheduleInvalidation("k*", "0 0 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	cancel()
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 内部使用的Lua脚本，启动时通过SCRIPT LOAD预加载，之后用EVALSHA只发送摘要
+var (
+	extendExpire    = redis.NewScript(extendExpireScript)
+	shrinkExpire    = redis.NewScript(shrinkExpireScript)
+	clampExpire     = redis.NewScript(clampExpireScript)
+	incrSequence    = redis.NewScript(reserveSequenceScript)
+	sequencedSet    = redis.NewScript(sequencedSetScript)
+	sequencedDelete = redis.NewScript(sequencedDeleteScript)
+	sequencedCopy   = redis.NewScript(sequencedCopyScript)
+	sequencedRename = redis.NewScript(sequencedRenameScript)
+	bumpGeneration  = redis.NewScript(bumpGenerationScript)
+)
+
+// internalScripts 需要预加载的全部内部脚本
+var internalScripts = []*redis.Script{extendExpire, shrinkExpire, clampExpire, incrSequence, sequencedSet, sequencedDelete, sequencedCopy, sequencedRename, bumpGeneration}
+
+// scriptCache 内部脚本的加载状态和复用的pipeline对象
+type scriptCache struct {
+	loaded    [2]int32     // 主客户端和gutter是否已加载全部内部脚本，未加载时使用EVAL
+	reloads   int64        // 因NOSCRIPT重新加载的次数
+	pipelines [2]sync.Pool // 主客户端和gutter的空闲pipeline
+}
+
+// 客户端在scriptCache中的下标
+const (
+	primaryClient = iota
+	gutterClientIndex
+)
+
+// pooledPipeline 从池中取出的pipeline，用完后调用release归还
+type pooledPipeline struct {
+	redis.Pipeliner
+	index int // 所属客户端的下标
+	pool  *sync.Pool
+}
+
+// release 丢弃未执行的命令并归还pipeline，归还后不能再使用
+func (p *pooledPipeline) release() {
+	_ = p.Discard()
+	p.pool.Put(p)
+}
+
+// pipeline 返回当前L2客户端的pipeline，优先复用池中的对象
+func (c *MultiLevelCache) pipeline() *pooledPipeline {
+	client, index := c.l2Client()
+	pool := &c.scripts.pipelines[index]
+	if p, ok := pool.Get().(*pooledPipeline); ok {
+		return p
+	}
+	return &pooledPipeline{Pipeliner: client.Pipeline(), index: index, pool: pool}
+}
+
+// clientAt 返回下标对应的L2客户端
+func (c *MultiLevelCache) clientAt(index int) RedisCommander {
+	if index == gutterClientIndex {
+		if c.gutterClient == nil {
+			return nil
+		}
+		return c.gutterClient
+	}
+	return c.redisClient
+}
+
+// loadScripts 在客户端上加载全部内部脚本，成功后该客户端的脚本调用改用EVALSHA
+// 代理模式下代理通常不支持SCRIPT LOAD，始终使用EVAL
+func (c *MultiLevelCache) loadScripts(ctx context.Context, index int) error {
+	client := c.clientAt(index)
+	if client == nil || c.proxyMode() {
+		return nil
+	}
+	for _, script := range internalScripts {
+		if err := script.Load(ctx, client).Err(); err != nil {
+			atomic.StoreInt32(&c.scripts.loaded[index], 0)
+			return err
+		}
+	}
+	atomic.StoreInt32(&c.scripts.loaded[index], 1)
+	return nil
+}
+
+// preloadScripts 启动时在主客户端和gutter上预加载内部脚本，失败时只记录日志，脚本调用退回EVAL
+func (c *MultiLevelCache) preloadScripts() {
+	for _, index := range []int{primaryClient, gutterClientIndex} {
+		if err := c.loadScripts(c.ctx, index); err != nil {
+			c.logf("dancache: preload lua scripts failed, falling back to EVAL: %v", err)
+		}
+	}
+}
+
+// evalScript 在pipeline中执行内部脚本，脚本已加载时使用EVALSHA
+func (c *MultiLevelCache) evalScript(ctx context.Context, pipe redis.Pipeliner, index int, script *redis.Script, keys []string, args ...interface{}) *redis.Cmd {
+	if atomic.LoadInt32(&c.scripts.loaded[index]) == 1 {
+		return script.EvalSha(ctx, pipe, keys, args...)
+	}
+	return script.Eval(ctx, pipe, keys, args...)
+}
+
+// isNoScript 判断错误是否为服务端脚本缓存中没有该脚本(重启、故障转移或SCRIPT FLUSH之后)
+func isNoScript(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
+
+// pipelinedScripts 用池中的pipeline执行fn中加入的命令，fn通过传入的eval执行内部脚本
+// 服务端返回NOSCRIPT时重新加载脚本并重试一次；重新加载失败时改用EVAL重试
+func (c *MultiLevelCache) pipelinedScripts(ctx context.Context, fn func(pipe redis.Pipeliner, eval func(script *redis.Script, keys []string, args ...interface{}) *redis.Cmd)) error {
+	for attempt := 0; ; attempt++ {
+		pipe := c.pipeline()
+		index := pipe.index
+		fn(pipe, func(script *redis.Script, keys []string, args ...interface{}) *redis.Cmd {
+			return c.evalScript(ctx, pipe, index, script, keys, args...)
+		})
+		_, err := pipe.Exec(ctx)
+		pipe.release()
+		if !isNoScript(err) || attempt > 0 {
+			return err
+		}
+		atomic.AddInt64(&c.scripts.reloads, 1)
+		if err := c.loadScripts(ctx, index); err != nil {
+			c.logf("dancache: reload lua scripts failed, falling back to EVAL: %v", err)
+		}
+	}
+}
+
+// scriptStatsMap 返回脚本和pipeline复用统计
+func (c *MultiLevelCache) scriptStatsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"scripts_loaded": atomic.LoadInt32(&c.scripts.loaded[primaryClient]) == 1,
+		"script_reloads": atomic.LoadInt64(&c.scripts.reloads),
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestScriptsUseEvalSha(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, recorder := newCommanderTestCache(t, mr, nil)
+	if loaded := c.GetStats()["scripts_loaded"]; loaded != true {
+		t.Fatalf("scripts_loaded = %v, want true", loaded)
+	}
+	if err := c.SetWithTags("k", "v", 60, "t"); err != nil {
+		t.Fatalf("SetWithTags: %v", err)
+	}
+	if recorder.count("evalsha") == 0 || recorder.count("eval") != 0 {
+		t.Errorf("evalsha = %d, eval = %d; want only evalsha", recorder.count("evalsha"), recorder.count("eval"))
+	}
+}
+
+func TestScriptsReloadAfterFlush(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	// 模拟Redis重启后脚本缓存被清空
+	if err := c.redisClient.ScriptFlush(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetWithTags("k", "v", 60, "t"); err != nil {
+		t.Fatalf("SetWithTags after SCRIPT FLUSH: %v", err)
+	}
+	if ttl := mr.TTL(tagKeyPrefix + "t"); ttl <= 0 {
+		t.Errorf("tag index TTL = %v, want it extended", ttl)
+	}
+	stats := c.GetStats()
+	if stats["script_reloads"] != int64(1) || stats["scripts_loaded"] != true {
+		t.Errorf("script stats = %v reloads, loaded %v; want 1, true", stats["script_reloads"], stats["scripts_loaded"])
+	}
+	// 重新加载后不再重试
+	if err := c.SetWithTags("k2", "v", 60, "t"); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.GetStats()["script_reloads"]; n != int64(1) {
+		t.Errorf("script_reloads = %v, want 1", n)
+	}
+}
+
+func TestScriptsUseEvalInProxyMode(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, recorder := newCommanderTestCache(t, mr, func(config *CacheConfig) { config.RedisProxyMode = true })
+	if loaded := c.GetStats()["scripts_loaded"]; loaded != false {
+		t.Errorf("scripts_loaded = %v, want false", loaded)
+	}
+	if err := c.SetWithTags("k", "v", 60, "t"); err != nil {
+		t.Fatalf("SetWithTags: %v", err)
+	}
+	if recorder.count("script") != 0 || recorder.count("eval") == 0 {
+		t.Errorf("script = %d, eval = %d; want EVAL without SCRIPT LOAD", recorder.count("script"), recorder.count("eval"))
+	}
+}
+
+func TestPooledPipelineDiscardsOnRelease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	p := c.pipeline()
+	p.Set(context.Background(), "queued", "v", 0)
+	p.release()
+
+	// 归还的pipeline不保留未执行的命令
+	q := c.pipeline()
+	defer q.release()
+	if n := q.Len(); n != 0 {
+		t.Fatalf("pipeline has %d queued commands, want 0", n)
+	}
+	if _, err := q.Exec(context.Background()); err != nil && err.Error() != "redis: pipeline is empty" {
+		t.Fatal(err)
+	}
+	if mr.Exists("queued") {
+		t.Error("discarded command was executed")
+	}
+}
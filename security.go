@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// clientNamePrefix 默认的Redis连接名前缀，CLIENT LIST中可据此识别缓存的连接
+const clientNamePrefix = "dancache-"
+
+// redisOptions 在opts的副本上应用TLS、ACL用户和连接名配置，不修改调用方传入的配置
+func (c *MultiLevelCache) redisOptions(config *CacheConfig, opts *redis.Options) (*redis.Options, error) {
+	copied := *opts
+	if config.RedisUsername != "" {
+		copied.Username = config.RedisUsername
+	}
+	if config.RedisPassword != "" {
+		copied.Password = config.RedisPassword
+	}
+	if config.RedisTLS {
+		tlsConfig, err := redisTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		copied.TLSConfig = tlsConfig
+	}
+
+	// 代理不支持CLIENT SETNAME，代理模式下默认不设置连接名
+	name := config.RedisClientName
+	if name == "" && !config.RedisProxyMode {
+		name = clientNamePrefix + c.instanceID
+	}
+	if name != "" && name != "-" {
+		onConnect := copied.OnConnect
+		copied.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+			if err := cn.ClientSetName(ctx, name).Err(); err != nil {
+				return fmt.Errorf("设置Redis连接名失败: %w", err)
+			}
+			if onConnect != nil {
+				return onConnect(ctx, cn)
+			}
+			return nil
+		}
+	}
+	return &copied, nil
+}
+
+// redisTLSConfig 根据证书文件创建TLS配置，未指定CA时使用系统根证书
+func redisTLSConfig(config *CacheConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: config.RedisTLSServerName,
+	}
+	if config.RedisTLSCAFile != "" {
+		pem, err := os.ReadFile(config.RedisTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取Redis CA证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("Redis CA证书中没有有效的证书")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.RedisTLSCertFile != "" || config.RedisTLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.RedisTLSCertFile, config.RedisTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取Redis客户端证书失败: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
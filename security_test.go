@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// writeTestCert 生成localhost的自签名证书，同时用作CA、服务端和客户端证书
+func writeTestCert(t *testing.T) (certFile, keyFile string, cert tls.Certificate, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+	return certFile, keyFile, cert, pool
+}
+
+func TestRedisMutualTLS(t *testing.T) {
+	certFile, keyFile, cert, pool := writeTestCert(t)
+	mr := miniredis.NewMiniRedis()
+	if err := mr.StartTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.RedisTLS = true
+		config.RedisTLSCAFile = certFile
+		config.RedisTLSCertFile = certFile
+		config.RedisTLSKeyFile = keyFile
+		config.RedisTLSServerName = "localhost"
+	})
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatalf("Set over TLS: %v", err)
+	}
+	if !mr.Exists("k") {
+		t.Error("k was not written over TLS")
+	}
+
+	// 没有客户端证书时服务端拒绝连接
+	if plain, err := NewMultiLevelCache(CacheConfig{
+		EnableL2Cache:      true,
+		RedisOptions:       &redis.Options{Addr: mr.Addr()},
+		RedisTLS:           true,
+		RedisTLSCAFile:     certFile,
+		RedisTLSServerName: "localhost",
+	}); err == nil {
+		plain.Close()
+		t.Error("connected without a client certificate")
+	}
+}
+
+func TestRedisTLSConfigErrors(t *testing.T) {
+	certFile, _, _, _ := writeTestCert(t)
+	if _, err := redisTLSConfig(&CacheConfig{RedisTLSCAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("redisTLSConfig accepted a missing CA file")
+	}
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("no certificates"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := redisTLSConfig(&CacheConfig{RedisTLSCAFile: empty}); err == nil {
+		t.Error("redisTLSConfig accepted a CA file without certificates")
+	}
+	// 私钥文件不是证书对应的私钥
+	if _, err := redisTLSConfig(&CacheConfig{RedisTLSCertFile: certFile, RedisTLSKeyFile: certFile}); err == nil {
+		t.Error("redisTLSConfig accepted a certificate without its key")
+	}
+	if err := (CacheConfig{EnableL1Cache: true, MaxL1Size: 10, RedisTLS: true, RedisTLSCertFile: certFile}).Validate(); err == nil {
+		t.Error("Validate accepted a client certificate without a key")
+	}
+}
+
+func TestRedisACLUser(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireUserAuth("cache", "secret")
+	opts := &redis.Options{Addr: mr.Addr()}
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.RedisOptions = opts
+		config.RedisUsername = "cache"
+		config.RedisPassword = "secret"
+	})
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatalf("Set as ACL user: %v", err)
+	}
+	// 调用方传入的RedisOptions不被修改
+	if opts.Username != "" || opts.Password != "" {
+		t.Errorf("RedisOptions was modified: %q/%q", opts.Username, opts.Password)
+	}
+
+	if wrong, err := NewMultiLevelCache(CacheConfig{
+		EnableL2Cache: true,
+		RedisOptions:  &redis.Options{Addr: mr.Addr()},
+		RedisUsername: "cache",
+		RedisPassword: "wrong",
+	}); err == nil {
+		wrong.Close()
+		t.Error("connected with a wrong password")
+	}
+}
+
+func TestRedisClientName(t *testing.T) {
+	mr := miniredis.RunT(t)
+	clientName := func(c *MultiLevelCache) string {
+		t.Helper()
+		name, err := c.redisClient.(*redis.Client).ClientGetName(context.Background()).Result()
+		if err != nil && err != redis.Nil {
+			t.Fatalf("CLIENT GETNAME: %v", err)
+		}
+		return name
+	}
+
+	c := newRedisTestCache(t, mr, nil)
+	if name := clientName(c); name != clientNamePrefix+c.instanceID || !strings.HasPrefix(name, "dancache-") {
+		t.Errorf("default client name = %q, want %q", name, clientNamePrefix+c.instanceID)
+	}
+	named := newRedisTestCache(t, mr, func(config *CacheConfig) { config.RedisClientName = "orders-api" })
+	if name := clientName(named); name != "orders-api" {
+		t.Errorf("client name = %q, want orders-api", name)
+	}
+	unnamed := newRedisTestCache(t, mr, func(config *CacheConfig) { config.RedisClientName = "-" })
+	if name := clientName(unnamed); name != "" {
+		t.Errorf("client name = %q, want none", name)
+	}
+}
@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// sequenceKeyPrefix Redis中键的写入序号的键前缀
+const sequenceKeyPrefix = "seq:"
+
+// defaultSequenceTTL 写入序号默认的最短保留时间
+const defaultSequenceTTL = time.Hour
+
+// ErrSuperseded 使用WithSequence的写入在登记序号之后已有其他写入或删除，值没有写入
+var ErrSuperseded = errors.New("写入已被更晚的写入或删除取代")
+
+// reserveSequenceScript 递增键的写入序号并返回，序号的保留时间不短于ARGV[1]秒
+const reserveSequenceScript = `
+local n = redis.call('INCR', KEYS[1])
+local ttl = redis.call('TTL', KEYS[1])
+if ttl == -1 or (ttl >= 0 and ttl < tonumber(ARGV[1])) then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`
+
+// sequencedSetScript 只在键的写入序号不大于ARGV[1]时写入值，返回是否写入
+// ARGV[2]为值，ARGV[3]为过期时间(毫秒，0表示不过期)，ARGV[4]为序号的最短保留时间(秒)
+const sequencedSetScript = `
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current > tonumber(ARGV[1]) then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[2], ARGV[2])
+end
+local ttl = redis.call('TTL', KEYS[1])
+if ttl >= 0 and ttl < tonumber(ARGV[4]) then
+	redis.call('EXPIRE', KEYS[1], ARGV[4])
+end
+return 1
+`
+
+// sequencedDeleteScript 递增键的写入序号并删除键，之前登记序号的写入都不再生效，返回删除的键数
+const sequencedDeleteScript = `
+redis.call('INCR', KEYS[1])
+local ttl = redis.call('TTL', KEYS[1])
+if ttl == -1 or (ttl >= 0 and ttl < tonumber(ARGV[1])) then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return redis.call('DEL', KEYS[2])
+`
+
+// sequencedCopyScript 递增目标键的写入序号并把KEYS[2]复制到KEYS[3]，返回是否复制
+const sequencedCopyScript = `
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return 0
+end
+redis.call('INCR', KEYS[1])
+local ttl = redis.call('TTL', KEYS[1])
+if ttl == -1 or (ttl >= 0 and ttl < tonumber(ARGV[1])) then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return redis.call('COPY', KEYS[2], KEYS[3], 'REPLACE')
+`
+
+// sequencedRenameScript 递增源键和目标键的写入序号并把KEYS[3]重命名为KEYS[4]，返回是否重命名
+const sequencedRenameScript = `
+if redis.call('EXISTS', KEYS[3]) == 0 then
+	return 0
+end
+for i = 1, 2 do
+	redis.call('INCR', KEYS[i])
+	local ttl = redis.call('TTL', KEYS[i])
+	if ttl == -1 or (ttl >= 0 and ttl < tonumber(ARGV[1])) then
+		redis.call('EXPIRE', KEYS[i], ARGV[1])
+	end
+end
+redis.call('RENAME', KEYS[3], KEYS[4])
+return 1
+`
+
+// sequenceKey 返回键的写入序号在Redis中的键，与键位于同一个集群槽位
+// 键已有hash tag时直接加前缀；键中没有花括号时把整个键作为hash tag
+func (c *MultiLevelCache) sequenceKey(key string) string {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if end := strings.IndexByte(key[open+1:], '}'); end > 0 {
+			return c.internalKey(sequenceKeyPrefix, key)
+		}
+	}
+	if !strings.ContainsAny(key, "{}") {
+		return c.internalKey(sequenceKeyPrefix, "{"+key+"}")
+	}
+	// 花括号不构成hash tag的键在集群中与序号可能位于不同槽位，脚本会返回CROSSSLOT错误
+	return c.internalKey(sequenceKeyPrefix, key)
+}
+
+// sequenced 判断写入和删除是否使用写入序号
+func (c *MultiLevelCache) sequenced() bool {
+	config := c.config()
+	return config.SequencedWrites && config.EnableL2Cache
+}
+
+// sequenceTTL 返回写入序号的最短保留时间(秒)，不短于值的过期时间
+func (c *MultiLevelCache) sequenceTTL(ttl time.Duration) int64 {
+	window := c.config().SequenceTTL
+	if window <= 0 {
+		window = defaultSequenceTTL
+	}
+	if ttl > window {
+		window = ttl
+	}
+	return int64((window + time.Second - 1) / time.Second)
+}
+
+// Sequence 为键登记一个写入序号，读取数据源之前调用，之后通过WithSequence写入读到的值
+// 登记之后该键有其他写入或删除时，带该序号的写入不会生效，避免较慢的goroutine用旧数据覆盖较新的写入或删除
+// 需要启用SequencedWrites
+func (c *MultiLevelCache) Sequence(key string) (uint64, error) {
+	if !c.enter() {
+		return 0, ErrClosed
+	}
+	defer c.exit()
+	if !c.sequenced() {
+		return 0, &DisabledError{Feature: "SequencedWrites"}
+	}
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return 0, err
+	}
+	return c.reserveSequence(c.ctx, key)
+}
+
+// reserveSequence 在L2中递增键的写入序号
+func (c *MultiLevelCache) reserveSequence(ctx context.Context, key string) (uint64, error) {
+	n, err := incrSequence.Run(ctx, c.l2(), []string{c.sequenceKey(key)}, c.sequenceTTL(0)).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+// reserveSequences 在一次pipeline中为多个键登记写入序号，登记失败的键序号为0
+func (c *MultiLevelCache) reserveSequences(ctx context.Context, keys []string) ([]uint64, error) {
+	seqs := make([]uint64, len(keys))
+	cmds := make([]*redis.Cmd, len(keys))
+	err := c.pipelinedScripts(ctx, func(pipe redis.Pipeliner, eval func(*redis.Script, []string, ...interface{}) *redis.Cmd) {
+		for i, key := range keys {
+			cmds[i] = eval(incrSequence, []string{c.sequenceKey(key)}, c.sequenceTTL(0))
+		}
+	})
+	for i, cmd := range cmds {
+		if n, cerr := cmd.Int64(); cerr == nil {
+			seqs[i] = uint64(n)
+		}
+	}
+	return seqs, err
+}
+
+// loadSequence 加载前为回填登记写入序号，登记失败时记录日志并按不带序号的写入回填
+func (c *MultiLevelCache) loadSequence(ctx context.Context, key string) uint64 {
+	if !c.sequenced() {
+		return 0
+	}
+	if normalized, err := c.normalizeKey(key); err == nil {
+		key = normalized
+	}
+	seq, err := c.reserveSequence(ctx, key)
+	if err != nil {
+		c.logf("dancache: reserve write sequence for %q failed: %v", key, err)
+	}
+	return seq
+}
+
+// loadSequences 批量加载前在一次pipeline中为全部键登记写入序号，登记失败的键按不带序号的写入回填
+func (c *MultiLevelCache) loadSequences(ctx context.Context, keys []string) map[string]uint64 {
+	seqs := make(map[string]uint64, len(keys))
+	if !c.sequenced() || len(keys) == 0 {
+		return seqs
+	}
+	normalized := make([]string, len(keys))
+	for i, key := range keys {
+		normalized[i] = key
+		if n, err := c.normalizeKey(key); err == nil {
+			normalized[i] = n
+		}
+	}
+	reserved, err := c.reserveSequences(ctx, normalized)
+	if err != nil {
+		c.logf("dancache: reserve write sequences for %d keys failed: %v", len(keys), err)
+	}
+	for i, key := range keys {
+		seqs[key] = reserved[i]
+	}
+	return seqs
+}
+
+// setL2Sequenced 只在登记序号之后没有其他写入或删除时把值写入L2，返回是否写入
+func (c *MultiLevelCache) setL2Sequenced(ctx context.Context, key string, data []byte, ttl time.Duration, seq uint64) (bool, error) {
+	ttl = c.l2TTL(ttl)
+	ttl, err := c.admitQuota(key, len(data), ttl)
+	if err != nil {
+		return false, err
+	}
+	written, err := sequencedSet.Run(ctx, c.l2(), []string{c.sequenceKey(key), key},
+		seq, data, ttl.Milliseconds(), c.sequenceTTL(ttl)).Int64()
+	if err != nil {
+		return false, err
+	}
+	if written == 0 {
+		atomic.AddInt64(&c.supersededWrites, 1)
+	}
+	return written == 1, nil
+}
+
+// rewriteL2 把L1中已有的缓存项重新写入L2(降级、访问信息回写、值版本迁移)，返回是否写入
+// 启用写入序号时只带写入该项时登记的序号写入，之后有其他写入或删除时不写入；
+// 序号未知的项(从L2读取后升级到L1的项)不回写，避免覆盖其他实例更新的值或恢复已删除的键
+func (c *MultiLevelCache) rewriteL2(ctx context.Context, key string, item *CacheItem, data []byte, ttl time.Duration) (bool, error) {
+	if !c.sequenced() {
+		if err := c.setL2(ctx, key, data, ttl).Err(); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if item.l2Seq == 0 {
+		return false, nil
+	}
+	return c.setL2Sequenced(ctx, key, data, ttl, item.l2Seq)
+}
+
+// deleteL2 删除L2中的键，返回删除的键数；启用写入序号时同时递增每个键的序号，之前登记的写入都不再生效
+func (c *MultiLevelCache) deleteL2(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if !c.sequenced() {
+		return c.l2().Del(ctx, keys...).Result()
+	}
+	cmds := make([]*redis.Cmd, len(keys))
+	err := c.pipelinedScripts(ctx, func(pipe redis.Pipeliner, eval func(*redis.Script, []string, ...interface{}) *redis.Cmd) {
+		for i, key := range keys {
+			cmds[i] = eval(sequencedDelete, []string{c.sequenceKey(key), key}, c.sequenceTTL(0))
+		}
+	})
+	var deleted int64
+	for _, cmd := range cmds {
+		if n, cerr := cmd.Int64(); cerr == nil {
+			deleted += n
+		}
+	}
+	return deleted, err
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// setLaneStripes 写入通道表的分段数
+const setLaneStripes = 64
+
+// setLane 一个键正在进行的写入，同一时间只有一个写入在执行
+type setLane struct {
+	mu      sync.Mutex
+	refs    int    // 登记了该通道的写入数，受所在分段的mu保护
+	next    uint64 // 下一个写入的序号，受所在分段的mu保护
+	applied uint64 // 最后执行的写入的序号，受mu保护
+}
+
+// setLaneStripe 写入通道表的一个分段
+type setLaneStripe struct {
+	mu    sync.Mutex
+	lanes map[string]*setLane
+}
+
+// setLanes 按键串行化写入的通道表，通道在没有写入登记时删除
+type setLanes struct {
+	stripes    [setLaneStripes]setLaneStripe
+	superseded int64 // 因已有更晚的写入执行而放弃的写入数
+}
+
+// stripeFor 返回键所在的分段
+func (l *setLanes) stripeFor(key string) *setLaneStripe {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &l.stripes[h.Sum32()%setLaneStripes]
+}
+
+// acquire 登记一次写入并等待轮到它执行，返回通道和本次写入的序号
+// 序号在登记时分配，登记越晚序号越大；通道在全部写入释放前不会删除，序号在此期间单调递增
+func (l *setLanes) acquire(key string) (*setLane, uint64) {
+	s := l.stripeFor(key)
+	s.mu.Lock()
+	if s.lanes == nil {
+		s.lanes = make(map[string]*setLane)
+	}
+	lane, ok := s.lanes[key]
+	if !ok {
+		lane = &setLane{}
+		s.lanes[key] = lane
+	}
+	lane.refs++
+	lane.next++
+	seq := lane.next
+	s.mu.Unlock()
+
+	lane.mu.Lock()
+	return lane, seq
+}
+
+// release 结束一次写入，没有其他写入登记时删除通道
+func (l *setLanes) release(key string, lane *setLane) {
+	lane.mu.Unlock()
+	s := l.stripeFor(key)
+	s.mu.Lock()
+	if lane.refs--; lane.refs == 0 {
+		delete(s.lanes, key)
+	}
+	s.mu.Unlock()
+}
+
+// supersede 判断本次写入是否已被登记更晚、先执行完的写入取代，未被取代时记录为最后执行的写入
+// 调用方需持有lane.mu
+func (l *setLanes) supersede(lane *setLane, seq uint64) bool {
+	if lane.applied > seq {
+		atomic.AddInt64(&l.superseded, 1)
+		return true
+	}
+	lane.applied = seq
+	return false
+}
+
+// serializeSet 启用SerializeSets时按键串行化写入，返回结束写入的函数和本次写入是否已被取代
+// 被取代的写入不修改任何级别，调用方直接返回nil，效果等同于写入后立即被更晚的写入覆盖
+func (c *MultiLevelCache) serializeSet(key string) (func(), bool) {
+	if !c.config().SerializeSets {
+		return func() {}, false
+	}
+	lane, seq := c.setLanes.acquire(key)
+	done := func() { c.setLanes.release(key, lane) }
+	return done, c.setLanes.supersede(lane, seq)
+}
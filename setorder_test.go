@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSetLanesSupersede(t *testing.T) {
+	l := &setLanes{}
+	lane, first := l.acquire("k")
+	if l.supersede(lane, first) {
+		t.Fatal("first write was superseded")
+	}
+	l.release("k", lane)
+
+	// 没有写入登记时通道已删除，之后的写入使用新的通道
+	lane, second := l.acquire("k")
+	if lane.applied != 0 {
+		t.Fatalf("lane applied = %d, want a fresh lane", lane.applied)
+	}
+	// 登记更晚的写入已执行时，较早的写入被放弃
+	lane.applied = second + 1
+	if !l.supersede(lane, second) {
+		t.Error("earlier write was not superseded")
+	}
+	l.release("k", lane)
+	if l.superseded != 1 {
+		t.Errorf("superseded = %d, want 1", l.superseded)
+	}
+	if n := len(l.stripeFor("k").lanes); n != 0 {
+		t.Errorf("%d lanes left after release, want 0", n)
+	}
+}
+
+func TestSerializeSetsKeepsLevelsInSync(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) { config.SerializeSets = true })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.Set("k", i, 60); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 并发写入结束后L1与L2保存同一个值
+	l1, ok := c.shardFor("k").load("k")
+	if !ok {
+		t.Fatal("k missing from L1")
+	}
+	l2, ok := c.Get("k", SkipL1())
+	if !ok || fmt.Sprint(l2) != fmt.Sprint(l1.Value) {
+		t.Errorf("L2 value = %v, L1 value = %v; want the same", l2, l1.Value)
+	}
+	if n := len(c.setLanes.stripeFor("k").lanes); n != 0 {
+		t.Errorf("%d lanes left after the writes, want 0", n)
+	}
+	if _, ok := c.GetStats()["sets_superseded"]; !ok {
+		t.Error("sets_superseded missing from stats")
+	}
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"hash/fnv"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultL1Shards 本地缓存默认分片数
+const defaultL1Shards = 16
+
+// l1Shard 本地缓存分片
+// 淘汰或删除只把*CacheItem从映射中移除，从不复用或清空，正在读取该项的调用方持有的指针在读完前一直有效，由GC回收；
+// 改用对象池或预分配的槽位复用缓存项时，需要先加入引用计数或基于epoch的延迟回收
+type l1Shard struct {
+	items sync.Map // 键->*CacheItem
+	count int64    // 分片中的缓存项数量
+}
+
+// newL1Shards 创建本地缓存分片
+func newL1Shards(n int) []*l1Shard {
+	if n <= 0 {
+		n = defaultL1Shards
+	}
+	shards := make([]*l1Shard, n)
+	for i := range shards {
+		shards[i] = &l1Shard{}
+	}
+	return shards
+}
+
+// load 获取缓存项
+func (s *l1Shard) load(key string) (*CacheItem, bool) {
+	v, ok := s.items.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*CacheItem), true
+}
+
+// store 写入缓存项，返回是否新增了键
+func (s *l1Shard) store(key string, item *CacheItem) bool {
+	if _, loaded := s.items.Swap(key, item); loaded {
+		return false
+	}
+	atomic.AddInt64(&s.count, 1)
+	return true
+}
+
+// remove 删除缓存项并返回被删除的项
+func (s *l1Shard) remove(key string) (*CacheItem, bool) {
+	v, ok := s.items.LoadAndDelete(key)
+	if !ok {
+		return nil, false
+	}
+	atomic.AddInt64(&s.count, -1)
+	return v.(*CacheItem), true
+}
+
+// removeIf 仅当键对应的仍是item时删除，避免误删并发写入的新值
+func (s *l1Shard) removeIf(key string, item *CacheItem) bool {
+	if !s.items.CompareAndDelete(key, item) {
+		return false
+	}
+	atomic.AddInt64(&s.count, -1)
+	return true
+}
+
+// rangeItems 遍历分片中的缓存项
+func (s *l1Shard) rangeItems(fn func(key string, item *CacheItem) bool) {
+	s.items.Range(func(key, value interface{}) bool {
+		return fn(key.(string), value.(*CacheItem))
+	})
+}
+
+// shardFor 返回键所在的分片
+func (c *MultiLevelCache) shardFor(key string) *l1Shard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+// l1Count 返回本地缓存项总数
+func (c *MultiLevelCache) l1Count() int {
+	total := int64(0)
+	for _, s := range c.shards {
+		total += atomic.LoadInt64(&s.count)
+	}
+	return int(total)
+}
+
+// rangeL1 遍历所有分片中的缓存项
+func (c *MultiLevelCache) rangeL1(fn func(key string, item *CacheItem) bool) {
+	c.rangeL1From(0, fn)
+}
+
+// rangeL1From 从指定分片开始依次遍历所有分片中的缓存项
+func (c *MultiLevelCache) rangeL1From(first int, fn func(key string, item *CacheItem) bool) {
+	for i := range c.shards {
+		s := c.shards[(first+i)%len(c.shards)]
+		stopped := false
+		s.rangeItems(func(key string, item *CacheItem) bool {
+			if !fn(key, item) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// cleanupRoutines 返回每个分片的清理协程
+// 各分片的清理时间错开，并通过信号量限制同时清理的分片数，清理耗时不随缓存总量线性增长，也不会同时停顿所有分片
+func (c *MultiLevelCache) cleanupRoutines(interval time.Duration) []func(stop <-chan struct{}) {
+	parallelism := c.config().CleanupParallelism
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	sem := make(chan struct{}, parallelism)
+
+	stagger := interval / time.Duration(len(c.shards))
+	routines := make([]func(stop <-chan struct{}), 0, len(c.shards))
+	for i, s := range c.shards {
+		s, offset := s, time.Duration(i)*stagger
+		routines = append(routines, func(stop <-chan struct{}) {
+			c.shardCleanupRoutine(s, interval, offset, sem, stop)
+		})
+	}
+	return routines
+}
+
+// shardCleanupRoutine 在错开的时间点定期清理单个分片
+func (c *MultiLevelCache) shardCleanupRoutine(s *l1Shard, interval, offset time.Duration, sem chan struct{}, stop <-chan struct{}) {
+	select {
+	case <-time.After(offset):
+	case <-stop:
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case sem <- struct{}{}:
+			c.cleanupShard(s)
+			<-sem
+		case <-stop:
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
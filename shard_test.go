@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+// demoteFunc 函数形式的DemotionStrategy
+type demoteFunc func(item *CacheItem) bool
+
+func (f demoteFunc) ShouldDemote(item *CacheItem) bool { return f(item) }
+
+func TestL1ShardCounts(t *testing.T) {
+	s := newL1Shards(1)[0]
+	a, b := &CacheItem{Value: "a"}, &CacheItem{Value: "b"}
+	if !s.store("k", a) || s.store("k", b) {
+		t.Fatal("store did not report only the first write as new")
+	}
+	if s.removeIf("k", a) {
+		t.Error("removeIf removed a replaced item")
+	}
+	if s.count != 1 {
+		t.Errorf("count = %d, want 1", s.count)
+	}
+	if item, ok := s.remove("k"); !ok || item != b {
+		t.Errorf("remove = %v, %v; want the latest item", item, ok)
+	}
+	if _, ok := s.remove("k"); ok || s.count != 0 {
+		t.Errorf("count after remove = %d, want 0", s.count)
+	}
+}
+
+func TestL1ShardsSpreadKeys(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) { config.L1Shards = 4 })
+	if len(c.shards) != 4 {
+		t.Fatalf("len(shards) = %d, want 4", len(c.shards))
+	}
+	for i := 0; i < 40; i++ {
+		if err := c.Set(fmt.Sprint(i), i, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.l1Count() != 40 {
+		t.Errorf("l1Count = %d, want 40", c.l1Count())
+	}
+	for i, s := range c.shards {
+		if s.count == 0 {
+			t.Errorf("shard %d is empty", i)
+		}
+	}
+
+	// rangeL1From从指定分片开始，回调返回false时停止
+	visited := 0
+	c.rangeL1From(2, func(key string, item *CacheItem) bool {
+		if visited == 0 && c.shardFor(key) != c.shards[2] {
+			t.Errorf("first key %q is not in shard 2", key)
+		}
+		visited++
+		return visited < 5
+	})
+	if visited != 5 {
+		t.Errorf("rangeL1From visited %d keys, want 5", visited)
+	}
+}
+
+func TestCleanupShardKeepsConcurrentWrite(t *testing.T) {
+	var c *MultiLevelCache
+	c = newL1TestCache(t, func(config *CacheConfig) {
+		config.L1Shards = 1
+		config.DemotionStrategy = demoteFunc(func(item *CacheItem) bool {
+			if item.Value != "old" {
+				return false
+			}
+			// 模拟收集之后、删除之前的并发写入
+			if err := c.Set("k", "new", 60); err != nil {
+				t.Error(err)
+			}
+			return true
+		})
+	})
+	if err := c.Set("k", "old", 60); err != nil {
+		t.Fatal(err)
+	}
+	c.cleanupShard(c.shards[0])
+	if v, found := c.Get("k"); !found || v != "new" {
+		t.Errorf("Get after cleanup = %v, %v; want the concurrently written value", v, found)
+	}
+}
+
+func TestL1ItemNotRecycledAfterRemoval(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) { config.MaxL1Size = 2 })
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	item, _ := c.shardFor("k").load("k")
+
+	// 删除、覆盖和淘汰都不修改读取方持有的项
+	if err := c.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := c.Set(fmt.Sprint("k", i), i, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Set("k", "new", 60); err != nil {
+		t.Fatal(err)
+	}
+	if item.Value != "v" {
+		t.Errorf("removed item value = %v, want v", item.Value)
+	}
+	if current, ok := c.shardFor("k").load("k"); ok && current == item {
+		t.Error("removed item was reused for a new write")
+	}
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"fmt"
+)
+
+// OversizePolicy 值超过MaxValueSize时的处理策略
+type OversizePolicy int
+
+const (
+	OversizeReject OversizePolicy = iota // 拒绝写入并返回ValueTooLargeError
+	OversizeL2Only                       // 只写入L2，不占用本地内存
+	OversizeSkip                         // 不缓存，静默忽略
+)
+
+// ValueTooLargeError 值序列化后超过MaxValueSize
+type ValueTooLargeError struct {
+	Key   string
+	Size  int
+	Limit int
+}
+
+// Error 实现error接口
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("缓存值过大: 键%q序列化后%d字节，上限%d字节", e.Key, e.Size, e.Limit)
+}
+
+// admitSize 按MaxValueSize检查缓存项序列化后的大小
+// 返回序列化结果(未限制大小时为nil，供L2写入复用)以及是否写入L1、L2
+// 超限的键会先删除已有的旧值，避免继续提供过期数据
+func (c *MultiLevelCache) admitSize(key string, item *CacheItem) ([]byte, bool, bool, error) {
+	toL1, toL2 := c.config().EnableL1Cache, c.config().EnableL2Cache
+	if toL1 && toL2 && c.trackingBypassesWrites(key) {
+		c.deleteL1(key)
+		toL1 = false
+	}
+	if c.config().MaxValueSize <= 0 {
+		return nil, toL1, toL2, nil
+	}
+
+	data, err := c.marshalItem(key, item)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if len(data) <= c.config().MaxValueSize {
+		return data, toL1, toL2, nil
+	}
+
+	switch c.config().OversizePolicy {
+	case OversizeL2Only:
+		c.deleteL1(key)
+		return data, false, toL2, nil
+	case OversizeSkip:
+		c.deleteL1(key)
+		if toL2 {
+			c.cancelL2Write(key)
+			if _, err := c.deleteL2(c.ctx, key); err != nil {
+				return nil, false, false, err
+			}
+		}
+		return nil, false, false, nil
+	default:
+		return nil, false, false, &ValueTooLargeError{Key: key, Size: len(data), Limit: c.config().MaxValueSize}
+	}
+}
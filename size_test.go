@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestMaxValueSizePolicies(t *testing.T) {
+	big := strings.Repeat("x", 256)
+	cases := []struct {
+		policy   OversizePolicy
+		wantErr  bool
+		wantL1   bool
+		wantL2   bool
+		wantOld  bool
+		scenario string
+	}{
+		{OversizeReject, true, false, false, true, "reject"},
+		{OversizeL2Only, false, false, true, false, "L2 only"},
+		{OversizeSkip, false, false, false, false, "skip"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+				config.MaxValueSize = 128
+				config.OversizePolicy = tc.policy
+			})
+
+			if err := c.Set("small", "v", 60); err != nil {
+				t.Fatalf("Set small value: %v", err)
+			}
+			if err := c.Set("k", "old", 60); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.Set("k", big, 60)
+			var tooLarge *ValueTooLargeError
+			if tc.wantErr {
+				if !errors.As(err, &tooLarge) || tooLarge.Limit != 128 || tooLarge.Size <= 128 {
+					t.Fatalf("Set = %v, want ValueTooLargeError", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Set = %v, want nil", err)
+			}
+
+			item, inL1 := c.shardFor("k").load("k")
+			if gotL1 := inL1 && item.Value == big; gotL1 != tc.wantL1 {
+				t.Errorf("large value in L1 = %v, want %v", gotL1, tc.wantL1)
+			}
+			if tc.wantL2 {
+				if v, found := c.Get("k"); !found || v != big {
+					t.Errorf("Get = %.10v, %v; want the large value from L2", v, found)
+				}
+			}
+			v, found := c.Get("k")
+			if gotOld := found && v == "old"; gotOld != tc.wantOld {
+				t.Errorf("old value still served = %v, want %v", gotOld, tc.wantOld)
+			}
+			if !tc.wantL2 && !tc.wantOld && found {
+				t.Errorf("Get = %.10v, want miss", v)
+			}
+		})
+	}
+}
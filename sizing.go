@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// 动态容量调整的默认参数
+const (
+	defaultL1SizeInterval      = time.Minute
+	defaultL1SizeGrowThreshold = 0.01
+)
+
+// l1Sizer 根据影子列表统计动态调整本地缓存容量
+// 影子列表命中说明如果容量更大这次请求本可以在L1命中，其占全部L1查找的比例即为扩容的预期收益
+type l1Sizer struct {
+	lookups   int64 // 本周期L1查找次数
+	ghostHits int64 // 本周期影子列表命中次数
+}
+
+// observeL1 记录一次L1查找，未命中时检查影子列表
+// 影子列表命中的键在下次从L2读取时直接升级，同时作为扩容收益计入容量控制器
+func (c *MultiLevelCache) observeL1(key string, hit bool) {
+	if c.sizer != nil {
+		atomic.AddInt64(&c.sizer.lookups, 1)
+	}
+	if hit || c.ghost == nil || !c.ghost.hit(key) {
+		return
+	}
+	if c.sizer != nil {
+		atomic.AddInt64(&c.sizer.ghostHits, 1)
+	}
+}
+
+// recordEvicted 将因容量或内存压力被淘汰的键记入影子列表
+func (c *MultiLevelCache) recordEvicted(items []keyedItem, reason EvictionReason) {
+	if c.ghost == nil || reason == EvictDemotion {
+		return
+	}
+	for _, ki := range items {
+		c.ghost.add(ki.key)
+	}
+}
+
+// readmitted 判断键是否在淘汰后很快被再次请求，是则应跳过升级策略直接升级
+func (c *MultiLevelCache) readmitted(key string) bool {
+	if c.ghost == nil || !c.ghost.readmit(key) {
+		return false
+	}
+	atomic.AddInt64(&c.readmissions, 1)
+	return true
+}
+
+// l1SizeRoutine 定期调整本地缓存容量
+func (c *MultiLevelCache) l1SizeRoutine(stop <-chan struct{}) {
+	interval := c.config().L1SizeInterval
+	if interval <= 0 {
+		interval = defaultL1SizeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.adjustL1Size()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// adjustL1Size 预期收益达到阈值时扩容，远低于阈值时缩容，每次调整一个步长且不超出上下限
+func (c *MultiLevelCache) adjustL1Size() {
+	lookups := atomic.SwapInt64(&c.sizer.lookups, 0)
+	ghostHits := atomic.SwapInt64(&c.sizer.ghostHits, 0)
+	if lookups == 0 {
+		return
+	}
+
+	config := c.config()
+	threshold := config.L1SizeGrowThreshold
+	if threshold <= 0 {
+		threshold = defaultL1SizeGrowThreshold
+	}
+	step := config.L1SizeStep
+	if step <= 0 {
+		step = (config.L1SizeMax - config.L1SizeMin) / 10
+		if step < 1 {
+			step = 1
+		}
+	}
+
+	gain := float64(ghostHits) / float64(lookups)
+	size := config.MaxL1Size
+	switch {
+	case gain >= threshold:
+		size += step
+	case gain < threshold/4:
+		size -= step
+	}
+	if size > config.L1SizeMax {
+		size = config.L1SizeMax
+	}
+	if size < config.L1SizeMin {
+		size = config.L1SizeMin
+	}
+	if size == config.MaxL1Size {
+		return
+	}
+
+	if err := c.UpdateConfig(ConfigPatch{MaxL1Size: &size}); err != nil {
+		c.logf("dancache: adjust L1 size: %v", err)
+		return
+	}
+	c.logf("dancache: L1 size %d -> %d (ghost hit rate %.4f)", config.MaxL1Size, size, gain)
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAdjustL1SizeFollowsGhostHits(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.MaxL1Size = 2
+		config.L1SizeMin = 2
+		config.L1SizeMax = 5
+		config.L1SizeStep = 2
+		config.L1SizeInterval = time.Hour
+	})
+	if c.sizer == nil || c.ghost == nil {
+		t.Fatal("dynamic sizing did not enable the sizer and ghost list")
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := c.Set(fmt.Sprint(i), i, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 被淘汰的键再次请求，影子列表命中说明扩容有收益
+	for i := 0; i < 4; i++ {
+		c.Get(fmt.Sprint(i))
+	}
+	c.adjustL1Size()
+	if size := c.config().MaxL1Size; size != 4 {
+		t.Fatalf("MaxL1Size after ghost hits = %d, want 4", size)
+	}
+	for i := 0; i < 4; i++ {
+		if err := c.Set(fmt.Sprint(i), i, 60); err != nil {
+			t.Fatal(err)
+		}
+		c.Get(fmt.Sprint(i))
+	}
+	c.adjustL1Size()
+	if size := c.config().MaxL1Size; size != 2 {
+		t.Errorf("MaxL1Size without ghost hits = %d, want 2", size)
+	}
+	// 不超出上下限
+	c.adjustL1Size()
+	if size := c.config().MaxL1Size; size != 2 {
+		t.Errorf("MaxL1Size with no lookups = %d, want 2", size)
+	}
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultSlowLogSize 慢操作日志默认保留的条数
+const defaultSlowLogSize = 128
+
+// SlowLogEntry 一条慢操作记录
+type SlowLogEntry struct {
+	ID       int64         `json:"id"`       // 递增编号
+	Time     time.Time     `json:"time"`     // 操作开始时间
+	Op       string        `json:"op"`       // get、set或load
+	Key      string        `json:"key"`      // 缓存键
+	Duration time.Duration `json:"duration"` // 耗时
+	Level    string        `json:"level"`    // 提供结果的级别: L1、L2、miss或loader
+	Size     int           `json:"size"`     // 值的大小(字节，无法估计时为-1)
+}
+
+// slowLog 固定容量的慢操作日志，写满后覆盖最早的记录
+type slowLog struct {
+	mu      sync.Mutex
+	entries []SlowLogEntry
+	next    int
+	nextID  int64
+}
+
+// newSlowLog 创建慢操作日志
+func newSlowLog(size int) *slowLog {
+	if size <= 0 {
+		size = defaultSlowLogSize
+	}
+	return &slowLog{entries: make([]SlowLogEntry, 0, size)}
+}
+
+// add 追加一条记录
+func (l *slowLog) add(entry SlowLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	entry.ID = l.nextID
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+}
+
+// String 返回缓存级别的名称
+func (l CacheLevel) String() string {
+	if l == L1Cache {
+		return "L1"
+	}
+	return "L2"
+}
+
+// recordSlow 操作耗时超过对应阈值时记入慢操作日志
+func (c *MultiLevelCache) recordSlow(op, key string, start time.Time, level string, value interface{}) {
+	if c.slowLog == nil {
+		return
+	}
+	var threshold time.Duration
+	config := c.config()
+	switch op {
+	case "get":
+		threshold = config.SlowGetThreshold
+	case "set":
+		threshold = config.SlowSetThreshold
+	case "load":
+		threshold = config.SlowLoadThreshold
+	}
+	elapsed := time.Since(start)
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+	c.slowLog.add(SlowLogEntry{
+		Time:     start,
+		Op:       op,
+		Key:      key,
+		Duration: elapsed,
+		Level:    level,
+		Size:     estimateSize(value),
+	})
+}
+
+// SlowLog 返回最近的n条慢操作记录，最新的在前(n<=0时返回全部)
+func (c *MultiLevelCache) SlowLog(n int) []SlowLogEntry {
+	if c.slowLog == nil {
+		return nil
+	}
+	l := c.slowLog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n <= 0 || n > len(l.entries) {
+		n = len(l.entries)
+	}
+	result := make([]SlowLogEntry, 0, n)
+	for i := 0; i < n; i++ {
+		// 从最新的记录开始向前遍历环形缓冲区
+		idx := (l.next - 1 - i + 2*len(l.entries)) % len(l.entries)
+		result = append(result, l.entries[idx])
+	}
+	return result
+}
+
+// SlowLogReset 清空慢操作日志
+func (c *MultiLevelCache) SlowLogReset() {
+	if c.slowLog == nil {
+		return
+	}
+	c.slowLog.mu.Lock()
+	c.slowLog.entries = c.slowLog.entries[:0]
+	c.slowLog.next = 0
+	c.slowLog.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSlowLogRecordsSlowLoads(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.SlowLoadThreshold = 5 * time.Millisecond
+		config.SlowLogSize = 2
+	})
+
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprint("slow", i)
+		if _, err := c.GetOrLoad(key, 60, func() (interface{}, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "v", nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.GetOrLoad("fast", 60, func() (interface{}, error) { return "v", nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	// 写满后覆盖最早的记录，最新的在前
+	entries := c.SlowLog(0)
+	if len(entries) != 2 {
+		t.Fatalf("SlowLog = %v, want 2 entries", entries)
+	}
+	for i, want := range []string{"slow2", "slow1"} {
+		e := entries[i]
+		if e.Key != want || e.Op != "load" || e.Duration < 10*time.Millisecond || e.ID != int64(3-i) {
+			t.Errorf("entry %d = %+v, want slow load of %s", i, e, want)
+		}
+	}
+	if got := c.SlowLog(1); len(got) != 1 || got[0].Key != "slow2" {
+		t.Errorf("SlowLog(1) = %v, want the newest entry", got)
+	}
+
+	c.SlowLogReset()
+	if got := c.SlowLog(0); len(got) != 0 {
+		t.Errorf("SlowLog after reset = %v, want empty", got)
+	}
+}
+
+func TestSlowLogDisabled(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	c.GetOrLoad("k", 60, func() (interface{}, error) {
+		time.Sleep(time.Millisecond)
+		return "v", nil
+	})
+	if got := c.SlowLog(0); got != nil {
+		t.Errorf("SlowLog without thresholds = %v, want nil", got)
+	}
+}
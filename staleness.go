@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RefreshSample 一次写入替换L1中已有值时的对比结果
+type RefreshSample struct {
+	Key       string
+	Changed   bool          // 新值与旧值是否不同
+	Age       time.Duration // 旧值被替换时已缓存的时长
+	Remaining time.Duration // 旧值被替换时剩余的有效期(已过期时为负数)
+}
+
+// stalenessStats 替换值的对比统计
+type stalenessStats struct {
+	refreshes    int64
+	changed      int64
+	changedAge   int64 // 被不同的新值替换的旧值的总缓存时长(秒)
+	unchangedAge int64 // 被相同的新值替换的旧值的总缓存时长(秒)
+}
+
+// measureRefresh 写入前对比L1中的旧值与新值，记录数据变化的频率和旧值缓存的时长
+// 只对比L1中的旧值，不额外读取L2
+func (c *MultiLevelCache) measureRefresh(key string, item *CacheItem) {
+	config := c.config()
+	if !config.MeasureStaleness || !config.EnableL1Cache {
+		return
+	}
+	old, ok := c.shardFor(key).load(key)
+	if !ok {
+		return
+	}
+	now := c.nowUnix()
+	age := now - old.CreateTime
+	sample := RefreshSample{
+		Key:       key,
+		Changed:   !c.sameValue(old.Value, item.Value),
+		Age:       time.Duration(age) * time.Second,
+		Remaining: time.Duration(old.ExpireTime-now) * time.Second,
+	}
+
+	s := &c.staleness
+	atomic.AddInt64(&s.refreshes, 1)
+	if sample.Changed {
+		atomic.AddInt64(&s.changed, 1)
+		atomic.AddInt64(&s.changedAge, age)
+	} else {
+		atomic.AddInt64(&s.unchangedAge, age)
+	}
+
+	if onRefresh := config.OnRefresh; onRefresh != nil {
+		c.protect("OnRefresh", func() { onRefresh(sample) })
+	}
+}
+
+// stalenessStatsMap 返回替换值的对比统计，时长为平均值(秒)
+func (c *MultiLevelCache) stalenessStatsMap() map[string]interface{} {
+	s := &c.staleness
+	refreshes := atomic.LoadInt64(&s.refreshes)
+	changed := atomic.LoadInt64(&s.changed)
+	stats := map[string]interface{}{
+		"refreshes":         refreshes,
+		"refreshes_changed": changed,
+	}
+	if changed > 0 {
+		stats["refresh_changed_age_avg"] = atomic.LoadInt64(&s.changedAge) / changed
+	}
+	if unchanged := refreshes - changed; unchanged > 0 {
+		stats["refresh_unchanged_age_avg"] = atomic.LoadInt64(&s.unchangedAge) / unchanged
+	}
+	return stats
+}
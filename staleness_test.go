@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeasureStalenessComparesReplacedValues(t *testing.T) {
+	var samples []RefreshSample
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.MeasureStaleness = true
+		config.OnRefresh = func(s RefreshSample) { samples = append(samples, s) }
+	})
+	if err := c.Set("k", "v1", 60); err != nil {
+		t.Fatal(err)
+	}
+	// 旧值已缓存10秒
+	item, _ := c.shardFor("k").load("k")
+	item.CreateTime -= 10
+
+	c.Set("k", "v1", 60)
+	c.Set("k", "v2", 60)
+	if len(samples) != 2 {
+		t.Fatalf("got %d samples, want 2 (the first write replaces nothing)", len(samples))
+	}
+	if samples[0].Changed || samples[0].Age != 10*time.Second {
+		t.Errorf("first sample = %+v, want unchanged after 10s", samples[0])
+	}
+	if !samples[1].Changed || samples[1].Remaining <= 0 {
+		t.Errorf("second sample = %+v, want changed with time remaining", samples[1])
+	}
+	stats := c.GetStats()
+	if stats["refreshes"] != int64(2) || stats["refreshes_changed"] != int64(1) || stats["refresh_unchanged_age_avg"] != int64(10) {
+		t.Errorf("staleness stats = %v, %v, %v", stats["refreshes"], stats["refreshes_changed"], stats["refresh_unchanged_age_avg"])
+	}
+}
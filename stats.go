@@ -0,0 +1,47 @@
+package cache
+
+import "sync/atomic"
+
+// hitStats 命中和淘汰统计
+type hitStats struct {
+	l1Hits    int64
+	l2Hits    int64
+	misses    int64
+	evictions int64 // 因容量或内存压力从L1淘汰的项数
+}
+
+// recordLookup 记录一次查找的结果
+func (c *MultiLevelCache) recordLookup(key string, level CacheLevel, found bool) {
+	recordRate(&c.rates.gets)
+	switch {
+	case !found:
+		atomic.AddInt64(&c.hits.misses, 1)
+		recordRate(&c.rates.misses)
+	case level == L1Cache:
+		atomic.AddInt64(&c.hits.l1Hits, 1)
+	default:
+		atomic.AddInt64(&c.hits.l2Hits, 1)
+	}
+	if c.topKeys != nil {
+		c.topKeys.record(key)
+	}
+}
+
+// hitStatsMap 返回命中统计，hit_ratio为L1和L2命中占全部查找的比例
+func (c *MultiLevelCache) hitStatsMap() map[string]interface{} {
+	l1Hits := atomic.LoadInt64(&c.hits.l1Hits)
+	l2Hits := atomic.LoadInt64(&c.hits.l2Hits)
+	misses := atomic.LoadInt64(&c.hits.misses)
+
+	ratio := 0.0
+	if total := l1Hits + l2Hits + misses; total > 0 {
+		ratio = float64(l1Hits+l2Hits) / float64(total)
+	}
+	return map[string]interface{}{
+		"l1_hits":      l1Hits,
+		"l2_hits":      l2Hits,
+		"misses":       misses,
+		"hit_ratio":    ratio,
+		"l1_evictions": atomic.LoadInt64(&c.hits.evictions),
+	}
+}
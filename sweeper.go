@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// sweepCheckEvery 每检查多少项核对一次耗时预算并让出CPU
+const sweepCheckEvery = 64
+
+// sweepRoutine 低优先级后台清扫，在两次整体清理之间持续回收过期项
+func (c *MultiLevelCache) sweepRoutine(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.config().SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.sweepExpired()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sweepExpired 在耗时预算内删除已过期的本地缓存项
+// 从随机分片开始遍历，多轮清扫后会覆盖全部缓存项
+func (c *MultiLevelCache) sweepExpired() {
+	budget := c.config().SweepBudget
+	if budget <= 0 {
+		budget = time.Millisecond
+	}
+
+	start := time.Now()
+	now := start.Unix()
+	checked := 0
+
+	c.rangeL1From(rand.Intn(len(c.shards)), func(key string, item *CacheItem) bool {
+		if !item.validInL1(now) {
+			// 只删除仍是同一项的键，避免误删并发写入的新值
+			if c.shardFor(key).removeIf(key, item) {
+				c.recordL1Removal(item)
+				atomic.AddInt64(&c.sweptItems, 1)
+			}
+		}
+
+		checked++
+		if checked%sweepCheckEvery == 0 {
+			if time.Since(start) >= budget {
+				return false
+			}
+			runtime.Gosched()
+		}
+		return true
+	})
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSweepExpiredRemovesOnlyExpired(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) { config.SweepBudget = time.Second })
+	now := time.Now().Unix()
+	for i := 0; i < 20; i++ {
+		c.storeL1(fmt.Sprintf("expired%d", i), &CacheItem{Value: i, CreateTime: now - 10, ExpireTime: now - 1})
+		if err := c.Set(fmt.Sprintf("live%d", i), i, 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.sweepExpired()
+	if n := c.l1Count(); n != 20 {
+		t.Errorf("L1 holds %d items after sweeping, want the 20 live ones", n)
+	}
+	if n := atomic.LoadInt64(&c.sweptItems); n != 20 {
+		t.Errorf("sweptItems = %d, want 20", n)
+	}
+	if _, ok := c.Get("live0"); !ok {
+		t.Error("sweep removed a live item")
+	}
+}
+
+func TestSweepRoutineRunsInBackground(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) { config.SweepInterval = 10 * time.Millisecond })
+	now := time.Now().Unix()
+	c.storeL1("expired", &CacheItem{Value: 1, CreateTime: now - 10, ExpireTime: now - 1})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for c.l1Count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := c.l1Count(); n != 0 {
+		t.Errorf("background sweep left %d expired items", n)
+	}
+}
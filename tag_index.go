@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultTagPruneSample = 20  // 默认每次抽查的索引成员数
+	tagPruneRounds        = 4   // 每次清理最多抽查的轮数
+	tagLocalPruneMin      = 64  // 本地标签索引达到该大小后才开始清理
+	tagScanCount          = 500 // 按标签失效时每次SSCAN读取的成员数
+)
+
+// tagSet 本地缓存中一个标签关联的键
+type tagSet struct {
+	keys    map[string]struct{}
+	pruneAt int // 大小达到该值时清理已不在L1中的键，每次清理后翻倍，摊还为O(1)
+}
+
+// indexLocalTags 在本地标签索引中记录键，返回因超过TagIndexMaxSize被移出索引的键
+// 调用方需持有c.mutex
+func (c *MultiLevelCache) indexLocalTags(key string, tags []string) []string {
+	if c.tagIndex == nil {
+		c.tagIndex = make(map[string]*tagSet)
+	}
+	var dropped []string
+	for _, tag := range tags {
+		set, ok := c.tagIndex[tag]
+		if !ok {
+			set = &tagSet{keys: make(map[string]struct{}), pruneAt: tagLocalPruneMin}
+			c.tagIndex[tag] = set
+		}
+		set.keys[key] = struct{}{}
+		if len(set.keys) >= set.pruneAt {
+			c.pruneLocalTag(set)
+			if set.pruneAt = len(set.keys) * 2; set.pruneAt < tagLocalPruneMin {
+				set.pruneAt = tagLocalPruneMin
+			}
+		}
+		dropped = append(dropped, c.capLocalTag(set, key)...)
+	}
+	return dropped
+}
+
+// pruneLocalTag 移除已不在L1中或已过期的键，L2中的键由Redis的标签索引负责
+func (c *MultiLevelCache) pruneLocalTag(set *tagSet) {
+	now := c.nowUnix()
+	pruned := 0
+	for key := range set.keys {
+		if item, ok := c.shardFor(key).load(key); !ok || item.ExpireTime <= now {
+			delete(set.keys, key)
+			pruned++
+		}
+	}
+	atomic.AddInt64(&c.tagPruned, int64(pruned))
+}
+
+// capLocalTag 索引超过TagIndexMaxSize时移除多余的键(保留刚写入的键)，返回被移除的键
+func (c *MultiLevelCache) capLocalTag(set *tagSet, keep string) []string {
+	limit := c.config().TagIndexMaxSize
+	if limit <= 0 || len(set.keys) <= limit {
+		return nil
+	}
+	dropped := make([]string, 0, len(set.keys)-limit)
+	for key := range set.keys {
+		if len(set.keys) <= limit {
+			break
+		}
+		if key != keep {
+			delete(set.keys, key)
+			dropped = append(dropped, key)
+		}
+	}
+	atomic.AddInt64(&c.tagCapped, int64(len(dropped)))
+	return dropped
+}
+
+// schedulePruneL2 索引大于抽查数量或超过TagIndexMaxSize时在后台清理Redis中的标签索引，同一标签同时只有一个清理任务
+func (c *MultiLevelCache) schedulePruneL2(ctx context.Context, tag string, size int64) {
+	config := c.config()
+	sample := config.TagPruneSample
+	if sample == 0 {
+		sample = defaultTagPruneSample
+	} else if sample < 0 {
+		sample = 0
+	}
+	prune := sample > 0 && size > int64(sample)
+	overCap := config.TagIndexMaxSize > 0 && size > int64(config.TagIndexMaxSize)
+	if !prune && !overCap {
+		return
+	}
+	if _, running := c.tagPruning.LoadOrStore(tag, struct{}{}); running {
+		return
+	}
+	c.retain()
+	go func() {
+		defer c.exit()
+		ctx := detach(ctx)
+		if err := c.pruneTagL2(ctx, tag, sample); err != nil {
+			c.tagPruning.Delete(tag)
+			c.logf("dancache: prune tag index %q failed: %v", tag, err)
+			return
+		}
+		c.tagPruning.Delete(tag)
+		// 清理期间写入的键被跳过了清理，索引仍超过上限时再清理一次，不必等到下一次写入
+		if limit := config.TagIndexMaxSize; limit > 0 {
+			if size, err := c.l2().SCard(ctx, c.internalKey(tagKeyPrefix, tag)).Result(); err == nil && size > int64(limit) {
+				c.schedulePruneL2(ctx, tag, size)
+			}
+		}
+	}()
+}
+
+// pruneTagL2 随机抽查标签索引的成员并移除已过期的键，过期键占比低于1/4时停止
+// 之后索引仍超过TagIndexMaxSize时随机移出多余的键并删除其缓存，保证按标签失效仍然完整
+func (c *MultiLevelCache) pruneTagL2(ctx context.Context, tag string, sample int) error {
+	tagKey := c.internalKey(tagKeyPrefix, tag)
+	for round := 0; sample > 0 && round < tagPruneRounds; round++ {
+		members, err := c.l2().SRandMemberN(ctx, tagKey, int64(sample)).Result()
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		pipe := c.pipeline()
+		exists := make([]*redis.IntCmd, len(members))
+		for i, key := range members {
+			exists[i] = pipe.Exists(ctx, key)
+		}
+		_, err = pipe.Exec(ctx)
+		pipe.release()
+		if err != nil {
+			return err
+		}
+		expired := make([]interface{}, 0, len(members))
+		for i, cmd := range exists {
+			if cmd.Val() == 0 {
+				expired = append(expired, members[i])
+			}
+		}
+		if len(expired) > 0 {
+			n, err := c.l2().SRem(ctx, tagKey, expired...).Result()
+			if err != nil {
+				return err
+			}
+			atomic.AddInt64(&c.tagPruned, n)
+		}
+		if len(expired)*4 < len(members) {
+			break
+		}
+	}
+
+	limit := c.config().TagIndexMaxSize
+	if limit <= 0 {
+		return nil
+	}
+	size, err := c.l2().SCard(ctx, tagKey).Result()
+	if err != nil || size <= int64(limit) {
+		return err
+	}
+	dropped, err := c.l2().SPopN(ctx, tagKey, size-int64(limit)).Result()
+	if err != nil || len(dropped) == 0 {
+		return err
+	}
+	for _, key := range dropped {
+		c.deleteL1(key)
+		c.cancelL2Write(key)
+	}
+	atomic.AddInt64(&c.tagCapped, int64(len(dropped)))
+	_, err = c.deleteL2(ctx, dropped...)
+	return err
+}
+
+// invalidateTagL2 分批删除标签索引中的键，最后删除索引本身
+func (c *MultiLevelCache) invalidateTagL2(ctx context.Context, tag string) error {
+	tagKey := c.internalKey(tagKeyPrefix, tag)
+	err := c.rangeSetMembers(ctx, tagKey, func(keys []string) error {
+		for _, key := range keys {
+			c.deleteL1(key)
+			c.cancelL2Write(key)
+		}
+		if _, err := c.deleteL2(ctx, keys...); err != nil {
+			return err
+		}
+		c.replicate(keys...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.l2().Del(ctx, tagKey).Err()
+}
+
+// tagStatsMap 返回标签索引的清理统计
+func (c *MultiLevelCache) tagStatsMap() map[string]interface{} {
+	return map[string]interface{}{
+		"tag_members_pruned": atomic.LoadInt64(&c.tagPruned),
+		"tag_members_capped": atomic.LoadInt64(&c.tagCapped),
+	}
+}
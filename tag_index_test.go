@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPruneTagL2RemovesExpiredMembers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.TagPruneSample = -1 // 测试中手动清理
+	})
+	for i := 0; i < 30; i++ {
+		if err := c.SetWithTags(fmt.Sprintf("k%d", i), i, 600, "t"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 模拟键在Redis中过期，标签索引中仍有它们
+	for i := 0; i < 25; i++ {
+		mr.Del(fmt.Sprintf("k%d", i))
+	}
+	if err := c.pruneTagL2(context.Background(), "t", 30); err != nil {
+		t.Fatal(err)
+	}
+	members, _ := mr.Members(c.internalKey(tagKeyPrefix, "t"))
+	if len(members) != 5 {
+		t.Errorf("tag index has %d members after pruning, want 5", len(members))
+	}
+	if n := c.GetStats()["tag_members_pruned"]; n != int64(25) {
+		t.Errorf("tag_members_pruned = %v, want 25", n)
+	}
+}
+
+func TestTagIndexMaxSizeKeepsInvalidationComplete(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.TagIndexMaxSize = 5
+	})
+	for i := 0; i < 8; i++ {
+		if err := c.SetWithTags(fmt.Sprintf("k%d", i), i, 600, "t"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tagKey := c.internalKey(tagKeyPrefix, "t")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		members, _ := mr.Members(tagKey)
+		if len(members) <= 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tag index still has %d members", len(members))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 被移出索引的键同时被删除，按标签失效后不会残留
+	if err := c.InvalidateTags("t"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 8; i++ {
+		key := fmt.Sprintf("k%d", i)
+		if v, ok := c.Get(key); ok {
+			t.Errorf("%s = %v after InvalidateTags, want miss", key, v)
+		}
+	}
+}
+
+func TestInvalidateTagScansInBatches(t *testing.T) {
+	mr := miniredis.RunT(t)
+	// miniredis的SSCAN游标是偏移量，并发清理索引会让扫描跳过成员，测试中关闭后台清理
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.TagPruneSample = -1
+	})
+	// 直接构造超过两批的标签索引
+	keys := make([]string, tagScanCount*2+10)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+		mr.Set(keys[i], "v")
+	}
+	mr.SAdd(c.internalKey(tagKeyPrefix, "big"), keys...)
+	if err := c.SetWithTags("cached", "v", 600, "big"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.InvalidateTags("big"); err != nil {
+		t.Fatal(err)
+	}
+	if left := mr.Keys(); !allInternal(c, left) {
+		t.Errorf("%d keys left after InvalidateTags", len(left))
+	}
+	if c.l1Count() != 0 {
+		t.Errorf("L1 holds %d items after InvalidateTags", c.l1Count())
+	}
+}
+
+// allInternal 判断键是否都是内部键
+func allInternal(c *MultiLevelCache, keys []string) bool {
+	for _, key := range keys {
+		if !c.isInternalKey(key) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// shrinkExpireScript 只在键没有过期时间或剩余时间更长时才缩短过期时间
+const shrinkExpireScript = `
+local ttl = redis.call('TTL', KEYS[1])
+if ttl == -2 or (ttl >= 0 and ttl <= tonumber(ARGV[1])) then
+	return 0
+end
+return redis.call('EXPIRE', KEYS[1], ARGV[1])
+`
+
+// clampExpireScript 值未被改写时替换为缩短了ExpireTime的值，过期时间取原剩余时间和上限中较短的一个
+// 值已被改写时只缩短过期时间，与shrinkExpireScript相同
+const clampExpireScript = `
+local pttl = redis.call('PTTL', KEYS[1])
+if pttl == -2 then
+	return 0
+end
+local limit = tonumber(ARGV[3]) * 1000
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	if pttl >= 0 and pttl <= limit then
+		return 0
+	end
+	return redis.call('PEXPIRE', KEYS[1], limit)
+end
+if pttl >= 0 and pttl < limit then
+	limit = pttl
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', limit)
+return 1
+`
+
+// SetTagMaxTTL 设置标签的最长过期时间(秒)，之后关联该标签的写入不会超过该时间，maxTTL为0表示取消限制
+// 设置后立即缩短该标签下已有键的过期时间：L2中的键缩短过期时间并改写值中的ExpireTime，之后升级到L1的项不会比上限存活更久；
+// 本实例L1中剩余时间更长的项被删除，下次从L2读取
+// 策略只保存在本实例，其他实例需要同样调用或在TagMaxTTLs中配置；其他实例L1中已升级的项不会被缩短
+func (c *MultiLevelCache) SetTagMaxTTL(tag string, maxTTL int64) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if maxTTL < 0 {
+		return errors.New("标签的最长过期时间不能为负数")
+	}
+	c.tagTTLs.Store(tag, maxTTL)
+	if maxTTL == 0 {
+		return nil
+	}
+	return c.reclampTag(c.ctx, tag, maxTTL)
+}
+
+// TagMaxTTL 返回标签当前的最长过期时间(秒)，0表示不限制
+func (c *MultiLevelCache) TagMaxTTL(tag string) int64 {
+	if v, ok := c.tagTTLs.Load(tag); ok {
+		return v.(int64)
+	}
+	return c.config().TagMaxTTLs[tag]
+}
+
+// applyTagTTL 按标签的最长过期时间缩短尚未写入的缓存项的过期时间，优先于MinTTL
+func (c *MultiLevelCache) applyTagTTL(item *CacheItem, tags []string) {
+	for _, tag := range tags {
+		if max := c.TagMaxTTL(tag); max > 0 && item.lifetime() > max {
+			item.ExpireTime = item.CreateTime + max
+		}
+	}
+}
+
+// reclampTag 将标签下已有键的过期时间缩短到maxTTL
+func (c *MultiLevelCache) reclampTag(ctx context.Context, tag string, maxTTL int64) error {
+	if c.config().EnableL1Cache {
+		c.mutex.RLock()
+		var keys []string
+		if set, ok := c.tagIndex[tag]; ok {
+			keys = make([]string, 0, len(set.keys))
+			for key := range set.keys {
+				keys = append(keys, key)
+			}
+		}
+		c.mutex.RUnlock()
+
+		deadline := c.nowUnix() + maxTTL
+		for _, key := range keys {
+			if item, ok := c.shardFor(key).load(key); ok && item.ExpireTime > deadline {
+				c.deleteL1(key)
+			}
+		}
+	}
+
+	if !c.config().EnableL2Cache {
+		return nil
+	}
+	return c.rangeSetMembers(ctx, c.internalKey(tagKeyPrefix, tag), func(keys []string) error {
+		values, err := c.l2().MGet(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		deadline := c.nowUnix() + maxTTL
+		return c.pipelinedScripts(ctx, func(pipe redis.Pipeliner, eval func(*redis.Script, []string, ...interface{}) *redis.Cmd) {
+			for i, key := range keys {
+				data, ok := values[i].(string)
+				if !ok {
+					continue
+				}
+				if clamped := c.clampPayload(key, []byte(data), deadline); clamped != nil {
+					eval(clampExpire, []string{key}, data, clamped, maxTTL)
+				} else {
+					eval(shrinkExpire, []string{key}, maxTTL)
+				}
+			}
+		})
+	})
+}
+
+// clampPayload 返回ExpireTime缩短到deadline后重新编码的值
+// 值无法解码、编码格式不含过期时间或已不晚于deadline时返回nil，只需缩短Redis中的过期时间
+func (c *MultiLevelCache) clampPayload(key string, data []byte, deadline int64) []byte {
+	var item CacheItem
+	if err := c.decodeItem(key, data, &item); err != nil || item.ExpireTime == 0 || item.ExpireTime <= deadline {
+		return nil
+	}
+	item.ExpireTime = deadline
+	clamped, err := c.encodeItem(key, &item)
+	if err != nil {
+		return nil
+	}
+	return clamped
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestSetTagMaxTTLClampsPayloadExpireTime(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	other := newRedisTestCache(t, mr, nil)
+
+	if err := c.SetWithTags("pii:1", "v", 3600, "pii"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetTagMaxTTL("pii", 60); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("pii:1"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("L2 TTL after SetTagMaxTTL = %v, want at most 1m", ttl)
+	}
+
+	// 值中记录的过期时间同样缩短，从L2读取后升级到L1的项不会超过上限
+	raw, err := mr.Get("pii:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item CacheItem
+	if err := other.decodeItem("pii:1", []byte(raw), &item); err != nil {
+		t.Fatal(err)
+	}
+	if remaining := item.ExpireTime - other.nowUnix(); remaining > 60 {
+		t.Errorf("stored item expires in %ds, want at most 60s", remaining)
+	}
+	if v, ok := other.Get("pii:1"); !ok || v != "v" {
+		t.Errorf("Get from another instance = %v, %v; want v, true", v, ok)
+	}
+
+	// 写入序号不受影响，之后的覆盖照常生效
+	if err := c.Set("pii:1", "new", 30); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := other.Get("pii:1", SkipL1()); !ok || v != "new" {
+		t.Errorf("Get after overwrite = %v, %v; want new, true", v, ok)
+	}
+}
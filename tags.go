@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// tagKeyPrefix Redis中标签索引集合的键前缀
+const tagKeyPrefix = "tag:"
+
+// extendExpireScript 只在新的过期时间更长时才更新键的过期时间
+const extendExpireScript = `
+local ttl = redis.call('TTL', KEYS[1])
+if ttl >= 0 and ttl >= tonumber(ARGV[1]) then
+	return 0
+end
+return redis.call('EXPIRE', KEYS[1], ARGV[1])
+`
+
+// SetWithTags 设置缓存并关联标签，之后可通过InvalidateTags按标签批量失效
+// 标签设置了最长过期时间时，ttl不超过其中最短的一个
+func (c *MultiLevelCache) SetWithTags(key string, value interface{}, ttl int64, tags ...string) error {
+	return c.setWithTags(c.ctx, key, value, ttl, tags, callOptions{})
+}
+
+// setWithTags SetWithTags的实现，ctx用于L2写入，调用方需传入已脱离取消的context
+func (c *MultiLevelCache) setWithTags(ctx context.Context, key string, value interface{}, ttl int64, tags []string, o callOptions) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return err
+	}
+	if err := c.set(ctx, key, value, ttl, tags, false, o); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return c.tagKey(ctx, key, c.boundTTL(ttl), tags)
+}
+
+// tagKey 在本地和Redis中记录键与标签的关联
+// 索引中已过期的键在写入时按需清理，超过TagIndexMaxSize时移出多余的键并删除其缓存
+func (c *MultiLevelCache) tagKey(ctx context.Context, key string, ttl int64, tags []string) error {
+	// 记录本地标签索引
+	if c.config().EnableL1Cache {
+		c.mutex.Lock()
+		dropped := c.indexLocalTags(key, tags)
+		c.mutex.Unlock()
+
+		for _, k := range dropped {
+			c.deleteL1(k)
+		}
+	}
+
+	// 在Redis集合中记录标签索引，索引的过期时间不短于其中的键
+	if c.config().EnableL2Cache {
+		sizes := make([]*redis.IntCmd, len(tags))
+		err := c.pipelinedScripts(ctx, func(pipe redis.Pipeliner, eval func(*redis.Script, []string, ...interface{}) *redis.Cmd) {
+			for i, tag := range tags {
+				tagKey := c.internalKey(tagKeyPrefix, tag)
+				pipe.SAdd(ctx, tagKey, key)
+				eval(extendExpire, []string{tagKey}, ttl)
+				sizes[i] = pipe.SCard(ctx, tagKey)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		for i, tag := range tags {
+			c.schedulePruneL2(ctx, tag, sizes[i].Val())
+		}
+	}
+
+	return nil
+}
+
+// InvalidateTags 删除关联了任一指定标签的所有缓存
+func (c *MultiLevelCache) InvalidateTags(tags ...string) error {
+	if !c.enter() {
+		return ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return err
+	}
+	// 失效本地缓存中的键
+	if c.config().EnableL1Cache {
+		c.mutex.Lock()
+		keys := make([]string, 0)
+		for _, tag := range tags {
+			if set, ok := c.tagIndex[tag]; ok {
+				for key := range set.keys {
+					keys = append(keys, key)
+				}
+			}
+			delete(c.tagIndex, tag)
+		}
+		c.mutex.Unlock()
+
+		for _, key := range keys {
+			c.deleteL1(key)
+			c.cancelL2Write(key)
+		}
+	}
+
+	// 失效Redis中的键及标签索引
+	if c.config().EnableL2Cache {
+		for _, tag := range tags {
+			if err := c.invalidateTagL2(c.ctx, tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
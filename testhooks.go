@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+)
+
+// TestHooks 测试同步点，用于在集成测试中构造确定的并发时序(如缓存击穿、加载与删除竞争)
+// 钩子中的panic会被恢复并计入recovered_panics，不会中断缓存操作
+// 钩子在调用协程中同步执行，可以在钩子中阻塞等待测试放行；生产环境不应配置
+type TestHooks struct {
+	BeforeL2Get    func(key string) // 从L2读取之前
+	AfterPromotion func(key string) // 缓存项升级到L1之后
+	BeforeLoad     func(key string) // 缓存未命中、调用loader之前(已合并并发加载)
+	BeforeBackfill func(key string) // loader返回后、结果写入缓存之前
+
+	BeforeLoadContext     func(ctx context.Context, key string) // 同BeforeLoad，ctx由调用方的context派生
+	BeforeBackfillContext func(ctx context.Context, key string) // 同BeforeBackfill，ctx由调用方的context派生
+}
+
+// hookBeforeL2Get 执行BeforeL2Get钩子
+func (c *MultiLevelCache) hookBeforeL2Get(key string) {
+	if h := c.config().TestHooks; h != nil && h.BeforeL2Get != nil {
+		c.protect("TestHooks", func() { h.BeforeL2Get(key) })
+	}
+}
+
+// hookAfterPromotion 执行AfterPromotion钩子
+func (c *MultiLevelCache) hookAfterPromotion(key string) {
+	if h := c.config().TestHooks; h != nil && h.AfterPromotion != nil {
+		c.protect("TestHooks", func() { h.AfterPromotion(key) })
+	}
+}
+
+// hookBeforeLoad 执行BeforeLoad钩子
+func (c *MultiLevelCache) hookBeforeLoad(ctx context.Context, key string) {
+	h := c.config().TestHooks
+	if h == nil {
+		return
+	}
+	if h.BeforeLoad != nil {
+		c.protect("TestHooks", func() { h.BeforeLoad(key) })
+	}
+	if h.BeforeLoadContext != nil {
+		c.protect("TestHooks", func() { h.BeforeLoadContext(ctx, key) })
+	}
+}
+
+// hookBeforeBackfill 执行BeforeBackfill钩子
+func (c *MultiLevelCache) hookBeforeBackfill(ctx context.Context, key string) {
+	h := c.config().TestHooks
+	if h == nil {
+		return
+	}
+	if h.BeforeBackfill != nil {
+		c.protect("TestHooks", func() { h.BeforeBackfill(key) })
+	}
+	if h.BeforeBackfillContext != nil {
+		c.protect("TestHooks", func() { h.BeforeBackfillContext(ctx, key) })
+	}
+}
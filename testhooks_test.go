@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// hookRecorder 按调用顺序记录测试钩子
+type hookRecorder struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (r *hookRecorder) record(name string) func(key string) {
+	return func(key string) {
+		r.mutex.Lock()
+		r.calls = append(r.calls, name+":"+key)
+		r.mutex.Unlock()
+	}
+}
+
+func (r *hookRecorder) String() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return strings.Join(r.calls, " ")
+}
+
+type hookCtxKey struct{}
+
+func TestTestHooksOrder(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r := &hookRecorder{}
+	var ctxValues []interface{}
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+		config.TestHooks = &TestHooks{
+			BeforeL2Get:    r.record("l2get"),
+			AfterPromotion: r.record("promoted"),
+			BeforeLoad:     r.record("load"),
+			BeforeBackfill: r.record("backfill"),
+			BeforeLoadContext: func(ctx context.Context, key string) {
+				ctxValues = append(ctxValues, ctx.Value(hookCtxKey{}))
+				// 钩子中的panic被恢复，不中断加载
+				panic("hook failed")
+			},
+		}
+	})
+
+	ctx := context.WithValue(context.Background(), hookCtxKey{}, "caller")
+	v, err := c.GetOrLoadContext(ctx, "k", 60, func(ctx context.Context) (interface{}, error) {
+		return "v", nil
+	})
+	if err != nil || v != "v" {
+		t.Fatalf("GetOrLoadContext = %v, %v; want v, nil", v, err)
+	}
+	if got := r.String(); !strings.HasPrefix(got, "l2get:k") || !strings.HasSuffix(got, "load:k backfill:k") {
+		t.Errorf("hooks on a miss = %q, want l2get before load and backfill", got)
+	}
+	if len(ctxValues) != 1 || ctxValues[0] != "caller" {
+		t.Errorf("BeforeLoadContext ctx values = %v, want [caller]", ctxValues)
+	}
+	if n := c.GetStats()["recovered_panics"]; n != int64(1) {
+		t.Errorf("recovered_panics = %v, want 1", n)
+	}
+
+	// 从L2读取并升级时依次调用BeforeL2Get和AfterPromotion
+	c.deleteL1("k")
+	r.calls = nil
+	if _, found := c.Get("k"); !found {
+		t.Fatal("Get missed")
+	}
+	if got := r.String(); got != "l2get:k promoted:k" {
+		t.Errorf("hooks on an L2 hit = %q, want %q", got, "l2get:k promoted:k")
+	}
+}
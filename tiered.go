@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Tier 分层缓存中的一层，如请求级缓存、本地内存、Redis、磁盘
+// 实现需要并发安全；Get返回的缓存项由调用方持有，不应再被层内部修改
+type Tier interface {
+	// Name 层的名称，用于统计和错误信息
+	Name() string
+	// Get 读取未过期的缓存项，不存在时返回nil, nil
+	Get(key string) (*CacheItem, error)
+	// Set 写入缓存项，过期时间取自item.ExpireTime
+	Set(key string, item *CacheItem) error
+	// Delete 删除缓存项
+	Delete(key string) error
+}
+
+// EvictingTier 容量有限、会主动淘汰缓存项的层，淘汰的项可以按降级策略写入下一层
+type EvictingTier interface {
+	Tier
+	// SetEvictionHandler 设置淘汰回调，回调在层内部锁之外调用
+	SetEvictionHandler(fn func(key string, item *CacheItem))
+}
+
+// TierOptions 单个层的升级/降级策略
+type TierOptions struct {
+	Promotion PromotionStrategy // 下层命中时是否写回本层(nil表示总是写回)
+	Demotion  DemotionStrategy  // 本层淘汰的项是否写入下一层(nil表示不降级，写入时已经写入了所有层)
+}
+
+// tier 分层缓存中的一层及其策略
+type tier struct {
+	Tier
+	opts TierOptions
+	hits int64
+}
+
+// TieredCache 由任意多个有序的层组成的缓存，读取时从快到慢逐层查找，命中后按策略写回更快的层
+// 与固定两级的MultiLevelCache相比，可以组合请求级缓存、本地内存、Redis和磁盘等任意层
+type TieredCache struct {
+	tiers  []*tier
+	logger Logger
+	misses int64
+}
+
+// TieredBuilder 分层缓存的构建器，按从快到慢的顺序添加层
+type TieredBuilder struct {
+	tiers  []*tier
+	logger Logger
+}
+
+// NewTieredBuilder 创建新的分层缓存构建器
+func NewTieredBuilder() *TieredBuilder {
+	return &TieredBuilder{}
+}
+
+// Tier 在最慢的一层之后添加一层
+func (b *TieredBuilder) Tier(t Tier, opts TierOptions) *TieredBuilder {
+	b.tiers = append(b.tiers, &tier{Tier: t, opts: opts})
+	return b
+}
+
+// Logger 设置日志输出，用于报告读取失败和降级失败
+func (b *TieredBuilder) Logger(logger Logger) *TieredBuilder {
+	b.logger = logger
+	return b
+}
+
+// Build 创建分层缓存
+func (b *TieredBuilder) Build() (*TieredCache, error) {
+	if len(b.tiers) == 0 {
+		return nil, errors.New("分层缓存至少需要一层")
+	}
+	names := make(map[string]bool, len(b.tiers))
+	for _, t := range b.tiers {
+		if t.Tier == nil {
+			return nil, errors.New("缓存层不能为nil")
+		}
+		if names[t.Name()] {
+			return nil, fmt.Errorf("缓存层名称重复: %s", t.Name())
+		}
+		names[t.Name()] = true
+	}
+	c := &TieredCache{tiers: b.tiers, logger: b.logger}
+	c.attachEvictionHandlers()
+	return c, nil
+}
+
+// WithFront 返回在最前面增加一层的新缓存，其余的层与c共享
+// 适合为每个请求创建一个请求级缓存(L0)，请求结束后丢弃
+func (c *TieredCache) WithFront(t Tier, opts TierOptions) *TieredCache {
+	tiers := make([]*tier, 0, len(c.tiers)+1)
+	tiers = append(tiers, &tier{Tier: t, opts: opts})
+	tiers = append(tiers, c.tiers...)
+	front := &TieredCache{tiers: tiers, logger: c.logger}
+	front.attachEvictionHandler(0)
+	return front
+}
+
+// attachEvictionHandlers 为配置了降级策略的层设置淘汰回调
+func (c *TieredCache) attachEvictionHandlers() {
+	for i := range c.tiers {
+		c.attachEvictionHandler(i)
+	}
+}
+
+// attachEvictionHandler 第i层淘汰的项按降级策略写入第i+1层
+func (c *TieredCache) attachEvictionHandler(i int) {
+	t := c.tiers[i]
+	et, ok := t.Tier.(EvictingTier)
+	if !ok || t.opts.Demotion == nil || i == len(c.tiers)-1 {
+		return
+	}
+	next := c.tiers[i+1]
+	et.SetEvictionHandler(func(key string, item *CacheItem) {
+		if item.ExpireTime <= time.Now().Unix() || !t.opts.Demotion.ShouldDemote(item) {
+			return
+		}
+		if err := next.Set(key, item); err != nil {
+			c.logf("dancache: demote %q from %s to %s failed: %v", key, t.Name(), next.Name(), err)
+		}
+	})
+}
+
+// Get 从快到慢逐层获取缓存，命中后按各层的升级策略写回更快的层
+// 某一层读取失败时视为该层未命中，继续查找下一层
+func (c *TieredCache) Get(key string) (interface{}, bool) {
+	now := time.Now().Unix()
+	for i, t := range c.tiers {
+		item, err := t.Get(key)
+		if err != nil {
+			c.logf("dancache: get %q from %s failed: %v", key, t.Name(), err)
+			continue
+		}
+		if item == nil || item.ExpireTime <= now {
+			continue
+		}
+		atomic.AddInt64(&t.hits, 1)
+		item.AccessTime = now
+		item.AccessCount++
+		c.promote(key, item, i)
+		return item.Value, true
+	}
+	atomic.AddInt64(&c.misses, 1)
+	return nil, false
+}
+
+// promote 将第level层命中的项写回更快的层
+func (c *TieredCache) promote(key string, item *CacheItem, level int) {
+	for i := level - 1; i >= 0; i-- {
+		t := c.tiers[i]
+		if t.opts.Promotion != nil && !t.opts.Promotion.ShouldPromote(item) {
+			continue
+		}
+		promoted := *item
+		if err := t.Set(key, &promoted); err != nil {
+			c.logf("dancache: promote %q to %s failed: %v", key, t.Name(), err)
+		}
+	}
+}
+
+// Set 设置缓存，从慢到快写入所有层，避免更快的层短暂持有下层尚未写入的值
+// 任一层写入失败时返回错误，已写入的层保持不变
+func (c *TieredCache) Set(key string, value interface{}, ttl int64) error {
+	now := time.Now().Unix()
+	for i := len(c.tiers) - 1; i >= 0; i-- {
+		t := c.tiers[i]
+		item := &CacheItem{
+			Value:      value,
+			ExpireTime: now + ttl,
+			CreateTime: now,
+			AccessTime: now,
+		}
+		if err := t.Set(key, item); err != nil {
+			return fmt.Errorf("写入%s失败: %w", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Delete 从快到慢删除所有层中的缓存，返回遇到的第一个错误
+func (c *TieredCache) Delete(key string) error {
+	var firstErr error
+	for _, t := range c.tiers {
+		if err := t.Delete(key); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("删除%s失败: %w", t.Name(), err)
+		}
+	}
+	return firstErr
+}
+
+// Tiers 按从快到慢的顺序返回各层的名称
+func (c *TieredCache) Tiers() []string {
+	names := make([]string, len(c.tiers))
+	for i, t := range c.tiers {
+		names[i] = t.Name()
+	}
+	return names
+}
+
+// GetStats 获取各层的命中次数和未命中次数
+func (c *TieredCache) GetStats() map[string]interface{} {
+	stats := make(map[string]interface{}, len(c.tiers)+1)
+	for _, t := range c.tiers {
+		stats[t.Name()+"_hits"] = atomic.LoadInt64(&t.hits)
+	}
+	stats["misses"] = atomic.LoadInt64(&c.misses)
+	return stats
+}
+
+// logf 输出日志，未配置Logger时忽略
+func (c *TieredCache) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
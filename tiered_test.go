@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// promoteFunc 函数形式的PromotionStrategy
+type promoteFunc func(item *CacheItem) bool
+
+func (f promoteFunc) ShouldPromote(item *CacheItem) bool { return f(item) }
+
+func TestTieredBuilderValidatesTiers(t *testing.T) {
+	if _, err := NewTieredBuilder().Build(); err == nil {
+		t.Error("Build accepted no tiers")
+	}
+	_, err := NewTieredBuilder().
+		Tier(NewMemoryTier("mem", 0), TierOptions{}).
+		Tier(NewMemoryTier("mem", 0), TierOptions{}).
+		Build()
+	if err == nil {
+		t.Error("Build accepted duplicate tier names")
+	}
+}
+
+func TestTieredCachePromotesThroughTiers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	disk, err := NewDiskTier("disk", t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := NewMemoryTier("mem", 0)
+	rt := NewRedisTier("redis", client, nil)
+	never := promoteFunc(func(*CacheItem) bool { return false })
+	c, err := NewTieredBuilder().
+		Tier(mem, TierOptions{}).
+		Tier(rt, TierOptions{Promotion: never}).
+		Tier(disk, TierOptions{}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Tiers(); len(got) != 3 || got[0] != "mem" || got[2] != "disk" {
+		t.Errorf("Tiers = %v", got)
+	}
+
+	// 只写入最慢的一层，命中后写回内存层，Redis层的升级策略拒绝写回
+	if err := disk.Set("k", &CacheItem{Value: "v", ExpireTime: time.Now().Unix() + 60}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get = %v, %v; want v", v, ok)
+	}
+	if item, _ := mem.Get("k"); item == nil {
+		t.Error("hit in disk was not promoted to mem")
+	}
+	if mr.Exists("k") {
+		t.Error("hit was promoted to redis despite its Promotion strategy")
+	}
+	c.Get("k")
+	c.Get("missing")
+	stats := c.GetStats()
+	if stats["disk_hits"] != int64(1) || stats["mem_hits"] != int64(1) || stats["misses"] != int64(1) {
+		t.Errorf("stats = %v", stats)
+	}
+
+	// Set写入所有层，Delete从所有层删除
+	if err := c.Set("s", "w", 60); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists("s") {
+		t.Error("Set did not write redis")
+	}
+	if item, _ := disk.Get("s"); item == nil || item.Value != "w" {
+		t.Errorf("disk item = %+v, want w", item)
+	}
+	if err := c.Delete("s"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("s"); ok {
+		t.Error("Get found s after Delete")
+	}
+}
+
+func TestTieredCacheDemotesEvictedItems(t *testing.T) {
+	front := NewMemoryTier("front", 1)
+	back := NewMemoryTier("back", 0)
+	demoted := []string{}
+	c, err := NewTieredBuilder().
+		Tier(front, TierOptions{Demotion: demoteFunc(func(item *CacheItem) bool {
+			demoted = append(demoted, item.Value.(string))
+			return item.Value != "skip"
+		})}).
+		Tier(back, TierOptions{}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Unix()
+	front.Set("a", &CacheItem{Value: "a", ExpireTime: now + 60})
+	front.Set("b", &CacheItem{Value: "skip", ExpireTime: now + 60})
+	front.Set("c", &CacheItem{Value: "c", ExpireTime: now + 60})
+	if len(demoted) != 2 {
+		t.Fatalf("demotion strategy consulted %d times, want 2", len(demoted))
+	}
+	if item, _ := back.Get("a"); item == nil {
+		t.Error("evicted a was not demoted")
+	}
+	if item, _ := back.Get("b"); item != nil {
+		t.Error("b was demoted although the strategy refused")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("c missing from the front tier")
+	}
+}
+
+func TestTieredCacheWithFront(t *testing.T) {
+	shared := NewMemoryTier("shared", 0)
+	c, err := NewTieredBuilder().Tier(shared, TierOptions{}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	request := NewMemoryTier("request", 0)
+	rc := c.WithFront(request, TierOptions{})
+	if v, ok := rc.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get = %v, %v; want v", v, ok)
+	}
+	if item, _ := request.Get("k"); item == nil {
+		t.Error("hit was not promoted to the request tier")
+	}
+	// 原缓存不包含请求级的层
+	if names := c.Tiers(); len(names) != 1 {
+		t.Errorf("WithFront changed the original tiers: %v", names)
+	}
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemoryTier 本地内存层，超过容量时按LRU淘汰
+// 每个请求创建一个容量较小的MemoryTier即可作为请求级缓存(L0)
+type MemoryTier struct {
+	name    string
+	maxSize int
+	mu      sync.Mutex
+	items   map[string]*CacheItem
+	onEvict func(key string, item *CacheItem)
+}
+
+// NewMemoryTier 创建新的内存层，maxSize为0表示不限制条目数
+func NewMemoryTier(name string, maxSize int) *MemoryTier {
+	return &MemoryTier{
+		name:    name,
+		maxSize: maxSize,
+		items:   make(map[string]*CacheItem),
+	}
+}
+
+// Name 层的名称
+func (t *MemoryTier) Name() string {
+	return t.name
+}
+
+// Get 读取缓存项，返回副本
+func (t *MemoryTier) Get(key string) (*CacheItem, error) {
+	now := time.Now().Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item, ok := t.items[key]
+	if !ok {
+		return nil, nil
+	}
+	if item.ExpireTime <= now {
+		delete(t.items, key)
+		return nil, nil
+	}
+	item.AccessTime = now
+	item.AccessCount++
+	copied := *item
+	return &copied, nil
+}
+
+// Set 写入缓存项的副本，超过容量时淘汰最久未访问的项
+func (t *MemoryTier) Set(key string, item *CacheItem) error {
+	copied := *item
+	t.mu.Lock()
+	t.items[key] = &copied
+	var evictedKey string
+	var evicted *CacheItem
+	if t.maxSize > 0 && len(t.items) > t.maxSize {
+		for k, v := range t.items {
+			if k != key && (evicted == nil || v.evictsBefore(evicted)) {
+				evictedKey, evicted = k, v
+			}
+		}
+		delete(t.items, evictedKey)
+	}
+	onEvict := t.onEvict
+	t.mu.Unlock()
+
+	if evicted != nil && onEvict != nil {
+		onEvict(evictedKey, evicted)
+	}
+	return nil
+}
+
+// Delete 删除缓存项
+func (t *MemoryTier) Delete(key string) error {
+	t.mu.Lock()
+	delete(t.items, key)
+	t.mu.Unlock()
+	return nil
+}
+
+// SetEvictionHandler 设置淘汰回调
+func (t *MemoryTier) SetEvictionHandler(fn func(key string, item *CacheItem)) {
+	t.mu.Lock()
+	t.onEvict = fn
+	t.mu.Unlock()
+}
+
+// RedisTier Redis层，缓存项用Codec编码，过期时间设为Redis的TTL
+type RedisTier struct {
+	name   string
+	client redis.UniversalClient
+	codec  Codec
+	ctx    context.Context
+}
+
+// NewRedisTier 创建新的Redis层，codec为nil时使用JSONCodec
+func NewRedisTier(name string, client redis.UniversalClient, codec Codec) *RedisTier {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &RedisTier{
+		name:   name,
+		client: client,
+		codec:  codec,
+		ctx:    context.Background(),
+	}
+}
+
+// Name 层的名称
+func (t *RedisTier) Name() string {
+	return t.name
+}
+
+// Get 读取缓存项，编码中没有过期时间时从Redis的TTL恢复
+func (t *RedisTier) Get(key string) (*CacheItem, error) {
+	data, err := t.client.Get(t.ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var item CacheItem
+	if err := t.codec.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	if item.ExpireTime == 0 {
+		ttl, err := t.client.TTL(t.ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ttl <= 0 {
+			return nil, nil
+		}
+		item.ExpireTime = time.Now().Add(ttl).Unix()
+	}
+	return &item, nil
+}
+
+// Set 写入缓存项，已过期的项不写入
+func (t *RedisTier) Set(key string, item *CacheItem) error {
+	ttl := time.Until(time.Unix(item.ExpireTime, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := t.codec.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return t.client.Set(t.ctx, key, data, ttl).Err()
+}
+
+// Delete 删除缓存项
+func (t *RedisTier) Delete(key string) error {
+	return t.client.Del(t.ctx, key).Err()
+}
+
+// DiskTier 磁盘层，每个键保存为目录下的一个文件，文件名为键的SHA-1
+// 过期的文件在读取时删除；适合容量远大于内存、可以容忍毫秒级延迟的数据
+type DiskTier struct {
+	name  string
+	dir   string
+	codec Codec
+}
+
+// NewDiskTier 创建新的磁盘层，目录不存在时创建；codec为nil时使用JSONCodec
+func NewDiskTier(name, dir string, codec Codec) (*DiskTier, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &DiskTier{name: name, dir: dir, codec: codec}, nil
+}
+
+// Name 层的名称
+func (t *DiskTier) Name() string {
+	return t.name
+}
+
+// path 返回键对应的文件路径
+func (t *DiskTier) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get 读取缓存项，已过期时删除文件
+func (t *DiskTier) Get(key string) (*CacheItem, error) {
+	path := t.path(key)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var item CacheItem
+	if err := t.codec.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	if item.ExpireTime <= time.Now().Unix() {
+		os.Remove(path)
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// Set 写入缓存项，先写临时文件再重命名，读取方不会看到写了一半的文件
+func (t *DiskTier) Set(key string, item *CacheItem) error {
+	data, err := t.codec.Marshal(item)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(t.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), t.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Delete 删除缓存项
+func (t *DiskTier) Delete(key string) error {
+	err := os.Remove(t.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
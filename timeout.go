@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// readCommands 按读超时处理的Redis命令
+var readCommands = map[string]bool{
+	"get":      true,
+	"mget":     true,
+	"ttl":      true,
+	"pttl":     true,
+	"exists":   true,
+	"smembers": true,
+	"scard":    true,
+	"scan":     true,
+	"sscan":    true,
+	"info":     true,
+	"dbsize":   true,
+	"memory":   true,
+}
+
+// timeoutCancelKey 在context中保存超时取消函数的键
+type timeoutCancelKey struct{}
+
+// timeoutHook 为每个L2命令设置独立的读/写超时，慢Redis不会让缓存查询比直接访问数据库更慢
+type timeoutHook struct {
+	read  time.Duration
+	write time.Duration
+}
+
+var _ redis.Hook = (*timeoutHook)(nil)
+
+// withTimeout 为命令设置超时，已有更早的截止时间时保持不变
+func (h *timeoutHook) withTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		return ctx
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return context.WithValue(ctx, timeoutCancelKey{}, cancel)
+}
+
+// release 释放withTimeout创建的定时器
+func (h *timeoutHook) release(ctx context.Context) {
+	if cancel, ok := ctx.Value(timeoutCancelKey{}).(context.CancelFunc); ok {
+		cancel()
+	}
+}
+
+// BeforeProcess 按命令类型设置超时
+func (h *timeoutHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if readCommands[cmd.Name()] {
+		return h.withTimeout(ctx, h.read), nil
+	}
+	return h.withTimeout(ctx, h.write), nil
+}
+
+// AfterProcess 释放超时定时器
+func (h *timeoutHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.release(ctx)
+	return nil
+}
+
+// BeforeProcessPipeline 全部为读命令的管道使用读超时，否则使用写超时
+func (h *timeoutHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if !readCommands[cmd.Name()] {
+			return h.withTimeout(ctx, h.write), nil
+		}
+	}
+	return h.withTimeout(ctx, h.read), nil
+}
+
+// AfterProcessPipeline 释放超时定时器
+func (h *timeoutHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	h.release(ctx)
+	return nil
+}
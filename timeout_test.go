@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// remaining 返回context距截止时间的剩余时长，没有截止时间时返回0
+func remaining(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return time.Until(deadline)
+}
+
+func TestTimeoutHookByCommandType(t *testing.T) {
+	h := &timeoutHook{read: 50 * time.Millisecond, write: time.Second}
+	ctx := context.Background()
+
+	get := redis.NewStringCmd(ctx, "get", "k")
+	readCtx, _ := h.BeforeProcess(ctx, get)
+	if d := remaining(readCtx); d <= 0 || d > 50*time.Millisecond {
+		t.Errorf("GET timeout = %v, want the read timeout", d)
+	}
+	h.AfterProcess(readCtx, get)
+	if readCtx.Err() == nil {
+		t.Error("AfterProcess did not release the timeout")
+	}
+
+	set := redis.NewStatusCmd(ctx, "set", "k", "v")
+	writeCtx, _ := h.BeforeProcess(ctx, set)
+	if d := remaining(writeCtx); d <= 50*time.Millisecond || d > time.Second {
+		t.Errorf("SET timeout = %v, want the write timeout", d)
+	}
+	h.AfterProcess(writeCtx, set)
+
+	// 管道中有写命令时使用写超时
+	pipeCtx, _ := h.BeforeProcessPipeline(ctx, []redis.Cmder{get, set})
+	if d := remaining(pipeCtx); d <= 50*time.Millisecond {
+		t.Errorf("mixed pipeline timeout = %v, want the write timeout", d)
+	}
+	h.AfterProcessPipeline(pipeCtx, nil)
+	pipeCtx, _ = h.BeforeProcessPipeline(ctx, []redis.Cmder{get, get})
+	if d := remaining(pipeCtx); d <= 0 || d > 50*time.Millisecond {
+		t.Errorf("read-only pipeline timeout = %v, want the read timeout", d)
+	}
+	h.AfterProcessPipeline(pipeCtx, nil)
+}
+
+func TestTimeoutHookKeepsEarlierDeadline(t *testing.T) {
+	h := &timeoutHook{read: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	get := redis.NewStringCmd(ctx, "get", "k")
+	got, _ := h.BeforeProcess(ctx, get)
+	if got != ctx {
+		t.Error("hook replaced a context with an earlier deadline")
+	}
+	h.AfterProcess(got, get)
+	if ctx.Err() != nil {
+		t.Error("AfterProcess cancelled the caller's context")
+	}
+
+	// 未设置写超时时写命令不加截止时间
+	set := redis.NewStatusCmd(context.Background(), "set", "k", "v")
+	if got, _ := h.BeforeProcess(context.Background(), set); remaining(got) != 0 {
+		t.Error("write command got a deadline without L2WriteTimeout")
+	}
+}
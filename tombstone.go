@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"time"
+)
+
+// tombstoneKeyPrefix Redis中墓碑键的前缀
+const tombstoneKeyPrefix = "tombstone:"
+
+// addTombstone 删除键后记录墓碑，窗口内阻止该键被重新写入
+// 启用L2时同时在Redis中记录，阻止其他实例上进行中的加载回填旧数据
+func (c *MultiLevelCache) addTombstone(key string) {
+	c.addTombstoneFor(key, c.config().TombstoneTTL)
+}
+
+// addTombstoneFor 记录指定时长的墓碑
+func (c *MultiLevelCache) addTombstoneFor(key string, window time.Duration) {
+	if window <= 0 {
+		return
+	}
+	c.tombstones.Store(key, time.Now().Add(window))
+	if c.config().EnableL2Cache {
+		if err := c.l2().Set(c.ctx, c.internalKey(tombstoneKeyPrefix, key), 1, window).Err(); err != nil {
+			c.logf("dancache: set tombstone %q failed: %v", key, err)
+		}
+	}
+}
+
+// hasTombstone 判断键是否处于本地墓碑窗口内
+func (c *MultiLevelCache) hasTombstone(key string) bool {
+	if !c.tombstonesEnabled() {
+		return false
+	}
+	v, ok := c.tombstones.Load(key)
+	if !ok {
+		return false
+	}
+	if time.Now().Before(v.(time.Time)) {
+		return true
+	}
+	c.tombstones.CompareAndDelete(key, v)
+	return false
+}
+
+// loadBlocked 判断加载结果是否不应写入缓存，除本地墓碑外还检查其他实例在Redis中记录的墓碑
+func (c *MultiLevelCache) loadBlocked(key string) bool {
+	if c.hasTombstone(key) {
+		return true
+	}
+	if !c.tombstonesEnabled() || !c.config().EnableL2Cache {
+		return false
+	}
+	n, err := c.l2().Exists(c.ctx, c.internalKey(tombstoneKeyPrefix, key)).Result()
+	return err == nil && n > 0
+}
+
+// tombstonesEnabled 判断是否可能存在墓碑(删除墓碑或隔离后的写入阻止)
+func (c *MultiLevelCache) tombstonesEnabled() bool {
+	config := c.config()
+	return config.TombstoneTTL > 0 || (config.DecodePolicy == DecodeQuarantine && config.QuarantineBlock > 0)
+}
+
+// cleanupTombstones 清理已过期的本地墓碑
+func (c *MultiLevelCache) cleanupTombstones() {
+	now := time.Now()
+	c.tombstones.Range(func(key, value interface{}) bool {
+		if !now.Before(value.(time.Time)) {
+			c.tombstones.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
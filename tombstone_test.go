@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestTombstoneBlocksWritesDuringWindow(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) { config.TombstoneTTL = 50 * time.Millisecond })
+
+	if err := c.Set("k", "v1", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("k", "v2", 60); err != ErrTombstoned {
+		t.Errorf("Set during tombstone window = %v, want ErrTombstoned", err)
+	}
+	if err := c.SetAsync("k", "v2", 60).Wait(); err != ErrTombstoned {
+		t.Errorf("SetAsync during tombstone window = %v, want ErrTombstoned", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := c.Set("k", "v3", 60); err != nil {
+		t.Errorf("Set after tombstone window = %v, want nil", err)
+	}
+	if v, _ := c.Get("k"); v != "v3" {
+		t.Errorf("Get = %v, want v3", v)
+	}
+}
+
+func TestTombstoneBlocksLoadOnOtherInstance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	configure := func(config *CacheConfig) {
+		config.TombstoneTTL = time.Minute
+		config.SequencedWrites = false
+	}
+	c := newRedisTestCache(t, mr, configure)
+	other := newRedisTestCache(t, mr, configure)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan interface{})
+	go func() {
+		val, err := other.GetOrLoad("k", 60, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "old", nil
+		})
+		if err != nil {
+			t.Errorf("GetOrLoad: %v", err)
+		}
+		done <- val
+	}()
+	<-started
+	if err := c.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	// 加载结果返回给调用方，但不回填任何一级缓存
+	if val := <-done; val != "old" {
+		t.Errorf("GetOrLoad = %v, want old", val)
+	}
+	if mr.Exists("k") {
+		t.Error("load that raced a Delete on another instance was backfilled to L2")
+	}
+	if _, ok := other.shardFor("k").load("k"); ok {
+		t.Error("load that raced a Delete on another instance was stored in L1")
+	}
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// topKeysSampleEvery 每多少次访问采样一次，降低锁竞争
+const topKeysSampleEvery = 16
+
+// KeyCount 键及其访问次数(采样估计值)
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// topKeys 用Space-Saving算法在固定内存内估计访问最多的键
+type topKeys struct {
+	mu       sync.Mutex
+	capacity int
+	counts   map[string]int64
+	accesses uint64
+}
+
+// newTopKeys 创建热点键统计
+func newTopKeys(capacity int) *topKeys {
+	return &topKeys{
+		capacity: capacity,
+		counts:   make(map[string]int64, capacity),
+	}
+}
+
+// record 记录一次访问，只有被采样的访问计入
+func (t *topKeys) record(key string) {
+	if atomic.AddUint64(&t.accesses, 1)%topKeysSampleEvery != 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.counts[key]; ok || len(t.counts) < t.capacity {
+		t.counts[key] += topKeysSampleEvery
+		return
+	}
+	// 替换计数最小的键，新键继承其计数
+	minKey, minCount := "", int64(-1)
+	for k, n := range t.counts {
+		if minCount < 0 || n < minCount {
+			minKey, minCount = k, n
+		}
+	}
+	delete(t.counts, minKey)
+	t.counts[key] = minCount + topKeysSampleEvery
+}
+
+// top 返回访问最多的n个键
+func (t *topKeys) top(n int) []KeyCount {
+	t.mu.Lock()
+	result := make([]KeyCount, 0, len(t.counts))
+	for k, c := range t.counts {
+		result = append(result, KeyCount{Key: k, Count: c})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Key < result[j].Key
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
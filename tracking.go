@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// trackingChannel Redis发送客户端缓存失效消息的频道
+const trackingChannel = "__redis__:invalidate"
+
+// defaultTrackingConnAge 启用客户端跟踪时连接的默认最长存活时间
+// 跟踪连接重连后，旧连接仍把失效消息重定向到已断开的连接，需要在该时间内全部替换
+const defaultTrackingConnAge = 5 * time.Minute
+
+// clientTracking Redis客户端缓存(CLIENT TRACKING)的状态
+// 数据连接开启跟踪并把失效消息重定向到一个专用的订阅连接
+type clientTracking struct {
+	client        *redis.Client // 专用于接收失效消息的客户端
+	prefixes      []string      // BCAST模式跟踪的键前缀(为空表示只跟踪读取过的键)
+	pubsub        *redis.PubSub
+	connAge       time.Duration // 数据连接的最长存活时间
+	id            int64         // 订阅连接的CLIENT ID
+	degradedUntil int64         // 订阅连接重连后，在此之前(纳秒)仍有连接重定向到旧的订阅连接，不升级新的项
+	invalidations int64         // 收到的失效键数
+	flushes       int64         // 收到的全部失效(FLUSHDB等)次数
+	reconnects    int64         // 订阅连接的重连次数
+}
+
+// setupTracking 创建订阅连接并等待其就绪，返回开启了跟踪的数据连接配置
+// 配置了TrackingPrefixes时使用BCAST模式，Redis通知匹配前缀的所有键的修改，而不只是读取过的键
+func (c *MultiLevelCache) setupTracking(opts *redis.Options, prefixes []string) (*redis.Options, error) {
+	t := &clientTracking{connAge: opts.MaxConnAge, prefixes: prefixes}
+	if t.connAge <= 0 {
+		t.connAge = defaultTrackingConnAge
+	}
+
+	subOpts := *opts
+	subOnConnect := subOpts.OnConnect
+	subOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if subOnConnect != nil {
+			if err := subOnConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+		if prev := atomic.SwapInt64(&t.id, id); prev != 0 && prev != id {
+			c.trackingReconnected()
+		}
+		return nil
+	}
+	t.client = redis.NewClient(&subOpts)
+	t.pubsub = t.client.Subscribe(c.ctx, trackingChannel)
+	// 等待订阅确认，保证数据连接建立时已知道订阅连接的ID
+	if _, err := t.pubsub.Receive(c.ctx); err != nil {
+		t.pubsub.Close()
+		t.client.Close()
+		return nil, err
+	}
+
+	dataOpts := *opts
+	dataOpts.MaxConnAge = t.connAge
+	dataOnConnect := dataOpts.OnConnect
+	dataOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		if dataOnConnect != nil {
+			if err := dataOnConnect(ctx, cn); err != nil {
+				return err
+			}
+		}
+		// NOLOOP：本连接写入的键不通知自己
+		args := []interface{}{"CLIENT", "TRACKING", "ON", "REDIRECT", atomic.LoadInt64(&t.id), "NOLOOP"}
+		if len(t.prefixes) > 0 {
+			args = append(args, "BCAST")
+			for _, prefix := range t.prefixes {
+				args = append(args, "PREFIX", prefix)
+			}
+		}
+		return cn.Process(ctx, redis.NewStatusCmd(ctx, args...))
+	}
+	c.tracking = t
+	return &dataOpts, nil
+}
+
+// trackingListenerRoutine 接收Redis发送的失效消息并删除L1中的项
+func (c *MultiLevelCache) trackingListenerRoutine(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	t := c.tracking
+	for {
+		msg, err := t.pubsub.ReceiveMessage(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// FLUSHDB/FLUSHALL时失效消息的内容为nil，go-redis无法解析，按全部失效处理
+			if strings.Contains(err.Error(), "unsupported pubsub message payload") {
+				atomic.AddInt64(&t.flushes, 1)
+				c.clearL1()
+				continue
+			}
+			c.logf("dancache: client tracking receive failed: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-stop:
+				return
+			}
+			continue
+		}
+		keys := msg.PayloadSlice
+		if msg.Payload != "" {
+			keys = append(keys, msg.Payload)
+		}
+		for _, key := range keys {
+			c.deleteL1(key)
+		}
+		atomic.AddInt64(&t.invalidations, int64(len(keys)))
+	}
+}
+
+// trackingReconnected 订阅连接重连后，期间的失效消息已丢失，清空L1
+// 已有的数据连接仍重定向到旧的订阅连接，直到它们超过最长存活时间被替换前不升级新的项
+func (c *MultiLevelCache) trackingReconnected() {
+	t := c.tracking
+	atomic.AddInt64(&t.reconnects, 1)
+	atomic.StoreInt64(&t.degradedUntil, time.Now().Add(t.connAge).UnixNano())
+	c.clearL1()
+	c.logf("dancache: client tracking connection reconnected, local cache cleared")
+}
+
+// trackingDegraded 判断是否有数据连接的失效消息无法送达，此时从L2读取的项不进入L1
+func (c *MultiLevelCache) trackingDegraded() bool {
+	return c.tracking != nil && time.Now().UnixNano() < atomic.LoadInt64(&c.tracking.degradedUntil)
+}
+
+// trackingBlocksPromotion 判断从L2读取的项是否不能进入L1：跟踪降级期间，或BCAST模式下键不匹配任何前缀
+func (c *MultiLevelCache) trackingBlocksPromotion(key string) bool {
+	if c.tracking == nil {
+		return false
+	}
+	return c.trackingDegraded() || (len(c.tracking.prefixes) > 0 && !c.tracking.covers(key))
+}
+
+// trackingBypassesWrites 判断写入的值是否不能进入L1
+// 默认模式只跟踪连接读取过的键，写入不会让Redis跟踪该键，其他实例随后的修改不会通知本实例；
+// BCAST模式下匹配前缀的键无论是否读取过都会通知
+func (c *MultiLevelCache) trackingBypassesWrites(key string) bool {
+	if c.tracking == nil {
+		return false
+	}
+	return len(c.tracking.prefixes) == 0 || !c.tracking.covers(key)
+}
+
+// covers 判断BCAST模式下键是否匹配跟踪的前缀
+func (t *clientTracking) covers(key string) bool {
+	for _, prefix := range t.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackingStatsMap 返回客户端跟踪的统计
+func (c *MultiLevelCache) trackingStatsMap() map[string]interface{} {
+	t := c.tracking
+	if t == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"tracking_invalidations": atomic.LoadInt64(&t.invalidations),
+		"tracking_flushes":       atomic.LoadInt64(&t.flushes),
+		"tracking_reconnects":    atomic.LoadInt64(&t.reconnects),
+		"tracking_degraded":      c.trackingDegraded(),
+	}
+}
+
+// closeTracking 关闭订阅连接
+func (c *MultiLevelCache) closeTracking() {
+	if c.tracking != nil {
+		c.tracking.pubsub.Close()
+		c.tracking.client.Close()
+	}
+}
+
+// InvalidatePrefix 删除以prefix开头的所有键，返回L2中删除的键数
+// 本实例的L1直接清理，L2中的键通过SCAN删除；启用ClientTracking时其他实例由Redis通知删除各自L1中的项，
+// 否则其他实例L1中的项保留到过期。配置了Authorizer时需要其允许OpInvalidatePrefix
+func (c *MultiLevelCache) InvalidatePrefix(ctx context.Context, prefix string) (int, error) {
+	if !c.enter() {
+		return 0, ErrClosed
+	}
+	defer c.exit()
+	if err := c.requireLevel(); err != nil {
+		return 0, err
+	}
+	if prefix == "" {
+		return 0, errors.New("前缀不能为空")
+	}
+	if err := c.authorize(ctx, OpInvalidatePrefix, prefix); err != nil {
+		return 0, err
+	}
+
+	if c.config().EnableL1Cache {
+		for _, shard := range c.shards {
+			shard.rangeItems(func(key string, item *CacheItem) bool {
+				if strings.HasPrefix(key, prefix) {
+					shard.removeIf(key, item)
+					c.cancelL2Write(key)
+				}
+				return true
+			})
+		}
+	}
+	if !c.config().EnableL2Cache {
+		return 0, nil
+	}
+	return c.deletePatternL2(globEscape(prefix) + "*")
+}
+
+// globEscape 转义Redis glob语法中的特殊字符
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTrackingTestCache 创建缓存并设置客户端跟踪的状态，miniredis不支持CLIENT TRACKING，不启动订阅连接
+func newTrackingTestCache(t *testing.T, mr *miniredis.Miniredis, prefixes ...string) *MultiLevelCache {
+	t.Helper()
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.PromotionStrategy = NewFrequencyBasedStrategy(1, 60, 0)
+	})
+	c.tracking = &clientTracking{prefixes: prefixes, connAge: time.Minute}
+	// 先于关闭缓存执行，没有订阅连接需要关闭
+	t.Cleanup(func() { c.tracking = nil })
+	return c
+}
+
+func TestTrackingDefaultModeOnlyCachesReads(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTrackingTestCache(t, mr)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	// 写入不会让Redis跟踪该键，值只写入L2
+	if _, ok := c.shardFor("k").load("k"); ok {
+		t.Fatal("written value entered L1 without being tracked")
+	}
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get = %v, %v; want v", v, ok)
+	}
+	if _, ok := c.shardFor("k").load("k"); !ok {
+		t.Error("value read from L2 was not promoted")
+	}
+}
+
+func TestTrackingBroadcastModeCoversPrefixes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTrackingTestCache(t, mr, "user:")
+	for _, key := range []string{"user:1", "order:1"} {
+		if err := c.Set(key, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := c.shardFor("user:1").load("user:1"); !ok {
+		t.Error("tracked prefix was not written to L1")
+	}
+	// 不匹配前缀的键不会收到失效通知，读写都不进入L1
+	if _, ok := c.Get("order:1"); !ok {
+		t.Fatal("order:1 missing from L2")
+	}
+	if _, ok := c.shardFor("order:1").load("order:1"); ok {
+		t.Error("untracked key entered L1")
+	}
+}
+
+func TestTrackingReconnectClearsL1AndDegrades(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTrackingTestCache(t, mr)
+	if err := c.Set("k", "v", 60); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("k")
+	if _, ok := c.shardFor("k").load("k"); !ok {
+		t.Fatal("k was not promoted")
+	}
+
+	c.trackingReconnected()
+	if _, ok := c.shardFor("k").load("k"); ok {
+		t.Error("L1 was not cleared after the tracking connection reconnected")
+	}
+	// 旧连接被替换之前不升级
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get = %v, %v; want v from L2", v, ok)
+	}
+	if _, ok := c.shardFor("k").load("k"); ok {
+		t.Error("value was promoted while tracking was degraded")
+	}
+	stats := c.GetStats()
+	if stats["tracking_reconnects"] != int64(1) || stats["tracking_degraded"] != true {
+		t.Errorf("tracking stats = %v, %v; want 1 reconnect while degraded", stats["tracking_reconnects"], stats["tracking_degraded"])
+	}
+
+	c.tracking.degradedUntil = time.Now().Add(-time.Second).UnixNano()
+	c.Get("k")
+	if _, ok := c.shardFor("k").load("k"); !ok {
+		t.Error("value was not promoted after the degraded window")
+	}
+}
+
+func TestClientTrackingValidated(t *testing.T) {
+	for name, config := range map[string]CacheConfig{
+		"L1 only":      {EnableL1Cache: true, MaxL1Size: 10, ClientTracking: true},
+		"proxy mode":   {EnableL1Cache: true, MaxL1Size: 10, EnableL2Cache: true, RedisOptions: &redis.Options{}, RedisProxyMode: true, ClientTracking: true},
+		"empty prefix": {EnableL1Cache: true, MaxL1Size: 10, EnableL2Cache: true, RedisOptions: &redis.Options{}, ClientTracking: true, TrackingPrefixes: []string{""}},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate accepted ClientTracking", name)
+		}
+	}
+}
+
+func TestInvalidatePrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	for _, key := range []string{"user:1", "user:2", "user*x", "order:1"} {
+		if err := c.Set(key, "v", 60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.InvalidatePrefix(context.Background(), ""); err == nil {
+		t.Error("InvalidatePrefix accepted an empty prefix")
+	}
+
+	// 前缀中的glob特殊字符按原样匹配
+	n, err := c.InvalidatePrefix(context.Background(), "user*")
+	if err != nil || n != 1 {
+		t.Fatalf("InvalidatePrefix(user*) = %d, %v; want 1", n, err)
+	}
+	if _, ok := c.Get("user:1"); !ok {
+		t.Error("user:1 was removed by prefix user*")
+	}
+
+	n, err = c.InvalidatePrefix(context.Background(), "user:")
+	if err != nil || n != 2 {
+		t.Fatalf("InvalidatePrefix(user:) = %d, %v; want 2", n, err)
+	}
+	for _, key := range []string{"user:1", "user:2", "user*x"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("%s survived InvalidatePrefix", key)
+		}
+	}
+	if _, ok := c.Get("order:1"); !ok {
+		t.Error("order:1 was removed")
+	}
+}
+
+func TestGlobEscape(t *testing.T) {
+	if got := globEscape(`a*b?c[d]e\f`); got != `a\*b\?c\[d\]e\\f` {
+		t.Errorf("globEscape = %q", got)
+	}
+}
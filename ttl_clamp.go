@@ -0,0 +1,50 @@
+package cache
+
+import "sync/atomic"
+
+// clampTTL 按MinTTL/MaxTTL限制写入的过期时间(秒)并记录调整次数
+func (c *MultiLevelCache) clampTTL(ttl int64) int64 {
+	clamped := c.boundTTL(ttl)
+	if clamped != ttl {
+		atomic.AddInt64(&c.clampedTTLs, 1)
+	}
+	return clamped
+}
+
+// boundTTL 返回按MinTTL/MaxTTL调整后的过期时间(秒)，启用L2时按L2的限制，否则按L1的限制
+// 不大于0的ttl保持不变
+func (c *MultiLevelCache) boundTTL(ttl int64) int64 {
+	if ttl <= 0 {
+		return ttl
+	}
+	config := c.config()
+	min, max := config.L1MinTTL, config.L1MaxTTL
+	if config.EnableL2Cache {
+		min, max = config.L2MinTTL, config.L2MaxTTL
+	}
+	clamped := ttl
+	if min > 0 && clamped < min {
+		clamped = min
+	}
+	if max > 0 && clamped > max {
+		clamped = max
+	}
+	return clamped
+}
+
+// boundL1 启用L2时按L1MaxTTL限制项在L1中停留的时间，到期后重新从L2读取
+func (c *MultiLevelCache) boundL1(item *CacheItem, now int64) {
+	config := c.config()
+	max := config.L1MaxTTL
+	if max <= 0 || !config.EnableL2Cache {
+		return
+	}
+	if deadline := now + max; deadline < item.ExpireTime && (item.revalidateAt == 0 || deadline < item.revalidateAt) {
+		item.revalidateAt = deadline
+	}
+}
+
+// lifetime 返回缓存项写入时的有效期(秒)
+func (item *CacheItem) lifetime() int64 {
+	return item.ExpireTime - item.CreateTime
+}
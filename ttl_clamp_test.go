@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestL2TTLBoundsClampWrites(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, func(config *CacheConfig) {
+		config.L2MinTTL = 60
+		config.L2MaxTTL = 3600
+		config.L1MaxTTL = 30
+	})
+	if err := c.Set("short", "v", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("long", "v", 86400); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("ok", "v", 600); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("short"); ttl < 59*time.Second || ttl > time.Minute {
+		t.Errorf("short TTL = %v, want L2MinTTL 1m", ttl)
+	}
+	if ttl := mr.TTL("long"); ttl < 59*time.Minute || ttl > time.Hour {
+		t.Errorf("long TTL = %v, want L2MaxTTL 1h", ttl)
+	}
+	if n := c.GetStats()["clamped_ttls"]; n != int64(2) {
+		t.Errorf("clamped_ttls = %v, want 2", n)
+	}
+
+	// 启用L2时L1MaxTTL限制项在L1中的停留时间，不改变过期时间
+	item, ok := c.shardFor("ok").load("ok")
+	if !ok {
+		t.Fatal("ok missing from L1")
+	}
+	now := time.Now().Unix()
+	if item.revalidateAt < now+29 || item.revalidateAt > now+30 {
+		t.Errorf("revalidateAt = %d, want about now+30", item.revalidateAt)
+	}
+	if item.ExpireTime < now+599 {
+		t.Errorf("ExpireTime = %d, want the full 600s", item.ExpireTime)
+	}
+}
+
+func TestL1TTLBoundsWithoutL2(t *testing.T) {
+	c := newL1TestCache(t, func(config *CacheConfig) {
+		config.L1MinTTL = 10
+		config.L1MaxTTL = 100
+	})
+	for key, ttl := range map[string]int64{"short": 1, "long": 1000} {
+		if err := c.Set(key, "v", ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	short, _ := c.shardFor("short").load("short")
+	long, _ := c.shardFor("long").load("long")
+	if got := short.lifetime(); got != 10 {
+		t.Errorf("short lifetime = %d, want 10", got)
+	}
+	if got := long.lifetime(); got != 100 {
+		t.Errorf("long lifetime = %d, want 100", got)
+	}
+}
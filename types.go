@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// typeRegistry 已注册的值类型，写入L2时记录类型名，读取时按类型名解码回原来的具体类型
+var typeRegistry = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{
+	byName: make(map[string]reflect.Type),
+	byType: make(map[reflect.Type]string),
+}
+
+// RegisterType 注册值类型T，之后写入L2的T和*T类型的值带上类型名，从L2读取时解码为T或*T而不是map[string]interface{}
+// 类型名为包路径加类型名，类型改名或移动包后需要用RegisterTypeName保留原来的名称；应在程序启动时注册
+func RegisterType[T any]() {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	RegisterTypeName[T](t.PkgPath() + "." + t.Name())
+}
+
+// RegisterTypeName 以指定的名称注册值类型T，使用同一Redis的服务需要为同一类型注册相同的名称
+// 同一名称重复注册为其他类型时panic
+func RegisterTypeName[T any](name string) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		panic(fmt.Sprintf("dancache: RegisterType需要具体的非指针类型，而不是%s", t))
+	}
+	if name == "" || name == "." || strings.HasPrefix(name, "*") {
+		panic(fmt.Sprintf("dancache: 类型%s的名称%q无效", t, name))
+	}
+
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+	if prev, ok := typeRegistry.byName[name]; ok && prev != t {
+		panic(fmt.Sprintf("dancache: 类型名%q已注册为%s", name, prev))
+	}
+	typeRegistry.byName[name] = t
+	typeRegistry.byType[t] = name
+}
+
+// typeTagOf 返回值的类型名，指针类型以*开头；未注册的类型返回空字符串
+func typeTagOf(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	t := reflect.TypeOf(v)
+	prefix := ""
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		prefix = "*"
+	}
+	typeRegistry.RLock()
+	name, ok := typeRegistry.byType[t]
+	typeRegistry.RUnlock()
+	if !ok {
+		return ""
+	}
+	return prefix + name
+}
+
+// tagItemType 返回带上值的类型名的缓存项副本，值的类型未注册时返回原缓存项
+func tagItemType(item *CacheItem) *CacheItem {
+	tag := typeTagOf(item.Value)
+	if tag == item.Type {
+		return item
+	}
+	tagged := *item
+	tagged.Type = tag
+	return &tagged
+}
+
+// restoreItemType 按类型名把解码得到的通用值转换回注册的具体类型
+// 本进程未注册该类型名时保留通用值；值为旧版本(ValueVersion)时保留通用值交给迁移函数
+func (c *MultiLevelCache) restoreItemType(item *CacheItem) error {
+	if item.Type == "" || item.Version != c.config().ValueVersion {
+		return nil
+	}
+	name := strings.TrimPrefix(item.Type, "*")
+	typeRegistry.RLock()
+	t, ok := typeRegistry.byName[name]
+	typeRegistry.RUnlock()
+	if !ok {
+		return nil
+	}
+	want := t
+	if name != item.Type {
+		want = reflect.PtrTo(t)
+	}
+	if item.Value != nil && reflect.TypeOf(item.Value) == want {
+		return nil
+	}
+
+	data, err := json.Marshal(item.Value)
+	if err != nil {
+		return err
+	}
+	p := reflect.New(t)
+	if err := json.Unmarshal(data, p.Interface()); err != nil {
+		return fmt.Errorf("解码为%s失败: %w", t, err)
+	}
+	if want == t {
+		item.Value = p.Elem().Interface()
+	} else {
+		item.Value = p.Interface()
+	}
+	return nil
+}
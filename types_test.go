@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// registeredOrder 注册后从L2读取时解码为具体类型
+type registeredOrder struct {
+	ID    int64   `json:"id"`
+	Total float64 `json:"total"`
+}
+
+// renamedOrder 以旧名称注册的类型
+type renamedOrder struct {
+	ID int64 `json:"id"`
+}
+
+func TestRegisterTypeRestoresConcreteTypes(t *testing.T) {
+	RegisterType[registeredOrder]()
+	mr := miniredis.RunT(t)
+	writer := newRedisTestCache(t, mr, nil)
+	reader := newRedisTestCache(t, mr, nil)
+
+	if err := writer.Set("value", registeredOrder{ID: 1, Total: 9.5}, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Set("pointer", &registeredOrder{ID: 2}, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Set("plain", struct{ ID int }{3}, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// 另一个实例从L2读取，得到注册的具体类型
+	if v, ok := reader.Get("value"); !ok || v != (registeredOrder{ID: 1, Total: 9.5}) {
+		t.Errorf("Get(value) = %#v, %v; want registeredOrder", v, ok)
+	}
+	if v, ok := reader.Get("pointer"); !ok {
+		t.Error("pointer missing")
+	} else if p, isPtr := v.(*registeredOrder); !isPtr || p.ID != 2 {
+		t.Errorf("Get(pointer) = %#v, want *registeredOrder", v)
+	}
+	v, _ := reader.Get("plain")
+	if _, isMap := v.(map[string]interface{}); !isMap {
+		t.Errorf("Get(plain) = %#v, want a generic map for an unregistered type", v)
+	}
+}
+
+func TestRegisterTypeNameKeepsStoredName(t *testing.T) {
+	RegisterTypeName[renamedOrder]("legacy.Order")
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+
+	// 写入L2的是注册的名称而不是当前的包路径
+	now := time.Now().Unix()
+	data, err := c.marshalItem("k", &CacheItem{Value: renamedOrder{ID: 7}, ExpireTime: now + 60, CreateTime: now, AccessTime: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type":"legacy.Order"`) {
+		t.Fatalf("encoded item %s does not carry the registered name", data)
+	}
+	mr.Set("k", string(data))
+	if v, ok := c.Get("k"); !ok || v != (renamedOrder{ID: 7}) {
+		t.Errorf("Get = %#v, %v; want renamedOrder", v, ok)
+	}
+	if tag := typeTagOf(&renamedOrder{}); tag != "*legacy.Order" {
+		t.Errorf("typeTagOf(*renamedOrder) = %q, want *legacy.Order", tag)
+	}
+}
+
+func TestRegisterTypeNamePanics(t *testing.T) {
+	RegisterTypeName[renamedOrder]("legacy.Order")
+	for name, register := range map[string]func(){
+		"pointer":   func() { RegisterType[*registeredOrder]() },
+		"interface": func() { RegisterType[error]() },
+		"empty":     func() { RegisterTypeName[renamedOrder]("") },
+		"star":      func() { RegisterTypeName[renamedOrder]("*x") },
+		"duplicate": func() { RegisterTypeName[registeredOrder]("legacy.Order") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: registration did not panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
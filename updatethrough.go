@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// UpdateFunc 执行权威数据源的写入(如数据库事务)，返回写入后的新值
+// 返回nil值且无错误表示数据已删除，缓存中的键被删除而不是更新
+type UpdateFunc func(ctx context.Context) (interface{}, error)
+
+// UpdateOptions UpdateThroughContext的选项
+type UpdateOptions struct {
+	Tags   []string         // 写入缓存时关联的标签
+	Outbox *OutboxPublisher // 写入提交后通过发件箱发布失效事件，通知使用其他缓存实例或服务的消费方
+	// OnCommitted 写入提交且缓存已更新或失效后调用，value为nil表示已删除
+	OnCommitted func(key string, value interface{})
+}
+
+// updateEventKey context中保存失效事件的键
+type updateEventKey struct{}
+
+// UpdateEvent 返回UpdateThrough本次写入对应的失效事件，在UpdateFunc中调用
+// 使用事务性发件箱时，把事件与业务数据写入同一个数据库事务，由CDC或轮询投递给InvalidationConsumer；不在UpdateFunc中调用时返回nil
+func UpdateEvent(ctx context.Context) *InvalidationEvent {
+	event, _ := ctx.Value(updateEventKey{}).(*InvalidationEvent)
+	return event
+}
+
+// UpdateThrough 先写入权威数据源，提交成功后再更新缓存；写入失败时删除缓存中的键
+// 写入返回错误时无法确定数据源是否已部分修改，删除缓存保证之后的读取回源
+func (c *MultiLevelCache) UpdateThrough(key string, ttl int64, mutate UpdateFunc) (interface{}, error) {
+	return c.UpdateThroughContext(c.ctx, key, ttl, mutate, UpdateOptions{})
+}
+
+// UpdateThroughContext 与UpdateThrough相同，mutate收到由ctx派生的context，可以指定标签和发件箱
+// 更新缓存失败时删除缓存中的键，删除也失败时返回更新的错误，调用方应按缓存可能不一致处理
+// 启用SequencedWrites时在mutate之前登记写入序号，mutate期间并发回填的旧值不会覆盖本次更新
+func (c *MultiLevelCache) UpdateThroughContext(ctx context.Context, key string, ttl int64, mutate UpdateFunc, opts UpdateOptions) (interface{}, error) {
+	if !c.enter() {
+		return nil, ErrClosed
+	}
+	defer c.exit()
+	key, err := c.normalizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var o callOptions
+	if c.sequenced() {
+		if o.seq, err = c.reserveSequence(ctx, key); err != nil {
+			c.logf("dancache: reserve write sequence for %q failed: %v", key, err)
+		}
+	}
+
+	event := &InvalidationEvent{Keys: []string{key}, Tags: opts.Tags}
+	var value interface{}
+	if perr := c.protect("UpdateThrough", func() {
+		value, err = mutate(context.WithValue(ctx, updateEventKey{}, event))
+	}); perr != nil {
+		err = perr
+	}
+	if err != nil {
+		atomic.AddInt64(&c.updateFailures, 1)
+		c.invalidateAfterUpdate(key)
+		return nil, err
+	}
+
+	// 数据源已提交，更新缓存；更新失败或数据已删除时删除缓存中的键
+	if value == nil {
+		if derr := c.Delete(key); derr != nil {
+			c.logf("dancache: invalidate %q after update failed: %v", key, derr)
+			err = derr
+		}
+	} else if serr := c.setWithTags(detach(ctx), key, value, ttl, opts.Tags, o); serr != nil {
+		c.logf("dancache: cache updated value %q failed: %v", key, serr)
+		if derr := c.Delete(key); derr != nil {
+			c.logf("dancache: invalidate %q after update failed: %v", key, derr)
+			err = serr
+		}
+	}
+
+	if opts.Outbox != nil {
+		if perr := opts.Outbox.Publish(event); perr != nil {
+			c.logf("dancache: publish invalidation for %q failed: %v", key, perr)
+			if err == nil {
+				err = perr
+			}
+		}
+	}
+	if opts.OnCommitted != nil {
+		c.protect("OnCommitted", func() { opts.OnCommitted(key, value) })
+	}
+	return value, err
+}
+
+// invalidateAfterUpdate 数据源写入失败后删除缓存中的键，删除失败时只记录日志，原错误返回给调用方
+func (c *MultiLevelCache) invalidateAfterUpdate(key string) {
+	if err := c.Delete(key); err != nil {
+		c.logf("dancache: invalidate %q after failed update failed: %v", key, err)
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestUpdateThroughUpdatesCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	publisher := &recordingPublisher{}
+	outbox, err := NewOutboxPublisher(publisher, filepath.Join(t.TempDir(), "outbox"), time.Millisecond, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outbox.Close()
+
+	var event *InvalidationEvent
+	var committed interface{}
+	v, err := c.UpdateThroughContext(context.Background(), "k", 60, func(ctx context.Context) (interface{}, error) {
+		event = UpdateEvent(ctx)
+		return "new", nil
+	}, UpdateOptions{
+		Tags:        []string{"t"},
+		Outbox:      outbox,
+		OnCommitted: func(key string, value interface{}) { committed = value },
+	})
+	if err != nil || v != "new" {
+		t.Fatalf("UpdateThroughContext = %v, %v; want new", v, err)
+	}
+	if event == nil || len(event.Keys) != 1 || event.Keys[0] != "k" || len(event.Tags) != 1 {
+		t.Errorf("UpdateEvent = %+v, want the key and tags", event)
+	}
+	if committed != "new" {
+		t.Errorf("OnCommitted value = %v, want new", committed)
+	}
+	if item, ok := c.shardFor("k").load("k"); !ok || item.Value != "new" || !mr.Exists("k") {
+		t.Error("updated value missing from L1 or L2")
+	}
+	if !mr.Exists(tagKeyPrefix + "t") {
+		t.Error("tag index not written")
+	}
+	// 提交后通过发件箱发布失效事件
+	waitDrained(t, outbox)
+	if keys := publisher.keys(t); len(keys) != 1 || keys[0] != "k" {
+		t.Errorf("published keys = %v, want [k]", keys)
+	}
+	if UpdateEvent(context.Background()) != nil {
+		t.Error("UpdateEvent outside UpdateThrough is not nil")
+	}
+}
+
+func TestUpdateThroughInvalidatesOnFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		mutate UpdateFunc
+		check  func(error) bool
+	}{
+		{"error", func(context.Context) (interface{}, error) { return nil, errors.New("tx failed") }, func(err error) bool { return err != nil && err.Error() == "tx failed" }},
+		{"panic", func(context.Context) (interface{}, error) { panic("boom") }, func(err error) bool { var p *PanicError; return errors.As(err, &p) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			c := newRedisTestCache(t, mr, nil)
+			if err := c.Set("k", "old", 60); err != nil {
+				t.Fatal(err)
+			}
+			committed := false
+			v, err := c.UpdateThroughContext(context.Background(), "k", 60, tc.mutate, UpdateOptions{
+				OnCommitted: func(string, interface{}) { committed = true },
+			})
+			if v != nil || !tc.check(err) {
+				t.Fatalf("UpdateThroughContext = %v, %v", v, err)
+			}
+			// 无法确定数据源是否已修改，两级缓存都删除该键
+			if _, ok := c.shardFor("k").load("k"); ok || mr.Exists("k") {
+				t.Error("cached value kept after a failed update")
+			}
+			if committed {
+				t.Error("OnCommitted called after a failed update")
+			}
+			if n := c.GetStats()["update_failures"]; n != int64(1) {
+				t.Errorf("update_failures = %v, want 1", n)
+			}
+		})
+	}
+}
+
+func TestUpdateThroughNilValueDeletes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+	if err := c.Set("k", "old", 60); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.UpdateThrough("k", 60, func(context.Context) (interface{}, error) { return nil, nil })
+	if v != nil || err != nil {
+		t.Fatalf("UpdateThrough = %v, %v; want nil, nil", v, err)
+	}
+	if _, ok := c.Get("k"); ok {
+		t.Error("deleted value still cached")
+	}
+}
+
+func TestUpdateThroughWinsOverConcurrentBackfill(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newRedisTestCache(t, mr, nil)
+
+	// 更新开始前已经在加载旧值的GetOrLoad
+	loading, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.GetOrLoad("k", 60, func() (interface{}, error) {
+			close(loading)
+			<-release
+			return "stale", nil
+		})
+	}()
+	<-loading
+
+	if _, err := c.UpdateThrough("k", 60, func(context.Context) (interface{}, error) { return "new", nil }); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+
+	// 之前登记的回填不覆盖本次更新
+	if v, ok := c.Get("k"); !ok || v != "new" {
+		t.Errorf("Get = %v, %v; want new", v, ok)
+	}
+	if v, ok := c.Get("k", SkipL1()); !ok || v != "new" {
+		t.Errorf("L2 value = %v, %v; want new", v, ok)
+	}
+}
@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfigIssue 配置中的一个问题
+type ConfigIssue struct {
+	Field   string // 相关的配置字段
+	Message string
+	Warning bool // true表示配置不会生效或可能不符合预期，但不阻止创建缓存
+}
+
+// String 返回问题描述
+func (i ConfigIssue) String() string {
+	level := "错误"
+	if i.Warning {
+		level = "警告"
+	}
+	return fmt.Sprintf("%s %s: %s", level, i.Field, i.Message)
+}
+
+// ConfigError 配置校验发现的问题
+type ConfigError struct {
+	Issues []ConfigIssue
+}
+
+// Error 实现error接口
+func (e *ConfigError) Error() string {
+	if len(e.Issues) == 1 {
+		return "缓存配置无效: " + e.Issues[0].String()
+	}
+	parts := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		parts[i] = issue.String()
+	}
+	return fmt.Sprintf("缓存配置有%d个问题: %s", len(e.Issues), strings.Join(parts, "; "))
+}
+
+// Validate 校验配置，返回包含全部错误和警告的*ConfigError，没有问题时返回nil
+// 创建缓存时只有错误会导致失败，警告通过Logger输出；在CI中调用Validate可以把警告也当作失败
+func (config CacheConfig) Validate() error {
+	if issues := config.issues(); len(issues) > 0 {
+		return &ConfigError{Issues: issues}
+	}
+	return nil
+}
+
+// configField 只在某个缓存级别启用时才起作用的配置字段
+type configField struct {
+	name string
+	set  bool
+}
+
+// issues 检查矛盾或无效的配置
+func (config CacheConfig) issues() []ConfigIssue {
+	var issues []ConfigIssue
+	fail := func(field, format string, args ...interface{}) {
+		issues = append(issues, ConfigIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(field, format string, args ...interface{}) {
+		issues = append(issues, ConfigIssue{Field: field, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	// 错误：无法按配置运行
+	if config.EnableL2Cache && regionRedisOptions(&config) == nil && config.RedisClient == nil {
+		fail("RedisOptions", "RedisOptions和RedisClient不能都为空")
+	}
+	if err := validatePartitions(config.L1Partitions); err != nil {
+		fail("L1Partitions", "%v", err)
+	}
+	if config.BypassPercent < 0 || config.BypassPercent > 100 {
+		fail("BypassPercent", "必须在0到100之间")
+	}
+	if config.ReadRepairPercent < 0 || config.ReadRepairPercent > 100 {
+		fail("ReadRepairPercent", "必须在0到100之间")
+	}
+	for _, q := range config.L2Quotas {
+		if q.SoftBytes < 0 || q.HardBytes < 0 {
+			fail("L2Quotas", "前缀%q的配额不能为负数", q.Prefix)
+		}
+		if q.HardBytes > 0 && q.SoftBytes > q.HardBytes {
+			warn("L2Quotas", "前缀%q的软配额大于硬配额，不会在拒绝写入前告警", q.Prefix)
+		}
+	}
+	if config.Encryption != nil && !config.Envelope {
+		fail("Encryption", "必须同时启用Envelope，否则值以明文写入L2")
+	}
+	if config.MaxL1Size < 0 {
+		fail("MaxL1Size", "不能为负数")
+	}
+	if config.L1TTL < 0 || config.L2TTL < 0 {
+		fail("L1TTL/L2TTL", "不能为负数")
+	}
+	for _, b := range []struct {
+		name     string
+		min, max int64
+	}{
+		{"L1MinTTL/L1MaxTTL", config.L1MinTTL, config.L1MaxTTL},
+		{"L2MinTTL/L2MaxTTL", config.L2MinTTL, config.L2MaxTTL},
+	} {
+		if b.min < 0 || b.max < 0 {
+			fail(b.name, "不能为负数")
+		} else if b.max > 0 && b.min > b.max {
+			fail(b.name, "最短过期时间不能大于最长过期时间")
+		}
+	}
+	if (config.RedisTLSCertFile == "") != (config.RedisTLSKeyFile == "") {
+		fail("RedisTLSCertFile/RedisTLSKeyFile", "客户端证书和私钥必须同时配置")
+	}
+	if config.ClientTracking && (!config.EnableL1Cache || !config.EnableL2Cache) {
+		fail("ClientTracking", "需要同时启用L1和L2")
+	}
+	if config.ClientTracking && (config.RedisProxyMode || config.RedisClient != nil) {
+		fail("ClientTracking", "不支持RedisProxyMode和自定义RedisClient")
+	}
+	for _, prefix := range config.TrackingPrefixes {
+		if prefix == "" {
+			fail("TrackingPrefixes", "前缀不能为空")
+		}
+	}
+	if config.Region != "" && config.RedisProxyMode {
+		fail("Region", "跨区域失效依赖pub/sub，不支持RedisProxyMode")
+	}
+	for name, opts := range config.RegionRedisOptions {
+		if opts == nil {
+			fail("RegionRedisOptions", "区域%q的Redis配置不能为空", name)
+		}
+	}
+	if config.RegionQueueSize < 0 {
+		fail("RegionQueueSize", "不能为负数")
+	}
+	if config.PurgeRate < 0 {
+		fail("PurgeRate", "不能为负数")
+	}
+	if config.TagIndexMaxSize < 0 {
+		fail("TagIndexMaxSize", "不能为负数")
+	}
+	for tag, ttl := range config.TagMaxTTLs {
+		if ttl < 0 {
+			fail("TagMaxTTLs", "标签%q的最长过期时间不能为负数", tag)
+		}
+	}
+	if config.MemoryLimitRatio < 0 {
+		fail("MemoryLimitRatio", "不能为负数")
+	}
+	if config.InvalidationLagAlarm < 0 {
+		fail("InvalidationLagAlarm", "不能为负数")
+	}
+
+	// 警告：配置不会生效
+	if config.MemoryLimitRatio > 1 {
+		warn("MemoryLimitRatio", "大于1时堆内存不会达到阈值")
+	}
+	if config.TimeSource == TimeRedis && !config.EnableL2Cache {
+		warn("TimeSource", "TimeRedis需要启用L2，未启用时使用本机时钟")
+	}
+	if !config.EnableL1Cache && !config.EnableL2Cache {
+		warn("EnableL1Cache/EnableL2Cache", "L1和L2均未启用，写入和删除将返回ErrNoCacheLevel")
+	}
+	if config.EnableL1Cache && config.MaxL1Size == 0 && config.L1SizeMax == 0 && config.MemoryLimitRatio == 0 {
+		warn("MaxL1Size", "启用L1但未限制条目数，也未配置内存压力收缩，L1可能无限增长")
+	}
+	if config.L1SizeMin > 0 && config.L1SizeMax <= config.L1SizeMin {
+		warn("L1SizeMax", "必须大于L1SizeMin才会动态调整容量")
+	}
+	if !config.EnableL1Cache {
+		for _, f := range []configField{
+			{"PromotionStrategy", config.PromotionStrategy != nil},
+			{"PromotionTTL", config.PromotionTTL > 0},
+			{"KeyOwner", config.KeyOwner != nil},
+			{"MaxBatchPromotions", config.MaxBatchPromotions > 0},
+			{"L1Partitions", len(config.L1Partitions) > 0},
+			{"MemoryLimitRatio", config.MemoryLimitRatio > 0},
+			{"SweepInterval", config.SweepInterval > 0},
+			{"InternValues", config.InternValues},
+			{"GhostListSize", config.GhostListSize > 0},
+			{"L1MinTTL", config.L1MinTTL > 0},
+			{"L1MaxTTL", config.L1MaxTTL > 0},
+		} {
+			if f.set {
+				warn(f.name, "未启用L1时不起作用")
+			}
+		}
+	}
+	if !config.EnableL2Cache {
+		for _, f := range []configField{
+			{"DemotionStrategy", config.DemotionStrategy != nil && config.EvictionExporter == nil},
+			{"BroadcastChannel", config.BroadcastChannel != ""},
+			{"OnExpire", config.OnExpire != nil},
+			{"CircuitFailureThreshold", config.CircuitFailureThreshold > 0},
+			{"WriteCoalesceWindow", config.WriteCoalesceWindow > 0},
+			{"L2RateLimit", config.L2RateLimit > 0},
+			{"Envelope", config.Envelope},
+			{"L2MinTTL", config.L2MinTTL > 0},
+			{"L2MaxTTL", config.L2MaxTTL > 0},
+			{"CompatibilityMode", config.CompatibilityMode},
+			{"Region", config.Region != ""},
+			{"ReadRepairPercent", config.ReadRepairPercent > 0},
+			{"L2Quotas", len(config.L2Quotas) > 0},
+			{"SequencedWrites", config.SequencedWrites},
+		} {
+			if f.set {
+				warn(f.name, "未启用L2时不起作用")
+			}
+		}
+	}
+	if config.GutterRedisOptions != nil && config.CircuitFailureThreshold <= 0 {
+		warn("GutterRedisOptions", "未配置CircuitFailureThreshold时不会切换到gutter")
+	}
+	if action, ok := config.DegradedModes[StateHealthy]; ok && action != DegradeUseAvailable {
+		warn("DegradedModes", "为StateHealthy配置的行为在正常状态下同样生效")
+	}
+	if len(config.DegradedModes) > 0 && config.CircuitFailureThreshold <= 0 && config.CanaryInterval <= 0 && config.MemoryLimitRatio <= 0 {
+		warn("DegradedModes", "未配置CircuitFailureThreshold、CanaryInterval或MemoryLimitRatio时无法发现故障")
+	}
+	if config.BroadcastHotAccesses > 0 && config.BroadcastChannel == "" {
+		warn("BroadcastHotAccesses", "未配置BroadcastChannel时不起作用")
+	}
+	if config.Compression != CompressionNone && !config.Envelope {
+		warn("Compression", "只有启用Envelope时才压缩")
+	}
+	if config.EnableL2Cache && config.L1MinTTL > 0 {
+		warn("L1MinTTL", "启用L2时以L2MinTTL为准")
+	}
+	if !config.RedisTLS {
+		for _, f := range []configField{
+			{"RedisTLSCAFile", config.RedisTLSCAFile != ""},
+			{"RedisTLSCertFile", config.RedisTLSCertFile != ""},
+			{"RedisTLSServerName", config.RedisTLSServerName != ""},
+		} {
+			if f.set {
+				warn(f.name, "未启用RedisTLS时不起作用")
+			}
+		}
+	}
+	if config.RedisClient != nil {
+		for _, f := range []configField{
+			{"RedisOptions", config.RedisOptions != nil},
+			{"RedisUsername", config.RedisUsername != ""},
+			{"RedisTLS", config.RedisTLS},
+			{"RedisClientName", config.RedisClientName != ""},
+		} {
+			if f.set {
+				warn(f.name, "设置RedisClient时不起作用，需要在自定义客户端中配置")
+			}
+		}
+	}
+	if config.Region == "" {
+		for _, f := range []configField{
+			{"RegionRedisOptions", len(config.RegionRedisOptions) > 0},
+			{"RegionChannel", config.RegionChannel != ""},
+			{"RegionQueueSize", config.RegionQueueSize > 0},
+		} {
+			if f.set {
+				warn(f.name, "未配置Region时不起作用")
+			}
+		}
+	} else if config.RedisClient != nil && config.RegionRedisOptions[config.Region] != nil {
+		warn("RegionRedisOptions", "设置RedisClient时本区域使用RedisClient，忽略区域%q的配置", config.Region)
+	}
+	if len(config.TrackingPrefixes) > 0 && !config.ClientTracking {
+		warn("TrackingPrefixes", "未启用ClientTracking时不起作用")
+	}
+	if config.RedisProxyMode && config.EnableL2Cache {
+		for _, f := range []configField{
+			{"BroadcastChannel", config.BroadcastChannel != ""},
+			{"OnExpire", config.OnExpire != nil},
+			{"TimeSource", config.TimeSource == TimeRedis},
+			{"CompatibilityMode", config.CompatibilityMode},
+		} {
+			if f.set {
+				warn(f.name, "启用RedisProxyMode时不起作用")
+			}
+		}
+	}
+	if config.OnRefresh != nil && !config.MeasureStaleness {
+		warn("OnRefresh", "未启用MeasureStaleness时不会调用")
+	}
+	return issues
+}
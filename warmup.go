@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WarmPriority 预热任务的优先级，高优先级的任务先执行
+type WarmPriority int
+
+const (
+	WarmHigh   WarmPriority = iota // 高优先级，如首页等最热的数据
+	WarmNormal                     // 普通优先级
+	WarmLow                        // 低优先级，如长尾数据
+	warmPriorities
+)
+
+// defaultWarmConcurrency 预热的默认并发数
+const defaultWarmConcurrency = 4
+
+// WarmTask 一个键的预热任务
+type WarmTask struct {
+	Key      string
+	TTL      int64        // 过期时间(秒)
+	Priority WarmPriority // 优先级(默认WarmHigh)
+	Load     LoaderFunc   // 加载数据
+}
+
+// Warmer 按优先级预热缓存，例如部署后预热大量键
+// 配置了L2RateLimit时，预热与正常请求共享同一个L2速率限制，正常请求从不等待，预热只使用剩余的额度
+type Warmer struct {
+	cache *MultiLevelCache
+
+	// Concurrency 同时进行的预热任务数(默认4)
+	Concurrency int
+	// Overwrite 为true时即使键已在缓存中也重新加载(默认跳过已缓存的键)
+	Overwrite bool
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queues [warmPriorities][]WarmTask
+	closed bool
+
+	warmed  int64
+	skipped int64
+	failed  int64
+}
+
+// NewWarmer 创建新的预热器
+func NewWarmer(cache *MultiLevelCache) *Warmer {
+	w := &Warmer{cache: cache}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// Submit 提交预热任务，Close之后提交的任务被忽略
+func (w *Warmer) Submit(tasks ...WarmTask) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	for _, task := range tasks {
+		p := task.Priority
+		if p < WarmHigh || p >= warmPriorities {
+			p = WarmNormal
+		}
+		w.queues[p] = append(w.queues[p], task)
+	}
+	w.cond.Broadcast()
+}
+
+// Close 不再接受新任务，Run在队列中的任务完成后返回
+func (w *Warmer) Close() {
+	w.mu.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+// Run 执行预热任务，直到Close后队列为空或ctx结束
+func (w *Warmer) Run(ctx context.Context) error {
+	concurrency := w.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultWarmConcurrency
+	}
+
+	// ctx结束时唤醒等待任务的协程
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			w.cond.Broadcast()
+			w.mu.Unlock()
+		case <-done:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, ok := w.next(ctx)
+				if !ok {
+					return
+				}
+				if err := w.cache.waitL2Budget(ctx); err != nil {
+					return
+				}
+				w.warm(task)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// next 取出优先级最高的任务，队列为空时等待，Close后队列为空或ctx结束时返回false
+func (w *Warmer) next(ctx context.Context) (WarmTask, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for {
+		if ctx.Err() != nil {
+			return WarmTask{}, false
+		}
+		for p := range w.queues {
+			if len(w.queues[p]) > 0 {
+				task := w.queues[p][0]
+				w.queues[p][0] = WarmTask{}
+				w.queues[p] = w.queues[p][1:]
+				return task, true
+			}
+		}
+		if w.closed {
+			return WarmTask{}, false
+		}
+		w.cond.Wait()
+	}
+}
+
+// warm 执行一个预热任务，不经过加载准入控制，避免占用正常请求的加载名额
+func (w *Warmer) warm(task WarmTask) {
+	c := w.cache
+	key, err := c.normalizeKey(task.Key)
+	if err != nil {
+		atomic.AddInt64(&w.failed, 1)
+		return
+	}
+	if !w.Overwrite && c.cached(key) {
+		atomic.AddInt64(&w.skipped, 1)
+		return
+	}
+	var val interface{}
+	if perr := c.protect("WarmTask.Load", func() { val, err = task.Load() }); perr != nil {
+		err = perr
+	}
+	if err != nil {
+		c.logf("dancache: warm %q failed: %v", key, err)
+		atomic.AddInt64(&w.failed, 1)
+		return
+	}
+	if c.loadBlocked(key) {
+		atomic.AddInt64(&w.skipped, 1)
+		return
+	}
+	if err := c.Set(key, val, task.TTL); err != nil {
+		c.logf("dancache: warm %q failed: %v", key, err)
+		atomic.AddInt64(&w.failed, 1)
+		return
+	}
+	atomic.AddInt64(&w.warmed, 1)
+}
+
+// Pending 返回各优先级尚未执行的任务数
+func (w *Warmer) Pending() map[WarmPriority]int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := make(map[WarmPriority]int, len(w.queues))
+	for p := range w.queues {
+		pending[WarmPriority(p)] = len(w.queues[p])
+	}
+	return pending
+}
+
+// Stats 返回预热、跳过和失败的任务数
+func (w *Warmer) Stats() map[string]int64 {
+	return map[string]int64{
+		"warmed":  atomic.LoadInt64(&w.warmed),
+		"skipped": atomic.LoadInt64(&w.skipped),
+		"failed":  atomic.LoadInt64(&w.failed),
+	}
+}
+
+// cached 判断键是否已在L1或L2中，不计入命中统计也不触发升级
+func (c *MultiLevelCache) cached(key string) bool {
+	config := c.config()
+	if config.EnableL1Cache {
+		if item, ok := c.shardFor(key).load(key); ok && item.validInL1(c.nowUnix()) {
+			return true
+		}
+	}
+	if config.EnableL2Cache {
+		n, err := c.l2().Exists(c.ctx, key).Result()
+		return err == nil && n > 0
+	}
+	return false
+}
+
+// rateLimiter L2操作的令牌桶，正常请求直接扣除令牌(允许透支)，预热等后台任务等待令牌
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter 创建每秒rate个令牌的令牌桶，桶容量为1秒的令牌
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// refill 按经过的时间补充令牌，调用方持有锁
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+}
+
+// take 扣除一个令牌，不等待；透支最多1秒的令牌，之后的后台任务需要等待透支还清
+func (l *rateLimiter) take() {
+	l.mu.Lock()
+	l.refill(time.Now())
+	if l.tokens > -l.rate {
+		l.tokens--
+	}
+	l.mu.Unlock()
+}
+
+// wait 等待并扣除一个令牌
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		l.refill(time.Now())
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// waitL2Budget 后台任务在访问L2前等待速率限制的剩余额度
+func (c *MultiLevelCache) waitL2Budget(ctx context.Context) error {
+	if c.l2Limiter == nil {
+		return ctx.Err()
+	}
+	return c.l2Limiter.wait(ctx)
+}
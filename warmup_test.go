@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWarmerRunsByPriority(t *testing.T) {
+	c := newL1TestCache(t, nil)
+	if err := c.Set("cached", "old", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	task := func(key string, p WarmPriority) WarmTask {
+		return WarmTask{Key: key, TTL: 60, Priority: p, Load: func() (interface{}, error) {
+			mu.Lock()
+			order = append(order, key)
+			mu.Unlock()
+			return "warm " + key, nil
+		}}
+	}
+	w := NewWarmer(c)
+	w.Concurrency = 1
+	w.Submit(task("low", WarmLow), task("normal", WarmNormal), task("high", WarmHigh), task("cached", WarmHigh))
+	w.Submit(WarmTask{Key: "bad", Priority: WarmLow, Load: func() (interface{}, error) { return nil, errors.New("boom") }})
+	if p := w.Pending(); p[WarmHigh] != 2 || p[WarmNormal] != 1 || p[WarmLow] != 2 {
+		t.Errorf("Pending = %v", p)
+	}
+	w.Close()
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if want := []string{"high", "normal", "low"}; len(order) != len(want) || order[0] != want[0] || order[1] != want[1] || order[2] != want[2] {
+		t.Errorf("load order = %v, want %v", order, want)
+	}
+	if v, _ := c.Get("cached"); v != "old" {
+		t.Errorf("cached = %v, want the existing value kept", v)
+	}
+	if v, _ := c.Get("low"); v != "warm low" {
+		t.Errorf("low = %v, want warm low", v)
+	}
+	stats := w.Stats()
+	if stats["warmed"] != 3 || stats["skipped"] != 1 || stats["failed"] != 1 {
+		t.Errorf("Stats = %v", stats)
+	}
+}
+
+func TestWarmerStopsWithContext(t *testing.T) {
+	w := NewWarmer(newL1TestCache(t, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+}
+
+func TestRateLimiterLeavesBudgetToTraffic(t *testing.T) {
+	l := newRateLimiter(100)
+	// 正常请求不等待，透支的额度由后台任务等待还清
+	for i := 0; i < 120; i++ {
+		l.take()
+	}
+	start := time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("wait returned after %v with the budget overdrawn, want about 210ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 200; i++ {
+		l.take()
+	}
+	if err := l.wait(ctx); err != context.Canceled {
+		t.Errorf("wait with a cancelled context = %v, want context.Canceled", err)
+	}
+}